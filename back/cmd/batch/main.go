@@ -20,6 +20,10 @@ func main() {
 	}
 	defer database.Close()
 
+	if err := database.CheckMigrations(); err != nil {
+		log.Fatal("Batch aborted:", err)
+	}
+
 	if _, err := batch.Run(); err != nil {
 		log.Fatal("Batch failed:", err)
 	}
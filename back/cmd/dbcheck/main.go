@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"sherpa-backend/internal/database"
+	"sherpa-backend/internal/dbcheck"
+
+	"github.com/joho/godotenv"
+)
+
+// 整合性チェック。問題があれば終了コード 1（--repair で修復した場合は 0）
+func main() {
+	repair := flag.Bool("repair", false, "検出した問題を修復する（孤児の削除・NULL 化、論理削除、外部キーの検証）")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+	if err := database.Connect(); err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer database.Close()
+	if err := database.CheckMigrations(); err != nil {
+		log.Fatal(err)
+	}
+
+	results, err := dbcheck.Run(database.DB, *repair)
+	if err != nil {
+		log.Fatal("Check failed:", err)
+	}
+
+	problems := 0
+	for _, r := range results {
+		if r.Count == 0 {
+			continue
+		}
+		status := "NG"
+		if r.Repaired {
+			status = "repaired"
+		} else {
+			problems++
+		}
+		fmt.Printf("%-60s %6d  %s\n", r.Name, r.Count, status)
+	}
+	if problems == 0 && !*repair {
+		fmt.Println("no problems found")
+	}
+	if problems > 0 {
+		fmt.Println("run with --repair to fix")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"sherpa-backend/internal/database"
+	"sherpa-backend/internal/migrate"
+
+	"github.com/joho/godotenv"
+)
+
+const usage = `Usage: go run ./cmd/migrate [-dir migrations] <command>
+
+Commands:
+  up [N]        未適用のマイグレーションを適用（N 件まで。省略時はすべて）
+  down [N]      適用済みのマイグレーションを新しい順に N 件取り消す（省略時は 1 件）
+  status        各マイグレーションの適用状況を表示
+  create NAME   次の番号の NAME.up.sql / NAME.down.sql を -dir に作成
+`
+
+func main() {
+	dir := flag.String("dir", "migrations", "create で新しいファイルを作るディレクトリ")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// create はデータベースに接続しない
+	if args[0] == "create" {
+		if len(args) != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		paths, err := migrate.Create(*dir, args[1])
+		if err != nil {
+			log.Fatal("Failed to create migration:", err)
+		}
+		for _, p := range paths {
+			fmt.Println("created", p)
+		}
+		return
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+	if err := database.Connect(); err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer database.Close()
+
+	m, err := database.NewMigrator()
+	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		done, err := m.Up(ctx, countArg(args, 0))
+		for _, mg := range done {
+			fmt.Println("applied", mg.ID())
+		}
+		if err != nil {
+			log.Fatal("Migration failed:", err)
+		}
+		if len(done) == 0 {
+			fmt.Println("already up to date")
+		}
+	case "down":
+		done, err := m.Down(ctx, countArg(args, 1))
+		for _, mg := range done {
+			fmt.Println("rolled back", mg.ID())
+		}
+		if err != nil {
+			log.Fatal("Rollback failed:", err)
+		}
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			log.Fatal("Failed to read status:", err)
+		}
+		for _, s := range status {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-50s %s\n", s.ID(), applied)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// countArg up / down の件数引数。省略時は def
+func countArg(args []string, def int) int {
+	if len(args) < 2 {
+		return def
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil || n < 0 {
+		log.Fatalf("invalid count: %s", args[1])
+	}
+	return n
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"sherpa-backend/internal/sso/mockoidc"
+)
+
+// ローカル開発用のモック OIDC プロバイダ。
+// バックエンド側は OIDC_PROVIDERS=mock OIDC_MOCK_ISSUER=http://localhost:9998 OIDC_MOCK_CLIENT_ID=sherpa OIDC_MOCK_CLIENT_SECRET=secret で利用する。
+func main() {
+	port := getenv("MOCK_OIDC_PORT", "9998")
+	issuer := getenv("MOCK_OIDC_ISSUER", "http://localhost:"+port)
+
+	srv, err := mockoidc.New(issuer,
+		getenv("MOCK_OIDC_CLIENT_ID", "sherpa"),
+		getenv("MOCK_OIDC_CLIENT_SECRET", "secret"),
+		mockoidc.User{
+			Subject:       getenv("MOCK_OIDC_SUBJECT", "mock-user-1"),
+			Email:         getenv("MOCK_OIDC_EMAIL", "dev@example.com"),
+			EmailVerified: true,
+			Name:          getenv("MOCK_OIDC_NAME", "Dev User"),
+		})
+	if err != nil {
+		log.Fatal("Failed to start mock OIDC provider:", err)
+	}
+
+	log.Printf("Mock OIDC provider is running on %s", issuer)
+	if err := http.ListenAndServe(":"+port, srv); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func getenv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -3,9 +3,13 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"sherpa-backend/internal/database"
 	"sherpa-backend/internal/handlers"
+	"sherpa-backend/internal/mail"
+	"sherpa-backend/internal/payment"
+	"sherpa-backend/internal/repository/postgres"
 	"sherpa-backend/internal/ws"
 
 	"github.com/gin-gonic/gin"
@@ -23,15 +27,26 @@ func main() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 	defer database.Close()
+	handlers.Repos = postgres.New(database.DB)
 
-	// マイグレーション実行
-	if err := database.AutoMigrate(); err != nil {
-		log.Fatal("Failed to migrate database:", err)
+	// スキーマが最新でなければ起動しない（適用は cmd/migrate で行う）
+	if err := database.CheckMigrations(); err != nil {
+		log.Fatal("Failed to start:", err)
 	}
-	// デフォルト組織を確保
-	if err := database.EnsureDefaultOrganization(); err != nil {
-		log.Fatal("Failed to ensure default organization:", err)
+
+	// メール送信（MAIL_DRIVER: smtp / file / memory）
+	sender, err := mail.NewSenderFromEnv()
+	if err != nil {
+		log.Fatal("Failed to configure mail sender:", err)
+	}
+	mail.DefaultSender = sender
+
+	// 有料チケットの決済（PAYMENT_PROVIDER: stripe / fake）
+	provider, err := payment.NewProviderFromEnv()
+	if err != nil {
+		log.Fatal("Failed to configure payment provider:", err)
 	}
+	payment.DefaultProvider = provider
 
 	// Ginルーターの設定
 	env := os.Getenv("ENV")
@@ -60,88 +75,14 @@ func main() {
 	hub := ws.NewHub()
 	ws.DefaultHub = hub
 	go hub.Run()
+	ws.CanWatchCheckins = handlers.CanWatchCheckins
+	ws.CanJoinChannel = handlers.CanJoinChannel
+	ws.MarkRead = handlers.MarkReadWS
 
-	// ヘルスチェック
-	r.GET("/api/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "ok",
-			"message": "Sherpa Backend API is running",
-		})
-	})
-
-	// WebSocket（認証は query token）
-	r.GET("/api/ws", handlers.WSHandler(hub))
-
-	// 管理者API（X-Admin-Key または Authorization: Bearer <ADMIN_API_KEY>）
-	admin := r.Group("/api/admin")
-	admin.Use(handlers.AdminMiddleware())
-	{
-		admin.GET("/events", handlers.GetAdminEvents)
-		admin.POST("/batch/run", handlers.RunBatch)
-	}
+	// キャンセル待ちの繰り上げ期限切れを定期的に次の人へ回す
+	go handlers.RunWaitlistSweeper(time.Minute)
 
-	// APIルート
-	api := r.Group("/api")
-	{
-		// タスク関連（より具体的なルートを先に定義）
-		api.GET("/events/:id/tasks", handlers.GetTasks)
-		api.POST("/events/:id/tasks", handlers.CreateTask)
-		api.PUT("/tasks/:id", handlers.UpdateTask)
-		api.DELETE("/tasks/:id", handlers.DeleteTask)
-		api.POST("/tasks/generate", handlers.GenerateTasks)
-
-		// イベント関連
-		api.GET("/events", handlers.GetEvents)
-		api.GET("/events/:id", handlers.GetEvent)
-		api.POST("/events", handlers.CreateEvent)
-		api.PUT("/events/:id", handlers.UpdateEvent)
-		api.DELETE("/events/:id", handlers.DeleteEvent)
-		api.POST("/events/create-chat", handlers.CreateEventChat)
-
-		// 予算関連
-		api.GET("/events/:id/budgets", handlers.GetBudgets)
-		api.POST("/events/:id/budgets", handlers.CreateBudget)
-		api.PUT("/budgets/:id", handlers.UpdateBudget)
-		api.DELETE("/budgets/:id", handlers.DeleteBudget)
-
-		// 認証関連
-		api.GET("/auth/google", handlers.StartOAuth)
-		api.GET("/auth/callback", handlers.OAuthCallback)
-		api.GET("/auth/me", handlers.AuthMiddleware(), handlers.GetMe)
-
-		// ユーザー関連（search は :id より先に定義）
-		api.POST("/users", handlers.CreateUser)
-		api.GET("/users/search", handlers.AuthMiddleware(), handlers.SearchUsers)
-		api.GET("/users/:id/events", handlers.GetUserEvents)
-		api.GET("/users/:id", handlers.GetUser)
-
-		// 招待・通知（認証必須）
-		auth := api.Group("")
-		auth.Use(handlers.AuthMiddleware())
-		auth.GET("/events/:id/invitable-users", handlers.GetInvitableUsers)
-		auth.GET("/events/:id/invitations", handlers.GetEventInvitations)
-		auth.POST("/events/:id/invitations", handlers.CreateInvitation)
-		auth.POST("/invitations/:id/accept", handlers.AcceptInvitation)
-		auth.POST("/invitations/:id/decline", handlers.DeclineInvitation)
-		auth.GET("/notifications", handlers.GetNotifications)
-		auth.GET("/notifications/unread-count", handlers.GetUnreadNotificationCount)
-		auth.PATCH("/notifications/:id/read", handlers.MarkNotificationRead)
-		auth.GET("/invitations/mine", handlers.GetMyPendingInvitations)
-
-		// チャット（チャンネル・メッセージ）
-		auth.GET("/events/:id/channels", handlers.GetChannels)
-		auth.POST("/events/:id/channels", handlers.CreateChannel)
-		auth.GET("/channels/:id/messages", handlers.GetMessages)
-		auth.POST("/channels/:id/messages", handlers.CreateMessage)
-		auth.PATCH("/messages/:id", handlers.UpdateMessage)
-		auth.DELETE("/messages/:id", handlers.DeleteMessage)
-		auth.POST("/messages/:id/reactions", handlers.ToggleReaction)
-		auth.PATCH("/channels/:id", handlers.UpdateChannel)
-		auth.DELETE("/channels/:id", handlers.DeleteChannel)
-		auth.GET("/channels/:id/members", handlers.GetChannelMembers)
-		auth.POST("/channels/:id/members", handlers.AddChannelMember)
-		auth.DELETE("/channels/:id/members/:userId", handlers.RemoveChannelMember)
-	}
+	handlers.RegisterRoutes(r, hub)
 
 	// サーバー起動
 	port := os.Getenv("PORT")
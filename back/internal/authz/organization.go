@@ -0,0 +1,66 @@
+package authz
+
+import (
+	"errors"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+)
+
+// 組織スコープの操作種別
+const (
+	ActionOrgRead         Action = "org:read"
+	ActionOrgUpdate       Action = "org:update"
+	ActionOrgDelete       Action = "org:delete"
+	ActionOrgMemberManage Action = "org:member:manage"
+	ActionOrgEventCreate  Action = "org:event:create"
+)
+
+// orgPolicy アクションごとに許可される組織ロール。メンバー管理は admin 以上、組織の削除は owner のみ。
+var orgPolicy = map[Action][]string{
+	ActionOrgRead:         {models.OrgRoleOwner, models.OrgRoleAdmin, models.OrgRoleMember},
+	ActionOrgUpdate:       {models.OrgRoleOwner, models.OrgRoleAdmin},
+	ActionOrgDelete:       {models.OrgRoleOwner},
+	ActionOrgMemberManage: {models.OrgRoleOwner, models.OrgRoleAdmin},
+	ActionOrgEventCreate:  {models.OrgRoleOwner, models.OrgRoleAdmin, models.OrgRoleMember},
+}
+
+// ErrNotOrgMember ユーザーが組織のメンバーではない
+var ErrNotOrgMember = errors.New("not an organization member")
+
+// CanOrg 組織ロールがアクションを実行できるか判定する。未定義のアクションは常に拒否。
+func CanOrg(role string, action Action) bool {
+	for _, r := range orgPolicy[action] {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// OrgRole ユーザーの組織内ロールを取得する。メンバーでない（承認待ちを含む）なら ErrNotOrgMember。
+func OrgRole(orgs repository.OrganizationRepository, orgID, userID uint) (string, error) {
+	m, err := orgs.GetMember(orgID, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return "", ErrNotOrgMember
+	}
+	if err != nil {
+		return "", err
+	}
+	if m.Status != models.MemberStatusActive {
+		return "", ErrNotOrgMember
+	}
+	return m.Role, nil
+}
+
+// AuthorizeOrg ユーザーが組織に対してアクションを実行できるか判定し、ロールを返す。
+func AuthorizeOrg(orgs repository.OrganizationRepository, orgID, userID uint, action Action) (string, bool, error) {
+	role, err := OrgRole(orgs, orgID, userID)
+	if errors.Is(err, ErrNotOrgMember) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return role, CanOrg(role, action), nil
+}
@@ -0,0 +1,77 @@
+package authz
+
+import "sherpa-backend/internal/models"
+
+// Action イベントスコープの操作種別
+type Action string
+
+const (
+	ActionEventRead   Action = "event:read"
+	ActionEventUpdate Action = "event:update"
+	ActionEventDelete Action = "event:delete"
+
+	ActionTaskRead   Action = "task:read"
+	ActionTaskWrite  Action = "task:write"
+	ActionTaskDelete Action = "task:delete"
+
+	ActionBudgetRead   Action = "budget:read"
+	ActionBudgetWrite  Action = "budget:write"
+	ActionBudgetDelete Action = "budget:delete"
+
+	ActionMeetingRead   Action = "meeting:read"
+	ActionMeetingWrite  Action = "meeting:write"
+	ActionMeetingDelete Action = "meeting:delete"
+
+	ActionTicketRead        Action = "ticket:read"
+	ActionTicketWrite       Action = "ticket:write"
+	ActionTicketDelete      Action = "ticket:delete"
+	ActionParticipantRead   Action = "participant:read"
+	ActionParticipantManage Action = "participant:manage"
+	ActionOrderRefund       Action = "order:refund"
+
+	ActionInvitationManage Action = "invitation:manage"
+	ActionChannelManage    Action = "channel:manage"
+	ActionChatRead         Action = "chat:read"
+	ActionChatPost         Action = "chat:post"
+)
+
+// policy アクションごとに許可されるイベントロール。Sponsor は閲覧のみ、削除は Admin のみ。
+var policy = map[Action][]string{
+	ActionEventRead:   {models.EventRoleAdmin, models.EventRoleStaff, models.EventRoleSponsor},
+	ActionEventUpdate: {models.EventRoleAdmin},
+	ActionEventDelete: {models.EventRoleAdmin},
+
+	ActionTaskRead:   {models.EventRoleAdmin, models.EventRoleStaff, models.EventRoleSponsor},
+	ActionTaskWrite:  {models.EventRoleAdmin, models.EventRoleStaff},
+	ActionTaskDelete: {models.EventRoleAdmin},
+
+	ActionBudgetRead:   {models.EventRoleAdmin, models.EventRoleStaff, models.EventRoleSponsor},
+	ActionBudgetWrite:  {models.EventRoleAdmin, models.EventRoleStaff},
+	ActionBudgetDelete: {models.EventRoleAdmin},
+
+	ActionMeetingRead:   {models.EventRoleAdmin, models.EventRoleStaff, models.EventRoleSponsor},
+	ActionMeetingWrite:  {models.EventRoleAdmin, models.EventRoleStaff},
+	ActionMeetingDelete: {models.EventRoleAdmin},
+
+	ActionTicketRead:        {models.EventRoleAdmin, models.EventRoleStaff, models.EventRoleSponsor},
+	ActionTicketWrite:       {models.EventRoleAdmin},
+	ActionTicketDelete:      {models.EventRoleAdmin},
+	ActionParticipantRead:   {models.EventRoleAdmin, models.EventRoleStaff},
+	ActionParticipantManage: {models.EventRoleAdmin, models.EventRoleStaff},
+	ActionOrderRefund:       {models.EventRoleAdmin},
+
+	ActionInvitationManage: {models.EventRoleAdmin},
+	ActionChannelManage:    {models.EventRoleAdmin},
+	ActionChatRead:         {models.EventRoleAdmin, models.EventRoleStaff, models.EventRoleSponsor},
+	ActionChatPost:         {models.EventRoleAdmin, models.EventRoleStaff, models.EventRoleSponsor},
+}
+
+// Can ロールがアクションを実行できるか判定する。未定義のアクションは常に拒否。
+func Can(role string, action Action) bool {
+	for _, r := range policy[action] {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package authz
+
+import (
+	"errors"
+
+	"sherpa-backend/internal/repository"
+)
+
+// ErrNotEventStaff ユーザーがイベントのスタッフではない
+var ErrNotEventStaff = errors.New("not an event staff member")
+
+// EventRole ユーザーのイベント内ロールを取得する。スタッフでなければ ErrNotEventStaff。
+func EventRole(events repository.EventRepository, eventID, userID uint) (string, error) {
+	staff, err := events.GetStaff(eventID, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return "", ErrNotEventStaff
+	}
+	if err != nil {
+		return "", err
+	}
+	return staff.Role, nil
+}
+
+// Authorize ユーザーがイベントに対してアクションを実行できるか判定し、ロールを返す。
+func Authorize(events repository.EventRepository, eventID, userID uint, action Action) (string, bool, error) {
+	role, err := EventRole(events, eventID, userID)
+	if errors.Is(err, ErrNotEventStaff) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return role, Can(role, action), nil
+}
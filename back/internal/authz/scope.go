@@ -0,0 +1,89 @@
+package authz
+
+// Scope パーソナルアクセストークンに付与できる権限。
+// トークンで呼べるのはスコープが宣言されたルートだけで、イベント単位のルートはさらにロールのポリシーも満たす必要がある。
+type Scope string
+
+const (
+	ScopeReadEvents        Scope = "read:events"
+	ScopeWriteEvents       Scope = "write:events"
+	ScopeReadTasks         Scope = "read:tasks"
+	ScopeWriteTasks        Scope = "write:tasks"
+	ScopeReadBudgets       Scope = "read:budgets"
+	ScopeWriteBudgets      Scope = "write:budgets"
+	ScopeReadMeetings      Scope = "read:meetings"
+	ScopeWriteMeetings     Scope = "write:meetings"
+	ScopeReadTickets       Scope = "read:tickets"
+	ScopeWriteTickets      Scope = "write:tickets"
+	ScopeChatRead          Scope = "chat:read"
+	ScopeChatPost          Scope = "chat:post"
+	ScopeChatManage        Scope = "chat:manage"
+	ScopeReadNotifications Scope = "read:notifications"
+	ScopeReadOrgs          Scope = "read:organizations"
+	ScopeWriteOrgs         Scope = "write:organizations"
+)
+
+// Scopes 定義済みスコープ一覧（表示順）
+var Scopes = []Scope{
+	ScopeReadEvents, ScopeWriteEvents,
+	ScopeReadTasks, ScopeWriteTasks,
+	ScopeReadBudgets, ScopeWriteBudgets,
+	ScopeReadMeetings, ScopeWriteMeetings,
+	ScopeReadTickets, ScopeWriteTickets,
+	ScopeChatRead, ScopeChatPost, ScopeChatManage,
+	ScopeReadNotifications,
+	ScopeReadOrgs, ScopeWriteOrgs,
+}
+
+// actionScopes アクションを実行するのに必要なスコープ
+var actionScopes = map[Action]Scope{
+	ActionEventRead:   ScopeReadEvents,
+	ActionEventUpdate: ScopeWriteEvents,
+	ActionEventDelete: ScopeWriteEvents,
+
+	ActionTaskRead:   ScopeReadTasks,
+	ActionTaskWrite:  ScopeWriteTasks,
+	ActionTaskDelete: ScopeWriteTasks,
+
+	ActionBudgetRead:   ScopeReadBudgets,
+	ActionBudgetWrite:  ScopeWriteBudgets,
+	ActionBudgetDelete: ScopeWriteBudgets,
+
+	ActionMeetingRead:   ScopeReadMeetings,
+	ActionMeetingWrite:  ScopeWriteMeetings,
+	ActionMeetingDelete: ScopeWriteMeetings,
+
+	ActionTicketRead:        ScopeReadTickets,
+	ActionTicketWrite:       ScopeWriteTickets,
+	ActionTicketDelete:      ScopeWriteTickets,
+	ActionParticipantRead:   ScopeReadTickets,
+	ActionParticipantManage: ScopeWriteTickets,
+	ActionOrderRefund:       ScopeWriteTickets,
+
+	ActionInvitationManage: ScopeWriteEvents,
+	ActionChannelManage:    ScopeChatManage,
+	ActionChatRead:         ScopeChatRead,
+	ActionChatPost:         ScopeChatPost,
+
+	ActionOrgRead:         ScopeReadOrgs,
+	ActionOrgUpdate:       ScopeWriteOrgs,
+	ActionOrgDelete:       ScopeWriteOrgs,
+	ActionOrgMemberManage: ScopeWriteOrgs,
+	ActionOrgEventCreate:  ScopeWriteEvents,
+}
+
+// ScopeFor アクションに必要なスコープ。未定義のアクションは ok=false（トークンでは常に拒否）
+func ScopeFor(action Action) (Scope, bool) {
+	s, ok := actionScopes[action]
+	return s, ok
+}
+
+// ValidScope 定義済みのスコープか
+func ValidScope(s Scope) bool {
+	for _, v := range Scopes {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package batch
 
 import (
 	"log"
+	"time"
 
 	"sherpa-backend/internal/database"
 	"sherpa-backend/internal/models"
@@ -11,99 +12,48 @@ import (
 type CleanupResult struct {
 	ChannelsDeleted int64
 	EventsDeleted   int64
+	SessionsDeleted int64
 }
 
-// CleanupSoftDeleted 論理削除済みのチャンネルを物理削除する。
-// FK 制約のため、先に messages・channel_members を削除してから channels を削除する。
-func CleanupSoftDeleted() (*CleanupResult, error) {
-	result := &CleanupResult{}
+// sessionRetention 失効・期限切れセッションを保持する期間（一覧・監査用）
+const sessionRetention = 30 * 24 * time.Hour
 
-	// 論理削除済みチャンネルID一覧
-	var ids []uint
-	if err := database.DB.Unscoped().Model(&models.Channel{}).
-		Where("deleted_at IS NOT NULL").Pluck("id", &ids).Error; err != nil {
-		return nil, err
-	}
-	if len(ids) == 0 {
-		return result, nil
+// CleanupExpiredSessions 失効または期限切れから一定期間経過したセッションを削除する。
+func CleanupExpiredSessions() (*CleanupResult, error) {
+	cutoff := time.Now().Add(-sessionRetention)
+	tx := database.DB.Where("expires_at < ? OR revoked_at < ?", cutoff, cutoff).Delete(&models.Session{})
+	if tx.Error != nil {
+		return nil, tx.Error
 	}
+	return &CleanupResult{SessionsDeleted: tx.RowsAffected}, nil
+}
 
-	// 1. スレッド返信（parent_message_id あり）を先に物理削除
-	if err := database.DB.Unscoped().Where("channel_id IN ? AND parent_message_id IS NOT NULL", ids).
-		Delete(&models.Message{}).Error; err != nil {
-		return nil, err
-	}
-	// 2. 親メッセージを物理削除
-	if err := database.DB.Unscoped().Where("channel_id IN ?", ids).
-		Delete(&models.Message{}).Error; err != nil {
-		return nil, err
-	}
-	// 3. チャンネルメンバーを物理削除
-	if err := database.DB.Unscoped().Where("channel_id IN ?", ids).
-		Delete(&models.ChannelMember{}).Error; err != nil {
-		return nil, err
-	}
-	// 4. チャンネルを物理削除
+// CleanupSoftDeleted 論理削除済みのチャンネルを物理削除する。
+// メッセージ・メンバー・リアクションは外部キーの ON DELETE CASCADE で一緒に消える。
+func CleanupSoftDeleted() (*CleanupResult, error) {
 	tx := database.DB.Unscoped().Where("deleted_at IS NOT NULL").Delete(&models.Channel{})
 	if tx.Error != nil {
 		return nil, tx.Error
 	}
-	result.ChannelsDeleted = tx.RowsAffected
-
-	return result, nil
+	return &CleanupResult{ChannelsDeleted: tx.RowsAffected}, nil
 }
 
 // CleanupMemberLessEvents メンバー（EventStaff）が0人のイベントを物理削除する。
-// FK のため、関連する channels/messages/tasks/budgets 等を先に削除する。
+// チャンネル・タスク・予算・チケットなど配下のデータは外部キーの ON DELETE CASCADE で一緒に消える。
+// 注文・割引コードの利用記録は金額の記録なので、それらがあるイベントは残す（外部キーも RESTRICT）。
 func CleanupMemberLessEvents() (*CleanupResult, error) {
-	result := &CleanupResult{}
-
-	// スタッフが0人のイベントID（論理削除済みは除外）
-	var ids []uint
-	if err := database.DB.Model(&models.Event{}).
+	tx := database.DB.Unscoped().
 		Where("deleted_at IS NULL AND id NOT IN (SELECT event_id FROM event_staffs WHERE deleted_at IS NULL)").
-		Pluck("id", &ids).Error; err != nil {
-		return nil, err
-	}
-	if len(ids) == 0 {
-		return result, nil
-	}
-
-	// 対象イベントのチャンネルID（論理削除含む）
-	var chIds []uint
-	if err := database.DB.Unscoped().Model(&models.Channel{}).Where("event_id IN ?", ids).Pluck("id", &chIds).Error; err != nil {
-		return nil, err
-	}
-	if len(chIds) > 0 {
-		_ = database.DB.Unscoped().Where("channel_id IN ? AND parent_message_id IS NOT NULL", chIds).Delete(&models.Message{}).Error
-		_ = database.DB.Unscoped().Where("channel_id IN ?", chIds).Delete(&models.Message{}).Error
-		_ = database.DB.Unscoped().Where("channel_id IN ?", chIds).Delete(&models.ChannelMember{}).Error
-		_ = database.DB.Unscoped().Where("event_id IN ?", ids).Delete(&models.Channel{}).Error
-	}
-
-	// チケット→参加者（Ticket 参照）の順で削除（論理削除含む）
-	var ticketIds []uint
-	_ = database.DB.Unscoped().Model(&models.Ticket{}).Where("event_id IN ?", ids).Pluck("id", &ticketIds).Error
-	if len(ticketIds) > 0 {
-		_ = database.DB.Unscoped().Where("ticket_id IN ?", ticketIds).Delete(&models.EventParticipant{}).Error
-	}
-	_ = database.DB.Unscoped().Where("event_id IN ?", ids).Delete(&models.Ticket{}).Error
-
-	_ = database.DB.Unscoped().Where("event_id IN ?", ids).Delete(&models.Task{}).Error
-	_ = database.DB.Unscoped().Where("event_id IN ?", ids).Delete(&models.Budget{}).Error
-	_ = database.DB.Unscoped().Where("event_id IN ?", ids).Delete(&models.EventInvitation{}).Error
-	_ = database.DB.Unscoped().Where("event_id IN ?", ids).Delete(&models.Meeting{}).Error
-	_ = database.DB.Unscoped().Where("event_id IN ?", ids).Delete(&models.EventStaff{}).Error
-
-	tx := database.DB.Unscoped().Where("id IN ?", ids).Delete(&models.Event{})
+		Where("NOT EXISTS (SELECT 1 FROM orders o WHERE o.event_id = events.id)").
+		Where("NOT EXISTS (SELECT 1 FROM promo_code_redemptions r JOIN promo_codes p ON p.id = r.promo_code_id WHERE p.event_id = events.id)").
+		Delete(&models.Event{})
 	if tx.Error != nil {
 		return nil, tx.Error
 	}
-	result.EventsDeleted = tx.RowsAffected
-	return result, nil
+	return &CleanupResult{EventsDeleted: tx.RowsAffected}, nil
 }
 
-// Run 週次バッチのエントリポイント。論理削除済みチャンネル・メンバー0イベントの物理削除。
+// Run 週次バッチのエントリポイント。論理削除済みチャンネル・メンバー0イベント・古いセッションの物理削除。
 func Run() (*CleanupResult, error) {
 	log.Println("[batch] CleanupSoftDeleted: start")
 	res1, err := CleanupSoftDeleted()
@@ -119,8 +69,16 @@ func Run() (*CleanupResult, error) {
 	}
 	log.Printf("[batch] CleanupMemberLessEvents: done events=%d", res2.EventsDeleted)
 
+	log.Println("[batch] CleanupExpiredSessions: start")
+	res3, err := CleanupExpiredSessions()
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[batch] CleanupExpiredSessions: done sessions=%d", res3.SessionsDeleted)
+
 	return &CleanupResult{
 		ChannelsDeleted: res1.ChannelsDeleted,
 		EventsDeleted:   res2.EventsDeleted,
+		SessionsDeleted: res3.SessionsDeleted,
 	}, nil
 }
@@ -1,11 +1,14 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
-	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/migrate"
+	"sherpa-backend/migrations"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -72,50 +75,34 @@ func Connect() error {
 	return nil
 }
 
-// AutoMigrate データベースのマイグレーションを実行
-func AutoMigrate() error {
-	err := DB.AutoMigrate(
-		&models.User{},
-		&models.Organization{},
-		&models.OrganizationMember{},
-		&models.Event{},
-		&models.EventStaff{},
-		&models.EventInvitation{},
-		&models.Notification{},
-		&models.Task{},
-		&models.Budget{},
-		&models.Meeting{},
-		&models.Ticket{},
-		&models.EventParticipant{},
-		&models.Channel{},
-		&models.ChannelMember{},
-		&models.Message{},
-		&models.MessageReaction{},
-	)
-
+// NewMigrator 接続中のデータベースに migrations/ の SQL を適用する Migrator
+func NewMigrator() (*migrate.Migrator, error) {
+	sqlDB, err := DB.DB()
 	if err != nil {
-		return fmt.Errorf("failed to migrate database: %w", err)
+		return nil, err
 	}
-
-	log.Println("Database migration completed successfully")
-	return nil
+	return migrate.New(sqlDB, migrations.FS)
 }
 
-// EnsureDefaultOrganization デフォルト組織がなければ作成
-func EnsureDefaultOrganization() error {
-	var count int64
-	if err := DB.Model(&models.Organization{}).Count(&count).Error; err != nil {
+// CheckMigrations 未適用のマイグレーションがあればエラーを返す。
+// サーバーは起動時にスキーマを変更せず、`go run ./cmd/migrate up` で明示的に適用する。
+func CheckMigrations() error {
+	m, err := NewMigrator()
+	if err != nil {
 		return err
 	}
-	if count > 0 {
-		return nil
+	pending, err := m.Pending(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to read schema_migrations: %w", err)
 	}
-	desc := "デフォルト組織"
-	org := models.Organization{Name: "My Organization", Description: &desc}
-	if err := DB.Create(&org).Error; err != nil {
-		return err
+	if len(pending) > 0 {
+		ids := make([]string, 0, len(pending))
+		for _, p := range pending {
+			ids = append(ids, p.ID())
+		}
+		return fmt.Errorf("database schema is behind: %d pending migration(s) (%s); run `go run ./cmd/migrate up`",
+			len(pending), strings.Join(ids, ", "))
 	}
-	log.Println("Created default organization:", org.Name)
 	return nil
 }
 
@@ -0,0 +1,147 @@
+// Package dbcheck データの整合性チェックと修復。
+// 参照先のない行（外部キーの孤児）、論理削除済みの親に残った子、未検証の外部キーを検出する。
+package dbcheck
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Result 1つのチェックの結果
+type Result struct {
+	Name     string
+	Count    int64 // 検出した件数（修復後は修復前の件数）
+	Repaired bool
+}
+
+// check 件数を数える SQL と修復する SQL の組
+type check struct {
+	name   string
+	count  string
+	repair string
+}
+
+// foreignKey pg_constraint から読み出した単一列の外部キー
+type foreignKey struct {
+	Name      string
+	Table     string
+	Column    string
+	RefTable  string
+	RefColumn string
+	OnDelete  string // pg_constraint.confdeltype（c: CASCADE, n: SET NULL, ...）
+	Validated bool
+}
+
+const foreignKeysSQL = `SELECT c.conname AS name,
+       c.conrelid::regclass::text AS "table",
+       a.attname AS "column",
+       c.confrelid::regclass::text AS ref_table,
+       fa.attname AS ref_column,
+       c.confdeltype::text AS on_delete,
+       c.convalidated AS validated
+FROM pg_constraint c
+JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = c.conkey[1]
+JOIN pg_attribute fa ON fa.attrelid = c.confrelid AND fa.attnum = c.confkey[1]
+WHERE c.contype = 'f'
+  AND array_length(c.conkey, 1) = 1
+  AND c.connamespace = 'public'::regnamespace
+ORDER BY 2, 3`
+
+// softDeletedParents 論理削除済みの親に残った未削除の子。親の順（イベント → チャンネル）に修復する
+var softDeletedParents = []struct {
+	parent, child, column string
+}{
+	{"events", "event_staffs", "event_id"},
+	{"events", "event_invitations", "event_id"},
+	{"events", "tasks", "event_id"},
+	{"events", "budgets", "event_id"},
+	{"events", "meetings", "event_id"},
+	{"events", "tickets", "event_id"},
+	{"events", "channels", "event_id"},
+	{"channels", "channel_members", "channel_id"},
+	{"channels", "messages", "channel_id"},
+}
+
+// quote 識別子のクォート
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func loadForeignKeys(db *gorm.DB) ([]foreignKey, error) {
+	var fks []foreignKey
+	err := db.Raw(foreignKeysSQL).Scan(&fks).Error
+	return fks, err
+}
+
+// checks データベースの外部キー定義から組み立てたチェック一覧
+func checks(fks []foreignKey) []check {
+	var list []check
+	for _, fk := range fks {
+		t, col := quote(fk.Table), quote(fk.Column)
+		where := fmt.Sprintf("%s IS NOT NULL AND NOT EXISTS (SELECT 1 FROM %s r WHERE r.%s = %s.%s)",
+			col, quote(fk.RefTable), quote(fk.RefColumn), t, col)
+		repair := fmt.Sprintf("DELETE FROM %s WHERE %s", t, where)
+		if fk.OnDelete == "n" {
+			repair = fmt.Sprintf("UPDATE %s SET %s = NULL WHERE %s", t, col, where)
+		}
+		list = append(list, check{
+			name:   fmt.Sprintf("orphan %s.%s -> %s", fk.Table, fk.Column, fk.RefTable),
+			count:  fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", t, where),
+			repair: repair,
+		})
+	}
+	for _, p := range softDeletedParents {
+		where := fmt.Sprintf("deleted_at IS NULL AND %s IN (SELECT id FROM %s WHERE deleted_at IS NOT NULL)",
+			quote(p.column), quote(p.parent))
+		list = append(list, check{
+			name:   fmt.Sprintf("live %s under deleted %s", p.child, p.parent),
+			count:  fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", quote(p.child), where),
+			repair: fmt.Sprintf("UPDATE %s SET deleted_at = CURRENT_TIMESTAMP WHERE %s", quote(p.child), where),
+		})
+	}
+	return list
+}
+
+// Run すべてのチェックを実行する。repair なら1トランザクションで修復し、NOT VALID の外部キーを検証する
+func Run(db *gorm.DB, repair bool) ([]Result, error) {
+	var results []Result
+	err := db.Transaction(func(tx *gorm.DB) error {
+		fks, err := loadForeignKeys(tx)
+		if err != nil {
+			return err
+		}
+		for _, c := range checks(fks) {
+			var n int64
+			if err := tx.Raw(c.count).Scan(&n).Error; err != nil {
+				return fmt.Errorf("%s: %w", c.name, err)
+			}
+			r := Result{Name: c.name, Count: n}
+			if repair && n > 0 {
+				if err := tx.Exec(c.repair).Error; err != nil {
+					return fmt.Errorf("repair %s: %w", c.name, err)
+				}
+				r.Repaired = true
+			}
+			results = append(results, r)
+		}
+
+		// 孤児があって 0003 で検証できなかった外部キー
+		for _, fk := range fks {
+			if fk.Validated {
+				continue
+			}
+			r := Result{Name: fmt.Sprintf("unvalidated foreign key %s", fk.Name), Count: 1}
+			if repair {
+				if err := tx.Exec(fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s", quote(fk.Table), quote(fk.Name))).Error; err != nil {
+					return fmt.Errorf("validate %s: %w", fk.Name, err)
+				}
+				r.Repaired = true
+			}
+			results = append(results, r)
+		}
+		return nil
+	})
+	return results, err
+}
@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// deletedUserName 退会したユーザーの表示名。メッセージなどの投稿者はこの名前で残る
+const deletedUserName = "退会済みユーザー"
+
+// exportFile エクスポートに含める1ファイル（name.json）
+type exportFile struct {
+	name string
+	data interface{}
+}
+
+// collectExport ユーザーに紐づくデータを集める。トークンやパスワードのハッシュは含めない
+func collectExport(uid uint) ([]exportFile, error) {
+	ex, err := Repos.Accounts.Export(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenViews := make([]personalTokenView, 0, len(ex.PersonalTokens))
+	for _, t := range ex.PersonalTokens {
+		tokenViews = append(tokenViews, newPersonalTokenView(t))
+	}
+
+	return []exportFile{
+		{"profile", ex.User},
+		{"organization_memberships", ex.OrganizationMembers},
+		{"event_staff", ex.EventStaffs},
+		{"event_participations", ex.Participants},
+		{"orders", ex.Orders},
+		{"promo_code_redemptions", ex.Redemptions},
+		{"messages", ex.Messages},
+		{"reactions", ex.Reactions},
+		{"tasks", ex.Tasks},
+		{"meeting_attendances", ex.Attendances},
+		{"meeting_minutes_revisions", ex.MinutesRevisions},
+		{"notifications", ex.Notifications},
+		{"event_invitations", ex.EventInvitations},
+		{"organization_invitations", ex.OrgInvitations},
+		{"sessions", ex.Sessions},
+		{"personal_access_tokens", tokenViews},
+		{"identities", ex.Identities},
+	}, nil
+}
+
+// ExportMyData GET /api/me/export。自分に紐づくデータを ZIP（各 JSON ファイル）で返す。?format=json なら1つの JSON
+func ExportMyData(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	files, err := collectExport(uid)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	base := fmt.Sprintf("sherpa-export-%d-%s", uid, time.Now().Format("20060102"))
+	if c.Query("format") == "json" {
+		out := gin.H{"exported_at": time.Now()}
+		for _, f := range files {
+			out[f.name] = f.data
+		}
+		c.Header("Content-Disposition", `attachment; filename="`+base+`.json"`)
+		c.JSON(http.StatusOK, out)
+		return
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		b, err := json.MarshalIndent(f.data, "", "  ")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		w, err := zw.Create(base + "/" + f.name + ".json")
+		if err == nil {
+			_, err = w.Write(b)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+base+`.zip"`)
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// handoff 退会すると管理者がいなくなるイベント・組織。candidates から後任を選んでもらう
+type handoff struct {
+	ID         uint          `json:"id"`
+	Name       string        `json:"name"`
+	Candidates []models.User `json:"candidates"`
+	target     uint
+}
+
+// hasCandidate 後任として指定された userID が候補に含まれるか
+func (h *handoff) hasCandidate(userID uint) bool {
+	for _, u := range h.Candidates {
+		if u.ID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// eventHandoffs 自分が唯一の Admin で、他のスタッフが残るイベント。
+// スタッフが自分だけのイベントは退会後にメンバー0人となり、バッチ（CleanupMemberLessEvents）で削除される。
+func eventHandoffs(uid uint) ([]handoff, error) {
+	staffs, err := Repos.Events.ListStaffByUser(uid)
+	if err != nil {
+		return nil, err
+	}
+	var out []handoff
+	for _, s := range staffs {
+		if s.Role != models.EventRoleAdmin {
+			continue
+		}
+		all, err := Repos.Events.ListStaff(s.EventID)
+		if err != nil {
+			return nil, err
+		}
+		h := handoff{ID: s.EventID, Name: s.Event.Title}
+		otherAdmin := false
+		for _, o := range all {
+			if o.UserID == uid {
+				continue
+			}
+			otherAdmin = otherAdmin || o.Role == models.EventRoleAdmin
+			h.Candidates = append(h.Candidates, o.User)
+		}
+		if otherAdmin || len(h.Candidates) == 0 {
+			continue
+		}
+		out = append(out, h)
+	}
+	return out, nil
+}
+
+// organizationHandoffs 自分が最後の owner で、他のメンバーが残る組織
+func organizationHandoffs(uid uint) ([]handoff, error) {
+	members, err := Repos.Organizations.ListMemberships(uid)
+	if err != nil {
+		return nil, err
+	}
+	var out []handoff
+	for _, m := range members {
+		if m.Role != models.OrgRoleOwner || m.Status != models.MemberStatusActive {
+			continue
+		}
+		n, err := ownerCount(m.OrganizationID)
+		if err != nil {
+			return nil, err
+		}
+		if n > 1 {
+			continue
+		}
+		others, err := Repos.Organizations.ListMembers(m.OrganizationID, models.MemberStatusActive)
+		if err != nil {
+			return nil, err
+		}
+		h := handoff{ID: m.OrganizationID, Name: m.Organization.Name}
+		for _, o := range others {
+			if o.UserID != uid {
+				h.Candidates = append(h.Candidates, o.User)
+			}
+		}
+		if len(h.Candidates) == 0 {
+			continue
+		}
+		out = append(out, h)
+	}
+	return out, nil
+}
+
+// assignHandoffs transfers（ID → 後任の userID）を割り当て、後任が未指定・不正なものを返す
+func assignHandoffs(list []handoff, transfers map[uint]uint) []handoff {
+	unresolved := []handoff{}
+	for i := range list {
+		target := transfers[list[i].ID]
+		if target == 0 || !list[i].hasCandidate(target) {
+			unresolved = append(unresolved, list[i])
+			continue
+		}
+		list[i].target = target
+	}
+	return unresolved
+}
+
+type deleteAccountRequest struct {
+	Password              string        `json:"password"`
+	EventTransfers        map[uint]uint `json:"event_transfers"`        // イベントID → 新しい Admin の userID
+	OrganizationTransfers map[uint]uint `json:"organization_transfers"` // 組織ID → 新しい owner の userID
+}
+
+// DeleteMe DELETE /api/me。退会する。
+// 唯一の Admin / owner になっているイベント・組織は後任の指定が必要で、未指定なら 409 で候補を返す。
+// 投稿したメッセージは匿名化したユーザーの投稿として残し、タスクの担当は外し、参加登録は取り消して支払い済みの注文は返金し、
+// スタッフ・チャンネル・組織のメンバーシップ、リアクション、通知、外部 IdP との紐付けは削除、セッションとトークンは失効させる。
+func DeleteMe(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	var req deleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := Repos.Users.Get(uid)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.PasswordHash != nil &&
+		bcrypt.CompareHashAndPassword([]byte(*user.PasswordHash), []byte(req.Password)) != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "パスワードが正しくありません"})
+		return
+	}
+
+	events, err := eventHandoffs(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	orgs, err := organizationHandoffs(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	pendingEvents := assignHandoffs(events, req.EventTransfers)
+	pendingOrgs := assignHandoffs(orgs, req.OrganizationTransfers)
+	if len(pendingEvents) > 0 || len(pendingOrgs) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":         "唯一の管理者になっているイベント・組織があります。後任を指定してください",
+			"events":        pendingEvents,
+			"organizations": pendingOrgs,
+		})
+		return
+	}
+
+	// 参加登録は注文の返金・席の繰り上げと合わせて取り消す。返金できなければ退会しない（再実行できる）
+	if err := cancelUserRegistrations(c.Request.Context(), uid); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "参加登録を取り消せませんでした: " + err.Error()})
+		return
+	}
+
+	now := time.Now()
+	err = Repos.Transaction(func(tx *repository.Repositories) error {
+		for _, h := range events {
+			staff, err := tx.Events.GetStaff(h.ID, h.target)
+			if err != nil {
+				return err
+			}
+			staff.Role = models.EventRoleAdmin
+			if err := tx.Events.UpdateStaff(staff); err != nil {
+				return err
+			}
+		}
+		for _, h := range orgs {
+			member, err := tx.Organizations.GetMember(h.ID, h.target)
+			if err != nil {
+				return err
+			}
+			member.Role = models.OrgRoleOwner
+			if err := tx.Organizations.UpdateMember(member); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Accounts.Purge(uid, now); err != nil {
+			return err
+		}
+
+		// メッセージの投稿者として表示されるため行は残し、個人を特定できる項目だけ消す
+		user.Name = deletedUserName
+		user.Email = fmt.Sprintf("deleted-%d@users.invalid", uid)
+		user.AvatarURL = nil
+		user.PasswordHash = nil
+		user.EmailVerifiedAt = nil
+		user.ActiveOrganizationID = nil
+		if err := tx.Users.Update(user); err != nil {
+			return err
+		}
+		return tx.Users.Delete(uid)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted successfully"})
+}
@@ -116,5 +116,6 @@ func RunBatch(c *gin.Context) {
 		"ok":               true,
 		"channels_deleted": result.ChannelsDeleted,
 		"events_deleted":   result.EventsDeleted,
+		"sessions_deleted": result.SessionsDeleted,
 	})
 }
@@ -1,32 +1,38 @@
 package handlers
 
 import (
-	"context"
 	"crypto/rand"
 	"encoding/base64"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
-	"sherpa-backend/internal/database"
 	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+	"sherpa-backend/internal/sso"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 )
 
 var (
-	oauthConfig *oauth2.Config
+	ssoRegistry *sso.Registry
+	ssoErr      error
+	ssoOnce     sync.Once
 	jwtSecret   []byte
 )
 
+// oauthCookieMaxAge 認可リクエスト中の state / nonce / verifier Cookie の寿命（秒）
+const oauthCookieMaxAge = 600
+
+var oauthCookies = []string{"oauth_state", "oauth_nonce", "oauth_verifier", "oauth_provider"}
 
 func ensureJWTSecret() {
 	if len(jwtSecret) > 0 {
@@ -39,26 +45,19 @@ func ensureJWTSecret() {
 	jwtSecret = []byte(secret)
 }
 
-// ensureOAuthConfig は .env 読込後によび出す。main の godotenv.Load のあとで初回リクエスト時に初期化される。
-func ensureOAuthConfig() {
-	if oauthConfig != nil {
-		return
-	}
-	clientID := os.Getenv("GOOGLE_CLIENT_ID")
-	clientSecret := os.Getenv("GOOGLE_CLIENT_SECRET")
-	redirectURL := os.Getenv("GOOGLE_REDIRECT_URL")
-	if redirectURL == "" {
-		redirectURL = "http://localhost:3001/api/auth/callback"
-	}
-	if clientID != "" && clientSecret != "" {
-		oauthConfig = &oauth2.Config{
-			ClientID:     clientID,
-			ClientSecret: clientSecret,
-			RedirectURL:  redirectURL,
-			Scopes:       []string{"openid", "profile", "email"},
-			Endpoint:     google.Endpoint,
+// ensureSSORegistry は .env 読込後によび出す。main の godotenv.Load のあとで初回リクエスト時に初期化される。
+func ensureSSORegistry() (*sso.Registry, error) {
+	ssoOnce.Do(func() {
+		base := os.Getenv("API_BASE_URL")
+		if base == "" {
+			base = "http://localhost:3001"
 		}
-	}
+		ssoRegistry, ssoErr = sso.NewRegistry(sso.ConfigsFromEnv(strings.TrimSuffix(base, "/")))
+		if ssoErr != nil {
+			log.Printf("SSO configuration error: %v", ssoErr)
+		}
+	})
+	return ssoRegistry, ssoErr
 }
 
 func generateRandomSecret() string {
@@ -67,96 +66,155 @@ func generateRandomSecret() string {
 	return base64.URLEncoding.EncodeToString(b)
 }
 
-// GoogleUserInfo Google OAuthから取得したユーザー情報
-type GoogleUserInfo struct {
-	ID            string `json:"id"`
-	Email         string `json:"email"`
-	VerifiedEmail bool   `json:"verified_email"`
-	Name          string `json:"name"`
-	Picture       string `json:"picture"`
+// GetAuthProviders ログインに使える外部 IdP の一覧
+func GetAuthProviders(c *gin.Context) {
+	registry, _ := ensureSSORegistry()
+	providers := []gin.H{}
+	for _, p := range registry.List() {
+		providers = append(providers, gin.H{
+			"name":         p.Name(),
+			"display_name": p.DisplayName(),
+			"login_path":   "/api/auth/oidc/" + p.Name(),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"providers": providers})
 }
 
-// StartOAuth Google OAuth認証を開始。Cookie をセットして Google へリダイレクトする。
-// フロントは /api/auth/google へリダイレクトすること（fetch 不可・cross-origin で Cookie が渡らないため）。
+// StartOAuth 外部 IdP の認証を開始。state / nonce / PKCE verifier を Cookie に入れて IdP へリダイレクトする。
+// フロントは /api/auth/oidc/:provider（後方互換: /api/auth/google）へリダイレクトすること（fetch 不可・cross-origin で Cookie が渡らないため）。
 func StartOAuth(c *gin.Context) {
-	ensureOAuthConfig()
-	if oauthConfig == nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "OAuth is not configured. Set GOOGLE_CLIENT_ID and GOOGLE_CLIENT_SECRET in back/.env. See back/README.md for Google OAuth setup.",
+	name := c.Param("provider")
+	if name == "" {
+		name = "google"
+	}
+	registry, err := ensureSSORegistry()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "SSO is misconfigured: " + err.Error()})
+		return
+	}
+	provider, err := registry.Get(name)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Login provider '" + name + "' is not configured. See back/README.md for OIDC setup.",
 		})
 		return
 	}
 
 	state := generateStateToken()
-	c.SetCookie("oauth_state", state, 600, "/", "", false, true)
+	nonce := generateStateToken()
+	verifier := oauth2.GenerateVerifier()
 
-	url := oauthConfig.AuthCodeURL(state, oauth2.AccessTypeOffline)
-	c.Redirect(http.StatusFound, url)
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), state, nonce, verifier)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to contact identity provider"})
+		return
+	}
+
+	c.SetCookie("oauth_state", state, oauthCookieMaxAge, "/", "", false, true)
+	c.SetCookie("oauth_nonce", nonce, oauthCookieMaxAge, "/", "", false, true)
+	c.SetCookie("oauth_verifier", verifier, oauthCookieMaxAge, "/", "", false, true)
+	c.SetCookie("oauth_provider", name, oauthCookieMaxAge, "/", "", false, true)
+	c.Redirect(http.StatusFound, authURL)
 }
 
-// OAuthCallback OAuth認証のコールバック
+// OAuthCallback IdP からのコールバック。/api/auth/callback（後方互換）では開始時の Cookie からプロバイダを決める
 func OAuthCallback(c *gin.Context) {
-	ensureOAuthConfig()
-	if oauthConfig == nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "OAuth is not configured"})
-		return
+	storedProvider, _ := c.Cookie("oauth_provider")
+	storedState, _ := c.Cookie("oauth_state")
+	nonce, _ := c.Cookie("oauth_nonce")
+	verifier, _ := c.Cookie("oauth_verifier")
+	for _, name := range oauthCookies {
+		c.SetCookie(name, "", -1, "/", "", false, true)
 	}
 
-	code := c.Query("code")
-	state := c.Query("state")
+	name := c.Param("provider")
+	if name == "" {
+		name = storedProvider
+	}
+	if name == "" {
+		name = "google"
+	}
 
-	storedState, err := c.Cookie("oauth_state")
-	if err != nil || state != storedState {
+	state := c.Query("state")
+	if storedState == "" || state != storedState || storedProvider != name {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid state"})
 		return
 	}
-	c.SetCookie("oauth_state", "", -1, "/", "", false, true)
+	if e := c.Query("error"); e != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Identity provider returned an error: " + e})
+		return
+	}
 
-	token, err := oauthConfig.Exchange(context.Background(), code)
+	registry, err := ensureSSORegistry()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to exchange token: " + err.Error()})
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "SSO is misconfigured"})
 		return
 	}
-
-	client := oauthConfig.Client(context.Background(), token)
-	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
+	provider, err := registry.Get(name)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user info"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown login provider"})
 		return
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	identity, err := provider.Exchange(c.Request.Context(), c.Query("code"), nonce, verifier)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read user info"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to verify identity: " + err.Error()})
 		return
 	}
 
-	var googleUser GoogleUserInfo
-	if err := json.Unmarshal(body, &googleUser); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse user info"})
+	user, err := findOrCreateSSOUser(name, identity)
+	if err != nil {
+		switch {
+		case errors.Is(err, errSSOEmailMissing):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Identity provider did not return an email address"})
+		case errors.Is(err, errSSOEmailUnverified):
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "An account with this email already exists. Log in with it first; the provider's email is not verified so it cannot be linked automatically.",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		}
 		return
 	}
 
-	user, err := getOrCreateUser(googleUser)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+	// トークンは URL に載せず、短命の認可コードをフロントが POST /api/auth/exchange で引き換える
+	q := url.Values{}
+	q.Set("code", authCodes.Issue(oneTimeGrant{UserID: user.ID}))
+	c.Redirect(http.StatusFound, frontendURL()+"/auth/callback?"+q.Encode())
+}
+
+type exchangeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// ExchangeAuthCode OAuth コールバックで渡した認可コードをアクセストークン・リフレッシュトークンに交換する（1回限り・60秒）
+func ExchangeAuthCode(c *gin.Context) {
+	var req exchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+		return
+	}
+	grant, ok := authCodes.Consume(req.Code)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired code"})
 		return
 	}
 
-	jwtToken, err := generateJWT(user.ID)
+	tokens, err := issueSession(c, grant.UserID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
+	c.JSON(http.StatusOK, tokens)
+}
 
-	redirectURL := os.Getenv("FRONTEND_URL")
-	if redirectURL == "" {
-		redirectURL = "http://localhost:5173"
+// frontendURL FRONTEND_URL（末尾スラッシュなし）。リダイレクトやメール内リンクの基点
+func frontendURL() string {
+	u := os.Getenv("FRONTEND_URL")
+	if u == "" {
+		u = "http://localhost:5173"
 	}
-	redirectURL = strings.TrimSuffix(redirectURL, "/")
-
-	c.Redirect(http.StatusFound, redirectURL+"/auth/callback?token="+url.QueryEscape(jwtToken))
+	return strings.TrimSuffix(u, "/")
 }
 
 func generateStateToken() string {
@@ -165,52 +223,102 @@ func generateStateToken() string {
 	return base64.URLEncoding.EncodeToString(b)
 }
 
-func getOrCreateUser(googleUser GoogleUserInfo) (*models.User, error) {
-	var user models.User
-	err := database.DB.Where("email = ?", googleUser.Email).First(&user).Error
+var (
+	errSSOEmailMissing    = errors.New("identity has no email")
+	errSSOEmailUnverified = errors.New("identity email is not verified")
+)
 
-	if err != nil {
-		user = models.User{
-			Name:      googleUser.Name,
-			Email:     googleUser.Email,
-			AvatarURL: &googleUser.Picture,
-		}
-		if err := database.DB.Create(&user).Error; err != nil {
+// findOrCreateSSOUser (provider, subject) で紐付け済みユーザーを探す。
+// 未紐付けの場合、IdP がメール確認済みと主張するときだけ同じメールの既存ユーザーへ紐付け、いなければ新規作成する。
+func findOrCreateSSOUser(provider string, id *sso.Identity) (*models.User, error) {
+	var user models.User
+	link, err := Repos.Identities.Find(provider, id.Subject)
+	switch {
+	case err == nil:
+		u, err := Repos.Users.Get(link.UserID)
+		if err != nil {
 			return nil, err
 		}
-
-		member := models.OrganizationMember{
-			UserID:         user.ID,
-			OrganizationID: defaultOrgID,
-			Role:           "member",
+		user = *u
+	case errors.Is(err, repository.ErrNotFound):
+		if id.Email == "" {
+			return nil, errSSOEmailMissing
 		}
-		if err := database.DB.Create(&member).Error; err != nil {
-			return nil, err
+		existing, findErr := findUserByEmail(id.Email)
+		switch {
+		case findErr == nil:
+			if !id.EmailVerified {
+				return nil, errSSOEmailUnverified
+			}
+			user = *existing
+		case errors.Is(findErr, repository.ErrNotFound):
+			user = models.User{Name: id.Name, Email: normalizeEmail(id.Email)}
+			if user.Name == "" {
+				user.Name = user.Email
+			}
+			if id.Picture != "" {
+				user.AvatarURL = &id.Picture
+			}
+			if id.EmailVerified {
+				now := time.Now()
+				user.EmailVerifiedAt = &now
+			}
+			if err := Repos.Users.Create(&user); err != nil {
+				return nil, err
+			}
+			if err := placeNewUser(&user); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, findErr
 		}
-	} else {
-		if googleUser.Picture != "" && (user.AvatarURL == nil || *user.AvatarURL != googleUser.Picture) {
-			user.AvatarURL = &googleUser.Picture
-			database.DB.Save(&user)
+		link = &models.UserIdentity{UserID: user.ID, Provider: provider, Subject: id.Subject, Email: id.Email}
+		if err := Repos.Identities.Create(link); err != nil {
+			return nil, err
 		}
+	default:
+		return nil, err
 	}
 
+	changed := false
+	if id.Picture != "" && (user.AvatarURL == nil || *user.AvatarURL != id.Picture) {
+		user.AvatarURL = &id.Picture
+		changed = true
+	}
+	if id.EmailVerified && user.EmailVerifiedAt == nil && strings.EqualFold(user.Email, id.Email) {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		changed = true
+	}
+	if changed {
+		_ = Repos.Users.Update(&user)
+	}
 	return &user, nil
 }
 
-func generateJWT(userID uint) (string, error) {
+// accessClaims アクセストークンから取り出した情報
+type accessClaims struct {
+	UserID    uint
+	SessionID uint
+}
+
+// generateAccessToken セッションに紐づく短命のアクセストークンを発行する
+func generateAccessToken(userID, sessionID uint) (string, error) {
 	ensureJWTSecret()
+	now := time.Now()
 	claims := jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24 * 7).Unix(),
-		"iat":     time.Now().Unix(),
+		"sid":     sessionID,
+		"exp":     now.Add(accessTokenTTL()).Unix(),
+		"iat":     now.Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(jwtSecret)
 }
 
-// VerifyToken JWTトークンを検証してユーザーIDを取得
-func VerifyToken(tokenString string) (uint, error) {
+// parseAccessToken 署名と有効期限のみ検証する（セッション状態は見ない）
+func parseAccessToken(tokenString string) (*accessClaims, error) {
 	ensureJWTSecret()
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -220,57 +328,105 @@ func VerifyToken(tokenString string) (uint, error) {
 	})
 
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userID, ok := claims["user_id"].(float64)
-		if !ok {
-			return 0, fmt.Errorf("invalid user_id in token")
-		}
-		return uint(userID), nil
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("invalid user_id in token")
 	}
+	sid, ok := claims["sid"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("invalid sid in token")
+	}
+	return &accessClaims{UserID: uint(userID), SessionID: uint(sid)}, nil
+}
 
-	return 0, fmt.Errorf("invalid token")
+// verifyAccessToken アクセストークンを検証し、セッションが失効していないことも確認する
+func verifyAccessToken(tokenString string) (*accessClaims, error) {
+	claims, err := parseAccessToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	sess, err := Repos.Sessions.Get(claims.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("session not found")
+	}
+	if sess.UserID != claims.UserID || !sess.IsActive(time.Now()) {
+		return nil, fmt.Errorf("session revoked or expired")
+	}
+	return claims, nil
+}
+
+// VerifyToken JWTトークンを検証してユーザーIDを取得。失効済みセッションのトークンは拒否する。
+func VerifyToken(tokenString string) (uint, error) {
+	claims, err := verifyAccessToken(tokenString)
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserID, nil
 }
 
-// AuthMiddleware 認証ミドルウェア
+func bearerToken(c *gin.Context) string {
+	authHeader := c.GetHeader("Authorization")
+	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+		return authHeader[7:]
+	}
+	return authHeader
+}
+
+// AuthMiddleware 認証ミドルウェア。JWT（セッション）とパーソナルアクセストークンの両方を受け付ける
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
+		tokenString := bearerToken(c)
+		if tokenString == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
 			c.Abort()
 			return
 		}
 
-		tokenString := authHeader
-		if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-			tokenString = authHeader[7:]
+		// パーソナルアクセストークン。user_id はスコープ確認（RequireScope / RequireEventPermission）を通過したときだけセットする
+		if strings.HasPrefix(tokenString, models.PersonalAccessTokenPrefix) {
+			pat, err := verifyPersonalAccessToken(tokenString)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+				c.Abort()
+				return
+			}
+			c.Set("token_user_id", pat.UserID)
+			c.Set("token_scopes", pat.ScopeList())
+			c.Next()
+			return
 		}
 
-		userID, err := VerifyToken(tokenString)
+		claims, err := verifyAccessToken(tokenString)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
 
-		c.Set("user_id", userID)
+		c.Set("user_id", claims.UserID)
+		c.Set("session_id", claims.SessionID)
 		c.Next()
 	}
 }
 
 // GetMe 現在のユーザー情報を取得
 func GetMe(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userID, ok := userIDFrom(c)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
 	}
 
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
+	user, err := Repos.Users.Get(userID)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/sso"
+	"sherpa-backend/internal/sso/mockoidc"
+)
+
+const mockCallbackURL = "http://api.test/api/auth/oidc/mock/callback"
+
+// useMockOIDC mockoidc を立ち上げ、"mock" プロバイダとしてだけ登録した SSO レジストリに差し替える
+func useMockOIDC(t *testing.T) {
+	t.Helper()
+	var mock *mockoidc.Server
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mock.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	mock, err := mockoidc.New(srv.URL, "sherpa", "sherpa-secret", mockoidc.User{
+		Subject: "mock-alice", Email: "alice@example.com", EmailVerified: true, Name: "Alice",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry, err := sso.NewRegistry([]sso.ProviderConfig{{
+		Name:         "mock",
+		DisplayName:  "Mock",
+		Issuer:       mock.Issuer,
+		ClientID:     mock.ClientID,
+		ClientSecret: mock.ClientSecret,
+		RedirectURL:  mockCallbackURL,
+		Scopes:       []string{"openid", "profile", "email"},
+		Claims:       sso.ClaimMapping{Subject: "sub", Email: "email", EmailVerified: "email_verified", Name: "name"},
+		UsePKCE:      true,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ssoOnce.Do(func() {})
+	prev, prevErr := ssoRegistry, ssoErr
+	ssoRegistry, ssoErr = registry, nil
+	t.Cleanup(func() { ssoRegistry, ssoErr = prev, prevErr })
+}
+
+// oidcLogin IdP の承認まで進んだログイン。ブラウザが持つ Cookie と IdP から戻った code・state
+type oidcLogin struct {
+	cookies     []*http.Cookie
+	code, state string
+}
+
+// startOIDCLogin StartOAuth から IdP の承認までを進める
+func startOIDCLogin(t *testing.T, r http.Handler) oidcLogin {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/mock", nil))
+	expectStatus(t, w, http.StatusFound)
+	login := oidcLogin{cookies: w.Result().Cookies()}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d, want 302", resp.StatusCode)
+	}
+	back, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := back.Scheme + "://" + back.Host + back.Path; got != mockCallbackURL {
+		t.Fatalf("redirected to %s, want %s", got, mockCallbackURL)
+	}
+	login.code, login.state = back.Query().Get("code"), back.Query().Get("state")
+	return login
+}
+
+// callback IdP からのリダイレクトを Cookie 付きでコールバックに送る
+func (l oidcLogin) callback(r http.Handler) *httptest.ResponseRecorder {
+	q := url.Values{"code": {l.code}, "state": {l.state}}
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/mock/callback?"+q.Encode(), nil)
+	for _, c := range l.cookies {
+		req.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// withCookie name の Cookie だけ value に差し替えたコピー
+func (l oidcLogin) withCookie(name, value string) oidcLogin {
+	out := l
+	out.cookies = nil
+	for _, c := range l.cookies {
+		if c.Name == name {
+			c = &http.Cookie{Name: name, Value: value}
+		}
+		out.cookies = append(out.cookies, c)
+	}
+	return out
+}
+
+// expectError status で返り、error に substr を含むこと
+func expectError(t *testing.T, w *httptest.ResponseRecorder, status int, substr string) {
+	t.Helper()
+	expectStatus(t, w, status)
+	var res struct {
+		Error string `json:"error"`
+	}
+	decode(t, w, &res)
+	if !strings.Contains(res.Error, substr) {
+		t.Fatalf("error = %q, want it to mention %q", res.Error, substr)
+	}
+}
+
+func TestOIDCLoginEndToEnd(t *testing.T) {
+	r, _ := newTestRouter(t)
+	useMockOIDC(t)
+
+	w := startOIDCLogin(t, r).callback(r)
+	expectStatus(t, w, http.StatusFound)
+	done, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(done.String(), frontendURL()+"/auth/callback?") {
+		t.Fatalf("redirected to %s, want the frontend callback", done)
+	}
+	// ID トークンもアクセストークンも URL には載らない
+	if done.Query().Get("token") != "" || done.Query().Get("id_token") != "" {
+		t.Fatalf("redirect leaks a token: %s", done)
+	}
+	for _, c := range w.Result().Cookies() {
+		if strings.HasPrefix(c.Name, "oauth_") && c.MaxAge >= 0 {
+			t.Errorf("cookie %s should be cleared after the callback", c.Name)
+		}
+	}
+
+	code := done.Query().Get("code")
+	w = send(r, http.MethodPost, "/api/auth/exchange", "", map[string]string{"code": code})
+	expectStatus(t, w, http.StatusOK)
+	var tokens tokenResponse
+	decode(t, w, &tokens)
+	expectStatus(t, send(r, http.MethodPost, "/api/auth/exchange", "", map[string]string{"code": code}), http.StatusUnauthorized)
+
+	w = send(r, http.MethodGet, "/api/auth/me", tokens.Token, nil)
+	expectStatus(t, w, http.StatusOK)
+	var me struct {
+		User models.User `json:"user"`
+	}
+	decode(t, w, &me)
+	if me.User.Email != "alice@example.com" || me.User.EmailVerifiedAt == nil {
+		t.Fatalf("user = %+v, want verified alice@example.com", me.User)
+	}
+	link, err := Repos.Identities.Find("mock", "mock-alice")
+	if err != nil || link.UserID != me.User.ID {
+		t.Fatalf("identity = %+v, %v; want linked to user %d", link, err, me.User.ID)
+	}
+
+	// 2回目のログインは同じユーザーに紐付く
+	expectStatus(t, startOIDCLogin(t, r).callback(r), http.StatusFound)
+	if users, _ := Repos.Users.Search("alice@example.com", 10); len(users) != 1 {
+		t.Fatalf("found %d users for alice@example.com, want 1", len(users))
+	}
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	r, _ := newTestRouter(t)
+	useMockOIDC(t)
+	login := startOIDCLogin(t, r)
+
+	forged := login
+	forged.state = "forged"
+	expectError(t, forged.callback(r), http.StatusBadRequest, "Invalid state")
+	expectError(t, login.withCookie("oauth_state", "").callback(r), http.StatusBadRequest, "Invalid state")
+	// 別プロバイダで始めたログインのコールバックとしては使えない
+	expectError(t, login.withCookie("oauth_provider", "google").callback(r), http.StatusBadRequest, "Invalid state")
+}
+
+func TestOIDCCallbackRejectsNonceMismatch(t *testing.T) {
+	r, _ := newTestRouter(t)
+	useMockOIDC(t)
+	login := startOIDCLogin(t, r)
+
+	expectError(t, login.withCookie("oauth_nonce", "other-nonce").callback(r), http.StatusBadRequest, "nonce mismatch")
+	if _, err := Repos.Identities.Find("mock", "mock-alice"); err == nil {
+		t.Fatal("no identity should be linked after a nonce mismatch")
+	}
+}
+
+func TestOIDCCallbackRejectsCodeReuse(t *testing.T) {
+	r, _ := newTestRouter(t)
+	useMockOIDC(t)
+	login := startOIDCLogin(t, r)
+
+	expectStatus(t, login.callback(r), http.StatusFound)
+	expectError(t, login.callback(r), http.StatusBadRequest, "invalid_grant")
+}
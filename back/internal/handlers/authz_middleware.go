@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"sherpa-backend/internal/authz"
+	"sherpa-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errInvalidID = errors.New("invalid id")
+
+// EventIDResolver リクエストから認可対象のイベントIDを解決する
+type EventIDResolver func(c *gin.Context) (uint, error)
+
+func paramID(c *gin.Context, name string) (uint, error) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		return 0, errInvalidID
+	}
+	return uint(id), nil
+}
+
+// EventFromParam :id をイベントIDとして扱う
+func EventFromParam(c *gin.Context) (uint, error) {
+	return paramID(c, "id")
+}
+
+// EventFromTask :id のタスクが属するイベント
+func EventFromTask(c *gin.Context) (uint, error) {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return 0, err
+	}
+	task, err := Repos.Tasks.Get(id)
+	if err != nil {
+		return 0, err
+	}
+	return task.EventID, nil
+}
+
+// EventFromBudget :id の予算項目が属するイベント
+func EventFromBudget(c *gin.Context) (uint, error) {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return 0, err
+	}
+	b, err := Repos.Budgets.Get(id)
+	if err != nil {
+		return 0, err
+	}
+	return b.EventID, nil
+}
+
+// EventFromMeeting :id の会議が属するイベント
+func EventFromMeeting(c *gin.Context) (uint, error) {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return 0, err
+	}
+	m, err := Repos.Meetings.Get(id)
+	if err != nil {
+		return 0, err
+	}
+	return m.EventID, nil
+}
+
+// EventFromTicket :id のチケット種別が属するイベント
+func EventFromTicket(c *gin.Context) (uint, error) {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return 0, err
+	}
+	t, err := Repos.Tickets.Get(id)
+	if err != nil {
+		return 0, err
+	}
+	return t.EventID, nil
+}
+
+// EventFromParticipant :id の参加登録のチケットが属するイベント
+func EventFromParticipant(c *gin.Context) (uint, error) {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return 0, err
+	}
+	p, err := Repos.Tickets.GetParticipant(id)
+	if err != nil {
+		return 0, err
+	}
+	return p.Ticket.EventID, nil
+}
+
+// EventFromOrder :id の注文のイベント
+func EventFromOrder(c *gin.Context) (uint, error) {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return 0, err
+	}
+	o, err := Repos.Orders.Get(id)
+	if err != nil {
+		return 0, err
+	}
+	return o.EventID, nil
+}
+
+// EventFromPromoCode :id の割引コードのイベント
+func EventFromPromoCode(c *gin.Context) (uint, error) {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return 0, err
+	}
+	pc, err := Repos.PromoCodes.Get(id)
+	if err != nil {
+		return 0, err
+	}
+	return pc.EventID, nil
+}
+
+// EventFromChannel :id のチャンネルが属するイベント
+func EventFromChannel(c *gin.Context) (uint, error) {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return 0, err
+	}
+	ch, err := Repos.Channels.Get(id)
+	if err != nil {
+		return 0, err
+	}
+	return ch.EventID, nil
+}
+
+// EventFromMessage :id のメッセージが投稿されたチャンネルのイベント
+func EventFromMessage(c *gin.Context) (uint, error) {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return 0, err
+	}
+	msg, err := Repos.Messages.Get(id)
+	if err != nil {
+		return 0, err
+	}
+	ch, err := Repos.Channels.Get(msg.ChannelID)
+	if err != nil {
+		return 0, err
+	}
+	return ch.EventID, nil
+}
+
+// RequireEventPermission イベントスコープの認可ミドルウェア。AuthMiddleware の後に置くこと。
+// 未認証は 401、スタッフでない・権限不足・トークンのスコープ不足は 403、対象リソースが無ければ 404 を返す。
+// 通過時は event_id / event_role をコンテキストにセットする。
+func RequireEventPermission(action authz.Action, resolve EventIDResolver) gin.HandlerFunc {
+	scope, _ := authz.ScopeFor(action)
+	return func(c *gin.Context) {
+		uid, ok := authorizeScope(c, scope)
+		if !ok {
+			c.Abort()
+			return
+		}
+
+		eventID, err := resolve(c)
+		if err != nil {
+			abortResolveError(c, err)
+			return
+		}
+
+		if !authorizeEvent(c, eventID, uid, action) {
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// abortResolveError リゾルバのエラーをレスポンスにして中断する
+func abortResolveError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errInvalidID):
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+	case errors.Is(err, repository.ErrNotFound):
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// ChannelIDResolver リクエストから対象のチャンネルIDを解決する
+type ChannelIDResolver func(c *gin.Context) (uint, error)
+
+// ChannelFromParam :id をチャンネルIDとして扱う
+func ChannelFromParam(c *gin.Context) (uint, error) {
+	return paramID(c, "id")
+}
+
+// ChannelFromMessage :id のメッセージが投稿されたチャンネル
+func ChannelFromMessage(c *gin.Context) (uint, error) {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return 0, err
+	}
+	msg, err := Repos.Messages.Get(id)
+	if err != nil {
+		return 0, err
+	}
+	return msg.ChannelID, nil
+}
+
+// RequireChannelAccess 非公開チャンネルはメンバーだけを通す（公開チャンネルはイベントの権限だけで使える）。
+// RequireEventPermission の後に置くこと。非メンバーは 403 を返す。
+func RequireChannelAccess(resolve ChannelIDResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, ok := userIDFrom(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+			return
+		}
+		channelID, err := resolve(c)
+		if err != nil {
+			abortResolveError(c, err)
+			return
+		}
+		ch, err := Repos.Channels.Get(channelID)
+		if err != nil {
+			abortResolveError(c, err)
+			return
+		}
+		allowed, err := canAccessChannel(ch, uid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Not a member of this private channel"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// authorizeEvent ハンドラ内で認可を行う。拒否時はレスポンスを書き込み false を返す。
+func authorizeEvent(c *gin.Context, eventID, uid uint, action authz.Action) bool {
+	role, allowed, err := authz.Authorize(Repos.Events, eventID, uid, action)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if role == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this event"})
+		return false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permission for this event", "action": action})
+		return false
+	}
+	c.Set("event_id", eventID)
+	c.Set("event_role", role)
+	return true
+}
+
+// RequireOrgPermission 組織スコープの認可ミドルウェア。:id を組織IDとして扱う。AuthMiddleware の後に置くこと。
+// 通過時は org_id / org_role をコンテキストにセットする。
+func RequireOrgPermission(action authz.Action) gin.HandlerFunc {
+	scope, _ := authz.ScopeFor(action)
+	return func(c *gin.Context) {
+		uid, ok := authorizeScope(c, scope)
+		if !ok {
+			c.Abort()
+			return
+		}
+		orgID, err := paramID(c, "id")
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+			return
+		}
+		if !authorizeOrg(c, orgID, uid, action) {
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// authorizeOrg ハンドラ内で組織の認可を行う。拒否時はレスポンスを書き込み false を返す。
+func authorizeOrg(c *gin.Context, orgID, uid uint, action authz.Action) bool {
+	role, allowed, err := authz.AuthorizeOrg(Repos.Organizations, orgID, uid, action)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if role == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this organization"})
+		return false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permission for this organization", "action": action})
+		return false
+	}
+	c.Set("org_id", orgID)
+	c.Set("org_role", role)
+	return true
+}
+
+// RequireScope パーソナルアクセストークンでの呼び出しを許可するルートに付ける。AuthMiddleware の後に置くこと。
+// 指定スコープをすべて持つトークンだけ通す（指定なしなら任意のトークン）。JWT は素通しする。
+// このミドルウェアも RequireEventPermission も付いていないルートにはトークンでアクセスできない。
+func RequireScope(scopes ...authz.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := authorizeScope(c, scopes...); !ok {
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// authorizeScope 認証済みユーザーIDを返す。トークン認証ならスコープを確認し、通過時に user_id をセットする。
+// 拒否時はレスポンスを書き込み false を返す。
+func authorizeScope(c *gin.Context, scopes ...authz.Scope) (uint, bool) {
+	v, isToken := c.Get("token_user_id")
+	if !isToken {
+		uid, ok := userIDFrom(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		}
+		return uid, ok
+	}
+
+	granted, _ := c.Get("token_scopes")
+	have, _ := granted.([]string)
+	for _, want := range scopes {
+		if want == "" || !containsString(have, string(want)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Token lacks required scope", "scope": want})
+			return 0, false
+		}
+	}
+	uid := v.(uint)
+	c.Set("user_id", uid)
+	return uid, true
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
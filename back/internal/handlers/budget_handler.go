@@ -4,7 +4,6 @@ import (
 	"net/http"
 	"strconv"
 
-	"sherpa-backend/internal/database"
 	"sherpa-backend/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -18,8 +17,8 @@ func GetBudgets(c *gin.Context) {
 		return
 	}
 
-	var budgets []models.Budget
-	if err := database.DB.Where("event_id = ?", uint(eventID)).Find(&budgets).Error; err != nil {
+	budgets, err := Repos.Budgets.ListByEvent(uint(eventID))
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -61,7 +60,7 @@ func CreateBudget(c *gin.Context) {
 		PlannedAmount: req.PlannedAmount,
 		ActualAmount:  req.ActualAmount,
 	}
-	if err := database.DB.Create(&b).Error; err != nil {
+	if err := Repos.Budgets.Create(&b); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -84,8 +83,8 @@ func UpdateBudget(c *gin.Context) {
 		return
 	}
 
-	var b models.Budget
-	if err := database.DB.First(&b, uint(id)).Error; err != nil {
+	b, err := Repos.Budgets.Get(uint(id))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Budget not found"})
 		return
 	}
@@ -114,7 +113,7 @@ func UpdateBudget(c *gin.Context) {
 		b.ActualAmount = *req.ActualAmount
 	}
 
-	if err := database.DB.Save(&b).Error; err != nil {
+	if err := Repos.Budgets.Update(b); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -130,7 +129,7 @@ func DeleteBudget(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Delete(&models.Budget{}, uint(id)).Error; err != nil {
+	if err := Repos.Budgets.Delete(uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -1,13 +1,19 @@
 package handlers
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
-	"sherpa-backend/internal/database"
+	"sherpa-backend/internal/authz"
 	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
 	"sherpa-backend/internal/ws"
 
 	"github.com/gin-gonic/gin"
@@ -15,9 +21,9 @@ import (
 
 // ensureDefaultChannels イベントにチャンネルがなければ #全体 を作成し、全スタッフをメンバーに
 func ensureDefaultChannels(eventID uint) error {
-	var n int64
-	if database.DB.Model(&models.Channel{}).Where("event_id = ?", eventID).Count(&n); n > 0 {
-		return nil
+	n, err := Repos.Channels.CountByEvent(eventID)
+	if err != nil || n > 0 {
+		return err
 	}
 	desc := "このチャンネルはイベント全体の連絡事項を確認するための場所です。"
 	c := models.Channel{
@@ -26,21 +32,51 @@ func ensureDefaultChannels(eventID uint) error {
 		Description: &desc,
 		IsPrivate:   false,
 	}
-	if err := database.DB.Create(&c).Error; err != nil {
+	if err := Repos.Channels.Create(&c); err != nil {
+		return err
+	}
+	staffIDs, err := Repos.Events.StaffUserIDs(eventID)
+	if err != nil {
 		return err
 	}
-	var staffIDs []uint
-	database.DB.Model(&models.EventStaff{}).Where("event_id = ?", eventID).Pluck("user_id", &staffIDs)
 	for _, uid := range staffIDs {
 		m := models.ChannelMember{ChannelID: c.ID, UserID: uid}
-		_ = database.DB.Create(&m).Error
+		_ = Repos.Channels.AddMember(&m)
 	}
 	return nil
 }
 
+// canAccessChannel uid がチャンネルのメッセージを読み書きできるか。公開チャンネルは誰でも、非公開はメンバーのみ。
+// イベントの権限は別に確認すること
+func canAccessChannel(ch *models.Channel, uid uint) (bool, error) {
+	if !ch.IsPrivate {
+		return true, nil
+	}
+	_, err := Repos.Channels.GetMember(ch.ID, uid)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// CanJoinChannel userID がチャンネルを WebSocket で購読できるか（チャットを閲覧できるスタッフで、非公開チャンネルならメンバー）
+func CanJoinChannel(userID, channelID uint) bool {
+	ch, err := Repos.Channels.Get(channelID)
+	if err != nil {
+		return false
+	}
+	if _, allowed, err := authz.Authorize(Repos.Events, ch.EventID, userID, authz.ActionChatRead); err != nil || !allowed {
+		return false
+	}
+	allowed, err := canAccessChannel(ch, userID)
+	return err == nil && allowed
+}
+
 // GetChannels イベントのチャンネル一覧。チャンネルがなければ #全体 を自動作成。
+// 非公開チャンネルはロールに関係なくメンバーになっているものだけを返す（RequireChannelAccess と同じ基準）。
+// 各チャンネルの未読数・未読のメンション数と、イベント全体の未読数 unread_count も返す
 func GetChannels(c *gin.Context) {
-	_, ok := userIDFrom(c)
+	uid, ok := userIDFrom(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
@@ -56,12 +92,32 @@ func GetChannels(c *gin.Context) {
 		return
 	}
 
-	var list []models.Channel
-	if err := database.DB.Where("event_id = ?", eventID).Order("is_private ASC").Order("name ASC").Find(&list).Error; err != nil {
+	list, err := Repos.Channels.ListByEvent(uint(eventID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	memberOf, err := Repos.Channels.MemberChannelIDs(uint(eventID), uid)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"channels": list})
+	unread, byEvent, err := unreadCounts(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	visible := make([]models.Channel, 0, len(list))
+	for _, ch := range list {
+		ch.IsMember = slices.Contains(memberOf, ch.ID)
+		if ch.IsPrivate && !ch.IsMember {
+			continue
+		}
+		ch.UnreadCount = unread[ch.ID].Count
+		ch.MentionCount = unread[ch.ID].Mentions
+		visible = append(visible, ch)
+	}
+	c.JSON(http.StatusOK, gin.H{"channels": visible, "unread_count": byEvent[uint(eventID)]})
 }
 
 type createChannelRequest struct {
@@ -70,7 +126,7 @@ type createChannelRequest struct {
 	IsPrivate   bool   `json:"is_private"`
 }
 
-// CreateChannel チャンネル作成（イベントAdmin用）
+// CreateChannel チャンネル作成（イベントAdmin用・認可はルートで実施）
 func CreateChannel(c *gin.Context) {
 	uid, ok := userIDFrom(c)
 	if !ok {
@@ -83,12 +139,6 @@ func CreateChannel(c *gin.Context) {
 		return
 	}
 
-	var staff models.EventStaff
-	if err := database.DB.Where("event_id = ? AND user_id = ? AND role = ?", eventID, uid, "Admin").First(&staff).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only event admins can create channels"})
-		return
-	}
-
 	var req createChannelRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -108,17 +158,92 @@ func CreateChannel(c *gin.Context) {
 	}
 
 	ch := models.Channel{EventID: uint(eventID), Name: name, Description: desc, IsPrivate: req.IsPrivate}
-	if err := database.DB.Create(&ch).Error; err != nil {
+	if err := Repos.Channels.Create(&ch); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	m := models.ChannelMember{ChannelID: ch.ID, UserID: uid}
-	_ = database.DB.Create(&m).Error
+	_ = Repos.Channels.AddMember(&m)
 
 	c.JSON(http.StatusCreated, gin.H{"channel": ch})
 }
 
-// GetMessages チャンネルのメッセージ一覧（親のみ・スレッドは除く）
+// メッセージ履歴の1ページの件数
+const (
+	defaultMessagePageSize = 50
+	maxMessagePageSize     = 100
+)
+
+// encodeMessageCursor タイムラインの位置 (created_at, id) を不透明なカーソル文字列にする
+func encodeMessageCursor(m *models.Message) string {
+	raw := fmt.Sprintf("%d.%d", m.CreatedAt.UnixNano(), m.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeMessageCursor encodeMessageCursor の逆
+func decodeMessageCursor(s string) (repository.MessageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return repository.MessageCursor{}, errors.New("invalid cursor")
+	}
+	ns, id, ok := strings.Cut(string(raw), ".")
+	nsec, err1 := strconv.ParseInt(ns, 10, 64)
+	mid, err2 := strconv.ParseUint(id, 10, 32)
+	if !ok || err1 != nil || err2 != nil || mid == 0 {
+		return repository.MessageCursor{}, errors.New("invalid cursor")
+	}
+	return repository.MessageCursor{CreatedAt: time.Unix(0, nsec).UTC(), ID: uint(mid)}, nil
+}
+
+// messagePage メッセージ履歴の1ページ（古い順）と前後にまだあるか
+type messagePage struct {
+	messages   []models.Message
+	moreBefore bool
+	moreAfter  bool
+}
+
+// olderPage cursor より前の limit 件。nil なら最新から
+func olderPage(channelID uint, cursor *repository.MessageCursor, limit int) ([]models.Message, bool, error) {
+	list, err := Repos.Messages.ListTopLevelBefore(channelID, cursor, limit+1)
+	if err != nil || len(list) <= limit {
+		return list, false, err
+	}
+	return list[1:], true, nil
+}
+
+// newerPage cursor より後の limit 件
+func newerPage(channelID uint, cursor repository.MessageCursor, limit int) ([]models.Message, bool, error) {
+	list, err := Repos.Messages.ListTopLevelAfter(channelID, cursor, limit+1)
+	if err != nil || len(list) <= limit {
+		return list, false, err
+	}
+	return list[:limit], true, nil
+}
+
+// messageAnchor around= で指定されたメッセージ。チャンネルに出ないスレッド返信なら親メッセージを中心にする
+func messageAnchor(channelID uint, raw string) (*models.Message, error) {
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		return nil, repository.ErrNotFound
+	}
+	m, err := Repos.Messages.GetThread(uint(id))
+	if err != nil {
+		return nil, err
+	}
+	if m.ParentMessageID != nil && !m.AlsoSentToChannel {
+		if m, err = Repos.Messages.GetThread(*m.ParentMessageID); err != nil {
+			return nil, err
+		}
+	}
+	if m.ChannelID != channelID || m.IsDeleted {
+		return nil, repository.ErrNotFound
+	}
+	return m, nil
+}
+
+// GetMessages チャンネルのメッセージ履歴（スレッドの返信はチャンネルにも送信したものだけ）。
+// (created_at, id) の順で並べ、?before= / ?after= のカーソルで前後に、?around=<メッセージID> でそのメッセージの前後を取得する。
+// いずれもなければ最新の limit 件。どのページも古い順で返す
 func GetMessages(c *gin.Context) {
 	_, ok := userIDFrom(c)
 	if !ok {
@@ -131,24 +256,110 @@ func GetMessages(c *gin.Context) {
 		return
 	}
 
-	var ch models.Channel
-	if err := database.DB.First(&ch, uint(channelID)).Error; err != nil {
+	if _, err := Repos.Channels.Get(uint(channelID)); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
 		return
 	}
 
-	var list []models.Message
-	if err := database.DB.Where("channel_id = ? AND parent_message_id IS NULL AND is_deleted = ?", channelID, false).
-		Preload("User").
-		Preload("Reactions").
-		Preload("Reactions.User").
-		Order("created_at ASC").
-		Limit(100).
-		Find(&list).Error; err != nil {
+	limit := defaultMessagePageSize
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxMessagePageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxMessagePageSize)})
+			return
+		}
+		limit = n
+	}
+	before, after, around := c.Query("before"), c.Query("after"), c.Query("around")
+	given := 0
+	for _, v := range []string{before, after, around} {
+		if v != "" {
+			given++
+		}
+	}
+	if given > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "before, after and around cannot be combined"})
+		return
+	}
+
+	var (
+		page   messagePage
+		anchor *models.Message
+	)
+	switch {
+	case around != "":
+		anchor, err = messageAnchor(uint(channelID), around)
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found in this channel"})
+			return
+		}
+		if err != nil {
+			break
+		}
+		// 指定したメッセージの前に半分、後ろに残りを並べる
+		cursor := repository.CursorOf(anchor)
+		var older, newer []models.Message
+		if older, page.moreBefore, err = olderPage(uint(channelID), &cursor, limit/2); err != nil {
+			break
+		}
+		if newer, page.moreAfter, err = newerPage(uint(channelID), cursor, limit-limit/2-1); err != nil {
+			break
+		}
+		page.messages = append(append(older, *anchor), newer...)
+	case after != "":
+		cursor, cerr := decodeMessageCursor(after)
+		if cerr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": cerr.Error()})
+			return
+		}
+		if page.messages, page.moreAfter, err = newerPage(uint(channelID), cursor, limit); err != nil {
+			break
+		}
+		// カーソルの位置（そのメッセージを含む）以前にあるか
+		inclusive := repository.MessageCursor{CreatedAt: cursor.CreatedAt, ID: cursor.ID + 1}
+		var prev []models.Message
+		prev, err = Repos.Messages.ListTopLevelBefore(uint(channelID), &inclusive, 1)
+		page.moreBefore = len(prev) > 0
+	default:
+		var cursor *repository.MessageCursor
+		if before != "" {
+			cur, cerr := decodeMessageCursor(before)
+			if cerr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": cerr.Error()})
+				return
+			}
+			cursor = &cur
+		}
+		if page.messages, page.moreBefore, err = olderPage(uint(channelID), cursor, limit); err != nil {
+			break
+		}
+		if cursor != nil {
+			// カーソルの位置（そのメッセージを含む）以降にあるか
+			inclusive := repository.MessageCursor{CreatedAt: cursor.CreatedAt, ID: cursor.ID - 1}
+			var next []models.Message
+			next, err = Repos.Messages.ListTopLevelAfter(uint(channelID), inclusive, 1)
+			page.moreAfter = len(next) > 0
+		}
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"messages": list})
+
+	res := gin.H{
+		"messages":        page.messages,
+		"has_more_before": page.moreBefore,
+		"has_more_after":  page.moreAfter,
+	}
+	if n := len(page.messages); n > 0 {
+		// 古い方へは before_cursor を ?before= に、新しい方へは after_cursor を ?after= に渡す
+		res["before_cursor"] = encodeMessageCursor(&page.messages[0])
+		res["after_cursor"] = encodeMessageCursor(&page.messages[n-1])
+	}
+	if anchor != nil {
+		res["anchor_id"] = anchor.ID
+	}
+	c.JSON(http.StatusOK, res)
 }
 
 type createMessageRequest struct {
@@ -168,18 +379,11 @@ func CreateMessage(c *gin.Context) {
 		return
 	}
 
-	var ch models.Channel
-	if err := database.DB.First(&ch, uint(channelID)).Error; err != nil {
+	if _, err := Repos.Channels.Get(uint(channelID)); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
 		return
 	}
 
-	var staff models.EventStaff
-	if err := database.DB.Where("event_id = ? AND user_id = ?", ch.EventID, uid).First(&staff).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only event staff can post"})
-		return
-	}
-
 	var req createMessageRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -187,11 +391,11 @@ func CreateMessage(c *gin.Context) {
 	}
 
 	msg := models.Message{ChannelID: uint(channelID), UserID: uid, Content: req.Content}
-	if err := database.DB.Create(&msg).Error; err != nil {
+	if err := Repos.Messages.Create(&msg); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	database.DB.Preload("User").First(&msg, msg.ID)
+	applyMentions(&msg)
 
 	// 保存成功後、同じチャンネルのクライアントへ WebSocket で配信
 	if b, err := json.Marshal(msg); err == nil {
@@ -201,24 +405,15 @@ func CreateMessage(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"message": msg})
 }
 
-// requireChannelAdmin チャンネルのイベントAdminか確認。ch は Load 済みであること。
-func requireChannelAdmin(c *gin.Context, ch *models.Channel, uid uint) bool {
-	var staff models.EventStaff
-	if database.DB.Where("event_id = ? AND user_id = ? AND role = ?", ch.EventID, uid, "Admin").First(&staff).Error != nil {
-		return false
-	}
-	return true
-}
-
 type updateChannelRequest struct {
 	Name        *string `json:"name"`
 	Description *string `json:"description"`
 	IsPrivate   *bool   `json:"is_private"`
 }
 
-// UpdateChannel チャンネル更新（Admin用）
+// UpdateChannel チャンネル更新（Admin用・認可はルートで実施）
 func UpdateChannel(c *gin.Context) {
-	uid, ok := userIDFrom(c)
+	_, ok := userIDFrom(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
@@ -229,15 +424,11 @@ func UpdateChannel(c *gin.Context) {
 		return
 	}
 
-	var ch models.Channel
-	if database.DB.First(&ch, uint(channelID)).Error != nil {
+	ch, err := Repos.Channels.Get(uint(channelID))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
 		return
 	}
-	if !requireChannelAdmin(c, &ch, uid) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only event admins can update channels"})
-		return
-	}
 
 	var req updateChannelRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -259,16 +450,20 @@ func UpdateChannel(c *gin.Context) {
 	if req.IsPrivate != nil {
 		ch.IsPrivate = *req.IsPrivate
 	}
-	if database.DB.Save(&ch).Error != nil {
+	if Repos.Channels.Update(ch) != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update"})
 		return
 	}
+	if ch.IsPrivate {
+		// 非公開にしたらメンバー以外の購読を外す
+		ws.EvictFromChannel(ch.ID, func(userID uint) bool { return CanJoinChannel(userID, ch.ID) })
+	}
 	c.JSON(http.StatusOK, gin.H{"channel": ch})
 }
 
-// DeleteChannel チャンネル削除（Admin用）.#全体は削除不可
+// DeleteChannel チャンネル削除（Admin用・認可はルートで実施）.#全体は削除不可
 func DeleteChannel(c *gin.Context) {
-	uid, ok := userIDFrom(c)
+	_, ok := userIDFrom(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
@@ -279,30 +474,26 @@ func DeleteChannel(c *gin.Context) {
 		return
 	}
 
-	var ch models.Channel
-	if database.DB.First(&ch, uint(channelID)).Error != nil {
+	ch, err := Repos.Channels.Get(uint(channelID))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
 		return
 	}
-	if !requireChannelAdmin(c, &ch, uid) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only event admins can delete channels"})
-		return
-	}
 	if ch.Name == "#全体" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "#全体 チャンネルは削除できません"})
 		return
 	}
 
-	if database.DB.Delete(&ch).Error != nil {
+	if Repos.Channels.Delete(ch.ID) != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete"})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
-// GetChannelMembers チャンネルメンバー一覧
+// GetChannelMembers チャンネルメンバー一覧。非公開チャンネルはメンバーとチャンネル管理権限を持つユーザーのみ
 func GetChannelMembers(c *gin.Context) {
-	_, ok := userIDFrom(c)
+	uid, ok := userIDFrom(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
@@ -313,8 +504,25 @@ func GetChannelMembers(c *gin.Context) {
 		return
 	}
 
-	var list []models.ChannelMember
-	if database.DB.Where("channel_id = ?", channelID).Preload("User").Find(&list).Error != nil {
+	ch, err := Repos.Channels.Get(uint(channelID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+	if !authz.Can(c.GetString("event_role"), authz.ActionChannelManage) {
+		allowed, err := canAccessChannel(ch, uid)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this private channel"})
+			return
+		}
+	}
+
+	list, err := Repos.Channels.Members(uint(channelID))
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -325,9 +533,9 @@ type addChannelMemberRequest struct {
 	UserID uint `json:"user_id" binding:"required"`
 }
 
-// AddChannelMember メンバー追加（Admin用）
+// AddChannelMember メンバー追加（Admin用・認可はルートで実施）
 func AddChannelMember(c *gin.Context) {
-	uid, ok := userIDFrom(c)
+	_, ok := userIDFrom(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
@@ -338,15 +546,11 @@ func AddChannelMember(c *gin.Context) {
 		return
 	}
 
-	var ch models.Channel
-	if database.DB.First(&ch, uint(channelID)).Error != nil {
+	ch, err := Repos.Channels.Get(uint(channelID))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
 		return
 	}
-	if !requireChannelAdmin(c, &ch, uid) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only event admins can add members"})
-		return
-	}
 
 	var req addChannelMemberRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -354,30 +558,27 @@ func AddChannelMember(c *gin.Context) {
 		return
 	}
 
-	var staff models.EventStaff
-	if database.DB.Where("event_id = ? AND user_id = ?", ch.EventID, req.UserID).First(&staff).Error != nil {
+	if _, err := Repos.Events.GetStaff(ch.EventID, req.UserID); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "ユーザーはイベントスタッフではありません"})
 		return
 	}
 
-	var exist models.ChannelMember
-	if database.DB.Where("channel_id = ? AND user_id = ?", channelID, req.UserID).First(&exist).Error == nil {
+	if _, err := Repos.Channels.GetMember(uint(channelID), req.UserID); err == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "既にメンバーです"})
 		return
 	}
 
 	m := models.ChannelMember{ChannelID: uint(channelID), UserID: req.UserID}
-	if database.DB.Create(&m).Error != nil {
+	if err := Repos.Channels.AddMember(&m); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	database.DB.Preload("User").First(&m, m.ID)
 	c.JSON(http.StatusCreated, gin.H{"member": m})
 }
 
-// RemoveChannelMember メンバー削除（Admin用）
+// RemoveChannelMember メンバー削除（Admin用・認可はルートで実施）
 func RemoveChannelMember(c *gin.Context) {
-	uid, ok := userIDFrom(c)
+	_, ok := userIDFrom(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
@@ -393,19 +594,89 @@ func RemoveChannelMember(c *gin.Context) {
 		return
 	}
 
-	var ch models.Channel
-	if database.DB.First(&ch, uint(channelID)).Error != nil {
+	ch, err := Repos.Channels.Get(uint(channelID))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
 		return
 	}
-	if !requireChannelAdmin(c, &ch, uid) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only event admins can remove members"})
+	if Repos.Channels.RemoveMember(ch.ID, uint(userID)) != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove"})
 		return
 	}
+	if ch.IsPrivate {
+		ws.EvictFromChannel(ch.ID, func(u uint) bool { return u != uint(userID) })
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
 
-	if database.DB.Where("channel_id = ? AND user_id = ?", channelID, userID).Delete(&models.ChannelMember{}).Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove"})
+// JoinChannel 公開チャンネルに自分で参加する。非公開チャンネルには管理者に追加してもらう
+func JoinChannel(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
 	}
+	channelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid channel ID"})
+		return
+	}
+
+	ch, err := Repos.Channels.Get(uint(channelID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+	if ch.IsPrivate {
+		c.JSON(http.StatusForbidden, gin.H{"error": "非公開チャンネルには管理者の追加でのみ参加できます"})
+		return
+	}
+	if m, err := Repos.Channels.GetMember(ch.ID, uid); err == nil {
+		c.JSON(http.StatusOK, gin.H{"member": m})
+		return
+	}
+
+	m := models.ChannelMember{ChannelID: ch.ID, UserID: uid}
+	if err := Repos.Channels.AddMember(&m); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"member": m})
+}
+
+// LeaveChannel チャンネルから抜ける。#全体 からは抜けられない
+func LeaveChannel(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	channelID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid channel ID"})
+		return
+	}
+
+	ch, err := Repos.Channels.Get(uint(channelID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+	if ch.Name == "#全体" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "#全体 チャンネルからは退出できません"})
+		return
+	}
+	if _, err := Repos.Channels.GetMember(ch.ID, uid); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not a member of this channel"})
+		return
+	}
+
+	if Repos.Channels.RemoveMember(ch.ID, uid) != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave"})
+		return
+	}
+	if ch.IsPrivate {
+		ws.EvictFromChannel(ch.ID, func(u uint) bool { return u != uid })
+	}
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
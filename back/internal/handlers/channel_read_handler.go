@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+	"sherpa-backend/internal/ws"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errNotChannelMember    = errors.New("not a member of this channel")
+	errMessageNotInChannel = errors.New("message not found in this channel")
+)
+
+// unreadCounts uid の未読数（チャンネルごとはメンション数も）をチャンネルごと・イベントごとに集計する
+func unreadCounts(uid uint) (byChannel map[uint]repository.ChannelUnread, byEvent map[uint]int64, err error) {
+	list, err := Repos.Channels.UnreadCounts(uid)
+	if err != nil {
+		return nil, nil, err
+	}
+	byChannel, byEvent = map[uint]repository.ChannelUnread{}, map[uint]int64{}
+	for _, u := range list {
+		byChannel[u.ChannelID] = u
+		byEvent[u.EventID] += u.Count
+	}
+	return byChannel, byEvent, nil
+}
+
+// markChannelRead uid の ch の既読位置を messageID のメッセージ（0 なら現在）まで進める。
+// 本人のすべての接続へ read（未読数付き）を、位置が進んだらチャンネルへ read_receipt を配信する
+func markChannelRead(ch *models.Channel, uid, messageID uint) (gin.H, error) {
+	at := time.Now().Truncate(time.Microsecond) // DB の精度に合わせる
+	if messageID != 0 {
+		msg, err := Repos.Messages.Get(messageID)
+		if errors.Is(err, repository.ErrNotFound) || (err == nil && msg.ChannelID != ch.ID) {
+			return nil, errMessageNotInChannel
+		}
+		if err != nil {
+			return nil, err
+		}
+		at = msg.CreatedAt
+	}
+	m, err := Repos.Channels.MarkRead(ch.ID, uid, at)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, errNotChannelMember
+	}
+	if err != nil {
+		return nil, err
+	}
+	byChannel, byEvent, err := unreadCounts(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	state := gin.H{
+		"channel_id":         ch.ID,
+		"event_id":           ch.EventID,
+		"last_read_at":       m.LastReadAt,
+		"unread_count":       byChannel[ch.ID].Count,
+		"mention_count":      byChannel[ch.ID].Mentions,
+		"event_unread_count": byEvent[ch.EventID],
+	}
+	if b, err := json.Marshal(state); err == nil {
+		ws.SendEventToUser(uid, "read", b)
+	}
+	if m.LastReadAt != nil && m.LastReadAt.Equal(at) {
+		if b, err := json.Marshal(gin.H{"channel_id": ch.ID, "user_id": uid, "last_read_at": m.LastReadAt}); err == nil {
+			ws.BroadcastEventToChannel(ch.ID, "read_receipt", b)
+		}
+	}
+	return state, nil
+}
+
+// MarkReadWS WebSocket の read を処理する（ws.MarkRead に設定する）。購読できるかは呼び出し側で確認する
+func MarkReadWS(userID, channelID, messageID uint) error {
+	ch, err := Repos.Channels.Get(channelID)
+	if err != nil {
+		return err
+	}
+	_, err = markChannelRead(ch, userID, messageID)
+	return err
+}
+
+// MarkChannelRead チャンネルを既読にする。body の message_id を指定するとそのメッセージまで（省略時はすべて）
+func MarkChannelRead(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	channelID, err := paramID(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid channel ID"})
+		return
+	}
+	var req struct {
+		MessageID uint `json:"message_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ch, err := Repos.Channels.Get(channelID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+	state, err := markChannelRead(ch, uid, req.MessageID)
+	switch {
+	case errors.Is(err, errNotChannelMember):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not a member of this channel"})
+	case errors.Is(err, errMessageNotInChannel):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found in this channel"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, state)
+	}
+}
+
+// GetMyUnread 自分の未読数の合計とイベントごとの内訳（イベント切り替えのバッジ用）
+func GetMyUnread(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	_, byEvent, err := unreadCounts(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var total int64
+	events := make([]gin.H, 0, len(byEvent))
+	for _, eventID := range slices.Sorted(maps.Keys(byEvent)) {
+		total += byEvent[eventID]
+		events = append(events, gin.H{"event_id": eventID, "unread_count": byEvent[eventID]})
+	}
+	c.JSON(http.StatusOK, gin.H{"unread_count": total, "events": events})
+}
+
+// GetMessageSeenBy メッセージを既読にしたチャンネルメンバー（投稿者を除く）
+func GetMessageSeenBy(c *gin.Context) {
+	if _, ok := userIDFrom(c); !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	msgID, err := paramID(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+	msg, err := Repos.Messages.Get(msgID)
+	if err != nil || msg.IsDeleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+	members, err := Repos.Channels.Members(msg.ChannelID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	seen := make([]gin.H, 0, len(members))
+	for _, m := range members {
+		if m.UserID == msg.UserID || m.LastReadAt == nil || m.LastReadAt.Before(msg.CreatedAt) {
+			continue
+		}
+		seen = append(seen, gin.H{"user": m.User, "last_read_at": m.LastReadAt})
+	}
+	c.JSON(http.StatusOK, gin.H{"message_id": msg.ID, "seen_by": seen, "count": len(seen)})
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sherpa-backend/internal/authz"
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+	"sherpa-backend/internal/ws"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	checkinSecret     []byte
+	checkinSecretOnce sync.Once
+)
+
+// checkinKey CHECKIN_SECRET。未設定なら JWT の署名鍵から導出する（JWT_SECRET も未設定だと再起動で QR が無効になる）
+func checkinKey() []byte {
+	checkinSecretOnce.Do(func() {
+		if s := os.Getenv("CHECKIN_SECRET"); s != "" {
+			checkinSecret = []byte(s)
+			return
+		}
+		ensureJWTSecret()
+		mac := hmac.New(sha256.New, jwtSecret)
+		mac.Write([]byte("sherpa-checkin"))
+		checkinSecret = mac.Sum(nil)
+	})
+	return checkinSecret
+}
+
+// checkinSignature 参加登録・チケット・ユーザーの組に対する署名。登録を別人・別チケットに付け替えた QR は通らない
+func checkinSignature(p *models.EventParticipant) []byte {
+	mac := hmac.New(sha256.New, checkinKey())
+	fmt.Fprintf(mac, "checkin:%d:%d:%d", p.ID, p.TicketID, p.UserID)
+	return mac.Sum(nil)
+}
+
+// checkinToken QR に埋め込む文字列（"<参加登録ID>.<署名>"）
+func checkinToken(p *models.EventParticipant) string {
+	return strconv.FormatUint(uint64(p.ID), 10) + "." + base64.RawURLEncoding.EncodeToString(checkinSignature(p))
+}
+
+// verifyCheckinToken トークンを検証して参加登録（Ticket・User 付き）を返す。改ざん・不明なトークンは ok=false
+func verifyCheckinToken(token string) (*models.EventParticipant, bool, error) {
+	idPart, sigPart, found := strings.Cut(strings.TrimSpace(token), ".")
+	if !found {
+		return nil, false, nil
+	}
+	id, err := strconv.ParseUint(idPart, 10, 32)
+	if err != nil {
+		return nil, false, nil
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(sigPart)
+	if err != nil {
+		return nil, false, nil
+	}
+	p, err := Repos.Tickets.GetParticipant(uint(id))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if !hmac.Equal(sig, checkinSignature(p)) {
+		return nil, false, nil
+	}
+	return p, true, nil
+}
+
+// CanWatchCheckins userID がイベントの受付状況を WebSocket で購読できるか（参加者を閲覧できるスタッフのみ）
+func CanWatchCheckins(userID, eventID uint) bool {
+	_, allowed, err := authz.Authorize(Repos.Events, eventID, userID, authz.ActionParticipantRead)
+	return err == nil && allowed
+}
+
+// GetMyCheckinToken 確定済みの自分の参加登録の受付用 QR トークン
+func GetMyCheckinToken(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	id, err := paramID(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid registration ID"})
+		return
+	}
+	p, err := Repos.Tickets.GetParticipant(id)
+	if err != nil || p.UserID != uid {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Registration not found"})
+		return
+	}
+	if p.Status != models.ParticipantStatusConfirmed {
+		c.JSON(http.StatusConflict, gin.H{"error": "確定済みの参加登録のみ受付できます", "status": p.Status})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"token": checkinToken(p), "registration": p})
+}
+
+// CheckInParticipant 受付スタッフが QR を読み取って受付済みにする。二重の受付は 409
+func CheckInParticipant(c *gin.Context) {
+	eventID, _ := paramID(c, "id")
+	staffID, _ := userIDFrom(c)
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p, ok, err := verifyCheckinToken(req.Token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid check-in code"})
+		return
+	}
+	if p.Ticket.EventID != eventID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "このイベントの QR ではありません"})
+		return
+	}
+	if p.Status != models.ParticipantStatusConfirmed {
+		c.JSON(http.StatusConflict, gin.H{"error": "確定済みの参加登録ではありません", "participant": p})
+		return
+	}
+
+	updated, err := Repos.Tickets.CheckIn(p.ID, staffID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	p, err = Repos.Tickets.GetParticipant(p.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !updated {
+		// 同時に読み取られた場合もここに来る
+		c.JSON(http.StatusConflict, gin.H{"error": "既に受付済みです", "participant": p})
+		return
+	}
+
+	checkedIn, confirmed, err := Repos.Tickets.CountCheckIns(eventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	payload, _ := json.Marshal(gin.H{
+		"event_id":   eventID,
+		"checked_in": checkedIn,
+		"confirmed":  confirmed,
+		"participant": gin.H{
+			"id":            p.ID,
+			"user_id":       p.UserID,
+			"user_name":     p.User.Name,
+			"ticket_name":   p.Ticket.Name,
+			"checked_in_at": p.CheckedInAt,
+			"checked_in_by": p.CheckedInBy,
+		},
+	})
+	ws.BroadcastCheckinUpdate(eventID, payload)
+
+	c.JSON(http.StatusOK, gin.H{"participant": p, "checked_in": checkedIn, "confirmed": confirmed})
+}
+
+// GetCheckinStats イベントの受付済み人数と確定済みの参加者数
+func GetCheckinStats(c *gin.Context) {
+	eventID, _ := paramID(c, "id")
+	checkedIn, confirmed, err := Repos.Tickets.CountCheckIns(eventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"event_id": eventID, "checked_in": checkedIn, "confirmed": confirmed})
+}
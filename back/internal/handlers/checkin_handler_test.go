@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"sherpa-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// myCheckinToken GetMyCheckinToken で uid 自身の参加登録の QR トークンを取り出す
+func myCheckinToken(t *testing.T, uid, participantID uint) string {
+	t.Helper()
+	w := call(GetMyCheckinToken, uid, idParam("id", participantID), nil)
+	expectStatus(t, w, http.StatusOK)
+	var res struct {
+		Token string `json:"token"`
+	}
+	decode(t, w, &res)
+	return res.Token
+}
+
+type checkinResult struct {
+	Participant models.EventParticipant `json:"participant"`
+	CheckedIn   int64                   `json:"checked_in"`
+	Confirmed   int64                   `json:"confirmed"`
+}
+
+func TestCheckinTokenVerification(t *testing.T) {
+	store := useMemoryRepos(t)
+	admin := addUser(store, "admin")
+	alice := addUser(store, "alice")
+	bob := addUser(store, "bob")
+	e := addEvent(t, admin)
+	free := register(t, alice.ID, addTicket(t, e, 0, -1).ID, nil)
+	paid := register(t, bob.ID, addTicket(t, e, 1000, -1).ID, nil)
+
+	// 確定済みの本人だけがトークンを受け取れる
+	expectStatus(t, call(GetMyCheckinToken, bob.ID, idParam("id", free.ID), nil), http.StatusNotFound)
+	expectStatus(t, call(GetMyCheckinToken, bob.ID, idParam("id", paid.ID), nil), http.StatusConflict)
+	token := myCheckinToken(t, alice.ID, free.ID)
+
+	p, ok, err := verifyCheckinToken(token)
+	if err != nil || !ok || p.ID != free.ID {
+		t.Fatalf("verify(%q) = %v, %v, %v; want registration %d", token, p, ok, err, free.ID)
+	}
+	// 署名を別の登録に付け替えたもの・壊れたもの・存在しない登録は通らない
+	_, sig, _ := strings.Cut(token, ".")
+	flipped := "A" + sig[1:]
+	if sig[0] == 'A' {
+		flipped = "B" + sig[1:]
+	}
+	for _, bad := range []string{
+		fmt.Sprintf("%d.%s", paid.ID, sig),
+		fmt.Sprintf("%d.%s", free.ID, flipped),
+		fmt.Sprintf("999999.%s", sig),
+		"garbage",
+		"",
+	} {
+		if _, ok, err := verifyCheckinToken(bad); ok || err != nil {
+			t.Errorf("verify(%q) = %v, %v; want rejected", bad, ok, err)
+		}
+	}
+
+	expectStatus(t, call(CheckInParticipant, admin.ID, idParam("id", e.ID), gin.H{"token": "garbage"}), http.StatusBadRequest)
+	other := addEvent(t, admin)
+	expectStatus(t, call(CheckInParticipant, admin.ID, idParam("id", other.ID), gin.H{"token": token}), http.StatusBadRequest)
+	expectStatus(t, call(CheckInParticipant, admin.ID, idParam("id", e.ID), gin.H{"token": token}), http.StatusOK)
+}
+
+func TestCheckInTwiceConflicts(t *testing.T) {
+	store := useMemoryRepos(t)
+	admin := addUser(store, "admin")
+	alice := addUser(store, "alice")
+	bob := addUser(store, "bob")
+	e := addEvent(t, admin)
+	ticket := addTicket(t, e, 0, -1)
+	p := register(t, alice.ID, ticket.ID, nil)
+	register(t, bob.ID, ticket.ID, nil)
+	token := myCheckinToken(t, alice.ID, p.ID)
+
+	w := call(CheckInParticipant, admin.ID, idParam("id", e.ID), gin.H{"token": token})
+	expectStatus(t, w, http.StatusOK)
+	var res checkinResult
+	decode(t, w, &res)
+	if res.CheckedIn != 1 || res.Confirmed != 2 {
+		t.Fatalf("checked_in = %d, confirmed = %d; want 1, 2", res.CheckedIn, res.Confirmed)
+	}
+	if res.Participant.CheckedInAt == nil || res.Participant.CheckedInBy == nil || *res.Participant.CheckedInBy != admin.ID {
+		t.Fatalf("participant = %+v, want checked in by admin", res.Participant)
+	}
+
+	expectStatus(t, call(CheckInParticipant, admin.ID, idParam("id", e.ID), gin.H{"token": token}), http.StatusConflict)
+	checkedIn, confirmed, err := Repos.Tickets.CountCheckIns(e.ID)
+	if err != nil || checkedIn != 1 || confirmed != 2 {
+		t.Fatalf("CountCheckIns = %d, %d, %v; want 1, 2", checkedIn, confirmed, err)
+	}
+}
+
+func TestReRegisterAfterCheckInRefused(t *testing.T) {
+	store := useMemoryRepos(t)
+	admin := addUser(store, "admin")
+	alice := addUser(store, "alice")
+	e := addEvent(t, admin)
+	ticket := addTicket(t, e, 0, -1)
+	p := register(t, alice.ID, ticket.ID, nil)
+	token := myCheckinToken(t, alice.ID, p.ID)
+	expectStatus(t, call(CheckInParticipant, admin.ID, idParam("id", e.ID), gin.H{"token": token}), http.StatusOK)
+	expectStatus(t, call(CancelMyRegistration, alice.ID, idParam("id", p.ID), nil), http.StatusOK)
+
+	expectStatus(t, call(RegisterForTicket, alice.ID, idParam("id", ticket.ID), nil), http.StatusConflict)
+	got := participantStatus(t, p.ID)
+	if got.Status != models.ParticipantStatusCancelled || got.CheckedInAt == nil || got.CheckedInBy == nil {
+		t.Fatalf("registration = %s (checked in %v), want cancelled with the check-in kept", got.Status, got.CheckedInAt)
+	}
+	if checkedIn, confirmed, _ := Repos.Tickets.CountCheckIns(e.ID); checkedIn != 0 || confirmed != 0 {
+		t.Fatalf("CountCheckIns = %d, %d; want 0, 0", checkedIn, confirmed)
+	}
+}
+
+func TestRouterCheckinPermissions(t *testing.T) {
+	r, store := newTestRouter(t)
+	admin := addUser(store, "admin")
+	staff := addUser(store, "staff")
+	sponsor := addUser(store, "sponsor")
+	alice := addUser(store, "alice")
+	e := addOrgEvent(t, addOrg(store, admin), admin)
+	addStaff(t, e, staff, models.EventRoleStaff)
+	addStaff(t, e, sponsor, models.EventRoleSponsor)
+	p := register(t, alice.ID, addTicket(t, e, 0, -1).ID, nil)
+	token := myCheckinToken(t, alice.ID, p.ID)
+	checkin := fmt.Sprintf("/api/events/%d/checkin", e.ID)
+	stats := fmt.Sprintf("/api/events/%d/checkins", e.ID)
+
+	// 参加者本人やスポンサーは自分で受付できない
+	for _, u := range []*models.User{alice, sponsor} {
+		expectStatus(t, send(r, http.MethodPost, checkin, loginAs(t, u), gin.H{"token": token}), http.StatusForbidden)
+		expectStatus(t, send(r, http.MethodGet, stats, loginAs(t, u), nil), http.StatusForbidden)
+	}
+	expectStatus(t, send(r, http.MethodPost, checkin, "", gin.H{"token": token}), http.StatusUnauthorized)
+
+	expectStatus(t, send(r, http.MethodPost, checkin, loginAs(t, staff), gin.H{"token": token}), http.StatusOK)
+	w := send(r, http.MethodGet, stats, loginAs(t, admin), nil)
+	expectStatus(t, w, http.StatusOK)
+	var res checkinResult
+	decode(t, w, &res)
+	if res.CheckedIn != 1 || res.Confirmed != 1 {
+		t.Fatalf("checked_in = %d, confirmed = %d; want 1, 1", res.CheckedIn, res.Confirmed)
+	}
+}
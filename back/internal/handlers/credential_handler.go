@@ -0,0 +1,428 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"sherpa-backend/internal/mail"
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	minPasswordLength = 8
+	// bcrypt は 72 バイトを超える入力を扱えないため上限を設ける
+	maxPasswordLength = 72
+
+	verifyEmailTTL   = 24 * time.Hour
+	passwordResetTTL = time.Hour
+	magicLinkTTL     = 15 * time.Minute
+)
+
+var errInvalidEmailToken = errors.New("invalid or expired token")
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func validatePassword(pw string) string {
+	if len(pw) < minPasswordLength {
+		return "パスワードは8文字以上にしてください"
+	}
+	if len(pw) > maxPasswordLength {
+		return "パスワードは72バイト以内にしてください"
+	}
+	return ""
+}
+
+func hashPassword(pw string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(pw), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func findUserByEmail(email string) (*models.User, error) {
+	return Repos.Users.FindByEmail(normalizeEmail(email))
+}
+
+// createEmailToken 使い捨てトークンを発行し、平文を返す（DB にはハッシュのみ保存）
+func createEmailToken(userID uint, purpose models.EmailTokenPurpose, ttl time.Duration) (string, error) {
+	raw := generateRefreshToken()
+	t := models.EmailToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := Repos.EmailTokens.Create(&t); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// consumeEmailToken トークンを使用済みにしてユーザーIDを返す。同時に使われても成功は1回だけ。
+func consumeEmailToken(raw string, purpose models.EmailTokenPurpose) (uint, error) {
+	if raw == "" {
+		return 0, errInvalidEmailToken
+	}
+	t, err := Repos.EmailTokens.Consume(hashToken(raw), purpose, time.Now())
+	if errors.Is(err, repository.ErrNotFound) {
+		return 0, errInvalidEmailToken
+	}
+	if err != nil {
+		return 0, err
+	}
+	return t.UserID, nil
+}
+
+// sendUserMail リンク付きメールを送る。失敗はログのみ（アカウント列挙を防ぐため呼び出し元には返さない）
+func sendUserMail(user *models.User, subject, intro, path, token string) {
+	link := frontendURL() + path + "?token=" + url.QueryEscape(token)
+	body := user.Name + " さん\n\n" + intro + "\n\n" + link + "\n\n心当たりがない場合はこのメールを破棄してください。\n\nSherpa Event Manager"
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := mail.Send(ctx, mail.Message{To: user.Email, Subject: subject, Body: body}); err != nil {
+		log.Printf("[mail] send %q to user %d: %v", subject, user.ID, err)
+	}
+}
+
+func sendVerificationMail(user *models.User) error {
+	token, err := createEmailToken(user.ID, models.EmailTokenVerifyEmail, verifyEmailTTL)
+	if err != nil {
+		return err
+	}
+	sendUserMail(user, "【Sherpa】メールアドレスの確認", "以下のリンクからメールアドレスを確認してください（24時間有効）。", "/auth/verify-email", token)
+	return nil
+}
+
+// markEmailVerified メール確認済みにする。登録直後のユーザーはここで所属組織が決まる
+func markEmailVerified(user *models.User) error {
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	if err := Repos.Users.Update(user); err != nil {
+		return err
+	}
+	return placeUnaffiliatedUser(user)
+}
+
+type registerRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// Register メールアドレス＋パスワードでアカウントを作成し、確認メールを送る。
+// 確認が済むまでログインはできない。
+func Register(c *gin.Context) {
+	var req registerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	email := normalizeEmail(req.Email)
+	if !strings.Contains(email, "@") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "メールアドレスの形式が正しくありません"})
+		return
+	}
+	if msg := validatePassword(req.Password); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	// 既存アカウント（Google 等）にはパスワード再設定で資格情報を追加してもらう
+	if _, err := findUserByEmail(email); err == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "このメールアドレスは既に登録されています。パスワードを設定する場合はパスワード再設定を利用してください"})
+		return
+	}
+
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+	user := models.User{Name: strings.TrimSpace(req.Name), Email: email, PasswordHash: &hash}
+	if err := Repos.Users.Create(&user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ユーザー作成に失敗しました"})
+		return
+	}
+	// 所属組織はメールアドレスのドメインで決めるため、確認が済んだ時点（markEmailVerified）で割り当てる
+	if err := sendVerificationMail(&user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"user": user, "message": "確認メールを送信しました"})
+}
+
+type tokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// VerifyEmail 確認メールのトークンでメールアドレスを確認し、そのままログインさせる
+func VerifyEmail(c *gin.Context) {
+	var req tokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+	uid, err := consumeEmailToken(req.Token, models.EmailTokenVerifyEmail)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リンクが無効か期限切れです"})
+		return
+	}
+
+	user, err := Repos.Users.Get(uid)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err := markEmailVerified(user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := issueSession(c, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+type emailRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// ResendVerification 確認メールを再送する。アカウントの有無にかかわらず同じ応答を返す
+func ResendVerification(c *gin.Context) {
+	var req emailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email is required"})
+		return
+	}
+	if user, err := findUserByEmail(req.Email); err == nil && user.EmailVerifiedAt == nil {
+		if err := sendVerificationMail(user); err != nil {
+			log.Printf("[auth] resend verification: %v", err)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+type loginRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// Login メールアドレス＋パスワードでログイン
+func Login(c *gin.Context) {
+	var req loginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email and password are required"})
+		return
+	}
+
+	user, err := findUserByEmail(req.Email)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if user == nil || user.PasswordHash == nil ||
+		bcrypt.CompareHashAndPassword([]byte(*user.PasswordHash), []byte(req.Password)) != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "メールアドレスまたはパスワードが正しくありません"})
+		return
+	}
+	if user.EmailVerifiedAt == nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "メールアドレスが未確認です", "code": "email_not_verified"})
+		return
+	}
+
+	tokens, err := issueSession(c, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// ForgotPassword パスワード再設定メールを送る。パスワード未設定のアカウントもこれで設定できる
+func ForgotPassword(c *gin.Context) {
+	var req emailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email is required"})
+		return
+	}
+	if user, err := findUserByEmail(req.Email); err == nil {
+		token, err := createEmailToken(user.ID, models.EmailTokenPasswordReset, passwordResetTTL)
+		if err != nil {
+			log.Printf("[auth] create reset token: %v", err)
+		} else {
+			sendUserMail(user, "【Sherpa】パスワードの再設定", "以下のリンクから新しいパスワードを設定してください（1時間有効）。", "/auth/reset-password", token)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// ResetPassword 再設定トークンで新しいパスワードを設定する。既存セッションはすべて失効させる
+func ResetPassword(c *gin.Context) {
+	var req resetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token and password are required"})
+		return
+	}
+	if msg := validatePassword(req.Password); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	uid, err := consumeEmailToken(req.Token, models.EmailTokenPasswordReset)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リンクが無効か期限切れです"})
+		return
+	}
+
+	user, err := Repos.Users.Get(uid)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err := setPassword(user, req.Password); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// メールを受け取れた＝アドレスの所有確認済み
+	if err := markEmailVerified(user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := revokeUserSessions(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func setPassword(user *models.User, pw string) error {
+	hash, err := hashPassword(pw)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = &hash
+	return Repos.Users.Update(user)
+}
+
+// revokeUserSessions ユーザーの全セッションを失効させる
+func revokeUserSessions(userID uint) error {
+	return Repos.Sessions.RevokeAllForUser(userID, 0, time.Now())
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// ChangePassword ログイン中のユーザーがパスワードを変更・新規設定する。現在のセッション以外は失効させる
+func ChangePassword(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	var req changePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "new_password is required"})
+		return
+	}
+	if msg := validatePassword(req.NewPassword); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	user, err := Repos.Users.Get(uid)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.PasswordHash != nil &&
+		bcrypt.CompareHashAndPassword([]byte(*user.PasswordHash), []byte(req.CurrentPassword)) != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "現在のパスワードが正しくありません"})
+		return
+	}
+	if err := setPassword(user, req.NewPassword); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	sid, _ := c.Get("session_id")
+	current, _ := sid.(uint)
+	if err := Repos.Sessions.RevokeAllForUser(uid, current, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// RequestMagicLink パスワードなしログイン用のリンクをメールで送る。アカウントの有無にかかわらず同じ応答を返す
+func RequestMagicLink(c *gin.Context) {
+	var req emailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email is required"})
+		return
+	}
+	if user, err := findUserByEmail(req.Email); err == nil {
+		token, err := createEmailToken(user.ID, models.EmailTokenMagicLink, magicLinkTTL)
+		if err != nil {
+			log.Printf("[auth] create magic link: %v", err)
+		} else {
+			sendUserMail(user, "【Sherpa】ログインリンク", "以下のリンクからログインできます（15分間・1回限り有効）。", "/auth/magic-link", token)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// VerifyMagicLink マジックリンクのトークンでログインする
+func VerifyMagicLink(c *gin.Context) {
+	var req tokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+	uid, err := consumeEmailToken(req.Token, models.EmailTokenMagicLink)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "リンクが無効か期限切れです"})
+		return
+	}
+
+	user, err := Repos.Users.Get(uid)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err := markEmailVerified(user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := issueSession(c, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
@@ -1,21 +1,35 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
-	"sherpa-backend/internal/database"
+	"sherpa-backend/internal/authz"
 	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
 	"sherpa-backend/internal/ws"
 
 	"github.com/gin-gonic/gin"
 )
 
-// GetEvents イベント一覧を取得
+// GetEvents 自分がスタッフとして参加しているイベント一覧を取得。
+// 対象は所属組織のイベントのみで、?organization_id=<id>|all で絞り込める（未指定ならアクティブ組織）
 func GetEvents(c *gin.Context) {
-	var events []models.Event
-	if err := database.DB.Preload("Organization").Find(&events).Error; err != nil {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	orgScope, ok := eventOrgScope(c, uid)
+	if !ok {
+		return
+	}
+
+	events, err := Repos.Events.ListForStaff(uid, orgScope)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -31,12 +45,8 @@ func GetEvent(c *gin.Context) {
 		return
 	}
 
-	var event models.Event
-	if err := database.DB.Preload("Organization").
-		Preload("EventStaffs.User").
-		Preload("Tasks").
-		Preload("Budgets").
-		First(&event, uint(id)).Error; err != nil {
+	event, err := Repos.Events.GetDetail(uint(id))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
 	}
@@ -44,24 +54,41 @@ func GetEvent(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"event": event})
 }
 
-// CreateEventRequest イベント作成リクエスト（user_id で作成者を紐付け）
+// CreateEventRequest イベント作成リクエスト
 type CreateEventRequest struct {
-	OrganizationID uint   `json:"organization_id" binding:"required"`
+	OrganizationID uint   `json:"organization_id"` // 省略時はアクティブ組織
 	Title          string `json:"title" binding:"required"`
 	StartAt        string `json:"start_at" binding:"required"`
 	EndAt          string `json:"end_at" binding:"required"`
 	Location       string `json:"location"`
 	Status         string `json:"status"`
-	UserID         *uint  `json:"user_id"` // 作成者。指定時は EventStaff Admin として登録
 }
 
-// CreateEvent イベントを作成し、user_id があれば EventStaff として登録
+// CreateEvent イベントを作成し、作成者（ログインユーザー）を EventStaff Admin として登録。
+// 作成者は対象組織のメンバーである必要がある。
 func CreateEvent(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
 	var req CreateEventRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.OrganizationID == 0 {
+		orgID, ok := activeOrganizationID(uid)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "organization_id is required (no active organization)"})
+			return
+		}
+		req.OrganizationID = orgID
+	}
+	if !authorizeOrg(c, req.OrganizationID, uid, authz.ActionOrgEventCreate) {
+		return
+	}
 
 	startAt, err := time.Parse(time.RFC3339, req.StartAt)
 	if err != nil {
@@ -87,16 +114,19 @@ func CreateEvent(c *gin.Context) {
 		Location:       strPtr(req.Location),
 		Status:         status,
 	}
-	if err := database.DB.Create(&event).Error; err != nil {
+	// 作成者を Admin にできなければイベントも作らない
+	err = Repos.Transaction(func(tx *repository.Repositories) error {
+		if err := tx.Events.Create(&event); err != nil {
+			return err
+		}
+		staff := models.EventStaff{EventID: event.ID, UserID: uid, Role: models.EventRoleAdmin}
+		return tx.Events.AddStaff(&staff)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if req.UserID != nil && *req.UserID != 0 {
-		staff := models.EventStaff{EventID: event.ID, UserID: *req.UserID, Role: "Admin"}
-		_ = database.DB.Create(&staff).Error
-	}
-
 	c.JSON(http.StatusCreated, gin.H{"event": event})
 }
 
@@ -107,6 +137,52 @@ func strPtr(s string) *string {
 	return &s
 }
 
+// updateEventRequest イベント更新の入力。指定された項目だけを反映する（location は空文字で消す）
+type updateEventRequest struct {
+	Title    *string `json:"title"`
+	StartAt  *string `json:"start_at"`
+	EndAt    *string `json:"end_at"`
+	Location *string `json:"location"`
+	Status   *string `json:"status"`
+}
+
+func (req *updateEventRequest) apply(e *models.Event) error {
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			return errors.New("title is required")
+		}
+		e.Title = title
+	}
+	if req.StartAt != nil {
+		t, err := time.Parse(time.RFC3339, *req.StartAt)
+		if err != nil {
+			return errors.New("invalid start_at: " + err.Error())
+		}
+		e.StartAt = t
+	}
+	if req.EndAt != nil {
+		t, err := time.Parse(time.RFC3339, *req.EndAt)
+		if err != nil {
+			return errors.New("invalid end_at: " + err.Error())
+		}
+		e.EndAt = t
+	}
+	if req.Location != nil {
+		e.Location = strPtr(strings.TrimSpace(*req.Location))
+	}
+	if req.Status != nil {
+		switch s := models.EventStatus(*req.Status); s {
+		case models.EventStatusDraft, models.EventStatusPublished, models.EventStatusOngoing,
+			models.EventStatusCompleted, models.EventStatusCancelled:
+			e.Status = s
+		default:
+			return errors.New("status must be one of draft, published, ongoing, completed, cancelled")
+		}
+	}
+	return nil
+}
+
 // UpdateEvent イベントを更新
 func UpdateEvent(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -115,18 +191,23 @@ func UpdateEvent(c *gin.Context) {
 		return
 	}
 
-	var event models.Event
-	if err := database.DB.First(&event, uint(id)).Error; err != nil {
+	event, err := Repos.Events.Get(uint(id))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
 	}
 
-	if err := c.ShouldBindJSON(&event); err != nil {
+	var req updateEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.apply(event); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := database.DB.Save(&event).Error; err != nil {
+	if err := Repos.Events.Update(event); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -143,7 +224,7 @@ func DeleteEvent(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Delete(&models.Event{}, uint(id)).Error; err != nil {
+	if err := Repos.Events.Delete(uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// useMemoryRepos Repos をインメモリの Store に差し替える。テストの終わりに元に戻す
+func useMemoryRepos(t *testing.T) *memory.Store {
+	t.Helper()
+	store := memory.New()
+	prev := Repos
+	Repos = store.Repositories()
+	t.Cleanup(func() { Repos = prev })
+	return store
+}
+
+// call ハンドラを直接呼ぶ（認可はルートのミドルウェアで行うので対象外）。
+// uid が 0 以外ならログイン済みとして user_id を設定し、body が nil 以外なら JSON にして送る
+func call(h gin.HandlerFunc, uid uint, params gin.Params, body interface{}) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	var r io.Reader = http.NoBody
+	if body != nil {
+		b, _ := json.Marshal(body)
+		r = bytes.NewReader(b)
+	}
+	c.Request = httptest.NewRequest(http.MethodPost, "/", r)
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = params
+	if uid != 0 {
+		c.Set("user_id", uid)
+	}
+	h(c)
+	return w
+}
+
+// idParam :name に id を入れたパスパラメータ
+func idParam(name string, id uint) gin.Params {
+	return gin.Params{{Key: name, Value: strconv.FormatUint(uint64(id), 10)}}
+}
+
+// decode レスポンスの JSON を v に読み込む
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode %q: %v", w.Body.String(), err)
+	}
+}
+
+// expectStatus ステータスコードを確認する
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if w.Code != want {
+		t.Fatalf("status = %d, want %d: %s", w.Code, want, w.Body.String())
+	}
+}
+
+// addUser テスト用のユーザー
+func addUser(store *memory.Store, name string) *models.User {
+	u := &models.User{Name: name, Email: name + "@example.com"}
+	store.AddUser(u)
+	return u
+}
+
+// addEvent テスト用のイベント。admin を Admin として登録する
+func addEvent(t *testing.T, admin *models.User) *models.Event {
+	t.Helper()
+	e := &models.Event{Title: "Tech Meetup", Status: models.EventStatusPublished}
+	if err := Repos.Events.Create(e); err != nil {
+		t.Fatal(err)
+	}
+	if err := Repos.Events.AddStaff(&models.EventStaff{EventID: e.ID, UserID: admin.ID, Role: models.EventRoleAdmin}); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
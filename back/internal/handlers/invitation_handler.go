@@ -5,8 +5,8 @@ import (
 	"strconv"
 	"strings"
 
-	"sherpa-backend/internal/database"
 	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,7 +20,7 @@ func userIDFrom(c *gin.Context) (uint, bool) {
 	return id, ok
 }
 
-// GetInvitableUsers 同一組織内で未参加・未招待のユーザー一覧（Admin用）
+// GetInvitableUsers 同一組織内で未参加・未招待のユーザー一覧（Admin用・認可はルートで実施）
 func GetInvitableUsers(c *gin.Context) {
 	uid, ok := userIDFrom(c)
 	if !ok {
@@ -33,23 +33,23 @@ func GetInvitableUsers(c *gin.Context) {
 		return
 	}
 
-	var event models.Event
-	if err := database.DB.First(&event, uint(eventID)).Error; err != nil {
+	event, err := Repos.Events.Get(uint(eventID))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
 	}
-	var admin models.EventStaff
-	if err := database.DB.Where("event_id = ? AND user_id = ? AND role = ?", eventID, uid, "Admin").First(&admin).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only event admins can invite"})
-		return
-	}
-	orgID := event.OrganizationID
 
 	// 招待可能: 同じ組織メンバー, 既にスタッフでない, 自分以外, pending招待もなし
-	var staffIDs []uint
-	database.DB.Model(&models.EventStaff{}).Where("event_id = ?", eventID).Pluck("user_id", &staffIDs)
-	var invitedIDs []uint
-	database.DB.Model(&models.EventInvitation{}).Where("event_id = ? AND status = ?", eventID, models.InvitationStatusPending).Pluck("user_id", &invitedIDs)
+	staffIDs, err := Repos.Events.StaffUserIDs(event.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	invitedIDs, err := Repos.Invitations.PendingUserIDs(event.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	exclude := make(map[uint]bool)
 	for _, id := range staffIDs {
@@ -60,18 +60,18 @@ func GetInvitableUsers(c *gin.Context) {
 	}
 	exclude[uid] = true
 
-	var members []models.OrganizationMember
-	if err := database.DB.Where("organization_id = ?", orgID).Preload("User").Find(&members).Error; err != nil {
+	members, err := Repos.Users.ListByOrganization(event.OrganizationID)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	var users []models.User
-	for _, m := range members {
-		if exclude[m.UserID] {
+	for _, u := range members {
+		if exclude[u.ID] {
 			continue
 		}
-		users = append(users, m.User)
+		users = append(users, u)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"users": users})
@@ -84,6 +84,7 @@ type createInviteRequest struct {
 }
 
 // CreateInvitation 招待を作成し、通知を送る。user_id または email のどちらかを指定。
+// 招待者がイベントの Admin であることはルートの RequireEventPermission で確認済み。
 func CreateInvitation(c *gin.Context) {
 	uid, ok := userIDFrom(c)
 	if !ok {
@@ -106,8 +107,8 @@ func CreateInvitation(c *gin.Context) {
 	if req.UserID != nil {
 		targetUserID = *req.UserID
 	} else if req.Email != "" {
-		var u models.User
-		if err := database.DB.Where("email = ?", strings.TrimSpace(req.Email)).First(&u).Error; err != nil {
+		u, err := Repos.Users.FindByEmail(strings.TrimSpace(req.Email))
+		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "このメールアドレスのユーザーが見つかりません"})
 			return
 		}
@@ -117,27 +118,18 @@ func CreateInvitation(c *gin.Context) {
 		return
 	}
 
-	var event models.Event
-	if err := database.DB.First(&event, uint(eventID)).Error; err != nil {
+	event, err := Repos.Events.Get(uint(eventID))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
 	}
 
-	// 招待者はイベントのAdminであること
-	var staff models.EventStaff
-	if err := database.DB.Where("event_id = ? AND user_id = ? AND role = ?", eventID, uid, "Admin").First(&staff).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only event admins can invite members"})
-		return
-	}
-
 	// 既にスタッフ or 重複pendingは弾く
-	var exist models.EventStaff
-	if database.DB.Where("event_id = ? AND user_id = ?", eventID, targetUserID).First(&exist).Error == nil {
+	if _, err := Repos.Events.GetStaff(event.ID, targetUserID); err == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "ユーザーは既にイベントのスタッフです"})
 		return
 	}
-	var dup models.EventInvitation
-	if database.DB.Where("event_id = ? AND user_id = ? AND status = ?", eventID, targetUserID, models.InvitationStatusPending).First(&dup).Error == nil {
+	if _, err := Repos.Invitations.FindPending(event.ID, targetUserID); err == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "既に招待を送信しています"})
 		return
 	}
@@ -149,17 +141,14 @@ func CreateInvitation(c *gin.Context) {
 		Role:      req.Role,
 		Status:    models.InvitationStatusPending,
 	}
-	if err := database.DB.Create(&inv).Error; err != nil {
+	if err := Repos.Invitations.Create(&inv); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	database.DB.Preload("User").Preload("Inviter").First(&inv, inv.ID)
 
 	// 通知作成
-	var inviter models.User
-	database.DB.First(&inviter, uid)
 	title := "イベントへの招待"
-	body := inviter.Name + " さんから「" + event.Title + "」への招待が届きました。"
+	body := inv.Inviter.Name + " さんから「" + event.Title + "」への招待が届きました。"
 	n := models.Notification{
 		UserID:     targetUserID,
 		Type:       models.NotificationTypeEventInvite,
@@ -168,34 +157,23 @@ func CreateInvitation(c *gin.Context) {
 		RelatedID:  inv.ID,
 		RelatedTyp: "event_invitation",
 	}
-	if err := database.DB.Create(&n).Error; err != nil {
+	if err := Repos.Notifications.Create(&n); err != nil {
 		// 招待は成立しているのでログだけ
 	}
 
 	c.JSON(http.StatusCreated, gin.H{"invitation": inv})
 }
 
-// GetEventInvitations イベントの招待一覧（Admin用）
+// GetEventInvitations イベントの招待一覧（Admin用・認可はルートで実施）
 func GetEventInvitations(c *gin.Context) {
-	uid, ok := userIDFrom(c)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
-		return
-	}
 	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
 		return
 	}
 
-	var staff models.EventStaff
-	if err := database.DB.Where("event_id = ? AND user_id = ? AND role = ?", eventID, uid, "Admin").First(&staff).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only event admins can list invitations"})
-		return
-	}
-
-	var list []models.EventInvitation
-	if err := database.DB.Where("event_id = ?", eventID).Preload("User").Preload("Inviter").Find(&list).Error; err != nil {
+	list, err := Repos.Invitations.ListByEvent(uint(eventID))
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -216,8 +194,8 @@ func AcceptInvitation(c *gin.Context) {
 		return
 	}
 
-	var inv models.EventInvitation
-	if err := database.DB.First(&inv, uint(id)).Error; err != nil {
+	inv, err := Repos.Invitations.Get(uint(id))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
 		return
 	}
@@ -230,20 +208,22 @@ func AcceptInvitation(c *gin.Context) {
 		return
 	}
 
+	// 承認済みの招待とスタッフ登録は必ずセットで残す
 	inv.Status = models.InvitationStatusAccepted
-	if err := database.DB.Save(&inv).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
 	staff := models.EventStaff{EventID: inv.EventID, UserID: inv.UserID, Role: inv.Role}
-	if err := database.DB.Create(&staff).Error; err != nil {
+	err = Repos.Transaction(func(tx *repository.Repositories) error {
+		if err := tx.Invitations.Update(inv); err != nil {
+			return err
+		}
+		return tx.Events.AddStaff(&staff)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	// 関連通知を既読に
-	database.DB.Exec("UPDATE notifications SET read_at = NOW() WHERE user_id = ? AND related_type = ? AND related_id = ?", uid, "event_invitation", inv.ID)
+	Repos.Notifications.MarkRelatedRead(uid, "event_invitation", inv.ID)
 
 	c.JSON(http.StatusOK, gin.H{"invitation": inv, "event_staff": staff})
 }
@@ -261,8 +241,8 @@ func DeclineInvitation(c *gin.Context) {
 		return
 	}
 
-	var inv models.EventInvitation
-	if err := database.DB.First(&inv, uint(id)).Error; err != nil {
+	inv, err := Repos.Invitations.Get(uint(id))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
 		return
 	}
@@ -276,12 +256,12 @@ func DeclineInvitation(c *gin.Context) {
 	}
 
 	inv.Status = models.InvitationStatusDeclined
-	if err := database.DB.Save(&inv).Error; err != nil {
+	if err := Repos.Invitations.Update(inv); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	database.DB.Exec("UPDATE notifications SET read_at = NOW() WHERE user_id = ? AND related_type = ? AND related_id = ?", uid, "event_invitation", inv.ID)
+	Repos.Notifications.MarkRelatedRead(uid, "event_invitation", inv.ID)
 
 	c.JSON(http.StatusOK, gin.H{"invitation": inv})
 }
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"sherpa-backend/internal/models"
+)
+
+func TestCreateInvitation(t *testing.T) {
+	store := useMemoryRepos(t)
+	admin := addUser(store, "alice")
+	bob := addUser(store, "bob")
+	event := addEvent(t, admin)
+
+	w := call(CreateInvitation, admin.ID, idParam("id", event.ID), map[string]interface{}{"email": "bob@example.com", "role": models.EventRoleStaff})
+	expectStatus(t, w, http.StatusCreated)
+	var res struct {
+		Invitation models.EventInvitation `json:"invitation"`
+	}
+	decode(t, w, &res)
+	if res.Invitation.UserID != bob.ID || res.Invitation.InviterID != admin.ID || res.Invitation.Status != models.InvitationStatusPending {
+		t.Fatalf("invitation = %+v", res.Invitation)
+	}
+
+	list, err := Repos.Notifications.ListForUser(bob.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Type != models.NotificationTypeEventInvite || list[0].RelatedID != res.Invitation.ID {
+		t.Fatalf("notifications = %+v", list)
+	}
+	if !strings.Contains(list[0].Body, "alice") || !strings.Contains(list[0].Body, event.Title) {
+		t.Errorf("notification body = %q", list[0].Body)
+	}
+
+	// 同じユーザーへの2通目の招待は弾く
+	w = call(CreateInvitation, admin.ID, idParam("id", event.ID), map[string]interface{}{"user_id": bob.ID, "role": models.EventRoleStaff})
+	expectStatus(t, w, http.StatusBadRequest)
+}
+
+func TestCreateInvitationRejects(t *testing.T) {
+	store := useMemoryRepos(t)
+	admin := addUser(store, "alice")
+	event := addEvent(t, admin)
+
+	tests := []struct {
+		name string
+		body map[string]interface{}
+		want int
+	}{
+		{"unknown email", map[string]interface{}{"email": "nobody@example.com", "role": models.EventRoleStaff}, http.StatusBadRequest},
+		{"no target", map[string]interface{}{"role": models.EventRoleStaff}, http.StatusBadRequest},
+		{"no role", map[string]interface{}{"user_id": admin.ID}, http.StatusBadRequest},
+		{"already staff", map[string]interface{}{"user_id": admin.ID, "role": models.EventRoleStaff}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, call(CreateInvitation, admin.ID, idParam("id", event.ID), tt.body), tt.want)
+		})
+	}
+	if ids, _ := Repos.Invitations.PendingUserIDs(event.ID); len(ids) != 0 {
+		t.Errorf("pending invitations = %v", ids)
+	}
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	store := useMemoryRepos(t)
+	admin := addUser(store, "alice")
+	bob := addUser(store, "bob")
+	event := addEvent(t, admin)
+
+	w := call(CreateInvitation, admin.ID, idParam("id", event.ID), map[string]interface{}{"user_id": bob.ID, "role": models.EventRoleStaff})
+	expectStatus(t, w, http.StatusCreated)
+	var created struct {
+		Invitation models.EventInvitation `json:"invitation"`
+	}
+	decode(t, w, &created)
+	inv := idParam("id", created.Invitation.ID)
+
+	// 本人以外は承諾できない
+	expectStatus(t, call(AcceptInvitation, admin.ID, inv, nil), http.StatusForbidden)
+
+	expectStatus(t, call(AcceptInvitation, bob.ID, inv, nil), http.StatusOK)
+	staff, err := Repos.Events.GetStaff(event.ID, bob.ID)
+	if err != nil {
+		t.Fatalf("bob is not staff: %v", err)
+	}
+	if staff.Role != models.EventRoleStaff {
+		t.Errorf("role = %q", staff.Role)
+	}
+	if n, _ := Repos.Notifications.CountUnread(bob.ID); n != 0 {
+		t.Errorf("unread notifications = %d, want 0", n)
+	}
+
+	expectStatus(t, call(AcceptInvitation, bob.ID, inv, nil), http.StatusBadRequest)
+}
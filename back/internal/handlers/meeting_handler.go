@@ -0,0 +1,576 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+	"sherpa-backend/internal/ws"
+
+	"github.com/gin-gonic/gin"
+)
+
+type meetingRequest struct {
+	Title       string `json:"title" binding:"required"`
+	StartAt     string `json:"start_at" binding:"required"`
+	EndAt       string `json:"end_at"`
+	Location    string `json:"location"`
+	AttendeeIDs []uint `json:"attendee_ids"` // 作成時のみ。省略時はイベントの全スタッフ
+}
+
+// apply 検証してから m に反映する。エラーはそのまま 400 で返せるメッセージ
+func (req *meetingRequest) apply(m *models.Meeting) error {
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		return errors.New("title is required")
+	}
+	startAt, err := time.Parse(time.RFC3339, req.StartAt)
+	if err != nil {
+		return errors.New("invalid start_at: " + err.Error())
+	}
+	var endAt *time.Time
+	if req.EndAt != "" {
+		t, err := time.Parse(time.RFC3339, req.EndAt)
+		if err != nil {
+			return errors.New("invalid end_at: " + err.Error())
+		}
+		if !t.After(startAt) {
+			return errors.New("end_at must be after start_at")
+		}
+		endAt = &t
+	}
+	m.Title = title
+	m.StartAt = startAt
+	m.EndAt = endAt
+	m.Location = strPtr(strings.TrimSpace(req.Location))
+	return nil
+}
+
+// notStaff userIDs のうちイベントのスタッフでないもの
+func notStaff(eventID uint, userIDs []uint) ([]uint, error) {
+	staffIDs, err := Repos.Events.StaffUserIDs(eventID)
+	if err != nil {
+		return nil, err
+	}
+	staff := map[uint]bool{}
+	for _, id := range staffIDs {
+		staff[id] = true
+	}
+	missing := []uint{}
+	for _, id := range userIDs {
+		if !staff[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+// loadMeeting :id の会議。なければ 404 を書き込み false を返す
+func loadMeeting(c *gin.Context) (*models.Meeting, bool) {
+	id, err := paramID(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meeting ID"})
+		return nil, false
+	}
+	m, err := Repos.Meetings.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Meeting not found"})
+		return nil, false
+	}
+	return m, true
+}
+
+// GetMeetings イベントの会議一覧（開始日時順・参加者付き）
+func GetMeetings(c *gin.Context) {
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+	list, err := Repos.Meetings.ListByEvent(uint(eventID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"meetings": list})
+}
+
+// CreateMeeting 会議を作成し、参加者（省略時はイベントの全スタッフ）を出欠未回答で登録する
+func CreateMeeting(c *gin.Context) {
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+	var req meetingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	m := models.Meeting{EventID: uint(eventID)}
+	if err := req.apply(&m); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	attendeeIDs := req.AttendeeIDs
+	if attendeeIDs == nil {
+		if attendeeIDs, err = Repos.Events.StaffUserIDs(m.EventID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		missing, err := notStaff(m.EventID, attendeeIDs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if len(missing) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "参加者はイベントのスタッフから選んでください", "user_ids": missing})
+			return
+		}
+	}
+
+	err = Repos.Transaction(func(tx *repository.Repositories) error {
+		if err := tx.Meetings.Create(&m); err != nil {
+			return err
+		}
+		seen := map[uint]bool{}
+		for _, uid := range attendeeIDs {
+			if seen[uid] {
+				continue
+			}
+			seen[uid] = true
+			if err := tx.Meetings.AddAttendee(&models.MeetingAttendee{MeetingID: m.ID, UserID: uid, RSVP: models.RSVPStatusPending}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	detail, err := Repos.Meetings.GetDetail(m.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ws.BroadcastCalendarUpdate(m.EventID)
+	c.JSON(http.StatusCreated, gin.H{"meeting": detail})
+}
+
+// GetMeeting 会議詳細（アジェンダ・参加者・議事録の最新版番号）
+func GetMeeting(c *gin.Context) {
+	id, _ := paramID(c, "id")
+	m, err := Repos.Meetings.GetDetail(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Meeting not found"})
+		return
+	}
+	revision := 0
+	if latest, err := Repos.Meetings.LatestRevision(id); err == nil {
+		revision = latest.Revision
+	}
+	c.JSON(http.StatusOK, gin.H{"meeting": m, "minutes_revision": revision})
+}
+
+// UpdateMeeting タイトル・日時・場所を更新（参加者は attendees、議事録は minutes で更新する）
+func UpdateMeeting(c *gin.Context) {
+	m, ok := loadMeeting(c)
+	if !ok {
+		return
+	}
+	var req meetingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.apply(m); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := Repos.Meetings.Update(m); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ws.BroadcastCalendarUpdate(m.EventID)
+	c.JSON(http.StatusOK, gin.H{"meeting": m})
+}
+
+// DeleteMeeting 会議を削除
+func DeleteMeeting(c *gin.Context) {
+	m, ok := loadMeeting(c)
+	if !ok {
+		return
+	}
+	if err := Repos.Meetings.Delete(m.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ws.BroadcastCalendarUpdate(m.EventID)
+	c.JSON(http.StatusOK, gin.H{"message": "Meeting deleted successfully"})
+}
+
+type agendaItemRequest struct {
+	Title           string `json:"title" binding:"required"`
+	Description     string `json:"description"`
+	DurationMinutes int    `json:"duration_minutes" binding:"min=0"`
+	PresenterID     *uint  `json:"presenter_id"` // イベントのスタッフ
+	Position        *int   `json:"position"`     // 作成時に省略すると末尾
+}
+
+// apply 検証してから item に反映する。発表者はイベントのスタッフに限る
+func (req *agendaItemRequest) apply(eventID uint, item *models.MeetingAgendaItem) error {
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		return errors.New("title is required")
+	}
+	if req.PresenterID != nil {
+		if _, err := Repos.Events.GetStaff(eventID, *req.PresenterID); err != nil {
+			return errors.New("発表者はイベントのスタッフから選んでください")
+		}
+	}
+	item.Title = title
+	item.Description = strPtr(req.Description)
+	item.DurationMinutes = req.DurationMinutes
+	item.PresenterID = req.PresenterID
+	if req.Position != nil {
+		item.Position = *req.Position
+	}
+	return nil
+}
+
+// CreateAgendaItem アジェンダ項目を追加
+func CreateAgendaItem(c *gin.Context) {
+	m, ok := loadMeeting(c)
+	if !ok {
+		return
+	}
+	var req agendaItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	item := models.MeetingAgendaItem{MeetingID: m.ID}
+	if req.Position == nil {
+		agenda, err := Repos.Meetings.ListAgenda(m.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if n := len(agenda); n > 0 {
+			item.Position = agenda[n-1].Position + 1
+		}
+	}
+	if err := req.apply(m.EventID, &item); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := Repos.Meetings.AddAgendaItem(&item); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"agenda_item": item})
+}
+
+// loadAgendaItem :itemId のアジェンダ項目（:id の会議のもの）。なければ 404 を書き込み false を返す
+func loadAgendaItem(c *gin.Context, meetingID uint) (*models.MeetingAgendaItem, bool) {
+	itemID, err := paramID(c, "itemId")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agenda item ID"})
+		return nil, false
+	}
+	item, err := Repos.Meetings.GetAgendaItem(meetingID, itemID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agenda item not found"})
+		return nil, false
+	}
+	return item, true
+}
+
+// UpdateAgendaItem アジェンダ項目を更新
+func UpdateAgendaItem(c *gin.Context) {
+	m, ok := loadMeeting(c)
+	if !ok {
+		return
+	}
+	item, ok := loadAgendaItem(c, m.ID)
+	if !ok {
+		return
+	}
+	var req agendaItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.apply(m.EventID, item); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := Repos.Meetings.UpdateAgendaItem(item); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"agenda_item": item})
+}
+
+// DeleteAgendaItem アジェンダ項目を削除
+func DeleteAgendaItem(c *gin.Context) {
+	m, ok := loadMeeting(c)
+	if !ok {
+		return
+	}
+	item, ok := loadAgendaItem(c, m.ID)
+	if !ok {
+		return
+	}
+	if err := Repos.Meetings.DeleteAgendaItem(item.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Agenda item deleted successfully"})
+}
+
+// ReorderAgenda アジェンダを item_ids の順に並べ替える（会議のすべての項目を1回ずつ指定する）
+func ReorderAgenda(c *gin.Context) {
+	m, ok := loadMeeting(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		ItemIDs []uint `json:"item_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	agenda, err := Repos.Meetings.ListAgenda(m.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	byID := map[uint]models.MeetingAgendaItem{}
+	for _, item := range agenda {
+		byID[item.ID] = item
+	}
+	if len(req.ItemIDs) != len(agenda) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "item_ids must list every agenda item exactly once"})
+		return
+	}
+	ordered := make([]models.MeetingAgendaItem, 0, len(agenda))
+	for _, id := range req.ItemIDs {
+		item, ok := byID[id]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "item_ids must list every agenda item exactly once"})
+			return
+		}
+		delete(byID, id)
+		ordered = append(ordered, item)
+	}
+
+	err = Repos.Transaction(func(tx *repository.Repositories) error {
+		for i := range ordered {
+			ordered[i].Position = i
+			if err := tx.Meetings.UpdateAgendaItem(&ordered[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"agenda": ordered})
+}
+
+// AddMeetingAttendee 参加者を追加（イベントのスタッフのみ）
+func AddMeetingAttendee(c *gin.Context) {
+	m, ok := loadMeeting(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		UserID uint `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := Repos.Events.GetStaff(m.EventID, req.UserID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参加者はイベントのスタッフから選んでください"})
+		return
+	}
+	if _, err := Repos.Meetings.GetAttendee(m.ID, req.UserID); err == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "既に参加者です"})
+		return
+	}
+	a := models.MeetingAttendee{MeetingID: m.ID, UserID: req.UserID, RSVP: models.RSVPStatusPending}
+	if err := Repos.Meetings.AddAttendee(&a); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ws.BroadcastCalendarUpdate(m.EventID)
+	c.JSON(http.StatusCreated, gin.H{"attendee": a})
+}
+
+// RemoveMeetingAttendee 参加者から外す
+func RemoveMeetingAttendee(c *gin.Context) {
+	m, ok := loadMeeting(c)
+	if !ok {
+		return
+	}
+	userID, err := paramID(c, "userId")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if err := Repos.Meetings.RemoveAttendee(m.ID, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ws.BroadcastCalendarUpdate(m.EventID)
+	c.JSON(http.StatusOK, gin.H{"message": "Attendee removed"})
+}
+
+// RespondMeeting 自分の出欠を回答する（accepted / tentative / declined）
+func RespondMeeting(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	m, ok := loadMeeting(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Status models.RSVPStatus `json:"status" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !models.ValidRSVPStatus(req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be accepted, tentative or declined"})
+		return
+	}
+	a, err := Repos.Meetings.GetAttendee(m.ID, uid)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "この会議の参加者ではありません"})
+		return
+	}
+	now := time.Now()
+	a.RSVP = req.Status
+	a.RespondedAt = &now
+	if err := Repos.Meetings.UpdateAttendee(a); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ws.BroadcastCalendarUpdate(m.EventID)
+	c.JSON(http.StatusOK, gin.H{"attendee": a})
+}
+
+// UpdateMeetingMinutes 議事録（Markdown）を保存し、新しい版として履歴に残す。
+// base_revision が最新版と違う（他の人が先に保存した）場合は 409 と最新版を返す
+func UpdateMeetingMinutes(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	m, ok := loadMeeting(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Content      string `json:"content"`
+		BaseRevision int    `json:"base_revision"` // 編集を始めた版（まだ版がなければ 0）
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	latestRevision := func() (*models.MeetingMinutesRevision, int, error) {
+		latest, err := Repos.Meetings.LatestRevision(m.ID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, 0, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		return latest, latest.Revision, nil
+	}
+	latest, current, err := latestRevision()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if req.BaseRevision != current {
+		c.JSON(http.StatusConflict, gin.H{"error": "議事録が他の人によって更新されています", "revision": latest})
+		return
+	}
+	if latest != nil && latest.Content == req.Content {
+		c.JSON(http.StatusOK, gin.H{"revision": latest})
+		return
+	}
+
+	rev := models.MeetingMinutesRevision{MeetingID: m.ID, Revision: current + 1, Content: req.Content, EditorID: &uid}
+	err = Repos.Transaction(func(tx *repository.Repositories) error {
+		if err := tx.Meetings.AddRevision(&rev); err != nil {
+			return err
+		}
+		m.Minutes = &rev.Content
+		return tx.Meetings.Update(m)
+	})
+	if err != nil {
+		// 同時に保存された場合は (meeting_id, revision) の一意制約で失敗する
+		if newer, n, lerr := latestRevision(); lerr == nil && n != current {
+			c.JSON(http.StatusConflict, gin.H{"error": "議事録が他の人によって更新されています", "revision": newer})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"revision": rev})
+}
+
+// GetMeetingMinutesRevisions 議事録の版の一覧（新しい順）
+func GetMeetingMinutesRevisions(c *gin.Context) {
+	m, ok := loadMeeting(c)
+	if !ok {
+		return
+	}
+	list, err := Repos.Meetings.ListRevisions(m.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"revisions": list})
+}
+
+// GetMeetingMinutesRevision 議事録の特定の版
+func GetMeetingMinutesRevision(c *gin.Context) {
+	m, ok := loadMeeting(c)
+	if !ok {
+		return
+	}
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision"})
+		return
+	}
+	rev, err := Repos.Meetings.GetRevision(m.ID, revision)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"revision": rev})
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+	"sherpa-backend/internal/services"
+	"sherpa-backend/internal/ws"
+
+	"github.com/gin-gonic/gin"
+)
+
+// taskProposal 議事録から作ったタスク案。確認・修正してから AcceptMeetingTasks に渡す
+type taskProposal struct {
+	Line           int        `json:"line,omitempty"`
+	Source         string     `json:"source"` // checklist / ai
+	Title          string     `json:"title"`
+	AssigneeName   string     `json:"assignee_name,omitempty"`
+	AssigneeID     *uint      `json:"assignee_id,omitempty"`
+	Deadline       *time.Time `json:"deadline,omitempty"`
+	ExistingTaskID *uint      `json:"existing_task_id,omitempty"` // この会議から作成済みの同名タスク
+	Warnings       []string   `json:"warnings,omitempty"`
+}
+
+// staffDirectory @名前 からイベントスタッフを引く。名前（空白なし）・メールのローカル部で大文字小文字を区別しない
+type staffDirectory struct {
+	byKey map[string][]uint
+	names []string
+}
+
+func newStaffDirectory(staffs []models.EventStaff) *staffDirectory {
+	d := &staffDirectory{byKey: map[string][]uint{}}
+	for _, s := range staffs {
+		u := s.User
+		d.names = append(d.names, u.Name)
+		keys := map[string]bool{
+			strings.ToLower(u.Name):                                   true,
+			strings.ToLower(strings.Join(strings.Fields(u.Name), "")): true,
+		}
+		if local, _, ok := strings.Cut(u.Email, "@"); ok {
+			keys[strings.ToLower(local)] = true
+		}
+		for k := range keys {
+			if k != "" {
+				d.byKey[k] = append(d.byKey[k], s.UserID)
+			}
+		}
+	}
+	return d
+}
+
+// resolve 一意に決まればユーザーID。見つからない・複数いる場合は警告文を返す
+func (d *staffDirectory) resolve(name string) (*uint, string) {
+	ids := d.byKey[strings.ToLower(strings.TrimPrefix(name, "@"))]
+	switch len(ids) {
+	case 0:
+		return nil, "担当者 @" + name + " はイベントのスタッフに見つかりません"
+	case 1:
+		id := ids[0]
+		return &id, ""
+	default:
+		return nil, "担当者 @" + name + " に該当するスタッフが複数います"
+	}
+}
+
+// normalizeTitle 重複判定用のタイトル
+func normalizeTitle(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), " "))
+}
+
+// extractActionItemsWithAI 議事録から AI でアクションアイテムを抽出する。AI を使えなければ（未設定・初期化や呼び出しの失敗）
+// ログだけ残して false を返し、呼び出し側はチェックリストの結果だけを返す
+func extractActionItemsWithAI(m *models.Meeting, members []string) ([]services.ActionItem, bool) {
+	geminiService, err := services.NewGeminiService()
+	if err != nil {
+		log.Printf("[ExtractMeetingTasks] meeting %d: init AI service: %v", m.ID, err)
+		return nil, false
+	}
+	defer geminiService.Close()
+	if !geminiService.Enabled() {
+		return nil, false
+	}
+	items, err := geminiService.ExtractActionItems(*m.Minutes, members, m.StartAt)
+	if err != nil {
+		log.Printf("[ExtractMeetingTasks] meeting %d: %v", m.ID, err)
+		return nil, false
+	}
+	return items, true
+}
+
+// ExtractMeetingTasks 議事録のチェックリスト（と任意で AI 抽出）からタスク案を作る。保存はしない
+func ExtractMeetingTasks(c *gin.Context) {
+	m, ok := loadMeeting(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		UseAI bool `json:"use_ai"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if m.Minutes == nil || strings.TrimSpace(*m.Minutes) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "議事録がありません"})
+		return
+	}
+
+	event, err := Repos.Events.GetDetail(m.EventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	staff := newStaffDirectory(event.EventStaffs)
+
+	items := services.ParseActionItems(*m.Minutes, m.StartAt)
+	aiUsed := false
+	if req.UseAI {
+		var aiItems []services.ActionItem
+		if aiItems, aiUsed = extractActionItemsWithAI(m, staff.names); aiUsed {
+			seen := map[string]bool{}
+			for _, it := range items {
+				seen[normalizeTitle(it.Title)] = true
+			}
+			for _, it := range aiItems {
+				if !seen[normalizeTitle(it.Title)] {
+					seen[normalizeTitle(it.Title)] = true
+					items = append(items, it)
+				}
+			}
+		}
+	}
+
+	existing, err := Repos.Tasks.ListByMeeting(m.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	existingByTitle := map[string]uint{}
+	for _, t := range existing {
+		existingByTitle[normalizeTitle(t.Title)] = t.ID
+	}
+
+	proposals := make([]taskProposal, 0, len(items))
+	for _, it := range items {
+		p := taskProposal{
+			Line:         it.Line,
+			Source:       it.Source,
+			Title:        it.Title,
+			AssigneeName: it.Assignee,
+			Deadline:     it.Due,
+		}
+		if it.Assignee != "" {
+			id, warning := staff.resolve(it.Assignee)
+			p.AssigneeID = id
+			if warning != "" {
+				p.Warnings = append(p.Warnings, warning)
+			}
+		}
+		if p.Deadline == nil {
+			p.Deadline = &event.StartAt
+			p.Warnings = append(p.Warnings, "期限がないためイベント開始日時を仮に設定しました")
+		}
+		if id, ok := existingByTitle[normalizeTitle(it.Title)]; ok {
+			p.ExistingTaskID = &id
+		}
+		proposals = append(proposals, p)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"proposals": proposals, "ai_used": aiUsed})
+}
+
+// AcceptMeetingTasks 確認済みのタスク案をまとめて作成する。1件でも不正なら何も作らない
+func AcceptMeetingTasks(c *gin.Context) {
+	m, ok := loadMeeting(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		Tasks []struct {
+			Title         string `json:"title" binding:"required"`
+			AssigneeID    *uint  `json:"assignee_id"`
+			Deadline      string `json:"deadline" binding:"required"` // RFC3339
+			IsAIGenerated bool   `json:"is_ai_generated"`
+		} `json:"tasks" binding:"required,min=1,dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tasks := make([]models.Task, 0, len(req.Tasks))
+	var assigneeIDs []uint
+	for i, t := range req.Tasks {
+		title := strings.TrimSpace(t.Title)
+		if title == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "title is required", "index": i})
+			return
+		}
+		deadline, err := time.Parse(time.RFC3339, t.Deadline)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid deadline: " + err.Error(), "index": i})
+			return
+		}
+		if t.AssigneeID != nil {
+			assigneeIDs = append(assigneeIDs, *t.AssigneeID)
+		}
+		meetingID := m.ID
+		tasks = append(tasks, models.Task{
+			EventID:         m.EventID,
+			AssigneeID:      t.AssigneeID,
+			Title:           title,
+			Deadline:        deadline,
+			Status:          models.TaskStatusTodo,
+			IsAIGenerated:   t.IsAIGenerated,
+			SourceMeetingID: &meetingID,
+		})
+	}
+	missing, err := notStaff(m.EventID, assigneeIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(missing) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "担当者はイベントのスタッフから選んでください", "user_ids": missing})
+		return
+	}
+
+	err = Repos.Transaction(func(tx *repository.Repositories) error {
+		for i := range tasks {
+			if err := tx.Tasks.Create(&tasks[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ws.BroadcastCalendarUpdate(m.EventID)
+	c.JSON(http.StatusCreated, gin.H{"tasks": tasks})
+}
+
+// GetMeetingTasks 会議の議事録から作ったタスク
+func GetMeetingTasks(c *gin.Context) {
+	m, ok := loadMeeting(c)
+	if !ok {
+		return
+	}
+	tasks, err := Repos.Tasks.ListByMeeting(m.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tasks": tasks})
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+	"sherpa-backend/internal/ws"
+
+	"github.com/gin-gonic/gin"
+)
+
+// メンション一覧の1ページの件数
+const (
+	defaultMentionPageSize = 50
+	maxMentionPageSize     = 100
+)
+
+// mentionExcerptRunes 通知本文に載せるメッセージの長さ
+const mentionExcerptRunes = 80
+
+// isMentionWordByte メンションの前後に続くと別の語とみなす文字（ASCII の英数字と _）
+func isMentionWordByte(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+// hasMentionPrefix s が word（大文字小文字を区別しない）で始まり、その後に英数字が続かないか
+func hasMentionPrefix(s, word string) bool {
+	if word == "" || len(s) < len(word) || !strings.EqualFold(s[:len(word)], word) {
+		return false
+	}
+	return len(s) == len(word) || !isMentionWordByte(s[len(word)])
+}
+
+// parseMentions content から @channel・@here と、candidates の名前へのメンション（@名前）を取り出す。
+// 名前は長いものを優先して照合する。英数字の直後の @（メールアドレスなど）や、@alice の中の @al のように
+// 英数字が続くものはメンションとみなさない
+func parseMentions(content string, candidates []models.User) (userIDs []uint, channel, here bool) {
+	users := slices.Clone(candidates)
+	slices.SortStableFunc(users, func(a, b models.User) int { return len(b.Name) - len(a.Name) })
+	for i := 0; i < len(content); i++ {
+		if content[i] != '@' || i > 0 && isMentionWordByte(content[i-1]) {
+			continue
+		}
+		rest := content[i+1:]
+		switch {
+		case hasMentionPrefix(rest, "channel"):
+			channel = true
+			continue
+		case hasMentionPrefix(rest, "here"):
+			here = true
+			continue
+		}
+		for _, u := range users {
+			if hasMentionPrefix(rest, u.Name) {
+				if !slices.Contains(userIDs, u.ID) {
+					userIDs = append(userIDs, u.ID)
+				}
+				break
+			}
+		}
+	}
+	return userIDs, channel, here
+}
+
+// buildMentions msg の本文からメンションを作る。@名前 はチャンネルを読めるユーザー（非公開ならメンバー、
+// 公開ならイベントのスタッフ）が対象で、@channel はチャンネルのメンバー全員、@here はそのうち接続中のメンバー。
+// 同じユーザーは @名前 > @channel > @here の順で1件にまとめ、投稿者は含めない
+func buildMentions(msg *models.Message, ch *models.Channel) ([]models.MessageMention, error) {
+	members, err := Repos.Channels.Members(ch.ID)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []models.User
+	if ch.IsPrivate {
+		for _, m := range members {
+			candidates = append(candidates, m.User)
+		}
+	} else {
+		ids, err := Repos.Events.StaffUserIDs(ch.EventID)
+		if err != nil {
+			return nil, err
+		}
+		if candidates, err = Repos.Users.ListByIDs(ids); err != nil {
+			return nil, err
+		}
+	}
+
+	userIDs, channel, here := parseMentions(msg.Content, candidates)
+	kinds := map[uint]models.MentionKind{}
+	if channel || here {
+		for _, m := range members {
+			switch {
+			case channel:
+				kinds[m.UserID] = models.MentionKindChannel
+			case ws.IsOnline(m.UserID):
+				kinds[m.UserID] = models.MentionKindHere
+			}
+		}
+	}
+	for _, id := range userIDs {
+		kinds[id] = models.MentionKindUser
+	}
+	delete(kinds, msg.UserID)
+
+	list := make([]models.MessageMention, 0, len(kinds))
+	for _, id := range slices.Sorted(maps.Keys(kinds)) {
+		list = append(list, models.MessageMention{MessageID: msg.ID, ChannelID: ch.ID, UserID: id, Kind: kinds[id]})
+	}
+	return list, nil
+}
+
+// mentionNotification メンションされたユーザーへの通知
+func mentionNotification(msg *models.Message, ch *models.Channel, userID uint) models.Notification {
+	excerpt := []rune(msg.Content)
+	if len(excerpt) > mentionExcerptRunes {
+		excerpt = append(excerpt[:mentionExcerptRunes], '…')
+	}
+	return models.Notification{
+		UserID:     userID,
+		Type:       models.NotificationTypeMention,
+		Title:      ch.Name + " でメンションされました",
+		Body:       fmt.Sprintf("%s さん: %s", msg.User.Name, string(excerpt)),
+		RelatedID:  msg.ID,
+		RelatedTyp: "message",
+	}
+}
+
+// saveMentions msg のメンションを本文から作り直して msg.Mentions に入れる。
+// 新たにメンションされたユーザー（編集前にメンションされていなかったユーザー）には通知を作り、
+// WebSocket で本人のすべての接続へ type: "mention" を配信する
+func saveMentions(msg *models.Message) error {
+	ch, err := Repos.Channels.Get(msg.ChannelID)
+	if err != nil {
+		return err
+	}
+	list, err := buildMentions(msg, ch)
+	if err != nil {
+		return err
+	}
+	prev, err := Repos.Messages.Mentions(msg.ID)
+	if err != nil {
+		return err
+	}
+
+	var notified []uint
+	err = Repos.Transaction(func(tx *repository.Repositories) error {
+		if err := tx.Messages.SetMentions(msg.ID, list); err != nil {
+			return err
+		}
+		for _, m := range list {
+			if slices.ContainsFunc(prev, func(p models.MessageMention) bool { return p.UserID == m.UserID }) {
+				continue
+			}
+			n := mentionNotification(msg, ch, m.UserID)
+			if err := tx.Notifications.Create(&n); err != nil {
+				return err
+			}
+			notified = append(notified, m.UserID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	msg.Mentions = list
+
+	for _, m := range list {
+		if !slices.Contains(notified, m.UserID) {
+			continue
+		}
+		payload, err := json.Marshal(gin.H{"mention": m, "message": msg, "event_id": ch.EventID})
+		if err == nil {
+			ws.SendEventToUser(m.UserID, "mention", payload)
+		}
+	}
+	return nil
+}
+
+// applyMentions saveMentions を呼び、失敗してもメッセージは保存済みなのでログだけ残す
+func applyMentions(msg *models.Message) {
+	if err := saveMentions(msg); err != nil {
+		log.Printf("[chat] mentions for message %d: %v", msg.ID, err)
+	}
+}
+
+// GetMyMentions 自分あてのメンション（新しい順）。?before=<メンションID> で続きを、?limit= で件数を指定する。
+// メンバーでなくなった非公開チャンネルのメンションは返さない
+func GetMyMentions(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	limit := defaultMentionPageSize
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxMentionPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxMentionPageSize)})
+			return
+		}
+		limit = n
+	}
+	var before uint
+	if v := c.Query("before"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before"})
+			return
+		}
+		before = uint(n)
+	}
+
+	list, err := Repos.Messages.ListMentionsForUser(uid, before, limit+1)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	hasMore := len(list) > limit
+	if hasMore {
+		list = list[:limit]
+	}
+	res := gin.H{"has_more": hasMore}
+	if len(list) > 0 {
+		res["next_before"] = list[len(list)-1].ID
+	}
+
+	access := map[uint]bool{}
+	mentions := make([]models.MessageMention, 0, len(list))
+	for _, m := range list {
+		ch := m.Message.Channel
+		if ch.ID == 0 {
+			continue // チャンネルが削除済み
+		}
+		allowed, seen := access[ch.ID]
+		if !seen {
+			if allowed, err = canAccessChannel(&ch, uid); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			access[ch.ID] = allowed
+		}
+		if allowed {
+			mentions = append(mentions, m)
+		}
+	}
+	res["mentions"] = mentions
+	c.JSON(http.StatusOK, res)
+}
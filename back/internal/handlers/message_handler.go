@@ -5,7 +5,6 @@ import (
 	"net/http"
 	"strconv"
 
-	"sherpa-backend/internal/database"
 	"sherpa-backend/internal/models"
 	"sherpa-backend/internal/ws"
 
@@ -25,21 +24,11 @@ func UpdateMessage(c *gin.Context) {
 		return
 	}
 
-	var msg models.Message
-	if err := database.DB.First(&msg, uint(msgID)).Error; err != nil {
+	msg, err := Repos.Messages.Get(uint(msgID))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
 		return
 	}
-	var ch models.Channel
-	if database.DB.First(&ch, msg.ChannelID).Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
-		return
-	}
-	var staff models.EventStaff
-	if database.DB.Where("event_id = ? AND user_id = ?", ch.EventID, uid).First(&staff).Error != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only event staff can edit messages"})
-		return
-	}
 	if msg.UserID != uid {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can edit"})
 		return
@@ -58,11 +47,12 @@ func UpdateMessage(c *gin.Context) {
 	}
 
 	msg.Content = req.Content
-	if err := database.DB.Save(&msg).Error; err != nil {
+	if err := Repos.Messages.Update(msg); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	database.DB.Preload("User").Preload("Reactions").Preload("Reactions.User").First(&msg, msg.ID)
+	// 編集で新たにメンションされたユーザーにだけ通知する
+	applyMentions(msg)
 
 	if b, err := json.Marshal(msg); err == nil {
 		ws.BroadcastEventToChannel(msg.ChannelID, "message_updated", b)
@@ -83,37 +73,129 @@ func DeleteMessage(c *gin.Context) {
 		return
 	}
 
-	var msg models.Message
-	if err := database.DB.First(&msg, uint(msgID)).Error; err != nil {
+	msg, err := Repos.Messages.Get(uint(msgID))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
 		return
 	}
-	var ch models.Channel
-	if database.DB.First(&ch, msg.ChannelID).Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
-		return
-	}
-	var staff models.EventStaff
-	if database.DB.Where("event_id = ? AND user_id = ?", ch.EventID, uid).First(&staff).Error != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only event staff can delete messages"})
-		return
-	}
 	if msg.UserID != uid {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can delete"})
 		return
 	}
 
 	msg.IsDeleted = true
-	if err := database.DB.Save(&msg).Error; err != nil {
+	if err := Repos.Messages.Update(msg); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	payload, _ := json.Marshal(gin.H{"message_id": msg.ID, "channel_id": msg.ChannelID})
+	deleted := gin.H{"message_id": msg.ID, "channel_id": msg.ChannelID}
+	if msg.ParentMessageID != nil {
+		// スレッドの返信なら、開いているスレッドと親の返信数を更新できるよう親の集計も送る
+		deleted["parent_message_id"] = *msg.ParentMessageID
+		if parent, err := Repos.Messages.GetThread(*msg.ParentMessageID); err == nil {
+			deleted["parent"] = parent
+		}
+	}
+	payload, _ := json.Marshal(deleted)
 	ws.BroadcastEventToChannel(msg.ChannelID, "message_deleted", payload)
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
+// maxThreadReplies スレッドの返信を一度に返す件数
+const maxThreadReplies = 100
+
+// threadParent :id のメッセージをスレッドの親として取得する。返信や削除済みなら書き込んで nil を返す
+func threadParent(c *gin.Context) *models.Message {
+	msgID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return nil
+	}
+	parent, err := Repos.Messages.GetThread(uint(msgID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return nil
+	}
+	if parent.ParentMessageID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Replies cannot have threads; reply to the parent message instead"})
+		return nil
+	}
+	if parent.IsDeleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return nil
+	}
+	return parent
+}
+
+// GetReplies スレッドの親メッセージ（返信数・最後の返信付き）と返信（古い順）
+func GetReplies(c *gin.Context) {
+	if _, ok := userIDFrom(c); !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	parent := threadParent(c)
+	if parent == nil {
+		return
+	}
+	replies, err := Repos.Messages.ListReplies(parent.ID, maxThreadReplies)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"parent": parent, "replies": replies})
+}
+
+type createReplyRequest struct {
+	Content           string `json:"content" binding:"required"`
+	AlsoSendToChannel bool   `json:"also_send_to_channel"` // チャンネルのタイムラインにも表示する
+}
+
+// CreateReply スレッドに返信する。チャンネルへ thread_reply（返信と親の集計）を配信し、
+// also_send_to_channel なら通常のメッセージとしても配信する
+func CreateReply(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	parent := threadParent(c)
+	if parent == nil {
+		return
+	}
+	var req createReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reply := models.Message{
+		ChannelID:         parent.ChannelID,
+		UserID:            uid,
+		Content:           req.Content,
+		ParentMessageID:   &parent.ID,
+		AlsoSentToChannel: req.AlsoSendToChannel,
+	}
+	if err := Repos.Messages.Create(&reply); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	applyMentions(&reply)
+	if updated, err := Repos.Messages.GetThread(parent.ID); err == nil {
+		parent = updated
+	}
+
+	if b, err := json.Marshal(gin.H{"message": reply, "parent": parent}); err == nil {
+		ws.BroadcastEventToChannel(reply.ChannelID, "thread_reply", b)
+	}
+	if reply.AlsoSentToChannel {
+		if b, err := json.Marshal(reply); err == nil {
+			ws.BroadcastMessageToChannel(reply.ChannelID, b)
+		}
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": reply, "parent": parent})
+}
+
 const defaultEmoji = "👍"
 
 // ToggleReaction リアクションのトグル（追加 or 削除）
@@ -129,21 +211,11 @@ func ToggleReaction(c *gin.Context) {
 		return
 	}
 
-	var msg models.Message
-	if err := database.DB.First(&msg, uint(msgID)).Error; err != nil {
+	msg, err := Repos.Messages.Get(uint(msgID))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
 		return
 	}
-	var ch models.Channel
-	if database.DB.First(&ch, msg.ChannelID).Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
-		return
-	}
-	var staff models.EventStaff
-	if database.DB.Where("event_id = ? AND user_id = ?", ch.EventID, uid).First(&staff).Error != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only event staff can add reactions"})
-		return
-	}
 	if msg.IsDeleted {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot react to deleted message"})
 		return
@@ -158,10 +230,9 @@ func ToggleReaction(c *gin.Context) {
 		emoji = defaultEmoji
 	}
 
-	var existing models.MessageReaction
-	err = database.DB.Where("message_id = ? AND user_id = ? AND emoji = ?", msgID, uid, emoji).First(&existing).Error
+	existing, err := Repos.Messages.FindReaction(msg.ID, uid, emoji)
 	if err == nil {
-		if database.DB.Delete(&existing).Error != nil {
+		if Repos.Messages.RemoveReaction(existing.ID) != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove reaction"})
 			return
 		}
@@ -172,11 +243,10 @@ func ToggleReaction(c *gin.Context) {
 	}
 
 	r := models.MessageReaction{MessageID: uint(msgID), UserID: uid, Emoji: emoji}
-	if err := database.DB.Create(&r).Error; err != nil {
+	if err := Repos.Messages.AddReaction(&r); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	database.DB.Preload("User").First(&r, r.ID)
 
 	payload, _ := json.Marshal(r)
 	ws.BroadcastEventToChannel(msg.ChannelID, "reaction", payload)
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"sherpa-backend/internal/models"
+)
+
+func TestToggleReaction(t *testing.T) {
+	store := useMemoryRepos(t)
+	alice := addUser(store, "alice")
+	bob := addUser(store, "bob")
+	event := addEvent(t, alice)
+	ch := &models.Channel{EventID: event.ID, Name: "general"}
+	if err := Repos.Channels.Create(ch); err != nil {
+		t.Fatal(err)
+	}
+	msg := &models.Message{ChannelID: ch.ID, UserID: alice.ID, Content: "hello"}
+	if err := Repos.Messages.Create(msg); err != nil {
+		t.Fatal(err)
+	}
+	params := idParam("id", msg.ID)
+
+	var res struct {
+		Action   string                 `json:"action"`
+		Emoji    string                 `json:"emoji"`
+		Reaction models.MessageReaction `json:"reaction"`
+	}
+	w := call(ToggleReaction, bob.ID, params, nil)
+	expectStatus(t, w, http.StatusOK)
+	decode(t, w, &res)
+	if res.Action != "added" || res.Reaction.Emoji != defaultEmoji || res.Reaction.UserID != bob.ID {
+		t.Fatalf("first toggle = %+v", res)
+	}
+
+	// 別の絵文字・別のユーザーのリアクションはそれぞれ独立
+	expectStatus(t, call(ToggleReaction, bob.ID, params, map[string]string{"emoji": "🎉"}), http.StatusOK)
+	expectStatus(t, call(ToggleReaction, alice.ID, params, nil), http.StatusOK)
+
+	res = struct {
+		Action   string                 `json:"action"`
+		Emoji    string                 `json:"emoji"`
+		Reaction models.MessageReaction `json:"reaction"`
+	}{}
+	w = call(ToggleReaction, bob.ID, params, nil)
+	expectStatus(t, w, http.StatusOK)
+	decode(t, w, &res)
+	if res.Action != "removed" || res.Emoji != defaultEmoji {
+		t.Fatalf("second toggle = %+v", res)
+	}
+	if _, err := Repos.Messages.FindReaction(msg.ID, bob.ID, defaultEmoji); err == nil {
+		t.Error("bob's 👍 was not removed")
+	}
+	for _, r := range []struct {
+		userID uint
+		emoji  string
+	}{{bob.ID, "🎉"}, {alice.ID, defaultEmoji}} {
+		if _, err := Repos.Messages.FindReaction(msg.ID, r.userID, r.emoji); err != nil {
+			t.Errorf("reaction %d %s: %v", r.userID, r.emoji, err)
+		}
+	}
+}
+
+func TestToggleReactionRejects(t *testing.T) {
+	store := useMemoryRepos(t)
+	alice := addUser(store, "alice")
+	event := addEvent(t, alice)
+	ch := &models.Channel{EventID: event.ID, Name: "general"}
+	if err := Repos.Channels.Create(ch); err != nil {
+		t.Fatal(err)
+	}
+	deleted := &models.Message{ChannelID: ch.ID, UserID: alice.ID, Content: "", IsDeleted: true}
+	if err := Repos.Messages.Create(deleted); err != nil {
+		t.Fatal(err)
+	}
+
+	expectStatus(t, call(ToggleReaction, 0, idParam("id", deleted.ID), nil), http.StatusUnauthorized)
+	expectStatus(t, call(ToggleReaction, alice.ID, idParam("id", deleted.ID+100), nil), http.StatusNotFound)
+	expectStatus(t, call(ToggleReaction, alice.ID, idParam("id", deleted.ID), nil), http.StatusBadRequest)
+}
@@ -4,9 +4,6 @@ import (
 	"net/http"
 	"strconv"
 
-	"sherpa-backend/internal/database"
-	"sherpa-backend/internal/models"
-
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,8 +14,8 @@ func GetUnreadNotificationCount(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
 		return
 	}
-	var n int64
-	if err := database.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", uid).Count(&n).Error; err != nil {
+	n, err := Repos.Notifications.CountUnread(uid)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -33,8 +30,8 @@ func GetNotifications(c *gin.Context) {
 		return
 	}
 
-	var list []models.Notification
-	if err := database.DB.Where("user_id = ?", uid).Order("created_at DESC").Limit(100).Find(&list).Error; err != nil {
+	list, err := Repos.Notifications.ListForUser(uid, 100)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -55,8 +52,8 @@ func MarkNotificationRead(c *gin.Context) {
 		return
 	}
 
-	var n models.Notification
-	if err := database.DB.First(&n, uint(id)).Error; err != nil {
+	n, err := Repos.Notifications.Get(uint(id))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
 		return
 	}
@@ -65,9 +62,14 @@ func MarkNotificationRead(c *gin.Context) {
 		return
 	}
 
-	database.DB.Exec("UPDATE notifications SET read_at = NOW() WHERE id = ?", id)
+	if err := Repos.Notifications.MarkRead(n.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	database.DB.First(&n, uint(id))
+	if updated, err := Repos.Notifications.Get(n.ID); err == nil {
+		n = updated
+	}
 	c.JSON(http.StatusOK, gin.H{"notification": n})
 }
 
@@ -79,9 +81,8 @@ func GetMyPendingInvitations(c *gin.Context) {
 		return
 	}
 
-	var list []models.EventInvitation
-	if err := database.DB.Where("user_id = ? AND status = ?", uid, models.InvitationStatusPending).
-		Preload("Event").Preload("Inviter").Find(&list).Error; err != nil {
+	list, err := Repos.Invitations.ListPendingForUser(uid)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
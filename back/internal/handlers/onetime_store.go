@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+)
+
+// oneTimeGrant 1回だけ引き換えられる短命の値（OAuth 後の認可コード・WebSocket チケット）
+type oneTimeGrant struct {
+	UserID    uint
+	SessionID uint
+	expiresAt time.Time
+}
+
+// oneTimeStore プロセス内メモリで保持する使い捨てトークン置き場。
+// WebSocket ハブと同様に単一インスタンス運用を前提とする。
+type oneTimeStore struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]oneTimeGrant
+}
+
+func newOneTimeStore(ttl time.Duration) *oneTimeStore {
+	return &oneTimeStore{ttl: ttl, entries: make(map[string]oneTimeGrant)}
+}
+
+var (
+	// authCodes OAuth コールバックからフロントへ渡す認可コード
+	authCodes = newOneTimeStore(60 * time.Second)
+	// wsTickets /api/ws の接続用チケット
+	wsTickets = newOneTimeStore(30 * time.Second)
+)
+
+// Issue 新しい値を発行する。ついでに期限切れのものを掃除する
+func (s *oneTimeStore) Issue(g oneTimeGrant) string {
+	key := generateRefreshToken()
+	now := time.Now()
+	g.expiresAt = now.Add(s.ttl)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, e := range s.entries {
+		if now.After(e.expiresAt) {
+			delete(s.entries, k)
+		}
+	}
+	s.entries[hashToken(key)] = g
+	return key
+}
+
+// Consume 値を取り出して無効化する。未発行・使用済み・期限切れなら ok=false
+func (s *oneTimeStore) Consume(key string) (oneTimeGrant, bool) {
+	if key == "" {
+		return oneTimeGrant{}, false
+	}
+	h := hashToken(key)
+
+	s.mu.Lock()
+	g, ok := s.entries[h]
+	delete(s.entries, h)
+	s.mu.Unlock()
+
+	if !ok || time.Now().After(g.expiresAt) {
+		return oneTimeGrant{}, false
+	}
+	return g, true
+}
@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// domainTXTPrefix 所有確認用 TXT レコードのホスト名の接頭辞（_sherpa-verification.example.ac.jp）
+	domainTXTPrefix = "_sherpa-verification."
+	// domainTXTValuePrefix TXT レコードの値の接頭辞
+	domainTXTValuePrefix = "sherpa-verification="
+	dnsLookupTimeout     = 5 * time.Second
+)
+
+var domainPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,}$`)
+
+// normalizeDomain "@Example.ac.jp" のような入力を "example.ac.jp" にそろえる
+func normalizeDomain(d string) string {
+	d = strings.ToLower(strings.TrimSpace(d))
+	d = strings.TrimPrefix(d, "@")
+	return strings.TrimSuffix(d, ".")
+}
+
+// emailDomainCandidates メールアドレスのドメインとその親ドメイン（cs.example.ac.jp → cs.example.ac.jp, example.ac.jp, ac.jp）
+func emailDomainCandidates(email string) []string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return nil
+	}
+	d := normalizeDomain(email[at+1:])
+	var out []string
+	for strings.Contains(d, ".") {
+		out = append(out, d)
+		d = d[strings.Index(d, ".")+1:]
+	}
+	return out
+}
+
+// placeNewUser 初回ログイン・登録時の所属先を決める。
+// 確認済みメールのドメインが組織の確認済みドメインに一致すればその組織へ参加し、
+// 一致しなければ DEFAULT_ORGANIZATION_ID の組織に承認待ちで入る（未設定なら本人が owner の組織を作る）。
+func placeNewUser(user *models.User) error {
+	joined, err := joinOrganizationByDomain(user)
+	if err != nil || joined {
+		return err
+	}
+	if orgID, ok := fallbackOrganizationID(); ok {
+		return requestMembership(orgID, user.ID)
+	}
+	return createPersonalOrganization(user)
+}
+
+// placeUnaffiliatedUser まだどの組織にも属していない（承認待ちも含めて）ユーザーだけ placeNewUser する
+func placeUnaffiliatedUser(user *models.User) error {
+	memberships, err := Repos.Organizations.ListMemberships(user.ID)
+	if err != nil {
+		return err
+	}
+	if len(memberships) > 0 {
+		return nil
+	}
+	return placeNewUser(user)
+}
+
+// fallbackOrganizationID ドメインが一致しないユーザーを承認待ちで受け付ける組織（DEFAULT_ORGANIZATION_ID）
+func fallbackOrganizationID() (uint, bool) {
+	v := os.Getenv("DEFAULT_ORGANIZATION_ID")
+	if v == "" {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(v, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	org, err := Repos.Organizations.Get(uint(id))
+	if err != nil {
+		return 0, false
+	}
+	return org.ID, true
+}
+
+// joinOrganizationByDomain 確認済みメールのドメイン（親ドメインを含む最長一致）を持つ組織へ既定ロールで参加させ、アクティブ組織にする
+func joinOrganizationByDomain(user *models.User) (bool, error) {
+	if user.EmailVerifiedAt == nil {
+		return false, nil
+	}
+	candidates := emailDomainCandidates(user.Email)
+	if len(candidates) == 0 {
+		return false, nil
+	}
+
+	domains, err := Repos.Organizations.ListVerifiedDomains(candidates)
+	if err != nil {
+		return false, err
+	}
+	var best *models.OrganizationDomain
+	for i := range domains {
+		if best == nil || len(domains[i].Domain) > len(best.Domain) {
+			best = &domains[i]
+		}
+	}
+	if best == nil {
+		return false, nil
+	}
+
+	err = Repos.Transaction(func(tx *repository.Repositories) error {
+		if err := activateMembership(tx, best.OrganizationID, user.ID, best.DefaultRole); err != nil {
+			return err
+		}
+		return tx.Users.SetActiveOrganization(user.ID, &best.OrganizationID)
+	})
+	if err != nil {
+		return false, err
+	}
+	user.ActiveOrganizationID = &best.OrganizationID
+	return true, nil
+}
+
+// requestMembership 承認待ちのメンバーとして登録する（既に行があれば何もしない）
+func requestMembership(orgID, userID uint) error {
+	if _, err := Repos.Organizations.GetMember(orgID, userID); err == nil {
+		return nil
+	}
+	return Repos.Organizations.AddMember(&models.OrganizationMember{
+		UserID:         userID,
+		OrganizationID: orgID,
+		Role:           models.OrgRoleMember,
+		Status:         models.MemberStatusPending,
+	})
+}
+
+// domainView ドメイン一覧の1行。未確認のものには設定すべき TXT レコードを添える
+type domainView struct {
+	models.OrganizationDomain
+	TXTRecordName  string `json:"txt_record_name"`
+	TXTRecordValue string `json:"txt_record_value"`
+}
+
+func newDomainView(d models.OrganizationDomain) domainView {
+	return domainView{
+		OrganizationDomain: d,
+		TXTRecordName:      domainTXTPrefix + d.Domain,
+		TXTRecordValue:     domainTXTValuePrefix + d.VerificationToken,
+	}
+}
+
+// GetOrganizationDomains 組織のドメイン一覧（admin 以上・認可はルートで実施）
+func GetOrganizationDomains(c *gin.Context) {
+	orgID, _ := paramID(c, "id")
+	list, err := Repos.Organizations.ListDomains(orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	out := make([]domainView, 0, len(list))
+	for _, d := range list {
+		out = append(out, newDomainView(d))
+	}
+	c.JSON(http.StatusOK, gin.H{"domains": out})
+}
+
+type createDomainRequest struct {
+	Domain      string `json:"domain" binding:"required"`
+	DefaultRole string `json:"default_role"`
+}
+
+// validDomainRole ドメイン参加時の既定ロール。owner は自動付与しない
+func validDomainRole(c *gin.Context, role string) bool {
+	if role != models.OrgRoleMember && role != models.OrgRoleAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "default_role must be member or admin"})
+		return false
+	}
+	if !canAssignOrgRole(c.GetString("org_role"), "", role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permission to grant this role"})
+		return false
+	}
+	return true
+}
+
+// CreateOrganizationDomain ドメインを登録する。DNS TXT レコードで所有確認するまでは自動参加に使われない
+func CreateOrganizationDomain(c *gin.Context) {
+	orgID, _ := paramID(c, "id")
+	var req createDomainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	domain := normalizeDomain(req.Domain)
+	if !domainPattern.MatchString(domain) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid domain"})
+		return
+	}
+	if req.DefaultRole == "" {
+		req.DefaultRole = models.OrgRoleMember
+	}
+	if !validDomainRole(c, req.DefaultRole) {
+		return
+	}
+
+	if _, err := Repos.Organizations.FindDomain(orgID, domain); err == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "このドメインは既に登録されています"})
+		return
+	}
+
+	d := models.OrganizationDomain{
+		OrganizationID:    orgID,
+		Domain:            domain,
+		DefaultRole:       req.DefaultRole,
+		VerificationToken: generateRefreshToken(),
+	}
+	if err := Repos.Organizations.CreateDomain(&d); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"domain": newDomainView(d)})
+}
+
+// loadOrgDomain :id の組織に属する :domainId のドメイン
+func loadOrgDomain(c *gin.Context) (*models.OrganizationDomain, bool) {
+	orgID, _ := paramID(c, "id")
+	domainID, err := paramID(c, "domainId")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid domain ID"})
+		return nil, false
+	}
+	d, err := Repos.Organizations.GetDomain(orgID, domainID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Domain not found"})
+		return nil, false
+	}
+	return d, true
+}
+
+var errTXTRecordNotFound = errors.New("verification TXT record not found")
+
+// lookupVerificationRecord _sherpa-verification.<domain> の TXT レコードに確認トークンがあるか
+func lookupVerificationRecord(ctx context.Context, d *models.OrganizationDomain) error {
+	ctx, cancel := context.WithTimeout(ctx, dnsLookupTimeout)
+	defer cancel()
+	records, err := net.DefaultResolver.LookupTXT(ctx, domainTXTPrefix+d.Domain)
+	if err != nil {
+		return err
+	}
+	want := domainTXTValuePrefix + d.VerificationToken
+	for _, r := range records {
+		if strings.TrimSpace(r) == want {
+			return nil
+		}
+	}
+	return errTXTRecordNotFound
+}
+
+// VerifyOrganizationDomain DNS TXT レコードを確認してドメインを確認済みにする。
+// 同じドメインを別の組織が確認済みの場合は 409。
+func VerifyOrganizationDomain(c *gin.Context) {
+	d, ok := loadOrgDomain(c)
+	if !ok {
+		return
+	}
+	if d.VerifiedAt != nil {
+		c.JSON(http.StatusOK, gin.H{"domain": newDomainView(*d)})
+		return
+	}
+
+	verified, err := Repos.Organizations.ListVerifiedDomains([]string{d.Domain})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, v := range verified {
+		if v.OrganizationID != d.OrganizationID {
+			c.JSON(http.StatusConflict, gin.H{"error": "このドメインは別の組織で確認済みです"})
+			return
+		}
+	}
+
+	if err := lookupVerificationRecord(c.Request.Context(), d); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":            "TXT レコードを確認できませんでした: " + err.Error(),
+			"txt_record_name":  domainTXTPrefix + d.Domain,
+			"txt_record_value": domainTXTValuePrefix + d.VerificationToken,
+		})
+		return
+	}
+
+	now := time.Now()
+	d.VerifiedAt = &now
+	if err := Repos.Organizations.UpdateDomain(d); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"domain": newDomainView(*d)})
+}
+
+type updateDomainRequest struct {
+	DefaultRole string `json:"default_role" binding:"required"`
+}
+
+// UpdateOrganizationDomain 自動参加時の既定ロールを変更
+func UpdateOrganizationDomain(c *gin.Context) {
+	d, ok := loadOrgDomain(c)
+	if !ok {
+		return
+	}
+	var req updateDomainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !validDomainRole(c, req.DefaultRole) {
+		return
+	}
+	d.DefaultRole = req.DefaultRole
+	if err := Repos.Organizations.UpdateDomain(d); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"domain": newDomainView(*d)})
+}
+
+// DeleteOrganizationDomain ドメインを削除（既に参加したメンバーはそのまま）
+func DeleteOrganizationDomain(c *gin.Context) {
+	d, ok := loadOrgDomain(c)
+	if !ok {
+		return
+	}
+	if err := Repos.Organizations.DeleteDomain(d.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Domain deleted successfully"})
+}
@@ -0,0 +1,702 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"sherpa-backend/internal/authz"
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createPersonalOrganization 新規ユーザー用の組織を作り owner にする。アクティブ組織にも設定する
+func createPersonalOrganization(user *models.User) error {
+	org := models.Organization{Name: user.Name + " の組織"}
+	err := Repos.Transaction(func(tx *repository.Repositories) error {
+		if err := tx.Organizations.Create(&org); err != nil {
+			return err
+		}
+		member := models.OrganizationMember{
+			UserID:         user.ID,
+			OrganizationID: org.ID,
+			Role:           models.OrgRoleOwner,
+			Status:         models.MemberStatusActive,
+		}
+		if err := tx.Organizations.AddMember(&member); err != nil {
+			return err
+		}
+		return tx.Users.SetActiveOrganization(user.ID, &org.ID)
+	})
+	if err != nil {
+		return err
+	}
+	user.ActiveOrganizationID = &org.ID
+	return nil
+}
+
+// activeOrganizationID ユーザーのアクティブ組織。未設定または既に所属していない場合は ok=false
+func activeOrganizationID(userID uint) (uint, bool) {
+	user, err := Repos.Users.Get(userID)
+	if err != nil || user.ActiveOrganizationID == nil {
+		return 0, false
+	}
+	if _, err := authz.OrgRole(Repos.Organizations, *user.ActiveOrganizationID, userID); err != nil {
+		return 0, false
+	}
+	return *user.ActiveOrganizationID, true
+}
+
+// activateMembership 承認済みメンバーにする。承認待ちの行があればロールを設定して承認し、承認済みなら何もしない。
+// repos にトランザクションを渡せば呼び出し側の書き込みとまとめて確定する
+func activateMembership(repos *repository.Repositories, orgID, userID uint, role string) error {
+	m, err := repos.Organizations.GetMember(orgID, userID)
+	if err == nil {
+		if m.Status == models.MemberStatusActive {
+			return nil
+		}
+		m.Status = models.MemberStatusActive
+		m.Role = role
+		return repos.Organizations.UpdateMember(m)
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	return repos.Organizations.AddMember(&models.OrganizationMember{
+		UserID:         userID,
+		OrganizationID: orgID,
+		Role:           role,
+		Status:         models.MemberStatusActive,
+	})
+}
+
+// ownerCount 組織の owner 数（最後の owner を外さないための確認用）
+func ownerCount(orgID uint) (int64, error) {
+	return Repos.Organizations.CountOwners(orgID)
+}
+
+// findMember 組織の status のメンバー。該当がなければ repository.ErrNotFound
+func findMember(orgID, userID uint, status string) (*models.OrganizationMember, error) {
+	m, err := Repos.Organizations.GetMember(orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if m.Status != status {
+		return nil, repository.ErrNotFound
+	}
+	return m, nil
+}
+
+// canAssignOrgRole actor が target（現在のロール）を role に変更・招待できるか。
+// owner は何でもでき、admin は member / admin の付与と member の管理のみ。
+func canAssignOrgRole(actorRole, targetRole, role string) bool {
+	if actorRole == models.OrgRoleOwner {
+		return true
+	}
+	if actorRole != models.OrgRoleAdmin {
+		return false
+	}
+	if targetRole != "" && targetRole != models.OrgRoleMember {
+		return false
+	}
+	return role == models.OrgRoleMember || role == models.OrgRoleAdmin
+}
+
+// orgView 組織一覧の1行。role / status は呼び出し元のメンバーシップ
+type orgView struct {
+	models.Organization
+	Role   string `json:"role"`
+	Status string `json:"status"`
+	Active bool   `json:"active"`
+}
+
+// GetOrganizations 自分が所属する組織一覧（承認待ちを含む）
+func GetOrganizations(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	members, err := Repos.Organizations.ListMemberships(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	activeID, _ := activeOrganizationID(uid)
+
+	out := make([]orgView, 0, len(members))
+	for _, m := range members {
+		out = append(out, orgView{Organization: m.Organization, Role: m.Role, Status: m.Status, Active: m.OrganizationID == activeID})
+	}
+	c.JSON(http.StatusOK, gin.H{"organizations": out})
+}
+
+type organizationRequest struct {
+	Name        string  `json:"name" binding:"required"`
+	Description *string `json:"description"`
+}
+
+// CreateOrganization 組織を作成し、作成者を owner にする
+func CreateOrganization(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	var req organizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	// owner のいない組織を残さない
+	org := models.Organization{Name: name, Description: req.Description}
+	err := Repos.Transaction(func(tx *repository.Repositories) error {
+		if err := tx.Organizations.Create(&org); err != nil {
+			return err
+		}
+		member := models.OrganizationMember{UserID: uid, OrganizationID: org.ID, Role: models.OrgRoleOwner, Status: models.MemberStatusActive}
+		return tx.Organizations.AddMember(&member)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if _, hasActive := activeOrganizationID(uid); !hasActive {
+		_ = Repos.Users.SetActiveOrganization(uid, &org.ID)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"organization": org})
+}
+
+// GetOrganization 組織詳細（メンバーのみ・認可はルートで実施）
+func GetOrganization(c *gin.Context) {
+	id, _ := paramID(c, "id")
+	org, err := Repos.Organizations.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	role, _ := c.Get("org_role")
+	c.JSON(http.StatusOK, gin.H{"organization": org, "role": role})
+}
+
+// UpdateOrganization 組織名・説明を更新（admin 以上・認可はルートで実施）
+func UpdateOrganization(c *gin.Context) {
+	id, _ := paramID(c, "id")
+	org, err := Repos.Organizations.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	var req organizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+	org.Name = name
+	org.Description = req.Description
+	if err := Repos.Organizations.Update(org); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"organization": org})
+}
+
+// DeleteOrganization 組織を削除（owner のみ・認可はルートで実施）。イベントが残っている組織は削除できない
+func DeleteOrganization(c *gin.Context) {
+	id, _ := paramID(c, "id")
+	events, err := Repos.Events.CountByOrganization(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if events > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "イベントが残っている組織は削除できません"})
+		return
+	}
+
+	err = Repos.Transaction(func(tx *repository.Repositories) error {
+		if err := tx.Users.ClearActiveOrganization(id); err != nil {
+			return err
+		}
+		return tx.Organizations.Delete(id)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Organization deleted successfully"})
+}
+
+// GetOrganizationMembers 組織メンバー一覧（メンバーのみ・認可はルートで実施）。承認待ちは GetPendingOrganizationMembers
+func GetOrganizationMembers(c *gin.Context) {
+	id, _ := paramID(c, "id")
+	members, err := Repos.Organizations.ListMembers(id, models.MemberStatusActive)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"members": members})
+}
+
+type updateOrgMemberRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// UpdateOrganizationMember メンバーのロールを変更（admin 以上・認可はルートで実施）
+func UpdateOrganizationMember(c *gin.Context) {
+	orgID, _ := paramID(c, "id")
+	targetID, err := paramID(c, "userId")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	var req updateOrgMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !models.ValidOrgRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be owner, admin or member"})
+		return
+	}
+
+	member, err := findMember(orgID, targetID, models.MemberStatusActive)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+	actorRole := c.GetString("org_role")
+	if !canAssignOrgRole(actorRole, member.Role, req.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permission to change this role"})
+		return
+	}
+	if member.Role == models.OrgRoleOwner && req.Role != models.OrgRoleOwner {
+		if n, err := ownerCount(orgID); err != nil || n <= 1 {
+			c.JSON(http.StatusConflict, gin.H{"error": "最後の owner のロールは変更できません"})
+			return
+		}
+	}
+
+	member.Role = req.Role
+	if err := Repos.Organizations.UpdateMember(member); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"member": member})
+}
+
+// RemoveOrganizationMember メンバーを外す。自分自身なら脱退（ロール不問）、他人は admin 以上
+func RemoveOrganizationMember(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	orgID, _ := paramID(c, "id")
+	targetID, err := paramID(c, "userId")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	member, err := findMember(orgID, targetID, models.MemberStatusActive)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+	if targetID != uid {
+		actorRole := c.GetString("org_role")
+		if !authz.CanOrg(actorRole, authz.ActionOrgMemberManage) || !canAssignOrgRole(actorRole, member.Role, models.OrgRoleMember) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permission to remove this member"})
+			return
+		}
+	}
+	if member.Role == models.OrgRoleOwner {
+		if n, err := ownerCount(orgID); err != nil || n <= 1 {
+			c.JSON(http.StatusConflict, gin.H{"error": "最後の owner は組織から外せません。先に別のメンバーを owner にしてください"})
+			return
+		}
+	}
+
+	err = Repos.Transaction(func(tx *repository.Repositories) error {
+		if err := tx.Organizations.RemoveMember(member.ID); err != nil {
+			return err
+		}
+		target, err := tx.Users.Get(targetID)
+		if err != nil || target.ActiveOrganizationID == nil || *target.ActiveOrganizationID != orgID {
+			return nil
+		}
+		return tx.Users.SetActiveOrganization(targetID, nil)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+// GetPendingOrganizationMembers 承認待ちのメンバー一覧（admin 以上・認可はルートで実施）
+func GetPendingOrganizationMembers(c *gin.Context) {
+	id, _ := paramID(c, "id")
+	members, err := Repos.Organizations.ListMembers(id, models.MemberStatusPending)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"members": members})
+}
+
+// loadPendingMember :id の組織の :userId の承認待ちメンバー
+func loadPendingMember(c *gin.Context) (*models.OrganizationMember, bool) {
+	orgID, _ := paramID(c, "id")
+	targetID, err := paramID(c, "userId")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, false
+	}
+	member, err := findMember(orgID, targetID, models.MemberStatusPending)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pending member not found"})
+		return nil, false
+	}
+	return member, true
+}
+
+type approveMemberRequest struct {
+	Role string `json:"role"`
+}
+
+// ApproveOrganizationMember 承認待ちのメンバーを承認する（role 省略時は member）
+func ApproveOrganizationMember(c *gin.Context) {
+	member, ok := loadPendingMember(c)
+	if !ok {
+		return
+	}
+	var req approveMemberRequest
+	_ = c.ShouldBindJSON(&req)
+	if req.Role == "" {
+		req.Role = models.OrgRoleMember
+	}
+	if !models.ValidOrgRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be owner, admin or member"})
+		return
+	}
+	if !canAssignOrgRole(c.GetString("org_role"), "", req.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permission to grant this role"})
+		return
+	}
+
+	member.Status = models.MemberStatusActive
+	member.Role = req.Role
+	if err := Repos.Organizations.UpdateMember(member); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if _, hasActive := activeOrganizationID(member.UserID); !hasActive {
+		_ = Repos.Users.SetActiveOrganization(member.UserID, &member.OrganizationID)
+	}
+
+	var orgName string
+	if org, err := Repos.Organizations.Get(member.OrganizationID); err == nil {
+		orgName = org.Name
+	}
+	n := models.Notification{
+		UserID:     member.UserID,
+		Type:       models.NotificationTypeOrgApproved,
+		Title:      "組織への参加が承認されました",
+		Body:       "組織「" + orgName + "」への参加が承認されました。",
+		RelatedID:  member.OrganizationID,
+		RelatedTyp: "organization",
+	}
+	_ = Repos.Notifications.Create(&n)
+
+	c.JSON(http.StatusOK, gin.H{"member": member})
+}
+
+// RejectOrganizationMember 承認待ちのメンバーを却下する（行を削除）
+func RejectOrganizationMember(c *gin.Context) {
+	member, ok := loadPendingMember(c)
+	if !ok {
+		return
+	}
+	if err := Repos.Organizations.RemoveMember(member.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Membership request rejected"})
+}
+
+type setActiveOrgRequest struct {
+	OrganizationID uint `json:"organization_id" binding:"required"`
+}
+
+// SetActiveOrganization PUT /api/me/active-organization。所属している組織にのみ切り替えられる
+func SetActiveOrganization(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	var req setActiveOrgRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "organization_id is required"})
+		return
+	}
+	if _, err := authz.OrgRole(Repos.Organizations, req.OrganizationID, uid); err != nil {
+		if errors.Is(err, authz.ErrNotOrgMember) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this organization"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := Repos.Users.SetActiveOrganization(uid, &req.OrganizationID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"active_organization_id": req.OrganizationID})
+}
+
+type createOrgInviteRequest struct {
+	UserID *uint  `json:"user_id"`
+	Email  string `json:"email"`
+	Role   string `json:"role"`
+}
+
+// CreateOrganizationInvitation 組織への招待を作成し、通知を送る。user_id または email のどちらかを指定。
+// 招待者が admin 以上であることはルートの RequireOrgPermission で確認済み。
+func CreateOrganizationInvitation(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	orgID, _ := paramID(c, "id")
+
+	var req createOrgInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.OrgRoleMember
+	}
+	if !models.ValidOrgRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be owner, admin or member"})
+		return
+	}
+	if !canAssignOrgRole(c.GetString("org_role"), "", req.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permission to invite with this role"})
+		return
+	}
+
+	var targetUserID uint
+	if req.UserID != nil {
+		targetUserID = *req.UserID
+	} else if req.Email != "" {
+		u, err := findUserByEmail(req.Email)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "このメールアドレスのユーザーが見つかりません"})
+			return
+		}
+		targetUserID = u.ID
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id または email を指定してください"})
+		return
+	}
+
+	org, err := Repos.Organizations.Get(orgID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	// 既にメンバー or 重複pendingは弾く
+	if _, err := authz.OrgRole(Repos.Organizations, orgID, targetUserID); err == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ユーザーは既に組織のメンバーです"})
+		return
+	}
+	if _, err := Repos.Organizations.FindPendingInvitation(orgID, targetUserID); err == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "既に招待を送信しています"})
+		return
+	}
+
+	inv := models.OrganizationInvitation{
+		OrganizationID: orgID,
+		InviterID:      uid,
+		UserID:         targetUserID,
+		Role:           req.Role,
+		Status:         models.InvitationStatusPending,
+	}
+	if err := Repos.Organizations.CreateInvitation(&inv); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	n := models.Notification{
+		UserID:     targetUserID,
+		Type:       models.NotificationTypeOrgInvite,
+		Title:      "組織への招待",
+		Body:       inv.Inviter.Name + " さんから組織「" + org.Name + "」への招待が届きました。",
+		RelatedID:  inv.ID,
+		RelatedTyp: "organization_invitation",
+	}
+	_ = Repos.Notifications.Create(&n) // 招待は成立しているので通知の失敗は無視
+
+	c.JSON(http.StatusCreated, gin.H{"invitation": inv})
+}
+
+// GetOrganizationInvitations 組織の招待一覧（admin 以上・認可はルートで実施）
+func GetOrganizationInvitations(c *gin.Context) {
+	orgID, _ := paramID(c, "id")
+	list, err := Repos.Organizations.ListInvitations(orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"invitations": list})
+}
+
+// GetMyOrganizationInvitations 自分宛ての未回答の組織招待
+func GetMyOrganizationInvitations(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	list, err := Repos.Organizations.ListPendingInvitationsForUser(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"invitations": list})
+}
+
+// loadMyOrgInvitation :id の招待を取得し、本人宛て・未回答であることを確認する
+func loadMyOrgInvitation(c *gin.Context) (*models.OrganizationInvitation, bool) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return nil, false
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return nil, false
+	}
+	inv, err := Repos.Organizations.GetInvitation(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return nil, false
+	}
+	if inv.UserID != uid {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not your invitation"})
+		return nil, false
+	}
+	if inv.Status != models.InvitationStatusPending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invitation already handled"})
+		return nil, false
+	}
+	return inv, true
+}
+
+// AcceptOrganizationInvitation 組織招待を承諾 → OrganizationMember 追加
+func AcceptOrganizationInvitation(c *gin.Context) {
+	inv, ok := loadMyOrgInvitation(c)
+	if !ok {
+		return
+	}
+
+	inv.Status = models.InvitationStatusAccepted
+	err := Repos.Transaction(func(tx *repository.Repositories) error {
+		if err := tx.Organizations.UpdateInvitation(inv); err != nil {
+			return err
+		}
+		return activateMembership(tx, inv.OrganizationID, inv.UserID, inv.Role)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	member, _ := Repos.Organizations.GetMember(inv.OrganizationID, inv.UserID)
+	if _, hasActive := activeOrganizationID(inv.UserID); !hasActive {
+		_ = Repos.Users.SetActiveOrganization(inv.UserID, &inv.OrganizationID)
+	}
+
+	Repos.Notifications.MarkRelatedRead(inv.UserID, "organization_invitation", inv.ID)
+
+	c.JSON(http.StatusOK, gin.H{"invitation": inv, "member": member})
+}
+
+// DeclineOrganizationInvitation 組織招待を辞退
+func DeclineOrganizationInvitation(c *gin.Context) {
+	inv, ok := loadMyOrgInvitation(c)
+	if !ok {
+		return
+	}
+
+	inv.Status = models.InvitationStatusDeclined
+	if err := Repos.Organizations.UpdateInvitation(inv); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	Repos.Notifications.MarkRelatedRead(inv.UserID, "organization_invitation", inv.ID)
+
+	c.JSON(http.StatusOK, gin.H{"invitation": inv})
+}
+
+// eventOrgScope イベント一覧の対象組織を決める。?organization_id=<id> / all、未指定ならアクティブ組織（なければ所属組織すべて）。
+// 所属していない組織を指定した場合はレスポンスを書き込み false を返す。
+func eventOrgScope(c *gin.Context, uid uint) ([]uint, bool) {
+	q := c.Query("organization_id")
+	switch {
+	case q == "all":
+		return memberOrganizationIDs(c, uid)
+	case q != "":
+		id, err := strconv.ParseUint(q, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+			return nil, false
+		}
+		if _, err := authz.OrgRole(Repos.Organizations, uint(id), uid); err != nil {
+			if errors.Is(err, authz.ErrNotOrgMember) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this organization"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return nil, false
+		}
+		return []uint{uint(id)}, true
+	}
+	if id, ok := activeOrganizationID(uid); ok {
+		return []uint{id}, true
+	}
+	return memberOrganizationIDs(c, uid)
+}
+
+// memberOrganizationIDs 承認済みメンバーとして所属する組織のID。失敗時はレスポンスを書き込み false を返す
+func memberOrganizationIDs(c *gin.Context, uid uint) ([]uint, bool) {
+	ids, err := Repos.Organizations.MemberOrganizationIDs(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return ids, true
+}
@@ -0,0 +1,572 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/payment"
+	"sherpa-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ticketCurrency チケット価格（Ticket.Price）の通貨
+const ticketCurrency = "jpy"
+
+// maxWebhookBody Webhook 本文の上限
+const maxWebhookBody = 1 << 20
+
+var errOrderNotPaid = errors.New("支払い済みの注文ではありません")
+
+var errOrderSuperseded = errors.New("決済画面を作成している間に注文が無効になりました")
+
+var errOrderProcessing = errors.New("入金待ちの注文があります")
+
+// ticketRevenueCategory チケット売上として自動計上する予算項目のカテゴリ名
+func ticketRevenueCategory(t *models.Ticket) string {
+	return "チケット売上（" + t.Name + "）"
+}
+
+// postTicketRevenue チケット種別の売上項目（なければ作る）の実績額に delta を加える。
+// tx の中でチケット行をロックしてから呼ぶこと（売上項目の作成が重複しないように）
+func postTicketRevenue(tx *repository.Repositories, t *models.Ticket, delta int) error {
+	b, err := tx.Budgets.FindByTicket(t.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		ticketID := t.ID
+		b = &models.Budget{
+			EventID:  t.EventID,
+			Category: ticketRevenueCategory(t),
+			Type:     models.BudgetTypeIncome,
+			TicketID: &ticketID,
+		}
+		err = tx.Budgets.Create(b)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Budgets.AddActual(b.ID, delta)
+}
+
+// applyRefund 注文を全額返金済みにし、売上を取り消して参加登録を取り消す（空いた席はキャンセル待ちに回す）。
+// 一部返金済みの分はすでに売上から差し引いてある。tx の中で t の行ロックを取ってから呼ぶこと
+func applyRefund(tx *repository.Repositories, t *models.Ticket, o *models.Order, now time.Time) error {
+	remaining := o.Amount - o.RefundedAmount
+	o.Status = models.OrderStatusRefunded
+	o.RefundedAmount = o.Amount
+	o.RefundedAt = &now
+	if err := tx.Orders.Update(o); err != nil {
+		return err
+	}
+	if err := postTicketRevenue(tx, t, -remaining); err != nil {
+		return err
+	}
+	p, err := tx.Tickets.GetParticipant(o.EventParticipantID)
+	if err != nil {
+		return err
+	}
+	if p.Status.CanTransitionTo(models.ParticipantStatusCancelled) {
+		if err := transitionParticipant(p, models.ParticipantStatusCancelled); err != nil {
+			return err
+		}
+		if err := tx.Tickets.UpdateParticipant(p); err != nil {
+			return err
+		}
+	}
+	return fillSeats(tx, t, now)
+}
+
+// markRefunded 返金待ちの注文を返金済みにする。売上にも参加登録にも触れない
+func markRefunded(tx *repository.Repositories, o *models.Order, now time.Time) error {
+	o.Status = models.OrderStatusRefunded
+	o.RefundedAmount = o.Amount
+	o.RefundedAt = &now
+	return tx.Orders.Update(o)
+}
+
+// applyPartialRefund 一部返金（refunded は返金済みの累計額）を売上から差し引く。参加登録はそのまま。
+// 累計額なので、古い Webhook が後から届いても二重に差し引かない
+func applyPartialRefund(tx *repository.Repositories, t *models.Ticket, o *models.Order, refunded int) error {
+	delta := refunded - o.RefundedAmount
+	if delta <= 0 {
+		return nil
+	}
+	o.RefundedAmount = refunded
+	if err := tx.Orders.Update(o); err != nil {
+		return err
+	}
+	if o.Status != models.OrderStatusPaid {
+		return nil
+	}
+	return postTicketRevenue(tx, t, -delta)
+}
+
+// refundPaidOrder プロバイダで返金してから注文に反映する。プロバイダ側は注文IDを冪等キーにするので、同時に呼ばれても二重に返金されない。
+// 返金待ち（OrderStatusRefundPending）の注文は売上に計上していないので、返金済みにするだけ
+func refundPaidOrder(ctx context.Context, orderID uint) (*models.Order, error) {
+	o, err := Repos.Orders.Get(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if o.Status != models.OrderStatusPaid && o.Status != models.OrderStatusRefundPending {
+		return nil, errOrderNotPaid
+	}
+	if o.Provider != payment.DefaultProvider.Name() {
+		return nil, fmt.Errorf("order was paid with %s provider", o.Provider)
+	}
+	// 一部返金済みなら残りを返金する
+	if err := payment.DefaultProvider.Refund(ctx, payment.RefundRequest{OrderID: o.ID, PaymentID: o.PaymentID, Amount: o.Amount - o.RefundedAmount}); err != nil {
+		return nil, err
+	}
+	err = Repos.Transaction(func(tx *repository.Repositories) error {
+		t, err := tx.Tickets.GetForUpdate(o.TicketID)
+		if err != nil {
+			return err
+		}
+		current, err := tx.Orders.Get(o.ID)
+		if err != nil {
+			return err
+		}
+		// 返金の Webhook が先に届いていれば反映済み
+		switch current.Status {
+		case models.OrderStatusPaid:
+			return applyRefund(tx, t, current, time.Now())
+		case models.OrderStatusRefundPending:
+			return markRefunded(tx, current, time.Now())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return Repos.Orders.Get(o.ID)
+}
+
+// cancelUserRegistrations 退会するユーザーの参加登録（キャンセル待ちを含む）を取り消し、支払い待ちの注文は期限切れにする。
+// 空いた席はキャンセル待ちに回す。最後に支払い済みの注文を返金し、売上から差し引く。
+// 取り消した後に決済が完了しても、Webhook の処理で返金される
+func cancelUserRegistrations(ctx context.Context, uid uint) error {
+	list, err := Repos.Tickets.ListParticipantsByUser(uid)
+	if err != nil {
+		return err
+	}
+	for _, reg := range list {
+		if !slices.Contains(models.OpenParticipantStatuses, reg.Status) {
+			continue
+		}
+		err := Repos.Transaction(func(tx *repository.Repositories) error {
+			t, err := tx.Tickets.GetForUpdate(reg.TicketID)
+			if err != nil {
+				return err
+			}
+			p, err := tx.Tickets.GetParticipant(reg.ID)
+			if err != nil {
+				return err
+			}
+			now := time.Now()
+			if p.Status.CanTransitionTo(models.ParticipantStatusCancelled) {
+				if err := transitionParticipant(p, models.ParticipantStatusCancelled); err != nil {
+					return err
+				}
+				if err := tx.Tickets.UpdateParticipant(p); err != nil {
+					return err
+				}
+			}
+			for {
+				o, err := tx.Orders.FindPendingByParticipant(p.ID)
+				if errors.Is(err, repository.ErrNotFound) {
+					break
+				}
+				if err != nil {
+					return err
+				}
+				o.Status = models.OrderStatusExpired
+				if err := tx.Orders.Update(o); err != nil {
+					return err
+				}
+			}
+			return fillSeats(tx, t, now)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	orders, err := Repos.Orders.ListByUser(uid)
+	if err != nil {
+		return err
+	}
+	for _, o := range orders {
+		if o.Status != models.OrderStatusPaid && o.Status != models.OrderStatusRefundPending {
+			continue
+		}
+		if payment.DefaultProvider == nil {
+			return errors.New("payment provider is not configured")
+		}
+		if _, err := refundPaidOrder(ctx, o.ID); err != nil && !errors.Is(err, errOrderNotPaid) {
+			return fmt.Errorf("refund order %d: %w", o.ID, err)
+		}
+	}
+	return nil
+}
+
+// handlePaymentEvent 検証済みの Webhook を1トランザクションで処理する。処理済みのイベントなら duplicate=true。
+// エラーを返すとイベントの記録も取り消されるので、プロバイダの再送で処理し直される
+func handlePaymentEvent(provider string, ev *payment.WebhookEvent) (duplicate bool, err error) {
+	var refundAfter uint
+	err = Repos.Transaction(func(tx *repository.Repositories) error {
+		recorded, err := tx.Orders.RecordWebhookEvent(&models.PaymentWebhookEvent{Provider: provider, EventID: ev.ID, Type: string(ev.Type)})
+		if err != nil {
+			return err
+		}
+		if !recorded {
+			duplicate = true
+			return nil
+		}
+
+		var o *models.Order
+		switch ev.Type {
+		case payment.EventCheckoutCompleted, payment.EventCheckoutProcessing, payment.EventCheckoutFailed, payment.EventCheckoutExpired:
+			o, err = tx.Orders.FindByCheckout(provider, ev.CheckoutID)
+		case payment.EventRefunded:
+			o, err = tx.Orders.FindByPayment(provider, ev.PaymentID)
+		default:
+			return nil
+		}
+		if errors.Is(err, repository.ErrNotFound) {
+			log.Printf("[payment] %s %s: order not found", provider, ev.ID)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// 参加登録・キャンセル待ちと同じくチケット行をロックしてから注文を読み直す
+		t, err := tx.Tickets.GetForUpdate(o.TicketID)
+		if err != nil {
+			return err
+		}
+		if o, err = tx.Orders.Get(o.ID); err != nil {
+			return err
+		}
+		now := time.Now()
+
+		switch ev.Type {
+		case payment.EventCheckoutCompleted:
+			switch o.Status {
+			case models.OrderStatusPaid, models.OrderStatusRefundPending, models.OrderStatusRefunded:
+				return nil
+			}
+			p, err := tx.Tickets.GetParticipant(o.EventParticipantID)
+			if err != nil {
+				return err
+			}
+			o.PaymentID = ev.PaymentID
+			o.PaidAt = &now
+			if p.Status != models.ParticipantStatusPending {
+				// 支払いの間に取り消された登録や、別の注文で支払い済みの登録には席を割り当てない。売上に計上せず返金する
+				log.Printf("[payment] order %d paid for %s registration %d: refunding", o.ID, p.Status, p.ID)
+				o.Status = models.OrderStatusRefundPending
+				refundAfter = o.ID
+				return tx.Orders.Update(o)
+			}
+			o.Status = models.OrderStatusPaid
+			if err := tx.Orders.Update(o); err != nil {
+				return err
+			}
+			if err := postTicketRevenue(tx, t, o.Amount); err != nil {
+				return err
+			}
+			if err := transitionParticipant(p, models.ParticipantStatusConfirmed); err != nil {
+				return err
+			}
+			return tx.Tickets.UpdateParticipant(p)
+		case payment.EventCheckoutProcessing:
+			// 入金されるまで席は確定しない。入金待ちの間は決済をやり直させない
+			if o.Status != models.OrderStatusPending && o.Status != models.OrderStatusExpired {
+				return nil
+			}
+			o.Status = models.OrderStatusProcessing
+			o.PaymentID = ev.PaymentID
+			return tx.Orders.Update(o)
+		case payment.EventCheckoutFailed:
+			// 登録は支払い待ちのまま残り、決済をやり直せる
+			if o.Status != models.OrderStatusPending && o.Status != models.OrderStatusProcessing {
+				return nil
+			}
+			o.Status = models.OrderStatusFailed
+			return tx.Orders.Update(o)
+		case payment.EventCheckoutExpired:
+			if o.Status != models.OrderStatusPending {
+				return nil
+			}
+			o.Status = models.OrderStatusExpired
+			return tx.Orders.Update(o)
+		case payment.EventRefunded:
+			if o.Status != models.OrderStatusPaid && o.Status != models.OrderStatusRefundPending {
+				return nil
+			}
+			// 一部返金なら売上から差し引くだけで、参加登録は取り消さない
+			if ev.Amount < o.Amount {
+				return applyPartialRefund(tx, t, o, ev.Amount)
+			}
+			if o.Status == models.OrderStatusRefundPending {
+				return markRefunded(tx, o, now)
+			}
+			return applyRefund(tx, t, o, now)
+		}
+		return nil
+	})
+	if err != nil || refundAfter == 0 {
+		return duplicate, err
+	}
+	if _, err := refundPaidOrder(context.Background(), refundAfter); err != nil {
+		log.Printf("[payment] refund order %d without a seat: %v", refundAfter, err)
+	}
+	return duplicate, nil
+}
+
+// PaymentWebhook 決済プロバイダからの Webhook（認証なし、署名で検証）。同じイベントの再送は処理せずに 200 を返す
+func PaymentWebhook(c *gin.Context) {
+	provider := payment.DefaultProvider
+	if provider == nil || c.Param("provider") != provider.Name() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown payment provider"})
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	processWebhook(c, provider, body, c.Request.Header)
+}
+
+func processWebhook(c *gin.Context, provider payment.Provider, body []byte, header http.Header) {
+	ev, err := provider.ParseWebhook(body, header)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	duplicate, err := handlePaymentEvent(provider.Name(), ev)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"received": true, "duplicate": duplicate})
+}
+
+// CompleteFakeCheckout フェイクの決済画面で支払ったことにする（PAYMENT_PROVIDER=fake のときだけ。開発用）
+func CompleteFakeCheckout(c *gin.Context) {
+	fake, ok := payment.DefaultProvider.(*payment.FakeProvider)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+	body, header, err := fake.Complete(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	processWebhook(c, fake, body, header)
+}
+
+// StartCheckout 支払い待ちの自分の参加登録の決済画面を作る。有効な決済画面があればそれを返し、なければ古い支払い待ちの注文を期限切れにして作り直す
+func StartCheckout(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	id, err := paramID(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid registration ID"})
+		return
+	}
+	provider := payment.DefaultProvider
+	if provider == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Payment provider is not configured"})
+		return
+	}
+	p, err := Repos.Tickets.GetParticipant(id)
+	if err != nil || p.UserID != uid {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Registration not found"})
+		return
+	}
+	if p.Status != models.ParticipantStatusPending || p.AmountDue() == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "支払い待ちの参加登録ではありません", "status": p.Status})
+		return
+	}
+
+	event, err := Repos.Events.Get(p.Ticket.EventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 同時に呼ばれても支払い待ちの注文が1件になるよう、チケット行をロックしてから探す。
+	// 使えない支払い待ちの注文（期限切れ・別のプロバイダ）は期限切れにしてから作り直す
+	var o, reuse *models.Order
+	err = Repos.Transaction(func(tx *repository.Repositories) error {
+		if _, err := tx.Tickets.GetForUpdate(p.TicketID); err != nil {
+			return err
+		}
+		current, err := tx.Tickets.GetParticipant(p.ID)
+		if err != nil {
+			return err
+		}
+		if current.Status != models.ParticipantStatusPending {
+			return errOrderSuperseded
+		}
+		// コンビニ払いなどの入金待ちの間に決済をやり直すと二重に支払われる
+		orders, err := tx.Orders.ListByUser(uid)
+		if err != nil {
+			return err
+		}
+		for i := range orders {
+			if orders[i].EventParticipantID == p.ID && orders[i].Status == models.OrderStatusProcessing {
+				reuse = &orders[i]
+				return errOrderProcessing
+			}
+		}
+		now := time.Now()
+		for {
+			pending, err := tx.Orders.FindPendingByParticipant(p.ID)
+			if errors.Is(err, repository.ErrNotFound) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if pending.Provider == provider.Name() && pending.CheckoutURL != "" && (pending.ExpiresAt == nil || pending.ExpiresAt.After(now)) {
+				reuse = pending
+				return nil
+			}
+			pending.Status = models.OrderStatusExpired
+			if err := tx.Orders.Update(pending); err != nil {
+				return err
+			}
+		}
+		o = &models.Order{
+			EventID:            event.ID,
+			TicketID:           p.TicketID,
+			EventParticipantID: p.ID,
+			UserID:             uid,
+			Amount:             p.AmountDue(),
+			Currency:           ticketCurrency,
+			Status:             models.OrderStatusPending,
+			Provider:           provider.Name(),
+		}
+		return tx.Orders.Create(o)
+	})
+	if errors.Is(err, errOrderSuperseded) {
+		c.JSON(http.StatusConflict, gin.H{"error": "支払い待ちの参加登録ではありません"})
+		return
+	}
+	if errors.Is(err, errOrderProcessing) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "order": reuse})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if reuse != nil {
+		c.JSON(http.StatusOK, gin.H{"order": reuse, "checkout_url": reuse.CheckoutURL})
+		return
+	}
+	returnURL := fmt.Sprintf("%s/events/%d/registration?order=%d", frontendURL(), event.ID, o.ID)
+	checkout, err := provider.CreateCheckout(c.Request.Context(), payment.CheckoutRequest{
+		OrderID:     o.ID,
+		Amount:      o.Amount,
+		Currency:    o.Currency,
+		Description: event.Title + " - " + p.Ticket.Name,
+		Email:       p.User.Email,
+		SuccessURL:  returnURL + "&payment=success",
+		CancelURL:   returnURL + "&payment=cancelled",
+	})
+	if err != nil {
+		o.Status = models.OrderStatusFailed
+		if uerr := Repos.Orders.Update(o); uerr != nil {
+			log.Printf("[payment] mark order %d failed: %v", o.ID, uerr)
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "決済画面を作成できませんでした: " + err.Error()})
+		return
+	}
+	// 決済画面を作っている間に期限切れにされた注文（登録の取り消し・別のリクエストでの作り直し）は支払い待ちに戻さない
+	err = Repos.Transaction(func(tx *repository.Repositories) error {
+		if _, err := tx.Tickets.GetForUpdate(o.TicketID); err != nil {
+			return err
+		}
+		current, err := tx.Orders.Get(o.ID)
+		if err != nil {
+			return err
+		}
+		if current.Status != models.OrderStatusPending {
+			return errOrderSuperseded
+		}
+		current.CheckoutID, current.CheckoutURL, current.ExpiresAt = checkout.ID, checkout.URL, checkout.ExpiresAt
+		o = current
+		return tx.Orders.Update(current)
+	})
+	if errors.Is(err, errOrderSuperseded) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"order": o, "checkout_url": o.CheckoutURL})
+}
+
+// GetMyOrders 自分の注文（チケット付き）
+func GetMyOrders(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	list, err := Repos.Orders.ListByUser(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"orders": list})
+}
+
+// GetEventOrders イベントの注文一覧
+func GetEventOrders(c *gin.Context) {
+	eventID, _ := paramID(c, "id")
+	list, err := Repos.Orders.ListByEvent(eventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"orders": list})
+}
+
+// RefundOrder 主催者による返金。参加登録は取り消され、売上から差し引く
+func RefundOrder(c *gin.Context) {
+	id, _ := paramID(c, "id")
+	if payment.DefaultProvider == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Payment provider is not configured"})
+		return
+	}
+	o, err := refundPaidOrder(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+	case errors.Is(err, errOrderNotPaid):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadGateway, gin.H{"error": "返金できませんでした: " + err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"order": o})
+	}
+}
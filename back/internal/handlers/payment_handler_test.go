@@ -0,0 +1,400 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/payment"
+	"sherpa-backend/internal/repository/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+// useFakeProvider payment.DefaultProvider を FakeProvider に差し替える。テストの終わりに元に戻す
+func useFakeProvider(t *testing.T) *payment.FakeProvider {
+	t.Helper()
+	fake := payment.NewFakeProvider("")
+	prev := payment.DefaultProvider
+	payment.DefaultProvider = fake
+	t.Cleanup(func() { payment.DefaultProvider = prev })
+	return fake
+}
+
+// startCheckout 参加登録の決済画面を作り、注文を返す
+func startCheckout(t *testing.T, uid, participantID uint) *models.Order {
+	t.Helper()
+	w := call(StartCheckout, uid, idParam("id", participantID), nil)
+	expectStatus(t, w, http.StatusCreated)
+	var res struct {
+		Order models.Order `json:"order"`
+	}
+	decode(t, w, &res)
+	if res.Order.Status != models.OrderStatusPending || res.Order.CheckoutID == "" {
+		t.Fatalf("order = %+v", res.Order)
+	}
+	return &res.Order
+}
+
+// deliver FakeProvider が作った Webhook を processWebhook に渡し、duplicate を返す
+func deliver(t *testing.T, fake *payment.FakeProvider, body []byte, header http.Header, err error) bool {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := call(func(c *gin.Context) { processWebhook(c, fake, body, header) }, 0, nil, nil)
+	expectStatus(t, w, http.StatusOK)
+	var res struct {
+		Duplicate bool `json:"duplicate"`
+	}
+	decode(t, w, &res)
+	return res.Duplicate
+}
+
+// paidTicketFixture 3000円・1枚のチケットに alice が申し込んだ状態
+func paidTicketFixture(t *testing.T) (store *memory.Store, alice *models.User, ticket *models.Ticket, p *models.EventParticipant) {
+	t.Helper()
+	store = useMemoryRepos(t)
+	admin := addUser(store, "admin")
+	alice = addUser(store, "alice")
+	event := addEvent(t, admin)
+	ticket = addTicket(t, event, 3000, 1)
+	p = register(t, alice.ID, ticket.ID, nil)
+	if p.Status != models.ParticipantStatusPending {
+		t.Fatalf("alice = %s, want pending", p.Status)
+	}
+	return store, alice, ticket, p
+}
+
+// expectOrder 注文のステータスを確認する
+func expectOrder(t *testing.T, id uint, want models.OrderStatus) *models.Order {
+	t.Helper()
+	o, err := Repos.Orders.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Status != want {
+		t.Fatalf("order %d = %s, want %s", id, o.Status, want)
+	}
+	return o
+}
+
+// expectParticipant 参加登録のステータスを確認する
+func expectParticipant(t *testing.T, id uint, want models.ParticipantStatus) {
+	t.Helper()
+	p, err := Repos.Tickets.GetParticipant(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != want {
+		t.Fatalf("participant %d = %s, want %s", id, p.Status, want)
+	}
+}
+
+// expectNoRevenue チケットの売上項目が作られていない
+func expectNoRevenue(t *testing.T, ticket *models.Ticket) {
+	t.Helper()
+	if b, err := Repos.Budgets.FindByTicket(ticket.ID); err == nil {
+		t.Fatalf("revenue posted: %+v", b)
+	}
+}
+
+// expectRevenue チケットの売上項目の実績額
+func expectRevenue(t *testing.T, ticket *models.Ticket, want int) {
+	t.Helper()
+	b, err := Repos.Budgets.FindByTicket(ticket.ID)
+	if err != nil {
+		t.Fatalf("revenue budget: %v", err)
+	}
+	if b.ActualAmount != want || b.Type != models.BudgetTypeIncome || b.Category != ticketRevenueCategory(ticket) {
+		t.Fatalf("revenue = %+v, want actual %d", b, want)
+	}
+}
+
+func TestWebhookCheckoutCompleted(t *testing.T) {
+	fake := useFakeProvider(t)
+	_, alice, ticket, p := paidTicketFixture(t)
+	o := startCheckout(t, alice.ID, p.ID)
+
+	body, header, err := fake.Complete(o.CheckoutID)
+	if deliver(t, fake, body, header, err) {
+		t.Fatal("first delivery reported as duplicate")
+	}
+	paid := expectOrder(t, o.ID, models.OrderStatusPaid)
+	if paid.PaymentID == "" || paid.PaidAt == nil {
+		t.Errorf("paid order = %+v", paid)
+	}
+	expectParticipant(t, p.ID, models.ParticipantStatusConfirmed)
+	expectRevenue(t, ticket, 3000)
+
+	// 同じイベント（evt_completed_*）の再送は処理しない
+	body, header, err = fake.Complete(o.CheckoutID)
+	if !deliver(t, fake, body, header, err) {
+		t.Fatal("redelivery not reported as duplicate")
+	}
+	expectRevenue(t, ticket, 3000)
+
+	// プロバイダの管理画面から返金された
+	body, header, err = fake.RefundEvent(paid.PaymentID, paid.Amount)
+	deliver(t, fake, body, header, err)
+	expectOrder(t, o.ID, models.OrderStatusRefunded)
+	expectParticipant(t, p.ID, models.ParticipantStatusCancelled)
+	expectRevenue(t, ticket, 0)
+	if refunds := fake.Refunds(); len(refunds) != 0 {
+		t.Errorf("refund requested to provider for provider-side refund: %+v", refunds)
+	}
+}
+
+func TestWebhookCompletedAfterCancel(t *testing.T) {
+	fake := useFakeProvider(t)
+	_, alice, ticket, p := paidTicketFixture(t)
+	o := startCheckout(t, alice.ID, p.ID)
+
+	// 決済画面で支払っている間に登録を取り消した
+	expectStatus(t, call(CancelMyRegistration, alice.ID, idParam("id", p.ID), nil), http.StatusOK)
+
+	body, header, err := fake.Complete(o.CheckoutID)
+	deliver(t, fake, body, header, err)
+
+	refunded := expectOrder(t, o.ID, models.OrderStatusRefunded)
+	if refunded.RefundedAt == nil {
+		t.Error("RefundedAt not set")
+	}
+	expectParticipant(t, p.ID, models.ParticipantStatusCancelled)
+	expectNoRevenue(t, ticket)
+	refunds := fake.Refunds()
+	if len(refunds) != 1 || refunds[0].OrderID != o.ID || refunds[0].Amount != 3000 {
+		t.Fatalf("refunds = %+v", refunds)
+	}
+}
+
+func TestWebhookCompletedTwice(t *testing.T) {
+	fake := useFakeProvider(t)
+	_, alice, ticket, p := paidTicketFixture(t)
+	first := startCheckout(t, alice.ID, p.ID)
+
+	// 決済画面の期限が切れたので作り直した。古い支払い待ちの注文は期限切れになる
+	past := time.Now().Add(-time.Minute)
+	first.ExpiresAt = &past
+	if err := Repos.Orders.Update(first); err != nil {
+		t.Fatal(err)
+	}
+	second := startCheckout(t, alice.ID, p.ID)
+	if second.ID == first.ID {
+		t.Fatal("expired checkout reused")
+	}
+	expectOrder(t, first.ID, models.OrderStatusExpired)
+
+	body, header, err := fake.Complete(second.CheckoutID)
+	deliver(t, fake, body, header, err)
+	expectParticipant(t, p.ID, models.ParticipantStatusConfirmed)
+	expectRevenue(t, ticket, 3000)
+
+	// 古い決済画面でも支払われた。席は1つなので売上に計上せず返金する
+	body, header, err = fake.Complete(first.CheckoutID)
+	deliver(t, fake, body, header, err)
+	expectOrder(t, first.ID, models.OrderStatusRefunded)
+	expectOrder(t, second.ID, models.OrderStatusPaid)
+	expectParticipant(t, p.ID, models.ParticipantStatusConfirmed)
+	expectRevenue(t, ticket, 3000)
+	refunds := fake.Refunds()
+	if len(refunds) != 1 || refunds[0].OrderID != first.ID {
+		t.Fatalf("refunds = %+v", refunds)
+	}
+}
+
+func TestWebhookRefundPendingRetried(t *testing.T) {
+	fake := useFakeProvider(t)
+	_, alice, ticket, p := paidTicketFixture(t)
+	o := startCheckout(t, alice.ID, p.ID)
+	expectStatus(t, call(CancelMyRegistration, alice.ID, idParam("id", p.ID), nil), http.StatusOK)
+
+	// プロバイダが返金を受け付けなかったので返金待ちのまま残る
+	payment.DefaultProvider = failingRefunds{fake}
+	body, header, err := fake.Complete(o.CheckoutID)
+	deliver(t, fake, body, header, err)
+	expectOrder(t, o.ID, models.OrderStatusRefundPending)
+
+	// 主催者が返金し直す。売上には計上していないので差し引かない
+	payment.DefaultProvider = fake
+	expectStatus(t, call(RefundOrder, 0, idParam("id", o.ID), nil), http.StatusOK)
+	expectOrder(t, o.ID, models.OrderStatusRefunded)
+	expectNoRevenue(t, ticket)
+	if refunds := fake.Refunds(); len(refunds) != 1 {
+		t.Fatalf("refunds = %+v", refunds)
+	}
+}
+
+// failingRefunds 返金だけ失敗する Provider
+type failingRefunds struct{ *payment.FakeProvider }
+
+func (failingRefunds) Refund(context.Context, payment.RefundRequest) error {
+	return errors.New("refund unavailable")
+}
+
+func TestWebhookAsyncPayment(t *testing.T) {
+	fake := useFakeProvider(t)
+	_, alice, ticket, p := paidTicketFixture(t)
+	o := startCheckout(t, alice.ID, p.ID)
+
+	// コンビニ払いの手続きをした。入金されるまで席は確定せず、売上にも計上しない
+	body, header, err := fake.Process(o.CheckoutID)
+	deliver(t, fake, body, header, err)
+	expectOrder(t, o.ID, models.OrderStatusProcessing)
+	expectParticipant(t, p.ID, models.ParticipantStatusPending)
+	expectNoRevenue(t, ticket)
+	expectStatus(t, call(StartCheckout, alice.ID, idParam("id", p.ID), nil), http.StatusConflict)
+
+	body, header, err = fake.Complete(o.CheckoutID)
+	deliver(t, fake, body, header, err)
+	expectOrder(t, o.ID, models.OrderStatusPaid)
+	expectParticipant(t, p.ID, models.ParticipantStatusConfirmed)
+	expectRevenue(t, ticket, 3000)
+}
+
+func TestWebhookAsyncPaymentFailed(t *testing.T) {
+	fake := useFakeProvider(t)
+	_, alice, ticket, p := paidTicketFixture(t)
+	o := startCheckout(t, alice.ID, p.ID)
+
+	body, header, err := fake.Process(o.CheckoutID)
+	deliver(t, fake, body, header, err)
+	body, header, err = fake.Fail(o.CheckoutID)
+	deliver(t, fake, body, header, err)
+	expectOrder(t, o.ID, models.OrderStatusFailed)
+	// 入金されなかったので登録は支払い待ちのまま残り、決済をやり直せる
+	expectParticipant(t, p.ID, models.ParticipantStatusPending)
+	expectNoRevenue(t, ticket)
+	if retry := startCheckout(t, alice.ID, p.ID); retry.ID == o.ID {
+		t.Error("failed order reused")
+	}
+}
+
+func TestWebhookPartialRefund(t *testing.T) {
+	fake := useFakeProvider(t)
+	_, alice, ticket, p := paidTicketFixture(t)
+	o := startCheckout(t, alice.ID, p.ID)
+	body, header, err := fake.Complete(o.CheckoutID)
+	deliver(t, fake, body, header, err)
+	paid := expectOrder(t, o.ID, models.OrderStatusPaid)
+
+	// プロバイダの管理画面から 1000円だけ返金した。参加登録はそのまま
+	body, header, err = fake.RefundEvent(paid.PaymentID, 1000)
+	deliver(t, fake, body, header, err)
+	if got := expectOrder(t, o.ID, models.OrderStatusPaid); got.RefundedAmount != 1000 {
+		t.Fatalf("refunded amount = %d, want 1000", got.RefundedAmount)
+	}
+	expectParticipant(t, p.ID, models.ParticipantStatusConfirmed)
+	expectRevenue(t, ticket, 2000)
+
+	// 累計 500円の Webhook が後から届いても差し引き直さない
+	body, header, err = fake.RefundEvent(paid.PaymentID, 500)
+	deliver(t, fake, body, header, err)
+	expectRevenue(t, ticket, 2000)
+
+	// 主催者が残りを返金する
+	expectStatus(t, call(RefundOrder, 0, idParam("id", o.ID), nil), http.StatusOK)
+	if got := expectOrder(t, o.ID, models.OrderStatusRefunded); got.RefundedAmount != 3000 {
+		t.Errorf("refunded amount = %d, want 3000", got.RefundedAmount)
+	}
+	expectParticipant(t, p.ID, models.ParticipantStatusCancelled)
+	expectRevenue(t, ticket, 0)
+	refunds := fake.Refunds()
+	if len(refunds) != 1 || refunds[0].Amount != 2000 {
+		t.Fatalf("refunds = %+v, want the remaining 2000", refunds)
+	}
+
+	// 全額返金の Webhook は反映済み
+	body, header, err = fake.RefundEvent(paid.PaymentID, 3000)
+	deliver(t, fake, body, header, err)
+	expectRevenue(t, ticket, 0)
+}
+
+func TestWebhookCheckoutExpired(t *testing.T) {
+	fake := useFakeProvider(t)
+	_, alice, ticket, p := paidTicketFixture(t)
+	o := startCheckout(t, alice.ID, p.ID)
+
+	body, header, err := fake.Expire(o.CheckoutID)
+	deliver(t, fake, body, header, err)
+	expectOrder(t, o.ID, models.OrderStatusExpired)
+	// 登録は支払い待ちのまま残り、決済をやり直せる
+	expectParticipant(t, p.ID, models.ParticipantStatusPending)
+	expectNoRevenue(t, ticket)
+	if retry := startCheckout(t, alice.ID, p.ID); retry.ID == o.ID {
+		t.Error("expired order reused")
+	}
+}
+
+func TestWebhookInvalidSignature(t *testing.T) {
+	fake := useFakeProvider(t)
+	_, alice, _, p := paidTicketFixture(t)
+	o := startCheckout(t, alice.ID, p.ID)
+
+	body, header, err := fake.Complete(o.CheckoutID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header.Set("X-Fake-Signature", "00")
+	w := call(func(c *gin.Context) { processWebhook(c, fake, body, header) }, 0, nil, nil)
+	expectStatus(t, w, http.StatusBadRequest)
+	expectOrder(t, o.ID, models.OrderStatusPending)
+}
+
+func TestCancelUserRegistrations(t *testing.T) {
+	fake := useFakeProvider(t)
+	store, alice, ticket, p := paidTicketFixture(t)
+	o := startCheckout(t, alice.ID, p.ID)
+	body, header, err := fake.Complete(o.CheckoutID)
+	deliver(t, fake, body, header, err)
+
+	// 別のイベントで bob は支払い待ち、carol はキャンセル待ち
+	bob := addUser(store, "bob")
+	carol := addUser(store, "carol")
+	event2 := addEvent(t, bob)
+	ticket2 := addTicket(t, event2, 1000, 1)
+	bp := register(t, bob.ID, ticket2.ID, nil)
+	bo := startCheckout(t, bob.ID, bp.ID)
+	cp := register(t, carol.ID, ticket2.ID, nil)
+	if cp.Status != models.ParticipantStatusWaitlisted {
+		t.Fatalf("carol = %s, want waitlisted", cp.Status)
+	}
+
+	if err := cancelUserRegistrations(context.Background(), alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	expectOrder(t, o.ID, models.OrderStatusRefunded)
+	expectParticipant(t, p.ID, models.ParticipantStatusCancelled)
+	expectRevenue(t, ticket, 0)
+
+	if err := cancelUserRegistrations(context.Background(), bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	expectOrder(t, bo.ID, models.OrderStatusExpired)
+	expectParticipant(t, bp.ID, models.ParticipantStatusCancelled)
+	// 空いた席はキャンセル待ちに回る
+	expectParticipant(t, cp.ID, models.ParticipantStatusOffered)
+
+	// 取り消した後に決済が完了しても返金される
+	body, header, err = fake.Complete(bo.CheckoutID)
+	deliver(t, fake, body, header, err)
+	expectOrder(t, bo.ID, models.OrderStatusRefunded)
+	expectNoRevenue(t, ticket2)
+	if refunds := fake.Refunds(); len(refunds) != 2 {
+		t.Errorf("refunds = %+v", refunds)
+	}
+}
+
+func TestStartCheckoutWithoutProvider(t *testing.T) {
+	prev := payment.DefaultProvider
+	payment.DefaultProvider = nil
+	t.Cleanup(func() { payment.DefaultProvider = prev })
+	_, alice, _, p := paidTicketFixture(t)
+
+	// 決済プロバイダがなくても申し込みはでき、有料の決済だけ 503 になる
+	expectStatus(t, call(StartCheckout, alice.ID, idParam("id", p.ID), nil), http.StatusServiceUnavailable)
+	expectParticipant(t, p.ID, models.ParticipantStatusPending)
+}
@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 割引コードの利用で返すエラー。registrationError でステータスコードに対応づける
+var (
+	errPromoCodeInvalid   = errors.New("割引コードが無効です")
+	errPromoCodeExpired   = errors.New("割引コードの有効期間外です")
+	errPromoCodeFree      = errors.New("無料チケットには割引コードを使えません")
+	errPromoCodeExhausted = errors.New("割引コードの利用上限に達しています")
+	errPromoCodeUserLimit = errors.New("この割引コードはこれ以上利用できません")
+)
+
+// 割引コードの管理で返すエラー
+var (
+	errPromoCodeTaken   = errors.New("同じ割引コードが既にあります")
+	errMaxBelowRedeemed = errors.New("利用上限を利用済みの回数より少なくはできません")
+)
+
+// promoCodePattern 大文字に揃えたあとのコードの形式
+var promoCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+type promoCodeRequest struct {
+	Code           string              `json:"code" binding:"required"`
+	Description    string              `json:"description"`
+	TicketID       *uint               `json:"ticket_id"` // 省略時はイベントのすべてのチケット種別
+	DiscountType   models.DiscountType `json:"discount_type" binding:"required"`
+	DiscountValue  int                 `json:"discount_value" binding:"min=1"`
+	MaxRedemptions *int                `json:"max_redemptions" binding:"omitempty,min=0"` // 省略時は無制限
+	PerUserLimit   *int                `json:"per_user_limit" binding:"omitempty,min=1"`  // 省略時は無制限
+	ValidFrom      string              `json:"valid_from"`
+	ValidUntil     string              `json:"valid_until"`
+}
+
+// apply 検証してから pc に反映する。チケット種別がイベントのものかは呼び出し側で確認する
+func (req *promoCodeRequest) apply(pc *models.PromoCode) error {
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	if !promoCodePattern.MatchString(code) {
+		return errors.New("code must be 3-32 characters of A-Z, 0-9, '-' or '_'")
+	}
+	switch req.DiscountType {
+	case models.DiscountTypePercent:
+		if req.DiscountValue > 100 {
+			return errors.New("percent discount_value must be between 1 and 100")
+		}
+	case models.DiscountTypeFixed:
+	default:
+		return errors.New("discount_type must be percent or fixed")
+	}
+	var from, until *time.Time
+	if req.ValidFrom != "" {
+		v, err := time.Parse(time.RFC3339, req.ValidFrom)
+		if err != nil {
+			return errors.New("invalid valid_from: " + err.Error())
+		}
+		from = &v
+	}
+	if req.ValidUntil != "" {
+		v, err := time.Parse(time.RFC3339, req.ValidUntil)
+		if err != nil {
+			return errors.New("invalid valid_until: " + err.Error())
+		}
+		until = &v
+	}
+	if from != nil && until != nil && !until.After(*from) {
+		return errors.New("valid_until must be after valid_from")
+	}
+	pc.Code = code
+	pc.Description = strings.TrimSpace(req.Description)
+	pc.TicketID = req.TicketID
+	pc.DiscountType = req.DiscountType
+	pc.DiscountValue = req.DiscountValue
+	pc.MaxRedemptions = req.MaxRedemptions
+	pc.PerUserLimit = req.PerUserLimit
+	pc.ValidFrom, pc.ValidUntil = from, until
+	return nil
+}
+
+// checkPromoTicket 対象のチケット種別が同じイベントのものか
+func checkPromoTicket(pc *models.PromoCode) error {
+	if pc.TicketID == nil {
+		return nil
+	}
+	t, err := Repos.Tickets.Get(*pc.TicketID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && t.EventID != pc.EventID) {
+		return errors.New("ticket_id must be a ticket of this event")
+	}
+	return err
+}
+
+// promoCodeDiscount uid がチケット t にコード pc を使えるか確かめ、割引額を返す。
+// 利用回数を正しく数えるには、pc を GetForUpdate で取ったトランザクションの中で呼ぶこと
+func promoCodeDiscount(repos *repository.Repositories, pc *models.PromoCode, t *models.Ticket, uid uint, now time.Time) (int, error) {
+	if !pc.AppliesTo(t) {
+		return 0, errPromoCodeInvalid
+	}
+	if !pc.ValidAt(now) {
+		return 0, errPromoCodeExpired
+	}
+	if t.Price == 0 {
+		return 0, errPromoCodeFree
+	}
+	if pc.Exhausted() {
+		return 0, errPromoCodeExhausted
+	}
+	if pc.PerUserLimit != nil {
+		used, err := repos.PromoCodes.CountRedemptionsByUser(pc.ID, uid)
+		if err != nil {
+			return 0, err
+		}
+		if used >= int64(*pc.PerUserLimit) {
+			return 0, errPromoCodeUserLimit
+		}
+	}
+	return pc.DiscountFor(t.Price), nil
+}
+
+// lockPromoCode イベント内のコードを行ロック付きで取得する。見つからなければ errPromoCodeInvalid
+func lockPromoCode(tx *repository.Repositories, eventID uint, code string) (*models.PromoCode, error) {
+	found, err := tx.PromoCodes.FindByCode(eventID, strings.TrimSpace(code))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, errPromoCodeInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tx.PromoCodes.GetForUpdate(found.ID)
+}
+
+// redeemHeldPromoCode キャンセル待ちの間は記録しなかった割引コードの利用を記録する。
+// その間にコードが削除されたり利用上限に達したりしていれば、割引を外して定価にする。
+// tx の中でチケット行をロックし、Ticket を読み込んだ p で呼ぶこと
+func redeemHeldPromoCode(tx *repository.Repositories, p *models.EventParticipant) error {
+	if p.PromoCodeID == nil {
+		return nil
+	}
+	pc, err := tx.PromoCodes.GetForUpdate(*p.PromoCodeID)
+	if errors.Is(err, repository.ErrNotFound) {
+		p.PromoCodeID, p.Discount = nil, 0
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	available := !pc.Exhausted()
+	if available && pc.PerUserLimit != nil {
+		used, err := tx.PromoCodes.CountRedemptionsByUser(pc.ID, p.UserID)
+		if err != nil {
+			return err
+		}
+		available = used < int64(*pc.PerUserLimit)
+	}
+	if !available {
+		p.PromoCodeID, p.Discount = nil, 0
+		return nil
+	}
+	return tx.PromoCodes.Redeem(&models.PromoCodeRedemption{
+		PromoCodeID:        pc.ID,
+		EventParticipantID: p.ID,
+		TicketID:           p.TicketID,
+		UserID:             p.UserID,
+		Price:              p.Ticket.Price,
+		Discount:           p.Discount,
+	})
+}
+
+// GetPromoCodes イベントの割引コード一覧（利用回数付き）
+func GetPromoCodes(c *gin.Context) {
+	eventID, _ := paramID(c, "id")
+	list, err := Repos.PromoCodes.ListByEvent(eventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"promo_codes": list})
+}
+
+// CreatePromoCode 割引コードを作成。イベント内で同じコードは作れない
+func CreatePromoCode(c *gin.Context) {
+	eventID, _ := paramID(c, "id")
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	var req promoCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	pc := models.PromoCode{EventID: eventID, CreatedBy: uid}
+	if err := req.apply(&pc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := checkPromoTicket(&pc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := Repos.PromoCodes.FindByCode(eventID, pc.Code); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": errPromoCodeTaken.Error()})
+		return
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := Repos.PromoCodes.Create(&pc); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	created, err := Repos.PromoCodes.Get(pc.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"promo_code": created})
+}
+
+// UpdatePromoCode 割引コードを更新。利用上限は利用済みの回数より少なくできない。
+// 割引内容を変えても、既に登録に適用した割引額は変わらない
+func UpdatePromoCode(c *gin.Context) {
+	id, _ := paramID(c, "id")
+	var req promoCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	current, err := Repos.PromoCodes.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promo code not found"})
+		return
+	}
+	fields := models.PromoCode{EventID: current.EventID}
+	if err := req.apply(&fields); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := checkPromoTicket(&fields); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var updated *models.PromoCode
+	err = Repos.Transaction(func(tx *repository.Repositories) error {
+		pc, err := tx.PromoCodes.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if fields.MaxRedemptions != nil && *fields.MaxRedemptions < pc.RedemptionCount {
+			return errMaxBelowRedeemed
+		}
+		if other, err := tx.PromoCodes.FindByCode(pc.EventID, fields.Code); err == nil && other.ID != pc.ID {
+			return errPromoCodeTaken
+		} else if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		pc.Code, pc.Description, pc.TicketID = fields.Code, fields.Description, fields.TicketID
+		pc.DiscountType, pc.DiscountValue = fields.DiscountType, fields.DiscountValue
+		pc.MaxRedemptions, pc.PerUserLimit = fields.MaxRedemptions, fields.PerUserLimit
+		pc.ValidFrom, pc.ValidUntil = fields.ValidFrom, fields.ValidUntil
+		if err := tx.PromoCodes.Update(pc); err != nil {
+			return err
+		}
+		updated, err = tx.PromoCodes.Get(pc.ID)
+		return err
+	})
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promo code not found"})
+	case errors.Is(err, errMaxBelowRedeemed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, errPromoCodeTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"promo_code": updated})
+	}
+}
+
+// DeletePromoCode 割引コードを削除。利用記録と適用済みの割引は残る
+func DeletePromoCode(c *gin.Context) {
+	id, _ := paramID(c, "id")
+	if err := Repos.PromoCodes.Delete(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Promo code deleted successfully"})
+}
+
+// GetPromoCodeRedemptions 割引コードの利用レポート。利用記録（利用者・チケット種別・登録の現在のステータス）と集計を返す
+func GetPromoCodeRedemptions(c *gin.Context) {
+	id, _ := paramID(c, "id")
+	pc, err := Repos.PromoCodes.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promo code not found"})
+		return
+	}
+	list, err := Repos.PromoCodes.ListRedemptions(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	totalDiscount := 0
+	byStatus := map[models.ParticipantStatus]int{}
+	byTicket := map[uint]int{}
+	for _, r := range list {
+		totalDiscount += r.Discount
+		byStatus[r.EventParticipant.Status]++
+		byTicket[r.TicketID]++
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"promo_code":  pc,
+		"redemptions": list,
+		"summary": gin.H{
+			"redemptions":    len(list),
+			"total_discount": totalDiscount,
+			"by_status":      byStatus,
+			"by_ticket":      byTicket,
+		},
+	})
+}
+
+// GetTicketPrice チケットの支払額を返す。?promo_code= を付けるとそのコードを使えるか確かめて割引後の金額を返す（利用はしない）
+func GetTicketPrice(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	ticketID, err := paramID(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+		return
+	}
+	t, err := Repos.Tickets.Get(ticketID)
+	if err != nil {
+		registrationError(c, err)
+		return
+	}
+	res := gin.H{"price": t.Price, "discount": 0, "amount_due": t.Price}
+	code := strings.TrimSpace(c.Query("promo_code"))
+	if code == "" {
+		c.JSON(http.StatusOK, res)
+		return
+	}
+	pc, err := Repos.PromoCodes.FindByCode(t.EventID, code)
+	if errors.Is(err, repository.ErrNotFound) {
+		err = errPromoCodeInvalid
+	}
+	if err != nil {
+		registrationError(c, err)
+		return
+	}
+	discount, err := promoCodeDiscount(Repos, pc, t, uid, time.Now())
+	if err != nil {
+		registrationError(c, err)
+		return
+	}
+	res["discount"], res["amount_due"], res["promo_code"] = discount, t.Price-discount, pc.Code
+	c.JSON(http.StatusOK, res)
+}
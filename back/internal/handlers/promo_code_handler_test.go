@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"sherpa-backend/internal/models"
+)
+
+// addPromoCode テスト用の 1000円引きのコード。maxRedemptions が 0 なら無制限
+func addPromoCode(t *testing.T, event *models.Event, creator *models.User, maxRedemptions int) *models.PromoCode {
+	t.Helper()
+	pc := &models.PromoCode{EventID: event.ID, Code: "STUDENT", DiscountType: models.DiscountTypeFixed, DiscountValue: 1000, CreatedBy: creator.ID}
+	if maxRedemptions > 0 {
+		pc.MaxRedemptions = &maxRedemptions
+	}
+	if err := Repos.PromoCodes.Create(pc); err != nil {
+		t.Fatal(err)
+	}
+	return pc
+}
+
+// expectRedemptions コードの利用回数と利用記録の件数を確認する
+func expectRedemptions(t *testing.T, pc *models.PromoCode, want int) {
+	t.Helper()
+	got, err := Repos.PromoCodes.Get(pc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := Repos.PromoCodes.ListRedemptions(pc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.RedemptionCount != want || len(list) != want {
+		t.Fatalf("redemptions = %d (%d records), want %d", got.RedemptionCount, len(list), want)
+	}
+}
+
+// confirm ConfirmMyRegistration を呼んで確定後の登録を返す
+func confirm(t *testing.T, uid, participantID uint) *models.EventParticipant {
+	t.Helper()
+	w := call(ConfirmMyRegistration, uid, idParam("id", participantID), nil)
+	expectStatus(t, w, http.StatusOK)
+	var res struct {
+		Registration models.EventParticipant `json:"registration"`
+	}
+	decode(t, w, &res)
+	return &res.Registration
+}
+
+func TestPromoCodeRedeemedWhenWaitlistPromoted(t *testing.T) {
+	store := useMemoryRepos(t)
+	admin := addUser(store, "admin")
+	alice := addUser(store, "alice")
+	bob := addUser(store, "bob")
+	event := addEvent(t, admin)
+	ticket := addTicket(t, event, 3000, 1)
+	pc := addPromoCode(t, event, admin, 0)
+
+	ap := register(t, alice.ID, ticket.ID, nil)
+	bp := register(t, bob.ID, ticket.ID, map[string]string{"promo_code": "student"})
+	if bp.Status != models.ParticipantStatusWaitlisted || bp.PromoCodeID == nil || bp.Discount != 1000 {
+		t.Fatalf("bob = %+v", bp)
+	}
+	// キャンセル待ちの間は利用回数に数えない
+	expectRedemptions(t, pc, 0)
+
+	expectStatus(t, call(CancelMyRegistration, alice.ID, idParam("id", ap.ID), nil), http.StatusOK)
+	expectParticipant(t, bp.ID, models.ParticipantStatusOffered)
+	expectRedemptions(t, pc, 0)
+
+	got := confirm(t, bob.ID, bp.ID)
+	if got.Status != models.ParticipantStatusPending || got.Discount != 1000 || got.AmountDue() != 2000 {
+		t.Fatalf("confirmed = %s discount %d due %d", got.Status, got.Discount, got.AmountDue())
+	}
+	expectRedemptions(t, pc, 1)
+}
+
+func TestPromoCodeDroppedWhenExhaustedBeforePromotion(t *testing.T) {
+	store := useMemoryRepos(t)
+	admin := addUser(store, "admin")
+	alice := addUser(store, "alice")
+	bob := addUser(store, "bob")
+	carol := addUser(store, "carol")
+	event := addEvent(t, admin)
+	full := addTicket(t, event, 3000, 1)
+	pc := addPromoCode(t, event, admin, 1)
+
+	ap := register(t, alice.ID, full.ID, nil)
+	bp := register(t, bob.ID, full.ID, map[string]string{"promo_code": "STUDENT"})
+	if bp.Status != models.ParticipantStatusWaitlisted {
+		t.Fatalf("bob = %s, want waitlisted", bp.Status)
+	}
+
+	// 待っている間に別のチケットで最後の1回が使われた
+	other := addTicket(t, event, 3000, -1)
+	if p := register(t, carol.ID, other.ID, map[string]string{"promo_code": "STUDENT"}); p.Discount != 1000 {
+		t.Fatalf("carol discount = %d", p.Discount)
+	}
+	expectRedemptions(t, pc, 1)
+
+	expectStatus(t, call(CancelMyRegistration, alice.ID, idParam("id", ap.ID), nil), http.StatusOK)
+	got := confirm(t, bob.ID, bp.ID)
+	if got.PromoCodeID != nil || got.Discount != 0 || got.AmountDue() != 3000 {
+		t.Fatalf("confirmed = %+v, want full price", got)
+	}
+	expectRedemptions(t, pc, 1)
+}
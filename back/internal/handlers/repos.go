@@ -0,0 +1,7 @@
+package handlers
+
+import "sherpa-backend/internal/repository"
+
+// Repos ハンドラが使うリポジトリ。main で postgres.New(database.DB) を設定する。
+// テストでは memory.New().Repositories() に差し替えると PostgreSQL なしでハンドラを呼べる。
+var Repos *repository.Repositories
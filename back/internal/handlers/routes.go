@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"sherpa-backend/internal/authz"
+	"sherpa-backend/internal/ws"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes API のルートと認可ミドルウェアを r に登録する。
+// main とルーター経由のテストで同じ定義を使う
+func RegisterRoutes(r *gin.Engine, hub *ws.Hub) {
+	// ヘルスチェック
+	r.GET("/api/health", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"status":  "ok",
+			"message": "Sherpa Backend API is running",
+		})
+	})
+
+	// WebSocket（認証は POST /api/ws/ticket で発行した使い捨てチケット）
+	r.GET("/api/ws", WSHandler(hub))
+
+	// 管理者API（X-Admin-Key または Authorization: Bearer <ADMIN_API_KEY>）
+	admin := r.Group("/api/admin")
+	admin.Use(AdminMiddleware())
+	{
+		admin.GET("/events", GetAdminEvents)
+		admin.POST("/batch/run", RunBatch)
+	}
+
+	// APIルート
+	api := r.Group("/api")
+	{
+		// 認証関連
+		api.GET("/auth/providers", GetAuthProviders)
+		api.GET("/auth/oidc/:provider", StartOAuth)
+		api.GET("/auth/oidc/:provider/callback", OAuthCallback)
+		api.GET("/auth/google", StartOAuth)
+		api.GET("/auth/callback", OAuthCallback)
+		api.GET("/auth/me", AuthMiddleware(), RequireScope(), GetMe)
+		api.POST("/auth/exchange", ExchangeAuthCode)
+		api.POST("/auth/refresh", RefreshToken)
+		api.POST("/auth/logout", Logout)
+
+		// メール＋パスワード・マジックリンク認証
+		api.POST("/auth/register", Register)
+		api.POST("/auth/login", Login)
+		api.POST("/auth/verify-email", VerifyEmail)
+		api.POST("/auth/verify-email/resend", ResendVerification)
+		api.POST("/auth/password/forgot", ForgotPassword)
+		api.POST("/auth/password/reset", ResetPassword)
+		api.POST("/auth/magic-link", RequestMagicLink)
+		api.POST("/auth/magic-link/verify", VerifyMagicLink)
+
+		// 決済プロバイダの Webhook（署名で検証するので認証なし）。fake は開発用に支払いを完了させるルートを持つ
+		api.POST("/payments/webhook/:provider", PaymentWebhook)
+		api.POST("/payments/fake/checkouts/:id/complete", CompleteFakeCheckout)
+
+		// ユーザー関連（search は :id より先に定義）
+		api.POST("/users", CreateUser)
+		api.GET("/users/search", AuthMiddleware(), SearchUsers)
+		api.GET("/users/:id/events", AuthMiddleware(), GetUserEvents)
+		api.GET("/users/:id", GetUser)
+
+		// 以降は認証必須
+		auth := api.Group("")
+		auth.Use(AuthMiddleware())
+
+		// セッション管理
+		auth.GET("/me/sessions", GetMySessions)
+		auth.DELETE("/me/sessions/:id", RevokeMySession)
+		auth.PUT("/me/password", ChangePassword)
+		auth.POST("/ws/ticket", IssueWSTicket)
+
+		// 退会・個人データのエクスポート（ログインセッションからのみ）
+		auth.GET("/me/export", ExportMyData)
+		auth.DELETE("/me", DeleteMe)
+
+		// パーソナルアクセストークン（管理はログインセッションからのみ）
+		auth.GET("/me/tokens", GetPersonalAccessTokens)
+		auth.POST("/me/tokens", CreatePersonalAccessToken)
+		auth.DELETE("/me/tokens/:id", RevokePersonalAccessToken)
+
+		// 組織（OrganizationMember.Role に基づくポリシー）
+		auth.GET("/organizations", RequireScope(authz.ScopeReadOrgs), GetOrganizations)
+		auth.POST("/organizations", RequireScope(authz.ScopeWriteOrgs), CreateOrganization)
+		auth.GET("/organizations/:id", RequireOrgPermission(authz.ActionOrgRead), GetOrganization)
+		auth.PUT("/organizations/:id", RequireOrgPermission(authz.ActionOrgUpdate), UpdateOrganization)
+		auth.DELETE("/organizations/:id", RequireOrgPermission(authz.ActionOrgDelete), DeleteOrganization)
+		auth.GET("/organizations/:id/members", RequireOrgPermission(authz.ActionOrgRead), GetOrganizationMembers)
+		auth.PUT("/organizations/:id/members/:userId", RequireOrgPermission(authz.ActionOrgMemberManage), UpdateOrganizationMember)
+		auth.DELETE("/organizations/:id/members/:userId", RequireScope(authz.ScopeWriteOrgs), RequireOrgPermission(authz.ActionOrgRead), RemoveOrganizationMember)
+		auth.GET("/organizations/:id/pending-members", RequireOrgPermission(authz.ActionOrgMemberManage), GetPendingOrganizationMembers)
+		auth.POST("/organizations/:id/members/:userId/approve", RequireOrgPermission(authz.ActionOrgMemberManage), ApproveOrganizationMember)
+		auth.POST("/organizations/:id/members/:userId/reject", RequireOrgPermission(authz.ActionOrgMemberManage), RejectOrganizationMember)
+		auth.GET("/organizations/:id/domains", RequireOrgPermission(authz.ActionOrgUpdate), GetOrganizationDomains)
+		auth.POST("/organizations/:id/domains", RequireOrgPermission(authz.ActionOrgUpdate), CreateOrganizationDomain)
+		auth.POST("/organizations/:id/domains/:domainId/verify", RequireOrgPermission(authz.ActionOrgUpdate), VerifyOrganizationDomain)
+		auth.PUT("/organizations/:id/domains/:domainId", RequireOrgPermission(authz.ActionOrgUpdate), UpdateOrganizationDomain)
+		auth.DELETE("/organizations/:id/domains/:domainId", RequireOrgPermission(authz.ActionOrgUpdate), DeleteOrganizationDomain)
+		auth.GET("/organizations/:id/invitations", RequireOrgPermission(authz.ActionOrgMemberManage), GetOrganizationInvitations)
+		auth.POST("/organizations/:id/invitations", RequireOrgPermission(authz.ActionOrgMemberManage), CreateOrganizationInvitation)
+		auth.GET("/organization-invitations/mine", GetMyOrganizationInvitations)
+		auth.POST("/organization-invitations/:id/accept", AcceptOrganizationInvitation)
+		auth.POST("/organization-invitations/:id/decline", DeclineOrganizationInvitation)
+		auth.PUT("/me/active-organization", SetActiveOrganization)
+
+		// イベント単位の認可（EventStaff.Role に基づくポリシー）。トークンはアクションに対応するスコープも必要
+		eventPerm := func(action authz.Action) gin.HandlerFunc {
+			return RequireEventPermission(action, EventFromParam)
+		}
+		taskPerm := func(action authz.Action) gin.HandlerFunc {
+			return RequireEventPermission(action, EventFromTask)
+		}
+		budgetPerm := func(action authz.Action) gin.HandlerFunc {
+			return RequireEventPermission(action, EventFromBudget)
+		}
+		meetingPerm := func(action authz.Action) gin.HandlerFunc {
+			return RequireEventPermission(action, EventFromMeeting)
+		}
+		ticketPerm := func(action authz.Action) gin.HandlerFunc {
+			return RequireEventPermission(action, EventFromTicket)
+		}
+		participantPerm := func(action authz.Action) gin.HandlerFunc {
+			return RequireEventPermission(action, EventFromParticipant)
+		}
+		orderPerm := func(action authz.Action) gin.HandlerFunc {
+			return RequireEventPermission(action, EventFromOrder)
+		}
+		promoCodePerm := func(action authz.Action) gin.HandlerFunc {
+			return RequireEventPermission(action, EventFromPromoCode)
+		}
+		channelPerm := func(action authz.Action) gin.HandlerFunc {
+			return RequireEventPermission(action, EventFromChannel)
+		}
+		messagePerm := func(action authz.Action) gin.HandlerFunc {
+			return RequireEventPermission(action, EventFromMessage)
+		}
+		// 非公開チャンネルのメッセージはメンバーだけが読み書きできる
+		channelAccess := RequireChannelAccess(ChannelFromParam)
+		messageAccess := RequireChannelAccess(ChannelFromMessage)
+
+		// タスク関連（より具体的なルートを先に定義）
+		auth.GET("/events/:id/tasks", eventPerm(authz.ActionTaskRead), GetTasks)
+		auth.POST("/events/:id/tasks", eventPerm(authz.ActionTaskWrite), CreateTask)
+		auth.PUT("/tasks/:id", taskPerm(authz.ActionTaskWrite), UpdateTask)
+		auth.DELETE("/tasks/:id", taskPerm(authz.ActionTaskDelete), DeleteTask)
+		auth.POST("/tasks/generate", RequireScope(authz.ScopeWriteTasks), GenerateTasks)
+
+		// イベント関連
+		auth.GET("/events", RequireScope(authz.ScopeReadEvents), GetEvents)
+		auth.GET("/events/:id", eventPerm(authz.ActionEventRead), GetEvent)
+		auth.POST("/events", RequireScope(authz.ScopeWriteEvents), CreateEvent)
+		auth.PUT("/events/:id", eventPerm(authz.ActionEventUpdate), UpdateEvent)
+		auth.DELETE("/events/:id", eventPerm(authz.ActionEventDelete), DeleteEvent)
+		auth.POST("/events/create-chat", CreateEventChat)
+
+		// 予算関連
+		auth.GET("/events/:id/budgets", eventPerm(authz.ActionBudgetRead), GetBudgets)
+		auth.POST("/events/:id/budgets", eventPerm(authz.ActionBudgetWrite), CreateBudget)
+		auth.PUT("/budgets/:id", budgetPerm(authz.ActionBudgetWrite), UpdateBudget)
+		auth.DELETE("/budgets/:id", budgetPerm(authz.ActionBudgetDelete), DeleteBudget)
+
+		// 会議（アジェンダ・参加者と出欠・議事録の版）
+		auth.GET("/events/:id/meetings", eventPerm(authz.ActionMeetingRead), GetMeetings)
+		auth.POST("/events/:id/meetings", eventPerm(authz.ActionMeetingWrite), CreateMeeting)
+		auth.GET("/meetings/:id", meetingPerm(authz.ActionMeetingRead), GetMeeting)
+		auth.PUT("/meetings/:id", meetingPerm(authz.ActionMeetingWrite), UpdateMeeting)
+		auth.DELETE("/meetings/:id", meetingPerm(authz.ActionMeetingDelete), DeleteMeeting)
+		auth.POST("/meetings/:id/agenda", meetingPerm(authz.ActionMeetingWrite), CreateAgendaItem)
+		auth.PUT("/meetings/:id/agenda/order", meetingPerm(authz.ActionMeetingWrite), ReorderAgenda)
+		auth.PUT("/meetings/:id/agenda/:itemId", meetingPerm(authz.ActionMeetingWrite), UpdateAgendaItem)
+		auth.DELETE("/meetings/:id/agenda/:itemId", meetingPerm(authz.ActionMeetingWrite), DeleteAgendaItem)
+		auth.POST("/meetings/:id/attendees", meetingPerm(authz.ActionMeetingWrite), AddMeetingAttendee)
+		auth.DELETE("/meetings/:id/attendees/:userId", meetingPerm(authz.ActionMeetingWrite), RemoveMeetingAttendee)
+		auth.PUT("/meetings/:id/rsvp", meetingPerm(authz.ActionMeetingRead), RespondMeeting)
+		auth.PUT("/meetings/:id/minutes", meetingPerm(authz.ActionMeetingWrite), UpdateMeetingMinutes)
+		auth.GET("/meetings/:id/minutes/revisions", meetingPerm(authz.ActionMeetingRead), GetMeetingMinutesRevisions)
+		auth.GET("/meetings/:id/minutes/revisions/:revision", meetingPerm(authz.ActionMeetingRead), GetMeetingMinutesRevision)
+		auth.POST("/meetings/:id/tasks/extract", meetingPerm(authz.ActionTaskWrite), ExtractMeetingTasks)
+		auth.POST("/meetings/:id/tasks", meetingPerm(authz.ActionTaskWrite), AcceptMeetingTasks)
+		auth.GET("/meetings/:id/tasks", meetingPerm(authz.ActionTaskRead), GetMeetingTasks)
+
+		// チケット種別・参加登録（登録・取り消しは本人、一覧・確定は主催者）
+		auth.GET("/events/:id/tickets", eventPerm(authz.ActionTicketRead), GetTickets)
+		auth.POST("/events/:id/tickets", eventPerm(authz.ActionTicketWrite), CreateTicket)
+		auth.PUT("/tickets/:id", ticketPerm(authz.ActionTicketWrite), UpdateTicket)
+		auth.DELETE("/tickets/:id", ticketPerm(authz.ActionTicketDelete), DeleteTicket)
+		auth.GET("/tickets/:id/participants", ticketPerm(authz.ActionParticipantRead), GetTicketParticipants)
+		auth.GET("/events/:id/participants", eventPerm(authz.ActionParticipantRead), GetEventParticipants)
+		auth.PUT("/participants/:id/status", participantPerm(authz.ActionParticipantManage), UpdateParticipantStatus)
+		auth.POST("/tickets/:id/register", RegisterForTicket)
+		auth.GET("/me/registrations", GetMyRegistrations)
+		auth.POST("/me/registrations/:id/cancel", CancelMyRegistration)
+		auth.POST("/me/registrations/:id/confirm", ConfirmMyRegistration)
+
+		// 割引コード（作成・更新は Admin、利用レポートは参加者を閲覧できるロール、価格の確認は誰でも）
+		auth.GET("/events/:id/promo-codes", eventPerm(authz.ActionTicketWrite), GetPromoCodes)
+		auth.POST("/events/:id/promo-codes", eventPerm(authz.ActionTicketWrite), CreatePromoCode)
+		auth.PUT("/promo-codes/:id", promoCodePerm(authz.ActionTicketWrite), UpdatePromoCode)
+		auth.DELETE("/promo-codes/:id", promoCodePerm(authz.ActionTicketDelete), DeletePromoCode)
+		auth.GET("/promo-codes/:id/redemptions", promoCodePerm(authz.ActionParticipantRead), GetPromoCodeRedemptions)
+		auth.GET("/tickets/:id/price", GetTicketPrice)
+
+		// 有料チケットの決済（決済画面の作成は本人、返金は Admin）
+		auth.POST("/me/registrations/:id/checkout", StartCheckout)
+		auth.GET("/me/orders", GetMyOrders)
+		auth.GET("/events/:id/orders", eventPerm(authz.ActionParticipantRead), GetEventOrders)
+		auth.POST("/orders/:id/refund", orderPerm(authz.ActionOrderRefund), RefundOrder)
+
+		// 当日受付（QR は本人が取得、読み取りは受付スタッフ）
+		auth.GET("/me/registrations/:id/checkin-token", GetMyCheckinToken)
+		auth.POST("/events/:id/checkin", eventPerm(authz.ActionParticipantManage), CheckInParticipant)
+		auth.GET("/events/:id/checkins", eventPerm(authz.ActionParticipantRead), GetCheckinStats)
+
+		// 招待・通知
+		auth.GET("/events/:id/invitable-users", eventPerm(authz.ActionInvitationManage), GetInvitableUsers)
+		auth.GET("/events/:id/invitations", eventPerm(authz.ActionInvitationManage), GetEventInvitations)
+		auth.POST("/events/:id/invitations", eventPerm(authz.ActionInvitationManage), CreateInvitation)
+		auth.POST("/invitations/:id/accept", AcceptInvitation)
+		auth.POST("/invitations/:id/decline", DeclineInvitation)
+		auth.GET("/notifications", RequireScope(authz.ScopeReadNotifications), GetNotifications)
+		auth.GET("/notifications/unread-count", RequireScope(authz.ScopeReadNotifications), GetUnreadNotificationCount)
+		auth.PATCH("/notifications/:id/read", MarkNotificationRead)
+		auth.GET("/invitations/mine", GetMyPendingInvitations)
+
+		// チャット（チャンネル・メッセージ）
+		auth.GET("/me/unread", GetMyUnread)
+		auth.GET("/me/mentions", GetMyMentions)
+		auth.GET("/events/:id/channels", eventPerm(authz.ActionChatRead), GetChannels)
+		auth.POST("/events/:id/channels", eventPerm(authz.ActionChannelManage), CreateChannel)
+		auth.GET("/channels/:id/messages", channelPerm(authz.ActionChatRead), channelAccess, GetMessages)
+		auth.POST("/channels/:id/messages", channelPerm(authz.ActionChatPost), channelAccess, CreateMessage)
+		auth.PATCH("/messages/:id", messagePerm(authz.ActionChatPost), messageAccess, UpdateMessage)
+		auth.DELETE("/messages/:id", messagePerm(authz.ActionChatPost), messageAccess, DeleteMessage)
+		auth.POST("/messages/:id/reactions", messagePerm(authz.ActionChatPost), messageAccess, ToggleReaction)
+		auth.GET("/messages/:id/replies", messagePerm(authz.ActionChatRead), messageAccess, GetReplies)
+		auth.POST("/messages/:id/replies", messagePerm(authz.ActionChatPost), messageAccess, CreateReply)
+		auth.PATCH("/channels/:id", channelPerm(authz.ActionChannelManage), UpdateChannel)
+		auth.DELETE("/channels/:id", channelPerm(authz.ActionChannelManage), DeleteChannel)
+		auth.GET("/channels/:id/members", channelPerm(authz.ActionChatRead), GetChannelMembers)
+		auth.POST("/channels/:id/join", channelPerm(authz.ActionChatRead), JoinChannel)
+		auth.POST("/channels/:id/leave", channelPerm(authz.ActionChatRead), LeaveChannel)
+		auth.POST("/channels/:id/read", channelPerm(authz.ActionChatRead), channelAccess, MarkChannelRead)
+		auth.GET("/messages/:id/seen", messagePerm(authz.ActionChatRead), messageAccess, GetMessageSeenBy)
+		auth.POST("/channels/:id/members", channelPerm(authz.ActionChannelManage), AddChannelMember)
+		auth.DELETE("/channels/:id/members/:userId", channelPerm(authz.ActionChannelManage), RemoveChannelMember)
+	}
+}
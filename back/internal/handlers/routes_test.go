@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository/memory"
+	"sherpa-backend/internal/ws"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestRouter main と同じルート定義（認可ミドルウェア込み）をインメモリの Repos で組み立てる
+func newTestRouter(t *testing.T) (*gin.Engine, *memory.Store) {
+	t.Helper()
+	store := useMemoryRepos(t)
+	r := gin.New()
+	RegisterRoutes(r, ws.NewHub())
+	return r, store
+}
+
+// send ルーター経由でリクエストを送る。token が空でなければ Authorization ヘッダに付ける
+func send(r *gin.Engine, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var rd io.Reader = http.NoBody
+	if body != nil {
+		b, _ := json.Marshal(body)
+		rd = bytes.NewReader(b)
+	}
+	req := httptest.NewRequest(method, path, rd)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// loginAs u のセッションを作り、アクセストークンを返す
+func loginAs(t *testing.T, u *models.User) string {
+	t.Helper()
+	sess := models.Session{
+		UserID:           u.ID,
+		RefreshTokenHash: hashToken(generateRefreshToken()),
+		ExpiresAt:        time.Now().Add(time.Hour),
+		LastUsedAt:       time.Now(),
+	}
+	if err := Repos.Sessions.Create(&sess); err != nil {
+		t.Fatal(err)
+	}
+	token, err := generateAccessToken(u.ID, sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// addOrgEvent 組織 org のイベント。admin を Admin として登録する
+func addOrgEvent(t *testing.T, org *models.Organization, admin *models.User) *models.Event {
+	t.Helper()
+	e := addEvent(t, admin)
+	e.OrganizationID = org.ID
+	if err := Repos.Events.Update(e); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// addStaff イベントに role のスタッフを追加する
+func addStaff(t *testing.T, e *models.Event, u *models.User, role string) {
+	t.Helper()
+	if err := Repos.Events.AddStaff(&models.EventStaff{EventID: e.ID, UserID: u.ID, Role: role}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// addOrg owner を owner とする組織
+func addOrg(store *memory.Store, owner *models.User) *models.Organization {
+	org := &models.Organization{Name: "Sherpa"}
+	store.AddOrganization(org)
+	store.AddOrganizationMember(&models.OrganizationMember{OrganizationID: org.ID, UserID: owner.ID, Role: models.OrgRoleOwner})
+	return org
+}
+
+func TestRouterRejectsMissingAndRevokedTokens(t *testing.T) {
+	r, store := newTestRouter(t)
+	alice := addUser(store, "alice")
+
+	expectStatus(t, send(r, http.MethodGet, "/api/events", "", nil), http.StatusUnauthorized)
+	expectStatus(t, send(r, http.MethodGet, "/api/events", "not-a-jwt", nil), http.StatusUnauthorized)
+
+	token := loginAs(t, alice)
+	expectStatus(t, send(r, http.MethodGet, "/api/auth/me", token, nil), http.StatusOK)
+
+	// ログアウト後は同じアクセストークンを受け付けない
+	expectStatus(t, send(r, http.MethodPost, "/api/auth/logout", token, nil), http.StatusOK)
+	expectStatus(t, send(r, http.MethodGet, "/api/auth/me", token, nil), http.StatusUnauthorized)
+}
+
+func TestRouterPasswordLoginAndRefreshRotation(t *testing.T) {
+	r, store := newTestRouter(t)
+	hash, err := hashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified := time.Now()
+	u := &models.User{Name: "alice", Email: "alice@example.com", PasswordHash: &hash, EmailVerifiedAt: &verified}
+	store.AddUser(u)
+
+	expectStatus(t, send(r, http.MethodPost, "/api/auth/login", "", gin.H{"email": "alice@example.com", "password": "wrong"}), http.StatusUnauthorized)
+
+	// メールアドレスは大文字小文字を区別しない
+	w := send(r, http.MethodPost, "/api/auth/login", "", gin.H{"email": "Alice@Example.com", "password": "correct horse"})
+	expectStatus(t, w, http.StatusOK)
+	var first tokenResponse
+	decode(t, w, &first)
+
+	w = send(r, http.MethodPost, "/api/auth/refresh", "", gin.H{"refresh_token": first.RefreshToken})
+	expectStatus(t, w, http.StatusOK)
+	var second tokenResponse
+	decode(t, w, &second)
+	expectStatus(t, send(r, http.MethodGet, "/api/auth/me", second.Token, nil), http.StatusOK)
+
+	// ローテーション済みのトークンの再利用は漏洩とみなしてセッションごと失効させる
+	expectStatus(t, send(r, http.MethodPost, "/api/auth/refresh", "", gin.H{"refresh_token": first.RefreshToken}), http.StatusUnauthorized)
+	expectStatus(t, send(r, http.MethodGet, "/api/auth/me", second.Token, nil), http.StatusUnauthorized)
+	expectStatus(t, send(r, http.MethodPost, "/api/auth/refresh", "", gin.H{"refresh_token": second.RefreshToken}), http.StatusUnauthorized)
+}
+
+func TestRouterEventPermissions(t *testing.T) {
+	r, store := newTestRouter(t)
+	admin := addUser(store, "admin")
+	staff := addUser(store, "staff")
+	outsider := addUser(store, "outsider")
+	org := addOrg(store, admin)
+	e := addOrgEvent(t, org, admin)
+	addStaff(t, e, staff, models.EventRoleStaff)
+	path := fmt.Sprintf("/api/events/%d", e.ID)
+
+	expectStatus(t, send(r, http.MethodGet, path, loginAs(t, outsider), nil), http.StatusForbidden)
+	expectStatus(t, send(r, http.MethodGet, path, loginAs(t, staff), nil), http.StatusOK)
+	expectStatus(t, send(r, http.MethodDelete, path, loginAs(t, staff), nil), http.StatusForbidden)
+	expectStatus(t, send(r, http.MethodGet, "/api/events/999999", loginAs(t, admin), nil), http.StatusForbidden)
+	expectStatus(t, send(r, http.MethodGet, "/api/tasks/999999", loginAs(t, admin), nil), http.StatusNotFound)
+}
+
+func TestRouterPrivateChannelRequiresMembership(t *testing.T) {
+	r, store := newTestRouter(t)
+	admin := addUser(store, "admin")
+	member := addUser(store, "member")
+	other := addUser(store, "other")
+	org := addOrg(store, admin)
+	e := addOrgEvent(t, org, admin)
+	addStaff(t, e, member, models.EventRoleStaff)
+	addStaff(t, e, other, models.EventRoleStaff)
+
+	ch := &models.Channel{EventID: e.ID, Name: "#staff-only", IsPrivate: true}
+	if err := Repos.Channels.Create(ch); err != nil {
+		t.Fatal(err)
+	}
+	if err := Repos.Channels.AddMember(&models.ChannelMember{ChannelID: ch.ID, UserID: member.ID}); err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/api/channels/%d/messages", ch.ID)
+
+	expectStatus(t, send(r, http.MethodGet, path, loginAs(t, member), nil), http.StatusOK)
+	expectStatus(t, send(r, http.MethodGet, path, loginAs(t, other), nil), http.StatusForbidden)
+	expectStatus(t, send(r, http.MethodPost, path, loginAs(t, other), gin.H{"content": "hi"}), http.StatusForbidden)
+
+	if !CanJoinChannel(member.ID, ch.ID) || CanJoinChannel(other.ID, ch.ID) {
+		t.Fatal("CanJoinChannel should follow private channel membership")
+	}
+
+	// 一覧も同じ基準。Admin でもメンバーでなければ非公開チャンネルは見えず、メッセージも読めない
+	list := fmt.Sprintf("/api/events/%d/channels", e.ID)
+	for _, tc := range []struct {
+		user    *models.User
+		visible bool
+	}{{member, true}, {other, false}, {admin, false}} {
+		w := send(r, http.MethodGet, list, loginAs(t, tc.user), nil)
+		expectStatus(t, w, http.StatusOK)
+		var res struct {
+			Channels []models.Channel `json:"channels"`
+		}
+		decode(t, w, &res)
+		listed := slices.ContainsFunc(res.Channels, func(c models.Channel) bool { return c.ID == ch.ID })
+		if listed != tc.visible {
+			t.Errorf("%s: private channel listed = %v, want %v", tc.user.Name, listed, tc.visible)
+		}
+	}
+	expectStatus(t, send(r, http.MethodGet, path, loginAs(t, admin), nil), http.StatusForbidden)
+}
+
+func TestRouterOrganizationPermissions(t *testing.T) {
+	r, store := newTestRouter(t)
+	owner := addUser(store, "owner")
+	member := addUser(store, "member")
+	outsider := addUser(store, "outsider")
+	org := addOrg(store, owner)
+	store.AddOrganizationMember(&models.OrganizationMember{OrganizationID: org.ID, UserID: member.ID, Role: models.OrgRoleMember})
+	path := fmt.Sprintf("/api/organizations/%d", org.ID)
+
+	expectStatus(t, send(r, http.MethodGet, path, loginAs(t, outsider), nil), http.StatusForbidden)
+	expectStatus(t, send(r, http.MethodGet, path, loginAs(t, member), nil), http.StatusOK)
+	expectStatus(t, send(r, http.MethodPut, path, loginAs(t, member), gin.H{"name": "renamed"}), http.StatusForbidden)
+	expectStatus(t, send(r, http.MethodPut, path, loginAs(t, owner), gin.H{"name": "renamed"}), http.StatusOK)
+
+	// 最後の owner は外せない
+	ownerPath := fmt.Sprintf("%s/members/%d", path, owner.ID)
+	expectStatus(t, send(r, http.MethodDelete, ownerPath, loginAs(t, owner), nil), http.StatusConflict)
+
+	// 切り替え先は所属している組織だけ
+	expectStatus(t, send(r, http.MethodPut, "/api/me/active-organization", loginAs(t, outsider), gin.H{"organization_id": org.ID}), http.StatusForbidden)
+	expectStatus(t, send(r, http.MethodPut, "/api/me/active-organization", loginAs(t, member), gin.H{"organization_id": org.ID}), http.StatusOK)
+
+	// 脱退するとアクティブ組織も外れ、以後は閲覧できない
+	memberToken := loginAs(t, member)
+	expectStatus(t, send(r, http.MethodDelete, fmt.Sprintf("%s/members/%d", path, member.ID), memberToken, nil), http.StatusOK)
+	if u, _ := Repos.Users.Get(member.ID); u.ActiveOrganizationID != nil {
+		t.Fatalf("active organization = %v, want nil after leaving", *u.ActiveOrganizationID)
+	}
+	expectStatus(t, send(r, http.MethodGet, path, memberToken, nil), http.StatusForbidden)
+
+	expectStatus(t, send(r, http.MethodDelete, path, loginAs(t, owner), nil), http.StatusOK)
+	if _, err := Repos.Organizations.Get(org.ID); err == nil {
+		t.Fatal("organization should be deleted")
+	}
+}
+
+func TestRouterOrganizationInvitationFlow(t *testing.T) {
+	r, store := newTestRouter(t)
+	owner := addUser(store, "owner")
+	invitee := addUser(store, "invitee")
+	org := addOrg(store, owner)
+
+	w := send(r, http.MethodPost, fmt.Sprintf("/api/organizations/%d/invitations", org.ID), loginAs(t, owner),
+		gin.H{"email": "INVITEE@example.com", "role": models.OrgRoleAdmin})
+	expectStatus(t, w, http.StatusCreated)
+	var created struct {
+		Invitation models.OrganizationInvitation `json:"invitation"`
+	}
+	decode(t, w, &created)
+	if created.Invitation.Inviter.Name != "owner" {
+		t.Fatalf("inviter = %q, want owner", created.Invitation.Inviter.Name)
+	}
+
+	inviteeToken := loginAs(t, invitee)
+	expectStatus(t, send(r, http.MethodPost, fmt.Sprintf("/api/organization-invitations/%d/accept", created.Invitation.ID), loginAs(t, owner), nil), http.StatusForbidden)
+	expectStatus(t, send(r, http.MethodPost, fmt.Sprintf("/api/organization-invitations/%d/accept", created.Invitation.ID), inviteeToken, nil), http.StatusOK)
+
+	role, err := Repos.Organizations.GetMember(org.ID, invitee.ID)
+	if err != nil || role.Role != models.OrgRoleAdmin || role.Status != models.MemberStatusActive {
+		t.Fatalf("membership = %+v, %v; want active admin", role, err)
+	}
+	if u, _ := Repos.Users.Get(invitee.ID); u.ActiveOrganizationID == nil || *u.ActiveOrganizationID != org.ID {
+		t.Fatal("accepted organization should become active")
+	}
+	expectStatus(t, send(r, http.MethodGet, fmt.Sprintf("/api/organizations/%d/invitations", org.ID), inviteeToken, nil), http.StatusOK)
+}
+
+func TestRouterPersonalAccessTokenScopes(t *testing.T) {
+	r, store := newTestRouter(t)
+	admin := addUser(store, "admin")
+	org := addOrg(store, admin)
+	e := addOrgEvent(t, org, admin)
+
+	w := send(r, http.MethodPost, "/api/me/tokens", loginAs(t, admin), gin.H{"name": "ci", "scopes": []string{"read:events"}})
+	expectStatus(t, w, http.StatusCreated)
+	var created struct {
+		Token string `json:"token"`
+		PAT   struct {
+			ID uint `json:"id"`
+		} `json:"personal_access_token"`
+	}
+	decode(t, w, &created)
+
+	expectStatus(t, send(r, http.MethodGet, fmt.Sprintf("/api/events/%d", e.ID), created.Token, nil), http.StatusOK)
+	expectStatus(t, send(r, http.MethodGet, fmt.Sprintf("/api/events/%d/tasks", e.ID), created.Token, nil), http.StatusForbidden)
+	expectStatus(t, send(r, http.MethodPost, "/api/events", created.Token, gin.H{"title": "x"}), http.StatusForbidden)
+	// トークンの管理はログインセッションからのみ
+	expectStatus(t, send(r, http.MethodGet, "/api/me/tokens", created.Token, nil), http.StatusUnauthorized)
+
+	expectStatus(t, send(r, http.MethodDelete, fmt.Sprintf("/api/me/tokens/%d", created.PAT.ID), loginAs(t, admin), nil), http.StatusOK)
+	expectStatus(t, send(r, http.MethodGet, fmt.Sprintf("/api/events/%d", e.ID), created.Token, nil), http.StatusUnauthorized)
+}
+
+func TestRouterDeleteMeRequiresHandoffAndRevokesSessions(t *testing.T) {
+	r, store := newTestRouter(t)
+	admin := addUser(store, "admin")
+	staff := addUser(store, "staff")
+	org := addOrg(store, admin)
+	e := addOrgEvent(t, org, admin)
+	addStaff(t, e, staff, models.EventRoleStaff)
+	token := loginAs(t, admin)
+
+	w := send(r, http.MethodGet, "/api/me/export?format=json", token, nil)
+	expectStatus(t, w, http.StatusOK)
+	var export struct {
+		Profile    models.User                 `json:"profile"`
+		EventStaff []models.EventStaff         `json:"event_staff"`
+		Sessions   []models.Session            `json:"sessions"`
+		Orgs       []models.OrganizationMember `json:"organization_memberships"`
+	}
+	decode(t, w, &export)
+	if export.Profile.ID != admin.ID || len(export.EventStaff) != 1 || len(export.Sessions) != 1 || len(export.Orgs) != 1 {
+		t.Fatalf("export = %+v", export)
+	}
+
+	// 唯一の Admin なので後任が必要
+	expectStatus(t, send(r, http.MethodDelete, "/api/me", token, nil), http.StatusConflict)
+	w = send(r, http.MethodDelete, "/api/me", token, gin.H{"event_transfers": map[uint]uint{e.ID: staff.ID}})
+	expectStatus(t, w, http.StatusOK)
+
+	if s, err := Repos.Events.GetStaff(e.ID, staff.ID); err != nil || s.Role != models.EventRoleAdmin {
+		t.Fatalf("successor = %+v, %v; want Admin", s, err)
+	}
+	if _, err := Repos.Events.GetStaff(e.ID, admin.ID); err == nil {
+		t.Fatal("deleted user should no longer be staff")
+	}
+	if _, err := Repos.Users.Get(admin.ID); err == nil {
+		t.Fatal("deleted user should not be found")
+	}
+	expectStatus(t, send(r, http.MethodGet, "/api/auth/me", token, nil), http.StatusUnauthorized)
+}
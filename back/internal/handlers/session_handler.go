@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"sherpa-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// accessTokenTTL ACCESS_TOKEN_TTL（例: 15m）。未設定・不正値はデフォルト
+func accessTokenTTL() time.Duration {
+	return durationEnv("ACCESS_TOKEN_TTL", defaultAccessTokenTTL)
+}
+
+// refreshTokenTTL REFRESH_TOKEN_TTL（例: 720h）。未設定・不正値はデフォルト
+func refreshTokenTTL() time.Duration {
+	return durationEnv("REFRESH_TOKEN_TTL", defaultRefreshTokenTTL)
+}
+
+func durationEnv(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return def
+}
+
+// tokenResponse ログイン・リフレッシュ時に返すトークン一式
+type tokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // アクセストークンの有効秒数
+}
+
+func generateRefreshToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// hashToken トークンは平文で保存せず SHA-256 の16進表現で保持する
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueSession セッションを作成し、アクセストークンとリフレッシュトークンを発行する
+func issueSession(c *gin.Context, userID uint) (*tokenResponse, error) {
+	refresh := generateRefreshToken()
+	now := time.Now()
+	sess := models.Session{
+		UserID:           userID,
+		RefreshTokenHash: hashToken(refresh),
+		UserAgent:        c.Request.UserAgent(),
+		IPAddress:        c.ClientIP(),
+		ExpiresAt:        now.Add(refreshTokenTTL()),
+		LastUsedAt:       now,
+	}
+	if err := Repos.Sessions.Create(&sess); err != nil {
+		return nil, err
+	}
+
+	access, err := generateAccessToken(userID, sess.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenResponse{Token: access, RefreshToken: refresh, ExpiresIn: int(accessTokenTTL().Seconds())}, nil
+}
+
+// revokeSession セッションを失効させる（冪等）
+func revokeSession(sessionID uint) error {
+	return Repos.Sessions.Revoke(sessionID, time.Now())
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshToken リフレッシュトークンをローテーションし、新しいトークン一式を返す。
+// ローテーション済みの古いトークンが再利用された場合は漏洩とみなしてセッションごと失効させる。
+func RefreshToken(c *gin.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+		return
+	}
+	hash := hashToken(req.RefreshToken)
+	now := time.Now()
+
+	sess, err := Repos.Sessions.FindByRefreshToken(hash)
+	if err != nil {
+		if reused, err := Repos.Sessions.FindByPreviousToken(hash); err == nil {
+			_ = revokeSession(reused.ID)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+	if !sess.IsActive(now) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired or revoked"})
+		return
+	}
+
+	// 同じトークンでの同時リフレッシュは1件だけ成功させる
+	next := generateRefreshToken()
+	sess.RefreshTokenHash = hashToken(next)
+	sess.PreviousTokenHash = &hash
+	sess.LastUsedAt = now
+	sess.IPAddress = c.ClientIP()
+	sess.UserAgent = c.Request.UserAgent()
+	rotated, err := Repos.Sessions.Rotate(sess, hash)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !rotated {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	access, err := generateAccessToken(sess.UserID, sess.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	c.JSON(http.StatusOK, tokenResponse{Token: access, RefreshToken: next, ExpiresIn: int(accessTokenTTL().Seconds())})
+}
+
+// Logout 現在のセッションを失効させる。body の refresh_token、なければ Authorization のアクセストークンで特定する。
+// アクセストークンの期限切れ後でもログアウトできるよう AuthMiddleware は通さない。
+func Logout(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	_ = c.ShouldBindJSON(&req)
+
+	if req.RefreshToken != "" {
+		if sess, err := Repos.Sessions.FindByRefreshToken(hashToken(req.RefreshToken)); err == nil {
+			if err := revokeSession(sess.ID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+		return
+	}
+
+	claims, err := parseAccessToken(bearerToken(c))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh_token or valid Authorization header required"})
+		return
+	}
+	if err := revokeSession(claims.SessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// sessionView セッション一覧の1行。current は呼び出し元自身のセッション
+type sessionView struct {
+	models.Session
+	Current bool `json:"current"`
+}
+
+// GetMySessions 自分の有効なセッション一覧
+func GetMySessions(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	currentID, _ := c.Get("session_id")
+
+	list, err := Repos.Sessions.ListActive(uid, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]sessionView, 0, len(list))
+	for _, s := range list {
+		out = append(out, sessionView{Session: s, Current: currentID == s.ID})
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": out})
+}
+
+// RevokeMySession 自分のセッションを1件失効させる（他端末のログアウト）
+func RevokeMySession(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	sess, err := Repos.Sessions.Get(uint(id))
+	if err != nil || sess.UserID != uid {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	if err := revokeSession(sess.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
-	"sherpa-backend/internal/database"
 	"sherpa-backend/internal/models"
 	"sherpa-backend/internal/services"
 	"sherpa-backend/internal/ws"
@@ -20,10 +22,8 @@ func GetTasks(c *gin.Context) {
 		return
 	}
 
-	var tasks []models.Task
-	if err := database.DB.Where("event_id = ?", uint(eventID)).
-		Preload("Assignee").
-		Find(&tasks).Error; err != nil {
+	tasks, err := Repos.Tasks.ListByEvent(uint(eventID))
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -31,6 +31,52 @@ func GetTasks(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"tasks": tasks})
 }
 
+// taskRequest タスクの作成・更新の入力。指定された項目だけを反映する（assignee_id は 0 で担当者を外す）
+type taskRequest struct {
+	Title         *string            `json:"title"`
+	Deadline      *time.Time         `json:"deadline"`
+	Status        *models.TaskStatus `json:"status"`
+	AssigneeID    *uint              `json:"assignee_id"`
+	IsAIGenerated *bool              `json:"is_ai_generated"`
+}
+
+// apply req の項目を t に反映する。担当者は t のイベントのスタッフに限る
+func (req *taskRequest) apply(t *models.Task) error {
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			return errors.New("title is required")
+		}
+		t.Title = title
+	}
+	if req.Deadline != nil {
+		t.Deadline = *req.Deadline
+	}
+	if req.Status != nil {
+		switch *req.Status {
+		case models.TaskStatusTodo, models.TaskStatusInProgress, models.TaskStatusCompleted, models.TaskStatusCancelled:
+			t.Status = *req.Status
+		default:
+			return errors.New("status must be one of todo, in_progress, completed, cancelled")
+		}
+	}
+	if req.AssigneeID != nil {
+		if *req.AssigneeID == 0 {
+			t.AssigneeID = nil
+		} else {
+			if _, err := Repos.Events.GetStaff(t.EventID, *req.AssigneeID); err != nil {
+				return errors.New("assignee must be a staff member of this event")
+			}
+			t.AssigneeID = req.AssigneeID
+		}
+		t.Assignee = nil
+	}
+	if req.IsAIGenerated != nil {
+		t.IsAIGenerated = *req.IsAIGenerated
+	}
+	return nil
+}
+
 // CreateTask タスクを作成
 func CreateTask(c *gin.Context) {
 	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -39,15 +85,23 @@ func CreateTask(c *gin.Context) {
 		return
 	}
 
-	var task models.Task
-	if err := c.ShouldBindJSON(&task); err != nil {
+	var req taskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.Title == nil || req.Deadline == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title and deadline are required"})
+		return
+	}
 
-	task.EventID = uint(eventID)
+	task := models.Task{EventID: uint(eventID), Status: models.TaskStatusTodo}
+	if err := req.apply(&task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := database.DB.Create(&task).Error; err != nil {
+	if err := Repos.Tasks.Create(&task); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -64,22 +118,23 @@ func UpdateTask(c *gin.Context) {
 		return
 	}
 
-	var task models.Task
-	if err := database.DB.First(&task, uint(id)).Error; err != nil {
+	task, err := Repos.Tasks.Get(uint(id))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
 		return
 	}
 
-	if err := c.ShouldBindJSON(&task); err != nil {
+	var req taskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	if err := database.DB.Save(&task).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := req.apply(task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := database.DB.Preload("Assignee").First(&task, task.ID).Error; err != nil {
+
+	if err := Repos.Tasks.Update(task); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -95,13 +150,13 @@ func DeleteTask(c *gin.Context) {
 		return
 	}
 
-	var task models.Task
-	if err := database.DB.First(&task, uint(id)).Error; err != nil {
+	task, err := Repos.Tasks.Get(uint(id))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
 		return
 	}
 
-	if err := database.DB.Delete(&models.Task{}, uint(id)).Error; err != nil {
+	if err := Repos.Tasks.Delete(uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -0,0 +1,480 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 参加登録で返すエラー。registrationError でステータスコードに対応づける
+var (
+	errEventNotOpen      = errors.New("このイベントは参加登録を受け付けていません")
+	errSalesClosed       = errors.New("チケットの販売期間外です")
+	errAlreadyRegistered = errors.New("このイベントには既に参加登録しています")
+	errQuantityBelowSold = errors.New("販売枚数を登録済みの枚数より少なくはできません")
+	errTicketInUse       = errors.New("参加登録のあるチケットは削除できません")
+	errInvalidTransition = errors.New("このステータスには変更できません")
+	errAlreadyCheckedIn  = errors.New("受付済みの参加登録は登録し直せません")
+)
+
+type ticketRequest struct {
+	Name         string `json:"name" binding:"required"`
+	Price        int    `json:"price" binding:"min=0"`
+	Quantity     *int   `json:"quantity" binding:"omitempty,min=0"` // 省略時は無制限
+	SalesStartAt string `json:"sales_start_at"`
+	SalesEndAt   string `json:"sales_end_at"`
+}
+
+// apply 検証してから t に反映する。エラーはそのまま 400 で返せるメッセージ
+func (req *ticketRequest) apply(t *models.Ticket) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return errors.New("name is required")
+	}
+	var start, end *time.Time
+	if req.SalesStartAt != "" {
+		v, err := time.Parse(time.RFC3339, req.SalesStartAt)
+		if err != nil {
+			return errors.New("invalid sales_start_at: " + err.Error())
+		}
+		start = &v
+	}
+	if req.SalesEndAt != "" {
+		v, err := time.Parse(time.RFC3339, req.SalesEndAt)
+		if err != nil {
+			return errors.New("invalid sales_end_at: " + err.Error())
+		}
+		end = &v
+	}
+	if start != nil && end != nil && !end.After(*start) {
+		return errors.New("sales_end_at must be after sales_start_at")
+	}
+	t.Name = name
+	t.Price = req.Price
+	t.Quantity = req.Quantity
+	t.SalesStartAt = start
+	t.SalesEndAt = end
+	return nil
+}
+
+// registrationError 参加登録まわりのエラーを書き込む
+func registrationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+	case errors.Is(err, errAlreadyRegistered), errors.Is(err, errTicketInUse), errors.Is(err, errInvalidTransition),
+		errors.Is(err, errAlreadyCheckedIn), errors.Is(err, errOfferExpired), errors.Is(err, errPromoCodeExhausted), errors.Is(err, errPromoCodeUserLimit):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, errEventNotOpen), errors.Is(err, errSalesClosed), errors.Is(err, errQuantityBelowSold),
+		errors.Is(err, errPromoCodeInvalid), errors.Is(err, errPromoCodeExpired), errors.Is(err, errPromoCodeFree):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GetTickets イベントのチケット種別一覧（登録数付き）
+func GetTickets(c *gin.Context) {
+	eventID, _ := paramID(c, "id")
+	tickets, err := Repos.Tickets.ListByEvent(eventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tickets": tickets})
+}
+
+// CreateTicket チケット種別を作成
+func CreateTicket(c *gin.Context) {
+	eventID, _ := paramID(c, "id")
+	var req ticketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	t := models.Ticket{EventID: eventID}
+	if err := req.apply(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := Repos.Tickets.Create(&t); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"ticket": t})
+}
+
+// UpdateTicket チケット種別を更新。販売枚数は登録済みの枚数を下回れない。増やした分はキャンセル待ちから繰り上げる
+func UpdateTicket(c *gin.Context) {
+	id, _ := paramID(c, "id")
+	var req ticketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var fields models.Ticket
+	if err := req.apply(&fields); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var ticket *models.Ticket
+	err := Repos.Transaction(func(tx *repository.Repositories) error {
+		t, err := tx.Tickets.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+		sold, err := tx.Tickets.CountActive(t.ID)
+		if err != nil {
+			return err
+		}
+		if fields.Quantity != nil && int64(*fields.Quantity) < sold {
+			return errQuantityBelowSold
+		}
+		t.Name, t.Price, t.Quantity = fields.Name, fields.Price, fields.Quantity
+		t.SalesStartAt, t.SalesEndAt = fields.SalesStartAt, fields.SalesEndAt
+		if err := tx.Tickets.Update(t); err != nil {
+			return err
+		}
+		if err := fillSeats(tx, t, time.Now()); err != nil {
+			return err
+		}
+		if t.Sold, err = tx.Tickets.CountActive(t.ID); err != nil {
+			return err
+		}
+		ticket = t
+		return nil
+	})
+	if err != nil {
+		registrationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ticket": ticket})
+}
+
+// DeleteTicket チケット種別を削除。取り消されていない登録（キャンセル待ちを含む）があれば 409
+func DeleteTicket(c *gin.Context) {
+	id, _ := paramID(c, "id")
+	err := Repos.Transaction(func(tx *repository.Repositories) error {
+		if _, err := tx.Tickets.GetForUpdate(id); err != nil {
+			return err
+		}
+		open, err := tx.Tickets.CountOpen(id)
+		if err != nil {
+			return err
+		}
+		if open > 0 {
+			return errTicketInUse
+		}
+		return tx.Tickets.Delete(id)
+	})
+	if err != nil {
+		registrationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Ticket deleted successfully"})
+}
+
+// participantStatusQuery ?status= の検証。不正なら 400 を書き込み false を返す
+func participantStatusQuery(c *gin.Context) (models.ParticipantStatus, bool) {
+	status := models.ParticipantStatus(c.Query("status"))
+	switch status {
+	case "", models.ParticipantStatusPending, models.ParticipantStatusConfirmed, models.ParticipantStatusCancelled,
+		models.ParticipantStatusWaitlisted, models.ParticipantStatusOffered:
+		return status, true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+	return "", false
+}
+
+// GetTicketParticipants チケット種別ごとの参加者一覧（?status= で絞り込み）
+func GetTicketParticipants(c *gin.Context) {
+	id, _ := paramID(c, "id")
+	status, ok := participantStatusQuery(c)
+	if !ok {
+		return
+	}
+	t, err := Repos.Tickets.Get(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+		return
+	}
+	list, err := Repos.Tickets.ListParticipants(t.EventID, t.ID, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"participants": list})
+}
+
+// GetEventParticipants イベント全体の参加者一覧（?status= / ?ticket_id= で絞り込み）
+func GetEventParticipants(c *gin.Context) {
+	eventID, _ := paramID(c, "id")
+	status, ok := participantStatusQuery(c)
+	if !ok {
+		return
+	}
+	var ticketID uint
+	if c.Query("ticket_id") != "" {
+		id, err := strconv.ParseUint(c.Query("ticket_id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket_id"})
+			return
+		}
+		ticketID = uint(id)
+	}
+	list, err := Repos.Tickets.ListParticipants(eventID, ticketID, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"participants": list})
+}
+
+// transitionParticipant ステータスを変更し、遷移した日時を記録する
+func transitionParticipant(p *models.EventParticipant, to models.ParticipantStatus) error {
+	if !p.Status.CanTransitionTo(to) {
+		return errInvalidTransition
+	}
+	now := time.Now()
+	if p.Status == models.ParticipantStatusOffered && to != models.ParticipantStatusCancelled {
+		p.OfferExpiresAt = nil
+	}
+	p.Status = to
+	switch to {
+	case models.ParticipantStatusConfirmed:
+		p.ConfirmedAt = &now
+	case models.ParticipantStatusCancelled:
+		p.CancelledAt = &now
+	}
+	return nil
+}
+
+// UpdateParticipantStatus 主催者による参加登録の確定・取り消し。取り消して空いた席はキャンセル待ちから繰り上げる
+func UpdateParticipantStatus(c *gin.Context) {
+	id, _ := paramID(c, "id")
+	var req struct {
+		Status models.ParticipantStatus `json:"status" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Status != models.ParticipantStatusConfirmed && req.Status != models.ParticipantStatusCancelled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be confirmed or cancelled"})
+		return
+	}
+	p, err := changeParticipantStatus(id, nil, func(*models.EventParticipant) models.ParticipantStatus { return req.Status })
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found"})
+		return
+	}
+	if err != nil {
+		registrationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"participant": p})
+}
+
+// RegisterForTicket 自分をチケットに参加登録する。
+// イベントとチケットの行をロックしてから数えるので、同時に申し込まれても販売枚数を超えず、
+// 同じイベントの別のチケット種別に同時に申し込まれても登録は1件だけになる。無料チケットは即確定、有料は保留中。
+// 売り切れのときはキャンセル待ちとして登録し、待ち順を返す。
+// promo_code を指定すると割引を適用し、コードの行ロックを取ってから利用回数を数えるので上限を超えて使われない。
+// キャンセル待ちになった場合、コードの利用は繰り上がって席が確定するまで記録しない
+func RegisterForTicket(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	ticketID, err := paramID(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+		return
+	}
+	var req struct {
+		PromoCode string `json:"promo_code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var participantID uint
+	err = Repos.Transaction(func(tx *repository.Repositories) error {
+		// 1人1件のチェックはイベント内の別チケットへの同時登録とも競合するので、イベントの行ロックで直列化する。
+		// ロックはイベント → チケットの順に取る（チケットだけをロックする処理はイベントをロックしない）
+		unlocked, err := tx.Tickets.Get(ticketID)
+		if err != nil {
+			return err
+		}
+		event, err := tx.Events.GetForUpdate(unlocked.EventID)
+		if err != nil {
+			return err
+		}
+		t, err := tx.Tickets.GetForUpdate(ticketID)
+		if err != nil {
+			return err
+		}
+		if event.Status != models.EventStatusPublished && event.Status != models.EventStatusOngoing {
+			return errEventNotOpen
+		}
+		now := time.Now()
+		if !t.OnSale(now) {
+			return errSalesClosed
+		}
+		if _, err := tx.Tickets.FindOpenParticipant(t.EventID, uid); err == nil {
+			return errAlreadyRegistered
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		// 期限切れの繰り上げを先に片付けて、空席があれば待っている人を優先する
+		if err := fillSeats(tx, t, now); err != nil {
+			return err
+		}
+		var promo *models.PromoCode
+		discount := 0
+		if strings.TrimSpace(req.PromoCode) != "" {
+			if promo, err = lockPromoCode(tx, t.EventID, req.PromoCode); err != nil {
+				return err
+			}
+			if discount, err = promoCodeDiscount(tx, promo, t, uid, now); err != nil {
+				return err
+			}
+		}
+		soldOut := false
+		if t.Quantity != nil {
+			sold, err := tx.Tickets.CountActive(t.ID)
+			if err != nil {
+				return err
+			}
+			soldOut = sold >= int64(*t.Quantity)
+		}
+
+		// 取り消し済みの登録があれば作り直さずに再利用する（ticket_id, user_id は一意）。
+		// 受付済みの登録を使い回すと受付の記録が消えて同じ QR で入り直せるので断る
+		p, err := tx.Tickets.FindParticipant(t.ID, uid)
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			p = &models.EventParticipant{TicketID: t.ID, UserID: uid}
+		case err != nil:
+			return err
+		case p.CheckedInAt != nil:
+			return errAlreadyCheckedIn
+		}
+		p.Status = models.ParticipantStatusPending
+		p.ConfirmedAt, p.CancelledAt = nil, nil
+		p.WaitlistedAt, p.OfferExpiresAt = nil, nil
+		p.PromoCodeID, p.Discount = nil, discount
+		if promo != nil {
+			p.PromoCodeID = &promo.ID
+		}
+		switch {
+		case soldOut:
+			p.Status = models.ParticipantStatusWaitlisted
+			p.WaitlistedAt = &now
+		case t.Price-discount == 0:
+			p.Status = models.ParticipantStatusConfirmed
+			p.ConfirmedAt = &now
+		}
+		if p.ID == 0 {
+			err = tx.Tickets.CreateParticipant(p)
+		} else {
+			err = tx.Tickets.UpdateParticipant(p)
+		}
+		if err != nil {
+			return err
+		}
+		// キャンセル待ちの間は割引コードと割引額だけを登録に残し、利用は席が確保されたときに記録する
+		if promo != nil && !soldOut {
+			err := tx.PromoCodes.Redeem(&models.PromoCodeRedemption{
+				PromoCodeID:        promo.ID,
+				EventParticipantID: p.ID,
+				TicketID:           t.ID,
+				UserID:             uid,
+				Price:              t.Price,
+				Discount:           discount,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		participantID = p.ID
+		return nil
+	})
+	if err != nil {
+		registrationError(c, err)
+		return
+	}
+	participant, err := Repos.Tickets.GetParticipant(participantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if participant.Status != models.ParticipantStatusWaitlisted {
+		c.JSON(http.StatusCreated, gin.H{"participant": participant})
+		return
+	}
+	position, err := Repos.Tickets.WaitlistPosition(participant)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"participant": participant, "waitlist_position": position})
+}
+
+// GetMyRegistrations 自分の参加登録（チケット・イベント付き）
+func GetMyRegistrations(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	list, err := Repos.Tickets.ListParticipantsByUser(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"registrations": list})
+}
+
+// CancelMyRegistration 自分の参加登録（キャンセル待ちを含む）を取り消す。空いた席は次の人に繰り上げる
+func CancelMyRegistration(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	id, err := paramID(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid registration ID"})
+		return
+	}
+	p, err := changeParticipantStatus(id,
+		func(p *models.EventParticipant) error {
+			if p.UserID != uid {
+				return repository.ErrNotFound
+			}
+			return nil
+		},
+		func(*models.EventParticipant) models.ParticipantStatus { return models.ParticipantStatusCancelled })
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Registration not found"})
+		return
+	}
+	if err != nil {
+		registrationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"registration": p})
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+)
+
+// addTicket テスト用のチケット。quantity が負なら枚数無制限
+func addTicket(t *testing.T, event *models.Event, price, quantity int) *models.Ticket {
+	t.Helper()
+	ticket := &models.Ticket{EventID: event.ID, Name: "一般", Price: price}
+	if quantity >= 0 {
+		ticket.Quantity = &quantity
+	}
+	if err := Repos.Tickets.Create(ticket); err != nil {
+		t.Fatal(err)
+	}
+	return ticket
+}
+
+// register RegisterForTicket を呼んで作られた参加登録を返す
+func register(t *testing.T, uid, ticketID uint, body interface{}) *models.EventParticipant {
+	t.Helper()
+	w := call(RegisterForTicket, uid, idParam("id", ticketID), body)
+	expectStatus(t, w, http.StatusCreated)
+	var res struct {
+		Participant models.EventParticipant `json:"participant"`
+	}
+	decode(t, w, &res)
+	return &res.Participant
+}
+
+func TestRegisterForTicketWaitlist(t *testing.T) {
+	store := useMemoryRepos(t)
+	admin := addUser(store, "admin")
+	alice := addUser(store, "alice")
+	bob := addUser(store, "bob")
+	event := addEvent(t, admin)
+	ticket := addTicket(t, event, 0, 1)
+
+	if p := register(t, alice.ID, ticket.ID, nil); p.Status != models.ParticipantStatusConfirmed {
+		t.Fatalf("alice = %s, want confirmed", p.Status)
+	}
+	w := call(RegisterForTicket, bob.ID, idParam("id", ticket.ID), nil)
+	expectStatus(t, w, http.StatusCreated)
+	var res struct {
+		Participant      models.EventParticipant `json:"participant"`
+		WaitlistPosition int64                   `json:"waitlist_position"`
+	}
+	decode(t, w, &res)
+	if res.Participant.Status != models.ParticipantStatusWaitlisted || res.WaitlistPosition != 1 {
+		t.Fatalf("bob = %s #%d, want waitlisted #1", res.Participant.Status, res.WaitlistPosition)
+	}
+	expectStatus(t, call(RegisterForTicket, bob.ID, idParam("id", ticket.ID), nil), http.StatusConflict)
+}
+
+func TestRegisterForTicketOnePerEventConcurrently(t *testing.T) {
+	store := useMemoryRepos(t)
+	admin := addUser(store, "admin")
+	alice := addUser(store, "alice")
+	event := addEvent(t, admin)
+	tickets := []*models.Ticket{addTicket(t, event, 0, -1), addTicket(t, event, 0, -1), addTicket(t, event, 0, -1)}
+
+	// 同じイベントの別々のチケット種別へ同時に申し込んでも登録は1件だけ
+	codes := make([]int, len(tickets))
+	var wg sync.WaitGroup
+	for i, ticket := range tickets {
+		wg.Add(1)
+		go func(i int, ticketID uint) {
+			defer wg.Done()
+			codes[i] = call(RegisterForTicket, alice.ID, idParam("id", ticketID), nil).Code
+		}(i, ticket.ID)
+	}
+	wg.Wait()
+
+	created := 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusCreated:
+			created++
+		case http.StatusConflict:
+		default:
+			t.Fatalf("status = %d, want 201 or 409", code)
+		}
+	}
+	if created != 1 {
+		t.Fatalf("created = %d, want 1 (codes %v)", created, codes)
+	}
+	if _, err := Repos.Tickets.FindOpenParticipant(event.ID, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeleteTicketWithWaitlist(t *testing.T) {
+	store := useMemoryRepos(t)
+	admin := addUser(store, "admin")
+	bob := addUser(store, "bob")
+	event := addEvent(t, admin)
+	ticket := addTicket(t, event, 0, 0)
+
+	p := register(t, bob.ID, ticket.ID, nil)
+	if p.Status != models.ParticipantStatusWaitlisted {
+		t.Fatalf("bob = %s, want waitlisted", p.Status)
+	}
+
+	// キャンセル待ちしかいなくても削除できない
+	expectStatus(t, call(DeleteTicket, admin.ID, idParam("id", ticket.ID), nil), http.StatusConflict)
+
+	expectStatus(t, call(CancelMyRegistration, bob.ID, idParam("id", p.ID), nil), http.StatusOK)
+	expectStatus(t, call(DeleteTicket, admin.ID, idParam("id", ticket.ID), nil), http.StatusOK)
+
+	if _, err := Repos.Tickets.Get(ticket.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Get deleted ticket: err = %v", err)
+	}
+	if list, _ := Repos.Tickets.ListByEvent(event.ID); len(list) != 0 {
+		t.Errorf("ListByEvent = %+v", list)
+	}
+	// 登録の履歴には削除済みのチケットも載る
+	w := call(GetMyRegistrations, bob.ID, nil, nil)
+	expectStatus(t, w, http.StatusOK)
+	var res struct {
+		Registrations []models.EventParticipant `json:"registrations"`
+	}
+	decode(t, w, &res)
+	if len(res.Registrations) != 1 || res.Registrations[0].Ticket.Name != ticket.Name {
+		t.Errorf("registrations = %+v", res.Registrations)
+	}
+}
+
+func TestFindOpenParticipantIgnoresDeletedTicket(t *testing.T) {
+	store := useMemoryRepos(t)
+	admin := addUser(store, "admin")
+	bob := addUser(store, "bob")
+	event := addEvent(t, admin)
+	old := addTicket(t, event, 0, -1)
+	p := register(t, bob.ID, old.ID, nil)
+
+	// 削除済みチケットに残った登録は、同じイベントの別のチケットへの申し込みを妨げない
+	if err := Repos.Tickets.Delete(old.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Repos.Tickets.FindOpenParticipant(event.ID, bob.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("FindOpenParticipant: err = %v, want ErrNotFound", err)
+	}
+	got, err := Repos.Tickets.GetParticipant(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Ticket.ID != 0 {
+		t.Errorf("GetParticipant loaded deleted ticket %d", got.Ticket.ID)
+	}
+
+	ticket := addTicket(t, event, 0, -1)
+	if p := register(t, bob.ID, ticket.ID, nil); p.Status != models.ParticipantStatusConfirmed {
+		t.Errorf("status = %s, want confirmed", p.Status)
+	}
+}
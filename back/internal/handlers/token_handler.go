@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"sherpa-backend/internal/authz"
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxPersonalTokenLifetimeDays = 365
+	// lastUsedResolution last_used_at の更新間隔。リクエストごとに書き込まないよう間引く
+	lastUsedResolution = time.Minute
+)
+
+var errInvalidPersonalToken = errors.New("invalid personal access token")
+
+// personalTokenView トークン一覧の1行
+type personalTokenView struct {
+	models.PersonalAccessToken
+	Scopes []string `json:"scopes"`
+}
+
+func newPersonalTokenView(t models.PersonalAccessToken) personalTokenView {
+	return personalTokenView{PersonalAccessToken: t, Scopes: t.ScopeList()}
+}
+
+type createPersonalTokenRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	ExpiresInDays *int     `json:"expires_in_days"` // 未指定なら無期限
+}
+
+// CreatePersonalAccessToken POST /api/me/tokens。平文のトークンはこのレスポンスでしか返さない
+func CreatePersonalAccessToken(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	var req createPersonalTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name and scopes are required"})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must be 1-100 characters"})
+		return
+	}
+
+	seen := map[string]bool{}
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, s := range req.Scopes {
+		if !authz.ValidScope(authz.Scope(s)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope: " + s, "scopes": authz.Scopes})
+			return
+		}
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+	if len(scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one scope is required", "scopes": authz.Scopes})
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInDays != nil {
+		days := *req.ExpiresInDays
+		if days < 1 || days > maxPersonalTokenLifetimeDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days must be between 1 and 365"})
+			return
+		}
+		t := time.Now().AddDate(0, 0, days)
+		expiresAt = &t
+	}
+
+	raw := models.PersonalAccessTokenPrefix + generateRefreshToken()
+	token := models.PersonalAccessToken{
+		UserID:      uid,
+		Name:        name,
+		TokenPrefix: raw[:len(models.PersonalAccessTokenPrefix)+4],
+		TokenHash:   hashToken(raw),
+		Scopes:      strings.Join(scopes, " "),
+		ExpiresAt:   expiresAt,
+	}
+	if err := Repos.PersonalTokens.Create(&token); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"token": raw, "personal_access_token": newPersonalTokenView(token)})
+}
+
+// GetPersonalAccessTokens GET /api/me/tokens。失効済みは含めない
+func GetPersonalAccessTokens(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	list, err := Repos.PersonalTokens.ListActive(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	out := make([]personalTokenView, 0, len(list))
+	for _, t := range list {
+		out = append(out, newPersonalTokenView(t))
+	}
+	c.JSON(http.StatusOK, gin.H{"tokens": out, "available_scopes": authz.Scopes})
+}
+
+// RevokePersonalAccessToken DELETE /api/me/tokens/:id
+func RevokePersonalAccessToken(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+	if err := Repos.PersonalTokens.Revoke(uint(id), uid, time.Now()); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// verifyPersonalAccessToken トークンを検証し、最終利用日時を記録する
+func verifyPersonalAccessToken(raw string) (*models.PersonalAccessToken, error) {
+	t, err := Repos.PersonalTokens.FindByHash(hashToken(raw))
+	if err != nil {
+		return nil, errInvalidPersonalToken
+	}
+	now := time.Now()
+	if !t.IsActive(now) {
+		return nil, errInvalidPersonalToken
+	}
+	if t.LastUsedAt == nil || now.Sub(*t.LastUsedAt) > lastUsedResolution {
+		_ = Repos.PersonalTokens.Touch(t.ID, now)
+	}
+	return t, nil
+}
@@ -5,21 +5,18 @@ import (
 	"strconv"
 	"strings"
 
-	"sherpa-backend/internal/database"
 	"sherpa-backend/internal/models"
 
 	"github.com/gin-gonic/gin"
 )
 
-const defaultOrgID = 1
-
 // CreateUserRequest ユーザー作成リクエスト
 type CreateUserRequest struct {
 	Name  string `json:"name" binding:"required"`
 	Email string `json:"email" binding:"required"`
 }
 
-// CreateUser ユーザーを作成し、デフォルト組織に追加
+// CreateUser ユーザーを作成し、所属組織を割り当てる（placeNewUser）
 func CreateUser(c *gin.Context) {
 	var req CreateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -27,23 +24,17 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
-	var count int64
-	if database.DB.Model(&models.User{}).Where("email = ?", req.Email).Count(&count); count > 0 {
+	if _, err := Repos.Users.FindByEmail(req.Email); err == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "このメールアドレスは既に登録されています"})
 		return
 	}
 	user := models.User{Name: req.Name, Email: req.Email}
-	if err := database.DB.Create(&user).Error; err != nil {
+	if err := Repos.Users.Create(&user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "ユーザー作成に失敗しました"})
 		return
 	}
 
-	member := models.OrganizationMember{
-		UserID:         user.ID,
-		OrganizationID: defaultOrgID,
-		Role:           "member",
-	}
-	if err := database.DB.Create(&member).Error; err != nil {
+	if err := placeNewUser(&user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "組織への追加に失敗しました"})
 		return
 	}
@@ -58,11 +49,8 @@ func SearchUsers(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"users": []models.User{}})
 		return
 	}
-	var users []models.User
-	pattern := "%" + q + "%"
-	if err := database.DB.Where("name ILIKE ? OR email ILIKE ?", pattern, pattern).
-		Limit(30).
-		Find(&users).Error; err != nil {
+	users, err := Repos.Users.Search(q, 30)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -77,8 +65,8 @@ func GetUser(c *gin.Context) {
 		return
 	}
 
-	var user models.User
-	if err := database.DB.First(&user, uint(id)).Error; err != nil {
+	user, err := Repos.Users.Get(uint(id))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
@@ -86,31 +74,29 @@ func GetUser(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"user": user})
 }
 
-// GetUserEvents ユーザーが EventStaff として参加しているイベント一覧を取得（ロール不問）
+// GetUserEvents ユーザーが EventStaff として参加しているイベント一覧を取得（ロール不問・本人のみ・所属組織のイベントに限る）
 func GetUserEvents(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
-
-	var eventIDs []uint
-	if err := database.DB.Model(&models.EventStaff{}).
-		Where("user_id = ?", uint(id)).
-		Pluck("event_id", &eventIDs).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if uint(id) != uid {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot list other users' events"})
 		return
 	}
 
-	if len(eventIDs) == 0 {
-		c.JSON(http.StatusOK, gin.H{"events": []models.Event{}})
+	orgIDs, ok := memberOrganizationIDs(c, uid)
+	if !ok {
 		return
 	}
-
-	var events []models.Event
-	if err := database.DB.Preload("Organization").
-		Where("id IN ?", eventIDs).
-		Find(&events).Error; err != nil {
+	events, err := Repos.Events.ListForStaff(uid, orgIDs)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errOfferExpired = errors.New("繰り上げの確定期限を過ぎています")
+
+// defaultWaitlistOfferTTL 繰り上げ後に参加を確定できる期間
+const defaultWaitlistOfferTTL = 24 * time.Hour
+
+// waitlistOfferTTL WAITLIST_OFFER_TTL（例: 12h）。未設定・不正値はデフォルト
+func waitlistOfferTTL() time.Duration {
+	return durationEnv("WAITLIST_OFFER_TTL", defaultWaitlistOfferTTL)
+}
+
+// fillSeats 期限切れの繰り上げを取り消し、空いている席をキャンセル待ちの古い順に繰り上げて通知する。
+// tx の中で t の行ロック（GetForUpdate）を取ってから呼ぶこと
+func fillSeats(tx *repository.Repositories, t *models.Ticket, now time.Time) error {
+	var event *models.Event
+	notify := func(p *models.EventParticipant, typ models.NotificationType, title, body string) error {
+		if event == nil {
+			e, err := tx.Events.Get(t.EventID)
+			if err != nil {
+				return err
+			}
+			event = e
+		}
+		return tx.Notifications.Create(&models.Notification{
+			UserID:     p.UserID,
+			Type:       typ,
+			Title:      title,
+			Body:       fmt.Sprintf(body, event.Title, t.Name),
+			RelatedID:  p.ID,
+			RelatedTyp: "event_participant",
+		})
+	}
+
+	expired, err := tx.Tickets.ExpiredOffers(t.ID, now)
+	if err != nil {
+		return err
+	}
+	for i := range expired {
+		p := &expired[i]
+		p.Status = models.ParticipantStatusCancelled
+		p.CancelledAt = &now
+		if err := tx.Tickets.UpdateParticipant(p); err != nil {
+			return err
+		}
+		if err := notify(p, models.NotificationTypeWaitlistExpired, "キャンセル待ちの繰り上げ期限切れ",
+			"「%s」の「%s」は確定期限を過ぎたため、繰り上げを取り消しました。"); err != nil {
+			return err
+		}
+	}
+
+	sold, err := tx.Tickets.CountActive(t.ID)
+	if err != nil {
+		return err
+	}
+	for t.Quantity == nil || sold < int64(*t.Quantity) {
+		p, err := tx.Tickets.NextWaitlisted(t.ID)
+		if errors.Is(err, repository.ErrNotFound) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		expiresAt := now.Add(waitlistOfferTTL())
+		p.Status = models.ParticipantStatusOffered
+		p.OfferExpiresAt = &expiresAt
+		if err := tx.Tickets.UpdateParticipant(p); err != nil {
+			return err
+		}
+		body := "「%s」の「%s」に空きが出ました。" + expiresAt.Format("1月2日 15:04") + " までに参加を確定してください。"
+		if err := notify(p, models.NotificationTypeWaitlistOffer, "キャンセル待ちから繰り上がりました", body); err != nil {
+			return err
+		}
+		sold++
+	}
+	return nil
+}
+
+// changeParticipantStatus チケット行をロックしてから参加登録のステータスを変更し、席が空けば繰り上げる。
+// check は変更前の登録を検証する（本人確認など）。エラーは registrationError で書き込める
+func changeParticipantStatus(id uint, check func(p *models.EventParticipant) error, to func(p *models.EventParticipant) models.ParticipantStatus) (*models.EventParticipant, error) {
+	current, err := Repos.Tickets.GetParticipant(id)
+	if err != nil {
+		return nil, err
+	}
+	// 期限切れの繰り上げは検証エラーでロールバックされないよう、先に別トランザクションで取り消す
+	if err := settleTicket(current.TicketID, time.Now()); err != nil {
+		return nil, err
+	}
+	err = Repos.Transaction(func(tx *repository.Repositories) error {
+		t, err := tx.Tickets.GetForUpdate(current.TicketID)
+		if err != nil {
+			return err
+		}
+		p, err := tx.Tickets.GetParticipant(id)
+		if err != nil {
+			return err
+		}
+		if check != nil {
+			if err := check(p); err != nil {
+				return err
+			}
+		}
+		next := to(p)
+		// 繰り上がった登録の席が確定するときに、保留していた割引コードの利用を記録する（割引が外れると支払額が変わる）
+		if p.Status == models.ParticipantStatusOffered && next != models.ParticipantStatusCancelled {
+			if err := redeemHeldPromoCode(tx, p); err != nil {
+				return err
+			}
+			next = to(p)
+		}
+		if err := transitionParticipant(p, next); err != nil {
+			return err
+		}
+		if err := tx.Tickets.UpdateParticipant(p); err != nil {
+			return err
+		}
+		return fillSeats(tx, t, time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return Repos.Tickets.GetParticipant(id)
+}
+
+// settleTicket チケット行をロックして fillSeats だけを実行する
+func settleTicket(ticketID uint, now time.Time) error {
+	return Repos.Transaction(func(tx *repository.Repositories) error {
+		t, err := tx.Tickets.GetForUpdate(ticketID)
+		if err != nil {
+			return err
+		}
+		return fillSeats(tx, t, now)
+	})
+}
+
+// ConfirmMyRegistration 繰り上がった登録を期限内に確定する。無料チケットは confirmed、有料は pending になる
+func ConfirmMyRegistration(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	id, err := paramID(c, "id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid registration ID"})
+		return
+	}
+	p, err := changeParticipantStatus(id,
+		func(p *models.EventParticipant) error {
+			if p.UserID != uid {
+				return repository.ErrNotFound
+			}
+			if p.Status == models.ParticipantStatusCancelled && p.OfferExpiresAt != nil && !p.OfferExpiresAt.After(time.Now()) {
+				return errOfferExpired
+			}
+			if p.Status != models.ParticipantStatusOffered {
+				return errInvalidTransition
+			}
+			return nil
+		},
+		func(p *models.EventParticipant) models.ParticipantStatus {
+			if p.AmountDue() == 0 {
+				return models.ParticipantStatusConfirmed
+			}
+			return models.ParticipantStatusPending
+		})
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Registration not found"})
+		return
+	}
+	if err != nil {
+		registrationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"registration": p})
+}
+
+// SweepWaitlists キャンセル待ちのあるチケットを順に処理し、期限切れの繰り上げを次の人に回す。
+// 退会などトランザクション外で空いた席もここで埋まる
+func SweepWaitlists() error {
+	now := time.Now()
+	ids, err := Repos.Tickets.TicketIDsWithWaitlist(now)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := settleTicket(id, now); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("ticket %d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// RunWaitlistSweeper interval ごとに SweepWaitlists を実行する（サーバー起動時に goroutine で呼ぶ）
+func RunWaitlistSweeper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := SweepWaitlists(); err != nil {
+			log.Printf("[waitlist] sweep: %v", err)
+		}
+	}
+}
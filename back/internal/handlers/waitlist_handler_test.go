@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"sherpa-backend/internal/models"
+)
+
+// waitlistFixture 定員1のチケットに alice が登録済みで、bob・carol がこの順でキャンセル待ちの状態
+type waitlistFixture struct {
+	ticket              *models.Ticket
+	alice, bob, carol   *models.User
+	seat, first, second *models.EventParticipant
+}
+
+func newWaitlistFixture(t *testing.T, price int) *waitlistFixture {
+	t.Helper()
+	store := useMemoryRepos(t)
+	admin := addUser(store, "admin")
+	f := &waitlistFixture{alice: addUser(store, "alice"), bob: addUser(store, "bob"), carol: addUser(store, "carol")}
+	f.ticket = addTicket(t, addEvent(t, admin), price, 1)
+	f.seat = register(t, f.alice.ID, f.ticket.ID, nil)
+	f.first = register(t, f.bob.ID, f.ticket.ID, nil)
+	f.second = register(t, f.carol.ID, f.ticket.ID, nil)
+	if f.first.Status != models.ParticipantStatusWaitlisted || f.second.Status != models.ParticipantStatusWaitlisted {
+		t.Fatalf("bob = %s, carol = %s, want waitlisted", f.first.Status, f.second.Status)
+	}
+	return f
+}
+
+// participantStatus 参加登録の現在のステータス
+func participantStatus(t *testing.T, id uint) *models.EventParticipant {
+	t.Helper()
+	p, err := Repos.Tickets.GetParticipant(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// hasNotification userID に typ の通知が届いているか
+func hasNotification(t *testing.T, userID uint, typ models.NotificationType) bool {
+	t.Helper()
+	list, err := Repos.Notifications.ListForUser(userID, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range list {
+		if n.Type == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// expireOffer 繰り上げの確定期限を過去にする
+func expireOffer(t *testing.T, id uint) {
+	t.Helper()
+	p := participantStatus(t, id)
+	past := time.Now().Add(-time.Minute)
+	p.OfferExpiresAt = &past
+	if err := Repos.Tickets.UpdateParticipant(p); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCancelPromotesNextWaitlisted(t *testing.T) {
+	f := newWaitlistFixture(t, 0)
+
+	expectStatus(t, call(CancelMyRegistration, f.alice.ID, idParam("id", f.seat.ID), nil), http.StatusOK)
+
+	bob := participantStatus(t, f.first.ID)
+	if bob.Status != models.ParticipantStatusOffered || bob.OfferExpiresAt == nil || !bob.OfferExpiresAt.After(time.Now()) {
+		t.Fatalf("bob = %s (expires %v), want offered with a future deadline", bob.Status, bob.OfferExpiresAt)
+	}
+	if !hasNotification(t, f.bob.ID, models.NotificationTypeWaitlistOffer) {
+		t.Fatal("bob should be notified of the offer")
+	}
+	if carol := participantStatus(t, f.second.ID); carol.Status != models.ParticipantStatusWaitlisted {
+		t.Fatalf("carol = %s, want still waitlisted", carol.Status)
+	}
+
+	expectStatus(t, call(ConfirmMyRegistration, f.carol.ID, idParam("id", f.first.ID), nil), http.StatusNotFound)
+	expectStatus(t, call(ConfirmMyRegistration, f.bob.ID, idParam("id", f.first.ID), nil), http.StatusOK)
+	if bob := participantStatus(t, f.first.ID); bob.Status != models.ParticipantStatusConfirmed || bob.ConfirmedAt == nil {
+		t.Fatalf("bob = %s, want confirmed", bob.Status)
+	}
+}
+
+func TestSweepWaitlistsPassesExpiredOfferToNext(t *testing.T) {
+	f := newWaitlistFixture(t, 0)
+	expectStatus(t, call(CancelMyRegistration, f.alice.ID, idParam("id", f.seat.ID), nil), http.StatusOK)
+	expireOffer(t, f.first.ID)
+
+	if err := SweepWaitlists(); err != nil {
+		t.Fatal(err)
+	}
+
+	if bob := participantStatus(t, f.first.ID); bob.Status != models.ParticipantStatusCancelled || bob.CancelledAt == nil {
+		t.Fatalf("bob = %s, want cancelled after the deadline", bob.Status)
+	}
+	if !hasNotification(t, f.bob.ID, models.NotificationTypeWaitlistExpired) {
+		t.Fatal("bob should be notified that the offer expired")
+	}
+	if carol := participantStatus(t, f.second.ID); carol.Status != models.ParticipantStatusOffered {
+		t.Fatalf("carol = %s, want offered", carol.Status)
+	}
+
+	// 期限内の繰り上げはもう一度回しても変わらない
+	if err := SweepWaitlists(); err != nil {
+		t.Fatal(err)
+	}
+	if carol := participantStatus(t, f.second.ID); carol.Status != models.ParticipantStatusOffered {
+		t.Fatalf("carol = %s after second sweep, want offered", carol.Status)
+	}
+}
+
+func TestConfirmAfterDeadlineReturnsOfferExpired(t *testing.T) {
+	f := newWaitlistFixture(t, 0)
+	expectStatus(t, call(CancelMyRegistration, f.alice.ID, idParam("id", f.seat.ID), nil), http.StatusOK)
+	expireOffer(t, f.first.ID)
+
+	w := call(ConfirmMyRegistration, f.bob.ID, idParam("id", f.first.ID), nil)
+	expectStatus(t, w, http.StatusConflict)
+	var res struct {
+		Error string `json:"error"`
+	}
+	decode(t, w, &res)
+	if res.Error != errOfferExpired.Error() {
+		t.Fatalf("error = %q, want %q", res.Error, errOfferExpired.Error())
+	}
+
+	// 検証エラーでも期限切れの取り消しと次の人への繰り上げは確定している
+	if bob := participantStatus(t, f.first.ID); bob.Status != models.ParticipantStatusCancelled {
+		t.Fatalf("bob = %s, want cancelled", bob.Status)
+	}
+	if carol := participantStatus(t, f.second.ID); carol.Status != models.ParticipantStatusOffered {
+		t.Fatalf("carol = %s, want offered", carol.Status)
+	}
+}
+
+func TestConfirmPaidOfferMovesToPending(t *testing.T) {
+	f := newWaitlistFixture(t, 3000)
+	if f.seat.Status != models.ParticipantStatusPending {
+		t.Fatalf("alice = %s, want pending for a paid ticket", f.seat.Status)
+	}
+	expectStatus(t, call(CancelMyRegistration, f.alice.ID, idParam("id", f.seat.ID), nil), http.StatusOK)
+
+	expectStatus(t, call(ConfirmMyRegistration, f.bob.ID, idParam("id", f.first.ID), nil), http.StatusOK)
+	bob := participantStatus(t, f.first.ID)
+	if bob.Status != models.ParticipantStatusPending || bob.ConfirmedAt != nil {
+		t.Fatalf("bob = %s, want pending until paid", bob.Status)
+	}
+	// 保留中も席を使うので、次の人は繰り上がらない
+	if carol := participantStatus(t, f.second.ID); carol.Status != models.ParticipantStatusWaitlisted {
+		t.Fatalf("carol = %s, want still waitlisted", carol.Status)
+	}
+	// 繰り上げを受けた後の登録はもう一度確定できない
+	expectStatus(t, call(ConfirmMyRegistration, f.bob.ID, idParam("id", f.first.ID), nil), http.StatusConflict)
+}
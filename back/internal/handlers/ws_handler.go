@@ -2,27 +2,47 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
 	"sherpa-backend/internal/ws"
 
 	"github.com/gin-gonic/gin"
 )
 
-// WSHandler は GET /api/ws?token=xxx で WebSocket 接続を処理する
+// IssueWSTicket POST /api/ws/ticket。/api/ws の接続に使う30秒・1回限りのチケットを発行する
+func IssueWSTicket(c *gin.Context) {
+	uid, ok := userIDFrom(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+	sid, _ := c.Get("session_id")
+	sessionID, _ := sid.(uint)
+
+	ticket := wsTickets.Issue(oneTimeGrant{UserID: uid, SessionID: sessionID})
+	c.JSON(http.StatusOK, gin.H{
+		"ticket":     ticket,
+		"expires_in": int(wsTickets.ttl.Seconds()),
+	})
+}
+
+// WSHandler は GET /api/ws?ticket=xxx で WebSocket 接続を処理する
 func WSHandler(hub *ws.Hub) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token := c.Query("token")
-		if token == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "token required"})
+		grant, ok := wsTickets.Consume(c.Query("ticket"))
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired ticket"})
 			return
 		}
 
-		userID, err := VerifyToken(token)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-			return
+		// チケット発行後にログアウト・失効したセッションでは接続させない
+		if grant.SessionID != 0 {
+			if sess, err := Repos.Sessions.Get(grant.SessionID); err != nil || !sess.IsActive(time.Now()) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "session revoked"})
+				return
+			}
 		}
 
-		ws.ServeWS(hub, c.Writer, c.Request, userID)
+		ws.ServeWS(hub, c.Writer, c.Request, grant.UserID)
 	}
 }
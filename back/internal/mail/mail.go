@@ -0,0 +1,67 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Message 送信するメール
+type Message struct {
+	To      string
+	Subject string
+	Body    string // text/plain
+}
+
+// Sender メール送信の抽象。SMTP・ファイル出力・メモリ保持を差し替えられる。
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// DefaultSender は main で設定する。ハンドラからのメール送信に利用する
+var DefaultSender Sender
+
+// Send は DefaultSender でメールを送る。未設定ならエラー
+func Send(ctx context.Context, msg Message) error {
+	if DefaultSender == nil {
+		return fmt.Errorf("mail sender is not configured")
+	}
+	return DefaultSender.Send(ctx, msg)
+}
+
+// NewSenderFromEnv MAIL_DRIVER（smtp / file / memory）に応じた Sender を生成する。
+// 未設定時は SMTP_HOST があれば smtp、なければ MAIL_DIR（既定 tmp/mail）へのファイル出力。
+func NewSenderFromEnv() (Sender, error) {
+	driver := strings.ToLower(os.Getenv("MAIL_DRIVER"))
+	if driver == "" {
+		if os.Getenv("SMTP_HOST") != "" {
+			driver = "smtp"
+		} else {
+			driver = "file"
+		}
+	}
+
+	switch driver {
+	case "smtp":
+		return NewSMTPSender(SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		})
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "tmp/mail"
+		}
+		log.Printf("[mail] using file sender: %s", dir)
+		return NewFileSender(dir)
+	case "memory":
+		return NewMemorySender(), nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER: %s", driver)
+	}
+}
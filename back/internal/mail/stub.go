@@ -0,0 +1,82 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemorySender 送信内容をメモリに保持する。テスト・開発用
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemorySender は MemorySender を生成する
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+// Send メッセージを記録する
+func (s *MemorySender) Send(_ context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// Messages 記録済みメッセージのコピーを返す
+func (s *MemorySender) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Message, len(s.messages))
+	copy(out, s.messages)
+	return out
+}
+
+// LastTo 指定アドレス宛ての最後のメッセージ
+func (s *MemorySender) LastTo(to string) (Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		if strings.EqualFold(s.messages[i].To, to) {
+			return s.messages[i], true
+		}
+	}
+	return Message{}, false
+}
+
+// Reset 記録を消去する
+func (s *MemorySender) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+}
+
+// FileSender 1通ごとに .eml ファイルとしてディレクトリへ書き出す。ローカル開発用
+type FileSender struct {
+	dir string
+	mu  sync.Mutex
+	seq int
+}
+
+// NewFileSender は出力先ディレクトリを作成して FileSender を返す
+func NewFileSender(dir string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create mail dir: %w", err)
+	}
+	return &FileSender{dir: dir}, nil
+}
+
+// Send メッセージをファイルに書き出す
+func (s *FileSender) Send(_ context.Context, msg Message) error {
+	s.mu.Lock()
+	s.seq++
+	name := fmt.Sprintf("%s-%03d.eml", time.Now().Format("20060102-150405"), s.seq)
+	s.mu.Unlock()
+	return os.WriteFile(filepath.Join(s.dir, name), buildRFC822("sherpa@localhost", msg), 0o644)
+}
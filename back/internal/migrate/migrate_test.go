@@ -0,0 +1,404 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// fakePostgres Migrator が発行する SQL だけを解釈する database/sql のドライバ。
+// advisory lock・トランザクション・schema_migrations を模して、実行されたマイグレーションを記録する
+type fakePostgres struct {
+	advisory sync.Mutex // pg_advisory_lock
+
+	mu       sync.Mutex
+	table    bool
+	applied  map[int64]time.Time
+	executed []string // コミットされたマイグレーションの SQL（実行順）
+	failSQL  string   // この文字列を含むマイグレーションは失敗する
+	failRec  int64    // このバージョンの schema_migrations 更新は失敗する
+	openTx   int
+}
+
+func newFakePostgres() *fakePostgres {
+	return &fakePostgres{applied: map[int64]time.Time{}}
+}
+
+func (d *fakePostgres) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: d}, nil }
+func (d *fakePostgres) Driver() driver.Driver                        { return nil }
+
+// Executed コミットされたマイグレーションの SQL
+func (d *fakePostgres) Executed() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.executed...)
+}
+
+type fakeConn struct {
+	db     *fakePostgres
+	locked bool
+	tx     *fakeTx
+}
+
+type fakeTx struct {
+	c   *fakeConn
+	ops []func()
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+
+func (c *fakeConn) Close() error {
+	// セッションが終われば Postgres は advisory lock を解放する
+	if c.locked {
+		c.locked = false
+		c.db.advisory.Unlock()
+	}
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.db.mu.Lock()
+	c.db.openTx++
+	c.db.mu.Unlock()
+	c.tx = &fakeTx{c: c}
+	return c.tx, nil
+}
+
+func (tx *fakeTx) Commit() error {
+	d := tx.c.db
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, op := range tx.ops {
+		op()
+	}
+	d.openTx--
+	tx.c.tx = nil
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	d := tx.c.db
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.openTx--
+	tx.c.tx = nil
+	return nil
+}
+
+// apply トランザクション中ならコミット時に、そうでなければすぐに反映する
+func (c *fakeConn) apply(op func()) {
+	if c.tx != nil {
+		c.tx.ops = append(c.tx.ops, op)
+		return
+	}
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	op()
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	d := c.db
+	switch {
+	case strings.HasPrefix(query, "SELECT pg_advisory_lock"):
+		d.advisory.Lock()
+		c.locked = true
+	case strings.HasPrefix(query, "SELECT pg_advisory_unlock"):
+		if c.locked {
+			c.locked = false
+			d.advisory.Unlock()
+		}
+	case query == createTableSQL:
+		c.apply(func() { d.table = true })
+	case strings.HasPrefix(query, "INSERT INTO schema_migrations"), strings.HasPrefix(query, "DELETE FROM schema_migrations"):
+		version := args[0].Value.(int64)
+		if version == d.failRec {
+			return nil, fmt.Errorf("cannot record version %d", version)
+		}
+		insert := strings.HasPrefix(query, "INSERT")
+		c.apply(func() {
+			if insert {
+				d.applied[version] = time.Now()
+			} else {
+				delete(d.applied, version)
+			}
+		})
+	default:
+		if !c.locked {
+			return nil, fmt.Errorf("migration %q ran without the advisory lock", query)
+		}
+		if d.failSQL != "" && strings.Contains(query, d.failSQL) {
+			return nil, fmt.Errorf("syntax error in %q", query)
+		}
+		c.apply(func() { d.executed = append(d.executed, query) })
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	d := c.db
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch {
+	case strings.HasPrefix(query, "SELECT to_regclass"):
+		return &fakeRows{cols: []string{"exists"}, rows: [][]driver.Value{{d.table}}}, nil
+	case strings.HasPrefix(query, "SELECT version, applied_at FROM schema_migrations"):
+		rows := &fakeRows{cols: []string{"version", "applied_at"}}
+		for v, at := range d.applied {
+			rows.rows = append(rows.rows, []driver.Value{v, at})
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("unexpected query %q", query)
+}
+
+type fakeRows struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// testMigrations バージョン 1, 2, 10。ファイル名の辞書順（10 が 2 より先）とは違う順で適用されるべきもの
+var testMigrations = fstest.MapFS{
+	"1_create_users.up.sql":       {Data: []byte("CREATE TABLE users")},
+	"1_create_users.down.sql":     {Data: []byte("DROP TABLE users")},
+	"10_add_index.up.sql":         {Data: []byte("CREATE INDEX users_email")},
+	"10_add_index.down.sql":       {Data: []byte("DROP INDEX users_email")},
+	"2_create_events.up.sql":      {Data: []byte("CREATE TABLE events")},
+	"2_create_events.down.sql":    {Data: []byte("DROP TABLE events")},
+	"README.md":                   {Data: []byte("not a migration")},
+	"embed.go":                    {Data: []byte("package migrations")},
+	"notes/0003_ignored.up.sql":   {Data: []byte("ignored")},
+	"notes/0003_ignored.down.sql": {Data: []byte("ignored")},
+}
+
+// newTestMigrator testMigrations を fakePostgres に適用する Migrator
+func newTestMigrator(t *testing.T) (*Migrator, *fakePostgres) {
+	t.Helper()
+	fake := newFakePostgres()
+	db := sql.OpenDB(fake)
+	t.Cleanup(func() { db.Close() })
+	m, err := New(db, testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, fake
+}
+
+func ids(list []Migration) []string {
+	out := make([]string, 0, len(list))
+	for _, m := range list {
+		out = append(out, m.ID())
+	}
+	return out
+}
+
+// expectIDs got が want の ID の並びと一致すること
+func expectIDs(t *testing.T, what string, got []Migration, want ...string) {
+	t.Helper()
+	if g := ids(got); strings.Join(g, ",") != strings.Join(want, ",") {
+		t.Fatalf("%s = %v, want %v", what, g, want)
+	}
+}
+
+// expectExecuted コミットされたマイグレーションの SQL が want の順であること
+func expectExecuted(t *testing.T, fake *fakePostgres, want ...string) {
+	t.Helper()
+	if got := fake.Executed(); strings.Join(got, ";") != strings.Join(want, ";") {
+		t.Fatalf("executed %q, want %q", got, want)
+	}
+}
+
+func TestLoadOrdersByVersion(t *testing.T) {
+	list, err := Load(testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectIDs(t, "Load", list, "0001_create_users", "0002_create_events", "0010_add_index")
+	if list[0].Up != "CREATE TABLE users" || list[0].Down != "DROP TABLE users" {
+		t.Fatalf("0001 = %+v, want both directions loaded", list[0])
+	}
+}
+
+func TestLoadRejectsBrokenSets(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"missing down": {
+			"0001_a.up.sql": {Data: []byte("SELECT 1")},
+		},
+		"duplicate version": {
+			"0001_a.up.sql":   {Data: []byte("SELECT 1")},
+			"0001_a.down.sql": {Data: []byte("SELECT 1")},
+			"0001_b.up.sql":   {Data: []byte("SELECT 1")},
+			"0001_b.down.sql": {Data: []byte("SELECT 1")},
+		},
+		"bad file name": {
+			"0001-A.up.sql": {Data: []byte("SELECT 1")},
+		},
+	}
+	for name, fsys := range cases {
+		if _, err := Load(fsys); err == nil {
+			t.Errorf("%s: Load should fail", name)
+		}
+	}
+}
+
+func TestUpAppliesPendingInOrder(t *testing.T) {
+	m, fake := newTestMigrator(t)
+	ctx := context.Background()
+
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectIDs(t, "Pending before Up", pending, "0001_create_users", "0002_create_events", "0010_add_index")
+
+	done, err := m.Up(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectIDs(t, "Up(1)", done, "0001_create_users")
+
+	done, err = m.Up(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectIDs(t, "Up(0)", done, "0002_create_events", "0010_add_index")
+	expectExecuted(t, fake, "CREATE TABLE users", "CREATE TABLE events", "CREATE INDEX users_email")
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range status {
+		if s.AppliedAt == nil {
+			t.Errorf("%s should be recorded as applied", s.ID())
+		}
+	}
+	// 最新なら何もしない（起動時の CheckMigrations も通る）
+	if done, err = m.Up(ctx, 0); err != nil || len(done) != 0 {
+		t.Fatalf("second Up = %v, %v; want nothing to do", ids(done), err)
+	}
+	if pending, err = m.Pending(ctx); err != nil || len(pending) != 0 {
+		t.Fatalf("Pending = %v, %v; want none", ids(pending), err)
+	}
+}
+
+func TestUpStopsAndRollsBackOnFailure(t *testing.T) {
+	m, fake := newTestMigrator(t)
+	ctx := context.Background()
+	fake.failSQL = "CREATE TABLE events"
+
+	done, err := m.Up(ctx, 0)
+	if err == nil || !strings.Contains(err.Error(), "0002_create_events up") {
+		t.Fatalf("Up error = %v, want it to name 0002_create_events", err)
+	}
+	expectIDs(t, "Up", done, "0001_create_users")
+	expectExecuted(t, fake, "CREATE TABLE users")
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 失敗したものより後は適用しない。起動時の CheckMigrations はこれで止まる
+	expectIDs(t, "Pending", pending, "0002_create_events", "0010_add_index")
+	if fake.openTx != 0 {
+		t.Fatalf("%d transaction(s) left open", fake.openTx)
+	}
+
+	// 記録に失敗した場合も SQL ごと取り消される
+	fake.failSQL, fake.failRec = "", 2
+	if _, err := m.Up(ctx, 0); err == nil {
+		t.Fatal("Up should fail when the version cannot be recorded")
+	}
+	expectExecuted(t, fake, "CREATE TABLE users")
+
+	// 直したあとは失敗した位置から再開できる（ロックも解放されている）
+	fake.failRec = 0
+	done, err = m.Up(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectIDs(t, "Up after fix", done, "0002_create_events", "0010_add_index")
+	expectExecuted(t, fake, "CREATE TABLE users", "CREATE TABLE events", "CREATE INDEX users_email")
+}
+
+func TestDownRevertsNewestFirst(t *testing.T) {
+	m, fake := newTestMigrator(t)
+	ctx := context.Background()
+	if _, err := m.Up(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	done, err := m.Down(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectIDs(t, "Down(2)", done, "0010_add_index", "0002_create_events")
+	expectExecuted(t, fake, "CREATE TABLE users", "CREATE TABLE events", "CREATE INDEX users_email",
+		"DROP INDEX users_email", "DROP TABLE events")
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectIDs(t, "Pending", pending, "0002_create_events", "0010_add_index")
+
+	fake.failSQL = "DROP TABLE users"
+	if done, err = m.Down(ctx, 1); err == nil || len(done) != 0 {
+		t.Fatalf("Down = %v, %v; want the failure reported", ids(done), err)
+	}
+	if pending, _ = m.Pending(ctx); len(pending) != 2 {
+		t.Fatalf("Pending = %v, want 0001 still applied", ids(pending))
+	}
+}
+
+func TestConcurrentUpAppliesEachMigrationOnce(t *testing.T) {
+	m, fake := newTestMigrator(t)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	results := make([][]Migration, 4)
+	errs := make([]error, len(results))
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = m.Up(ctx, 0)
+		}(i)
+	}
+	wg.Wait()
+
+	total := 0
+	for i, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += len(results[i])
+	}
+	if total != 3 {
+		t.Fatalf("applied %d migrations across runners, want 3", total)
+	}
+	expectExecuted(t, fake, "CREATE TABLE users", "CREATE TABLE events", "CREATE INDEX users_email")
+}
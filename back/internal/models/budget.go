@@ -22,6 +22,7 @@ type Budget struct {
 	Type          BudgetType     `gorm:"type:varchar(20);not null" json:"type"`
 	PlannedAmount int            `gorm:"not null;default:0" json:"planned_amount"`
 	ActualAmount  int            `gorm:"default:0" json:"actual_amount"`
+	TicketID      *uint          `gorm:"index" json:"ticket_id,omitempty"` // チケット売上として自動計上する収入項目
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
@@ -16,6 +16,9 @@ type Channel struct {
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	IsMember    bool           `gorm:"-" json:"is_member"` // 一覧を取得したユーザーがメンバーか
+	UnreadCount int64          `gorm:"-" json:"unread_count"` // 一覧を取得したユーザーの未読数（メンバーのチャンネルのみ）
+	MentionCount int64         `gorm:"-" json:"mention_count"` // 未読のうち自分あてのメンション数
 
 	// Relations
 	Event          Event           `gorm:"foreignKey:EventID" json:"event,omitempty"`
@@ -56,6 +59,7 @@ type Message struct {
 	UserID         uint           `gorm:"not null;index" json:"user_id"`
 	Content        string         `gorm:"type:text;not null" json:"content"`
 	ParentMessageID *uint          `gorm:"index" json:"parent_message_id,omitempty"` // スレッドの親ID
+	AlsoSentToChannel bool         `gorm:"not null;default:false" json:"also_sent_to_channel"` // スレッドの返信をチャンネルにも表示する
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	IsDeleted      bool           `gorm:"default:false" json:"is_deleted"`
@@ -67,6 +71,12 @@ type Message struct {
 	ParentMessage  *Message          `gorm:"foreignKey:ParentMessageID" json:"parent_message,omitempty"`
 	Replies        []Message         `gorm:"foreignKey:ParentMessageID" json:"replies,omitempty"`
 	Reactions      []MessageReaction `gorm:"foreignKey:MessageID" json:"reactions,omitempty"`
+	Mentions       []MessageMention  `gorm:"foreignKey:MessageID" json:"mentions,omitempty"`
+
+	// スレッドの集計（リポジトリが親メッセージを返すときに埋める。削除済みの返信は数えない）
+	ReplyCount    int64      `gorm:"-" json:"reply_count"`
+	LastReplyAt   *time.Time `gorm:"-" json:"last_reply_at,omitempty"`
+	LastReplyUser *User      `gorm:"-" json:"last_reply_user,omitempty"`
 }
 
 // MessageReaction メッセージへのリアクション（とりあえず1種類 👍）
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// EmailTokenPurpose メールで送る使い捨てトークンの用途
+type EmailTokenPurpose string
+
+const (
+	EmailTokenVerifyEmail   EmailTokenPurpose = "verify_email"
+	EmailTokenPasswordReset EmailTokenPurpose = "password_reset"
+	EmailTokenMagicLink     EmailTokenPurpose = "magic_link"
+)
+
+// EmailToken メール確認・パスワード再設定・マジックリンク用の使い捨てトークン（ハッシュのみ保存）
+type EmailToken struct {
+	ID        uint              `gorm:"primaryKey" json:"id"`
+	UserID    uint              `gorm:"not null;index" json:"user_id"`
+	Purpose   EmailTokenPurpose `gorm:"type:varchar(32);not null" json:"purpose"`
+	TokenHash string            `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time         `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time        `json:"used_at,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName テーブル名を指定
+func (EmailToken) TableName() string {
+	return "email_tokens"
+}
@@ -45,6 +45,13 @@ func (Event) TableName() string {
 	return "events"
 }
 
+// イベントスタッフのロール
+const (
+	EventRoleAdmin   = "Admin"
+	EventRoleStaff   = "Staff"
+	EventRoleSponsor = "Sponsor"
+)
+
 // EventStaff イベントスタッフモデル
 type EventStaff struct {
 	ID        uint           `gorm:"primaryKey" json:"id"`
@@ -12,16 +12,90 @@ type Meeting struct {
 	EventID   uint           `gorm:"not null;index" json:"event_id"`
 	Title     string         `gorm:"not null" json:"title"`
 	StartAt   time.Time      `gorm:"not null" json:"start_at"`
-	Minutes   *string        `gorm:"type:text" json:"minutes,omitempty"`
+	EndAt     *time.Time     `json:"end_at,omitempty"`
+	Location  *string        `json:"location,omitempty"`
+	Minutes   *string        `gorm:"type:text" json:"minutes,omitempty"` // 最新の議事録（Markdown）。履歴は MeetingMinutesRevision
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Relations
-	Event Event `gorm:"foreignKey:EventID" json:"event,omitempty"`
+	Event       Event               `gorm:"foreignKey:EventID" json:"event,omitempty"`
+	AgendaItems []MeetingAgendaItem `gorm:"foreignKey:MeetingID" json:"agenda_items,omitempty"`
+	Attendees   []MeetingAttendee   `gorm:"foreignKey:MeetingID" json:"attendees,omitempty"`
 }
 
 // TableName テーブル名を指定
 func (Meeting) TableName() string {
 	return "meetings"
 }
+
+// MeetingAgendaItem 会議のアジェンダ項目。Position の昇順で並べる
+type MeetingAgendaItem struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	MeetingID       uint      `gorm:"not null;index" json:"meeting_id"`
+	Position        int       `gorm:"not null;default:0" json:"position"`
+	Title           string    `gorm:"not null" json:"title"`
+	Description     *string   `gorm:"type:text" json:"description,omitempty"`
+	DurationMinutes int       `gorm:"not null;default:0" json:"duration_minutes"`
+	PresenterID     *uint     `gorm:"index" json:"presenter_id,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	Presenter *User `gorm:"foreignKey:PresenterID" json:"presenter,omitempty"`
+}
+
+// TableName テーブル名を指定
+func (MeetingAgendaItem) TableName() string {
+	return "meeting_agenda_items"
+}
+
+// RSVPStatus 会議への出欠回答
+type RSVPStatus string
+
+const (
+	RSVPStatusPending   RSVPStatus = "pending"
+	RSVPStatusAccepted  RSVPStatus = "accepted"
+	RSVPStatusTentative RSVPStatus = "tentative"
+	RSVPStatusDeclined  RSVPStatus = "declined"
+)
+
+// ValidRSVPStatus 回答として受け付ける値か（pending には戻せない）
+func ValidRSVPStatus(s RSVPStatus) bool {
+	return s == RSVPStatusAccepted || s == RSVPStatusTentative || s == RSVPStatusDeclined
+}
+
+// MeetingAttendee 会議の参加者。イベントスタッフから選ぶ
+type MeetingAttendee struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	MeetingID   uint       `gorm:"not null;index" json:"meeting_id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	RSVP        RSVPStatus `gorm:"column:rsvp;type:varchar(20);not null;default:'pending'" json:"rsvp"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName テーブル名を指定
+func (MeetingAttendee) TableName() string {
+	return "meeting_attendees"
+}
+
+// MeetingMinutesRevision 議事録の版。保存のたびに Revision を1つ増やして追加する
+type MeetingMinutesRevision struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	MeetingID uint      `gorm:"not null;index" json:"meeting_id"`
+	Revision  int       `gorm:"not null" json:"revision"`
+	Content   string    `gorm:"type:text;not null" json:"content"`
+	EditorID  *uint     `gorm:"index" json:"editor_id,omitempty"` // 導入前の議事録から移した版は nil
+	CreatedAt time.Time `json:"created_at"`
+
+	Editor *User `gorm:"foreignKey:EditorID" json:"editor,omitempty"`
+}
+
+// TableName テーブル名を指定
+func (MeetingMinutesRevision) TableName() string {
+	return "meeting_minutes_revisions"
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// MentionKind メンションの種類
+type MentionKind string
+
+const (
+	MentionKindUser    MentionKind = "user"    // @名前
+	MentionKindChannel MentionKind = "channel" // @channel チャンネルのメンバー全員
+	MentionKindHere    MentionKind = "here"    // @here 接続中のメンバー
+)
+
+// MessageMention メッセージでメンションされたユーザー。@channel / @here は展開して1人1件（投稿者は含めない）
+type MessageMention struct {
+	ID        uint        `gorm:"primaryKey" json:"id"`
+	MessageID uint        `gorm:"not null;uniqueIndex:idx_message_mentions_message_user" json:"message_id"`
+	ChannelID uint        `gorm:"not null" json:"channel_id"`
+	UserID    uint        `gorm:"not null;uniqueIndex:idx_message_mentions_message_user" json:"user_id"`
+	Kind      MentionKind `gorm:"type:varchar(16);not null" json:"kind"`
+	CreatedAt time.Time   `json:"created_at"`
+
+	// Relations
+	Message *Message `gorm:"foreignKey:MessageID" json:"message,omitempty"`
+}
+
+// TableName テーブル名を指定
+func (MessageMention) TableName() string {
+	return "message_mentions"
+}
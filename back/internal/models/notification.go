@@ -10,17 +10,22 @@ import (
 type NotificationType string
 
 const (
-	NotificationTypeEventInvite NotificationType = "event_invite"
+	NotificationTypeEventInvite     NotificationType = "event_invite"
+	NotificationTypeOrgInvite       NotificationType = "org_invite"
+	NotificationTypeOrgApproved     NotificationType = "org_approved"
+	NotificationTypeWaitlistOffer   NotificationType = "waitlist_offer"
+	NotificationTypeWaitlistExpired NotificationType = "waitlist_expired"
+	NotificationTypeMention         NotificationType = "mention"
 )
 
 // Notification 通知
 type Notification struct {
 	ID         uint             `gorm:"primaryKey" json:"id"`
 	UserID     uint             `gorm:"not null;index" json:"user_id"`
-	Type       NotificationType  `gorm:"type:varchar(32);not null" json:"type"`
+	Type       NotificationType `gorm:"type:varchar(32);not null" json:"type"`
 	Title      string           `gorm:"not null" json:"title"`
 	Body       string           `json:"body"`
-	RelatedID  uint             `json:"related_id"`  // invitation_id etc.
+	RelatedID  uint             `json:"related_id"` // invitation_id etc.
 	RelatedTyp string           `gorm:"size:32" json:"related_type"`
 	ReadAt     *time.Time       `json:"read_at,omitempty"`
 	CreatedAt  time.Time        `json:"created_at"`
@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// OrderStatus 注文ステータス
+type OrderStatus string
+
+const (
+	OrderStatusPending    OrderStatus = "pending"    // 決済画面を作成済み、支払い待ち
+	OrderStatusProcessing OrderStatus = "processing" // 決済画面での手続きは済んだが入金待ち（コンビニ払いなど）
+	OrderStatusPaid       OrderStatus = "paid"       // 支払い済み
+	OrderStatusExpired    OrderStatus = "expired"    // 支払われないまま決済画面の期限が切れた
+	OrderStatusFailed     OrderStatus = "failed"     // 決済画面を作成できなかった・入金されなかった
+	OrderStatusRefunded   OrderStatus = "refunded"   // 返金済み
+	// OrderStatusRefundPending 支払われたが席を割り当てない（登録が取り消し済み・別の注文で支払い済み）ので返金する。売上には計上しない
+	OrderStatusRefundPending OrderStatus = "refund_pending"
+)
+
+// Order 有料チケットの注文。参加登録1件ごとに、決済をやり直すたびに1件ずつ作る。
+// 金額の記録なので論理削除はしない
+type Order struct {
+	ID                 uint        `gorm:"primaryKey" json:"id"`
+	EventID            uint        `gorm:"not null;index" json:"event_id"`
+	TicketID           uint        `gorm:"not null;index" json:"ticket_id"`
+	EventParticipantID uint        `gorm:"not null;index" json:"event_participant_id"`
+	UserID             uint        `gorm:"not null;index" json:"user_id"`
+	Amount             int         `gorm:"not null" json:"amount"`
+	Currency           string      `gorm:"type:varchar(3);not null;default:'jpy'" json:"currency"`
+	Status             OrderStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	Provider           string      `gorm:"type:varchar(32);not null" json:"provider"`
+	CheckoutID         string      `gorm:"index" json:"checkout_id,omitempty"`
+	CheckoutURL        string      `json:"checkout_url,omitempty"`
+	PaymentID          string      `gorm:"index" json:"payment_id,omitempty"`
+	ExpiresAt          *time.Time  `json:"expires_at,omitempty"`
+	PaidAt             *time.Time  `json:"paid_at,omitempty"`
+	RefundedAmount     int         `gorm:"not null;default:0" json:"refunded_amount"` // 返金済みの累計額（一部返金では参加登録を取り消さない）
+	RefundedAt         *time.Time  `json:"refunded_at,omitempty"`
+	CreatedAt          time.Time   `json:"created_at"`
+	UpdatedAt          time.Time   `json:"updated_at"`
+
+	// Relations
+	Ticket Ticket `gorm:"foreignKey:TicketID" json:"ticket,omitempty"`
+	User   User   `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName テーブル名を指定
+func (Order) TableName() string {
+	return "orders"
+}
+
+// PaymentWebhookEvent 処理済みの Webhook。同じイベントが再送されても二重に処理しないために記録する
+type PaymentWebhookEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Provider  string    `gorm:"type:varchar(32);not null;uniqueIndex:idx_payment_webhook_events_provider_event" json:"provider"`
+	EventID   string    `gorm:"not null;uniqueIndex:idx_payment_webhook_events_provider_event" json:"event_id"`
+	Type      string    `gorm:"type:varchar(64);not null" json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName テーブル名を指定
+func (PaymentWebhookEvent) TableName() string {
+	return "payment_webhook_events"
+}
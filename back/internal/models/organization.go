@@ -25,12 +25,31 @@ func (Organization) TableName() string {
 	return "organizations"
 }
 
+// 組織内ロール
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+// 組織メンバーの状態。pending はドメイン不一致などで管理者の承認待ち
+const (
+	MemberStatusActive  = "active"
+	MemberStatusPending = "pending"
+)
+
+// ValidOrgRole 定義済みの組織ロールか
+func ValidOrgRole(role string) bool {
+	return role == OrgRoleOwner || role == OrgRoleAdmin || role == OrgRoleMember
+}
+
 // OrganizationMember 組織メンバーモデル
 type OrganizationMember struct {
 	ID             uint           `gorm:"primaryKey" json:"id"`
 	UserID         uint           `gorm:"not null;index" json:"user_id"`
 	OrganizationID uint           `gorm:"not null;index" json:"organization_id"`
-	Role           string         `gorm:"not null" json:"role"` // admin, member, etc.
+	Role           string         `gorm:"not null" json:"role"` // owner, admin, member
+	Status         string         `gorm:"type:varchar(20);not null;default:'active'" json:"status"`
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
@@ -44,3 +63,44 @@ type OrganizationMember struct {
 func (OrganizationMember) TableName() string {
 	return "organization_members"
 }
+
+// OrganizationInvitation 組織への招待
+type OrganizationInvitation struct {
+	ID             uint             `gorm:"primaryKey" json:"id"`
+	OrganizationID uint             `gorm:"not null;index" json:"organization_id"`
+	InviterID      uint             `gorm:"not null;index" json:"inviter_id"`
+	UserID         uint             `gorm:"not null;index" json:"user_id"` // 招待相手
+	Role           string           `gorm:"not null" json:"role"`
+	Status         InvitationStatus `gorm:"type:varchar(20);default:'pending'" json:"status"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt   `gorm:"index" json:"-"`
+
+	Organization Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+	Inviter      User         `gorm:"foreignKey:InviterID" json:"inviter,omitempty"`
+	User         User         `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName テーブル名を指定
+func (OrganizationInvitation) TableName() string {
+	return "organization_invitations"
+}
+
+// OrganizationDomain 組織が所有を証明したメールドメイン。確認済みドメインのユーザーは初回ログイン時に自動で参加する
+type OrganizationDomain struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	OrganizationID    uint       `gorm:"not null;index" json:"organization_id"`
+	Domain            string     `gorm:"size:253;not null;index" json:"domain"`
+	DefaultRole       string     `gorm:"not null;default:'member'" json:"default_role"`
+	VerificationToken string     `gorm:"size:64;not null" json:"verification_token"`
+	VerifiedAt        *time.Time `json:"verified_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	Organization Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+}
+
+// TableName テーブル名を指定
+func (OrganizationDomain) TableName() string {
+	return "organization_domains"
+}
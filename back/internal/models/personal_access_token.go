@@ -0,0 +1,41 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// PersonalAccessTokenPrefix トークン文字列の接頭辞。JWT と区別するのに使う
+const PersonalAccessTokenPrefix = "sherpa_pat_"
+
+// PersonalAccessToken スクリプト・外部連携用のユーザー所有 API トークン（ハッシュのみ保存）
+type PersonalAccessToken struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	Name        string     `gorm:"size:100;not null" json:"name"`
+	TokenPrefix string     `gorm:"size:32;not null" json:"token_prefix"` // 一覧で見分けるための先頭数文字
+	TokenHash   string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Scopes      string     `gorm:"not null;default:''" json:"-"` // スペース区切り
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName テーブル名を指定
+func (PersonalAccessToken) TableName() string {
+	return "personal_access_tokens"
+}
+
+// ScopeList スコープを配列で返す
+func (t *PersonalAccessToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
+
+// IsActive 失効・期限切れでないか
+func (t *PersonalAccessToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
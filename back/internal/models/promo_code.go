@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DiscountType 割引の種類
+type DiscountType string
+
+const (
+	DiscountTypePercent DiscountType = "percent" // DiscountValue % 引き（1〜100）
+	DiscountTypeFixed   DiscountType = "fixed"   // DiscountValue 円引き
+)
+
+// PromoCode 割引コード。TicketID が nil ならイベントのすべてのチケット種別に使える
+type PromoCode struct {
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	EventID         uint           `gorm:"not null;index" json:"event_id"`
+	TicketID        *uint          `gorm:"index" json:"ticket_id,omitempty"`
+	Code            string         `gorm:"type:varchar(32);not null" json:"code"` // 大文字で保存する
+	Description     string         `json:"description,omitempty"`
+	DiscountType    DiscountType   `gorm:"type:varchar(20);not null" json:"discount_type"`
+	DiscountValue   int            `gorm:"not null" json:"discount_value"`
+	MaxRedemptions  *int           `json:"max_redemptions,omitempty"` // 全体の利用回数の上限。nil なら無制限
+	PerUserLimit    *int           `json:"per_user_limit,omitempty"`  // 1人あたりの利用回数の上限。nil なら無制限
+	RedemptionCount int            `gorm:"not null;default:0" json:"redemption_count"`
+	ValidFrom       *time.Time     `json:"valid_from,omitempty"`
+	ValidUntil      *time.Time     `json:"valid_until,omitempty"`
+	CreatedBy       uint           `gorm:"not null" json:"created_by"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Relations
+	Ticket *Ticket `gorm:"foreignKey:TicketID" json:"ticket,omitempty"`
+}
+
+// TableName テーブル名を指定
+func (PromoCode) TableName() string {
+	return "promo_codes"
+}
+
+// ValidAt at の時点で有効期間内か
+func (c *PromoCode) ValidAt(at time.Time) bool {
+	if c.ValidFrom != nil && at.Before(*c.ValidFrom) {
+		return false
+	}
+	if c.ValidUntil != nil && !at.Before(*c.ValidUntil) {
+		return false
+	}
+	return true
+}
+
+// AppliesTo チケット種別 t に使えるか
+func (c *PromoCode) AppliesTo(t *Ticket) bool {
+	return c.EventID == t.EventID && (c.TicketID == nil || *c.TicketID == t.ID)
+}
+
+// DiscountFor 価格 price からの割引額。価格を超えない。% 引きの端数は切り捨て
+func (c *PromoCode) DiscountFor(price int) int {
+	d := c.DiscountValue
+	if c.DiscountType == DiscountTypePercent {
+		d = price * c.DiscountValue / 100
+	}
+	return min(max(d, 0), price)
+}
+
+// Exhausted 全体の利用回数の上限に達しているか
+func (c *PromoCode) Exhausted() bool {
+	return c.MaxRedemptions != nil && c.RedemptionCount >= *c.MaxRedemptions
+}
+
+// PromoCodeRedemption 割引コードの利用記録。席を確保した参加登録ごとに1件（キャンセル待ちは繰り上がって確定したときに作る）。
+// 登録を取り消しても利用回数は戻さない
+type PromoCodeRedemption struct {
+	ID                 uint      `gorm:"primaryKey" json:"id"`
+	PromoCodeID        uint      `gorm:"not null;index" json:"promo_code_id"`
+	EventParticipantID uint      `gorm:"not null;index" json:"event_participant_id"`
+	TicketID           uint      `gorm:"not null" json:"ticket_id"`
+	UserID             uint      `gorm:"not null;index" json:"user_id"`
+	Price              int       `gorm:"not null" json:"price"`    // 割引前の価格
+	Discount           int       `gorm:"not null" json:"discount"` // 割引額
+	CreatedAt          time.Time `json:"created_at"`
+
+	// Relations
+	Ticket           Ticket           `gorm:"foreignKey:TicketID" json:"ticket,omitempty"`
+	User             User             `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	EventParticipant EventParticipant `gorm:"foreignKey:EventParticipantID" json:"event_participant,omitempty"`
+}
+
+// TableName テーブル名を指定
+func (PromoCodeRedemption) TableName() string {
+	return "promo_code_redemptions"
+}
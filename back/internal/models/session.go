@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Session ログインセッション。リフレッシュトークン1系列につき1行で、ローテーションのたびにハッシュを差し替える。
+type Session struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	UserID            uint       `gorm:"not null;index" json:"user_id"`
+	RefreshTokenHash  string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	PreviousTokenHash *string    `gorm:"size:64;index" json:"-"` // 直前のリフレッシュトークン（再利用検知用）
+	UserAgent         string     `json:"user_agent"`
+	IPAddress         string     `gorm:"size:64" json:"ip_address"`
+	ExpiresAt         time.Time  `gorm:"not null" json:"expires_at"`
+	LastUsedAt        time.Time  `json:"last_used_at"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName テーブル名を指定
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// IsActive 失効・期限切れでないか
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
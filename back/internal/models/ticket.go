@@ -8,17 +8,34 @@ import (
 
 // Ticket チケットモデル
 type Ticket struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	EventID   uint           `gorm:"not null;index" json:"event_id"`
-	Name      string         `gorm:"not null" json:"name"`
-	Price     int            `gorm:"not null;default:0" json:"price"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	EventID      uint           `gorm:"not null;index" json:"event_id"`
+	Name         string         `gorm:"not null" json:"name"`
+	Price        int            `gorm:"not null;default:0" json:"price"`
+	Quantity     *int           `json:"quantity,omitempty"` // 販売枚数の上限。nil なら無制限
+	SalesStartAt *time.Time     `json:"sales_start_at,omitempty"`
+	SalesEndAt   *time.Time     `json:"sales_end_at,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Relations
-	Event             Event             `gorm:"foreignKey:EventID" json:"event,omitempty"`
+	Event             Event              `gorm:"foreignKey:EventID" json:"event,omitempty"`
 	EventParticipants []EventParticipant `gorm:"foreignKey:TicketID" json:"event_participants,omitempty"`
+
+	// Sold 保留中・確定済みの参加登録数（リポジトリが一覧時に数える）
+	Sold int64 `gorm:"-" json:"sold"`
+}
+
+// OnSale at の時点で販売期間内か
+func (t *Ticket) OnSale(at time.Time) bool {
+	if t.SalesStartAt != nil && at.Before(*t.SalesStartAt) {
+		return false
+	}
+	if t.SalesEndAt != nil && !at.Before(*t.SalesEndAt) {
+		return false
+	}
+	return true
 }
 
 // TableName テーブル名を指定
@@ -30,26 +47,63 @@ func (Ticket) TableName() string {
 type ParticipantStatus string
 
 const (
-	ParticipantStatusPending  ParticipantStatus = "pending"
-	ParticipantStatusConfirmed ParticipantStatus = "confirmed"
-	ParticipantStatusCancelled ParticipantStatus = "cancelled"
+	ParticipantStatusPending    ParticipantStatus = "pending"
+	ParticipantStatusConfirmed  ParticipantStatus = "confirmed"
+	ParticipantStatusCancelled  ParticipantStatus = "cancelled"
+	ParticipantStatusWaitlisted ParticipantStatus = "waitlisted" // 売り切れ時のキャンセル待ち
+	ParticipantStatusOffered    ParticipantStatus = "offered"    // キャンセル待ちから繰り上がり、期限までの確定待ち
 )
 
+// ActiveParticipantStatuses 席を確保している（定員に数える）ステータス
+var ActiveParticipantStatuses = []ParticipantStatus{ParticipantStatusPending, ParticipantStatusConfirmed, ParticipantStatusOffered}
+
+// OpenParticipantStatuses 取り消されていない（キャンセル待ちを含む）ステータス
+var OpenParticipantStatuses = []ParticipantStatus{ParticipantStatusPending, ParticipantStatusConfirmed, ParticipantStatusOffered, ParticipantStatusWaitlisted}
+
+// CanTransitionTo ステータスを to に変更できるか。
+// waitlisted → offered / cancelled、offered → pending / confirmed / cancelled、pending → confirmed / cancelled、confirmed → cancelled のみ
+func (s ParticipantStatus) CanTransitionTo(to ParticipantStatus) bool {
+	switch s {
+	case ParticipantStatusWaitlisted:
+		return to == ParticipantStatusOffered || to == ParticipantStatusCancelled
+	case ParticipantStatusOffered:
+		return to == ParticipantStatusPending || to == ParticipantStatusConfirmed || to == ParticipantStatusCancelled
+	case ParticipantStatusPending:
+		return to == ParticipantStatusConfirmed || to == ParticipantStatusCancelled
+	case ParticipantStatusConfirmed:
+		return to == ParticipantStatusCancelled
+	}
+	return false
+}
+
 // EventParticipant イベント参加者モデル
 type EventParticipant struct {
-	ID        uint             `gorm:"primaryKey" json:"id"`
-	TicketID  uint             `gorm:"not null;index" json:"ticket_id"`
-	UserID    uint             `gorm:"not null;index" json:"user_id"`
-	Status    ParticipantStatus `gorm:"type:varchar(20);default:'pending'" json:"status"`
-	CreatedAt time.Time        `json:"created_at"`
-	UpdatedAt time.Time        `json:"updated_at"`
-	DeletedAt gorm.DeletedAt   `gorm:"index" json:"-"`
+	ID             uint              `gorm:"primaryKey" json:"id"`
+	TicketID       uint              `gorm:"not null;index" json:"ticket_id"`
+	UserID         uint              `gorm:"not null;index" json:"user_id"`
+	Status         ParticipantStatus `gorm:"type:varchar(20);default:'pending'" json:"status"`
+	ConfirmedAt    *time.Time        `json:"confirmed_at,omitempty"`
+	CancelledAt    *time.Time        `json:"cancelled_at,omitempty"`
+	WaitlistedAt   *time.Time        `json:"waitlisted_at,omitempty"`    // キャンセル待ちの順番（古い順に繰り上げる）
+	OfferExpiresAt *time.Time        `json:"offer_expires_at,omitempty"` // 繰り上げの確定期限
+	CheckedInAt    *time.Time        `json:"checked_in_at,omitempty"`    // 当日の受付時刻
+	CheckedInBy    *uint             `json:"checked_in_by,omitempty"`    // 受付したスタッフ
+	PromoCodeID    *uint             `json:"promo_code_id,omitempty"`    // 登録時に使った割引コード
+	Discount       int               `gorm:"not null;default:0" json:"discount"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt    `gorm:"index" json:"-"`
 
 	// Relations
 	Ticket Ticket `gorm:"foreignKey:TicketID" json:"ticket,omitempty"`
 	User   User   `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
 
+// AmountDue 支払う金額（チケット価格から割引を引いたもの）。Ticket を読み込んでおくこと
+func (p *EventParticipant) AmountDue() int {
+	return max(p.Ticket.Price-p.Discount, 0)
+}
+
 // TableName テーブル名を指定
 func (EventParticipant) TableName() string {
 	return "event_participants"
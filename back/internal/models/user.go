@@ -8,13 +8,16 @@ import (
 
 // User ユーザーモデル
 type User struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Name      string         `gorm:"not null" json:"name"`
-	Email     string         `gorm:"uniqueIndex;not null" json:"email"`
-	AvatarURL *string        `json:"avatar_url,omitempty"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                   uint           `gorm:"primaryKey" json:"id"`
+	Name                 string         `gorm:"not null" json:"name"`
+	Email                string         `gorm:"uniqueIndex;not null" json:"email"`
+	AvatarURL            *string        `json:"avatar_url,omitempty"`
+	PasswordHash         *string        `json:"-"` // 未設定なら OAuth / マジックリンクのみ
+	EmailVerifiedAt      *time.Time     `json:"email_verified_at,omitempty"`
+	ActiveOrganizationID *uint          `gorm:"index" json:"active_organization_id,omitempty"` // イベント一覧・作成の既定の組織
+	CreatedAt            time.Time      `json:"created_at"`
+	UpdatedAt            time.Time      `json:"updated_at"`
+	DeletedAt            gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Relations
 	OrganizationMembers []OrganizationMember `gorm:"foreignKey:UserID" json:"organization_members,omitempty"`
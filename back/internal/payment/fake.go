@@ -0,0 +1,189 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// fakeSignatureHeader FakeProvider の Webhook 署名ヘッダ
+const fakeSignatureHeader = "X-Fake-Signature"
+
+// FakeCheckout FakeProvider が作成した決済画面
+type FakeCheckout struct {
+	Checkout
+	Request   CheckoutRequest
+	PaymentID string // 支払い済みなら設定される
+	Refunded  bool
+}
+
+// FakeProvider 実際には課金しない Provider。ローカル開発・テスト用。
+// Complete / Process / Fail / Expire / RefundEvent で、本物のプロバイダが送ってくるのと同じ形の署名付き Webhook を作れる
+type FakeProvider struct {
+	secret []byte
+
+	mu        sync.Mutex
+	seq       int
+	checkouts map[string]*FakeCheckout
+	refunds   []RefundRequest
+}
+
+// NewFakeProvider は FakeProvider を生成する。secret が空なら固定値で署名する
+func NewFakeProvider(secret string) *FakeProvider {
+	if secret == "" {
+		secret = "fake-payment-secret"
+	}
+	return &FakeProvider{secret: []byte(secret), checkouts: map[string]*FakeCheckout{}}
+}
+
+// Name プロバイダ識別子
+func (f *FakeProvider) Name() string { return "fake" }
+
+// CreateCheckout 決済画面を記録する。URL は POST で支払いを完了できる開発用エンドポイント
+func (f *FakeProvider) CreateCheckout(_ context.Context, req CheckoutRequest) (*Checkout, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	id := fmt.Sprintf("fake_cs_%d", f.seq)
+	expires := time.Now().Add(time.Hour)
+	c := &FakeCheckout{
+		Checkout: Checkout{ID: id, URL: "/api/payments/fake/checkouts/" + id + "/complete", ExpiresAt: &expires},
+		Request:  req,
+	}
+	f.checkouts[id] = c
+	out := c.Checkout
+	return &out, nil
+}
+
+// Refund 返金を記録する
+func (f *FakeProvider) Refund(_ context.Context, req RefundRequest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.checkouts {
+		if c.PaymentID != "" && c.PaymentID == req.PaymentID {
+			c.Refunded = true
+			f.refunds = append(f.refunds, req)
+			return nil
+		}
+	}
+	return fmt.Errorf("fake: unknown payment %q", req.PaymentID)
+}
+
+// Refunds 記録済みの返金のコピー
+func (f *FakeProvider) Refunds() []RefundRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]RefundRequest(nil), f.refunds...)
+}
+
+// Checkout 作成済みの決済画面
+func (f *FakeProvider) Checkout(id string) (FakeCheckout, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.checkouts[id]
+	if !ok {
+		return FakeCheckout{}, false
+	}
+	return *c, true
+}
+
+// fakeEvent Webhook の本文
+type fakeEvent struct {
+	ID         string    `json:"id"`
+	Type       EventType `json:"type"`
+	CheckoutID string    `json:"checkout_id,omitempty"`
+	PaymentID  string    `json:"payment_id,omitempty"`
+	Amount     int       `json:"amount,omitempty"`
+}
+
+// Complete 決済画面を支払い済みにし、checkout.completed の Webhook（本文とヘッダ）を返す。
+// 同じ決済画面で何度呼んでも同じイベントIDになるので、重複配信の確認にも使える
+func (f *FakeProvider) Complete(checkoutID string) ([]byte, http.Header, error) {
+	f.mu.Lock()
+	c, ok := f.checkouts[checkoutID]
+	if ok && c.PaymentID == "" {
+		c.PaymentID = "fake_pi_" + checkoutID
+	}
+	f.mu.Unlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("fake: unknown checkout %q", checkoutID)
+	}
+	return f.sign(fakeEvent{
+		ID:         "evt_completed_" + checkoutID,
+		Type:       EventCheckoutCompleted,
+		CheckoutID: checkoutID,
+		PaymentID:  c.PaymentID,
+		Amount:     c.Request.Amount,
+	})
+}
+
+// Process 決済画面を入金待ちにし、checkout.processing の Webhook を返す（コンビニ払いなど）。入金は Complete、入金されなければ Fail
+func (f *FakeProvider) Process(checkoutID string) ([]byte, http.Header, error) {
+	f.mu.Lock()
+	c, ok := f.checkouts[checkoutID]
+	if ok && c.PaymentID == "" {
+		c.PaymentID = "fake_pi_" + checkoutID
+	}
+	f.mu.Unlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("fake: unknown checkout %q", checkoutID)
+	}
+	return f.sign(fakeEvent{ID: "evt_processing_" + checkoutID, Type: EventCheckoutProcessing, CheckoutID: checkoutID, PaymentID: c.PaymentID, Amount: c.Request.Amount})
+}
+
+// Fail 入金待ちの決済画面が入金されなかったときの checkout.failed の Webhook を返す
+func (f *FakeProvider) Fail(checkoutID string) ([]byte, http.Header, error) {
+	if _, ok := f.Checkout(checkoutID); !ok {
+		return nil, nil, fmt.Errorf("fake: unknown checkout %q", checkoutID)
+	}
+	return f.sign(fakeEvent{ID: "evt_failed_" + checkoutID, Type: EventCheckoutFailed, CheckoutID: checkoutID})
+}
+
+// Expire checkout.expired の Webhook を返す
+func (f *FakeProvider) Expire(checkoutID string) ([]byte, http.Header, error) {
+	if _, ok := f.Checkout(checkoutID); !ok {
+		return nil, nil, fmt.Errorf("fake: unknown checkout %q", checkoutID)
+	}
+	return f.sign(fakeEvent{ID: "evt_expired_" + checkoutID, Type: EventCheckoutExpired, CheckoutID: checkoutID})
+}
+
+// RefundEvent プロバイダ側の管理画面から返金したときの Webhook を返す。amount は返金済みの累計額
+func (f *FakeProvider) RefundEvent(paymentID string, amount int) ([]byte, http.Header, error) {
+	return f.sign(fakeEvent{ID: fmt.Sprintf("evt_refunded_%s_%d", paymentID, amount), Type: EventRefunded, PaymentID: paymentID, Amount: amount})
+}
+
+func (f *FakeProvider) sign(e fakeEvent) ([]byte, http.Header, error) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return nil, nil, err
+	}
+	mac := hmac.New(sha256.New, f.secret)
+	mac.Write(payload)
+	header := http.Header{}
+	header.Set(fakeSignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	return payload, header, nil
+}
+
+// ParseWebhook X-Fake-Signature（本文の HMAC-SHA256）を検証してイベントを読み取る
+func (f *FakeProvider) ParseWebhook(payload []byte, header http.Header) (*WebhookEvent, error) {
+	got, err := hex.DecodeString(header.Get(fakeSignatureHeader))
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	mac := hmac.New(sha256.New, f.secret)
+	mac.Write(payload)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return nil, ErrInvalidSignature
+	}
+	var e fakeEvent
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	return &WebhookEvent{ID: e.ID, Type: e.Type, CheckoutID: e.CheckoutID, PaymentID: e.PaymentID, Amount: e.Amount}, nil
+}
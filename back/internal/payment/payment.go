@@ -0,0 +1,109 @@
+// Package payment 有料チケットの決済。Stripe 互換の本番用プロバイダとローカル用のフェイクを差し替えられる。
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSignature Webhook の署名が検証できない
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// CheckoutRequest 決済画面の作成に必要な情報
+type CheckoutRequest struct {
+	OrderID     uint
+	Amount      int // 円
+	Currency    string
+	Description string // 決済画面に出す商品名
+	Email       string
+	SuccessURL  string
+	CancelURL   string
+}
+
+// Checkout 作成した決済画面
+type Checkout struct {
+	ID        string
+	URL       string
+	ExpiresAt *time.Time
+}
+
+// EventType Webhook のイベント種別（プロバイダ固有の種別をこのどれかに読み替える）
+type EventType string
+
+const (
+	EventCheckoutCompleted  EventType = "checkout.completed"  // 支払われた（入金待ちだった支払いの入金を含む）
+	EventCheckoutProcessing EventType = "checkout.processing" // 決済画面での手続きは済んだが入金待ち
+	EventCheckoutFailed     EventType = "checkout.failed"     // 入金待ちの支払いが入金されなかった
+	EventCheckoutExpired    EventType = "checkout.expired"
+	EventRefunded           EventType = "refunded"
+	EventIgnored            EventType = "ignored" // 扱わない種別。受信済みとして記録だけする
+)
+
+// WebhookEvent 検証済みの Webhook
+type WebhookEvent struct {
+	ID         string // プロバイダが振るイベントID。重複配信の判定に使う
+	Type       EventType
+	CheckoutID string
+	PaymentID  string
+	Amount     int // EventRefunded では返金済みの累計額（一部返金なら支払額より少ない）
+}
+
+// RefundRequest 返金
+type RefundRequest struct {
+	OrderID   uint // 冪等キーに使う
+	PaymentID string
+	Amount    int
+}
+
+// Provider 決済プロバイダ
+type Provider interface {
+	Name() string
+	CreateCheckout(ctx context.Context, req CheckoutRequest) (*Checkout, error)
+	// ParseWebhook 署名を検証してイベントを読み取る。検証できなければ ErrInvalidSignature
+	ParseWebhook(payload []byte, header http.Header) (*WebhookEvent, error)
+	Refund(ctx context.Context, req RefundRequest) error
+}
+
+// DefaultProvider は main で設定する。チケットの決済・Webhook・返金に利用する
+var DefaultProvider Provider
+
+// NewProviderFromEnv PAYMENT_PROVIDER（stripe / fake）に応じた Provider を生成する。
+// 未設定時は STRIPE_SECRET_KEY があれば stripe、なければ fake。fake は ENV=production では使えない。
+// ENV=production でどちらも設定されていなければ nil を返す（無料チケットだけで運用でき、有料チケットの決済は 503 になる）
+func NewProviderFromEnv() (Provider, error) {
+	driver := strings.ToLower(os.Getenv("PAYMENT_PROVIDER"))
+	if driver == "" {
+		switch {
+		case os.Getenv("STRIPE_SECRET_KEY") != "":
+			driver = "stripe"
+		case os.Getenv("ENV") == "production":
+			log.Printf("[payment] no provider configured (paid checkout is disabled)")
+			return nil, nil
+		default:
+			driver = "fake"
+		}
+	}
+
+	switch driver {
+	case "stripe":
+		return NewStripeProvider(StripeConfig{
+			SecretKey:     os.Getenv("STRIPE_SECRET_KEY"),
+			WebhookSecret: os.Getenv("STRIPE_WEBHOOK_SECRET"),
+			BaseURL:       os.Getenv("STRIPE_API_BASE"),
+		})
+	case "fake":
+		if os.Getenv("ENV") == "production" {
+			return nil, fmt.Errorf("fake payment provider cannot be used in production")
+		}
+		log.Printf("[payment] using fake provider (payments are not collected)")
+		return NewFakeProvider(os.Getenv("FAKE_PAYMENT_SECRET")), nil
+	default:
+		return nil, fmt.Errorf("unknown PAYMENT_PROVIDER: %s", driver)
+	}
+}
@@ -0,0 +1,43 @@
+package payment
+
+import "testing"
+
+func TestNewProviderFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    string // 空なら nil
+		wantErr bool
+	}{
+		{name: "default is fake", want: "fake"},
+		{name: "stripe key", env: map[string]string{"STRIPE_SECRET_KEY": "sk", "STRIPE_WEBHOOK_SECRET": "whsec"}, want: "stripe"},
+		{name: "production without provider", env: map[string]string{"ENV": "production"}},
+		{name: "production stripe", env: map[string]string{"ENV": "production", "STRIPE_SECRET_KEY": "sk", "STRIPE_WEBHOOK_SECRET": "whsec"}, want: "stripe"},
+		{name: "production explicit fake", env: map[string]string{"ENV": "production", "PAYMENT_PROVIDER": "fake"}, wantErr: true},
+		{name: "stripe without webhook secret", env: map[string]string{"PAYMENT_PROVIDER": "stripe", "STRIPE_SECRET_KEY": "sk"}, wantErr: true},
+		{name: "unknown", env: map[string]string{"PAYMENT_PROVIDER": "paypal"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range []string{"ENV", "PAYMENT_PROVIDER", "STRIPE_SECRET_KEY", "STRIPE_WEBHOOK_SECRET", "STRIPE_API_BASE", "FAKE_PAYMENT_SECRET"} {
+				t.Setenv(k, tt.env[k])
+			}
+			p, err := NewProviderFromEnv()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("provider = %v, want error", p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch {
+			case tt.want == "" && p != nil:
+				t.Errorf("provider = %s, want nil", p.Name())
+			case tt.want != "" && (p == nil || p.Name() != tt.want):
+				t.Errorf("provider = %v, want %s", p, tt.want)
+			}
+		})
+	}
+}
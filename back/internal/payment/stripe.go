@@ -0,0 +1,203 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stripeSignatureTolerance Stripe-Signature のタイムスタンプの許容範囲（リプレイ対策）
+const stripeSignatureTolerance = 5 * time.Minute
+
+// StripeConfig Stripe API の設定
+type StripeConfig struct {
+	SecretKey     string
+	WebhookSecret string
+	BaseURL       string // 省略時は https://api.stripe.com
+}
+
+// StripeProvider Stripe Checkout を使う本番用 Provider（公式 SDK は使わず REST API を直接呼ぶ）
+type StripeProvider struct {
+	cfg    StripeConfig
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewStripeProvider は StripeProvider を生成する。SecretKey・WebhookSecret は必須
+func NewStripeProvider(cfg StripeConfig) (*StripeProvider, error) {
+	if cfg.SecretKey == "" || cfg.WebhookSecret == "" {
+		return nil, fmt.Errorf("STRIPE_SECRET_KEY and STRIPE_WEBHOOK_SECRET are required for stripe provider")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://api.stripe.com"
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	return &StripeProvider{cfg: cfg, client: &http.Client{Timeout: 15 * time.Second}, now: time.Now}, nil
+}
+
+// Name プロバイダ識別子
+func (p *StripeProvider) Name() string { return "stripe" }
+
+// CreateCheckout Checkout Session を作成する
+func (p *StripeProvider) CreateCheckout(ctx context.Context, req CheckoutRequest) (*Checkout, error) {
+	orderID := strconv.FormatUint(uint64(req.OrderID), 10)
+	form := url.Values{}
+	form.Set("mode", "payment")
+	form.Set("success_url", req.SuccessURL)
+	form.Set("cancel_url", req.CancelURL)
+	form.Set("client_reference_id", orderID)
+	form.Set("metadata[order_id]", orderID)
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("line_items[0][price_data][currency]", req.Currency)
+	form.Set("line_items[0][price_data][unit_amount]", strconv.Itoa(req.Amount))
+	form.Set("line_items[0][price_data][product_data][name]", req.Description)
+	if req.Email != "" {
+		form.Set("customer_email", req.Email)
+	}
+
+	var res struct {
+		ID        string `json:"id"`
+		URL       string `json:"url"`
+		ExpiresAt int64  `json:"expires_at"`
+	}
+	if err := p.post(ctx, "/v1/checkout/sessions", form, "checkout-"+orderID, &res); err != nil {
+		return nil, err
+	}
+	c := &Checkout{ID: res.ID, URL: res.URL}
+	if res.ExpiresAt > 0 {
+		t := time.Unix(res.ExpiresAt, 0)
+		c.ExpiresAt = &t
+	}
+	return c, nil
+}
+
+// Refund 支払い（PaymentIntent）を返金する
+func (p *StripeProvider) Refund(ctx context.Context, req RefundRequest) error {
+	form := url.Values{}
+	form.Set("payment_intent", req.PaymentID)
+	if req.Amount > 0 {
+		form.Set("amount", strconv.Itoa(req.Amount))
+	}
+	return p.post(ctx, "/v1/refunds", form, "refund-"+strconv.FormatUint(uint64(req.OrderID), 10), nil)
+}
+
+// post フォームで POST し、JSON の応答を out に読み込む。Idempotency-Key で同じ注文の二重作成を防ぐ
+func (p *StripeProvider) post(ctx context.Context, path string, form url.Values, idempotencyKey string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.BaseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.cfg.SecretKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Idempotency-Key", idempotencyKey)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(body, &e)
+		return fmt.Errorf("stripe %s: %d %s", path, resp.StatusCode, e.Error.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(body, out)
+}
+
+// ParseWebhook Stripe-Signature（t=タイムスタンプ,v1=HMAC-SHA256）を検証してイベントを読み取る
+func (p *StripeProvider) ParseWebhook(payload []byte, header http.Header) (*WebhookEvent, error) {
+	if !p.validSignature(payload, header.Get("Stripe-Signature")) {
+		return nil, ErrInvalidSignature
+	}
+
+	var e struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Object struct {
+				ID             string `json:"id"`
+				PaymentIntent  string `json:"payment_intent"`
+				PaymentStatus  string `json:"payment_status"`
+				AmountTotal    int    `json:"amount_total"`
+				AmountRefunded int    `json:"amount_refunded"`
+			} `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	obj := e.Data.Object
+	ev := &WebhookEvent{ID: e.ID, Type: EventIgnored}
+	switch e.Type {
+	case "checkout.session.completed":
+		// コンビニ払いなどは入金前に決済画面が完了する（payment_status: unpaid）。入金は async_payment_succeeded で届く
+		ev.Type, ev.CheckoutID, ev.PaymentID, ev.Amount = EventCheckoutProcessing, obj.ID, obj.PaymentIntent, obj.AmountTotal
+		if obj.PaymentStatus == "paid" {
+			ev.Type = EventCheckoutCompleted
+		}
+	case "checkout.session.async_payment_succeeded":
+		ev.Type, ev.CheckoutID, ev.PaymentID, ev.Amount = EventCheckoutCompleted, obj.ID, obj.PaymentIntent, obj.AmountTotal
+	case "checkout.session.async_payment_failed":
+		ev.Type, ev.CheckoutID, ev.PaymentID = EventCheckoutFailed, obj.ID, obj.PaymentIntent
+	case "checkout.session.expired":
+		ev.Type, ev.CheckoutID = EventCheckoutExpired, obj.ID
+	case "charge.refunded":
+		// amount_refunded はこの支払いの返金の累計額
+		ev.Type, ev.PaymentID, ev.Amount = EventRefunded, obj.PaymentIntent, obj.AmountRefunded
+	}
+	return ev, nil
+}
+
+func (p *StripeProvider) validSignature(payload []byte, header string) bool {
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return false
+	}
+	if d := p.now().Sub(time.Unix(sec, 0)); d > stripeSignatureTolerance || d < -stripeSignatureTolerance {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(p.cfg.WebhookSecret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+	for _, s := range sigs {
+		got, err := hex.DecodeString(s)
+		if err == nil && hmac.Equal(got, expected) {
+			return true
+		}
+	}
+	return false
+}
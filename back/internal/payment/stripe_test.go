@@ -0,0 +1,90 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// stripeWebhook Stripe と同じ形で署名した Webhook を作る
+func stripeWebhook(t *testing.T, secret string, at time.Time, payload string) http.Header {
+	t.Helper()
+	ts := fmt.Sprint(at.Unix())
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "." + payload))
+	header := http.Header{}
+	header.Set("Stripe-Signature", "t="+ts+",v1="+hex.EncodeToString(mac.Sum(nil)))
+	return header
+}
+
+func TestStripeParseWebhook(t *testing.T) {
+	now := time.Unix(1767225600, 0)
+	p, err := NewStripeProvider(StripeConfig{SecretKey: "sk_test", WebhookSecret: "whsec_test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.now = func() time.Time { return now }
+
+	session := func(typ, paymentStatus string) string {
+		return `{"id":"evt_1","type":"` + typ + `","data":{"object":{"id":"cs_1","payment_intent":"pi_1","payment_status":"` + paymentStatus + `","amount_total":3000}}}`
+	}
+	tests := []struct {
+		name    string
+		payload string
+		want    WebhookEvent
+	}{
+		{"card", session("checkout.session.completed", "paid"), WebhookEvent{ID: "evt_1", Type: EventCheckoutCompleted, CheckoutID: "cs_1", PaymentID: "pi_1", Amount: 3000}},
+		{"konbini before payment", session("checkout.session.completed", "unpaid"), WebhookEvent{ID: "evt_1", Type: EventCheckoutProcessing, CheckoutID: "cs_1", PaymentID: "pi_1", Amount: 3000}},
+		{"konbini paid", session("checkout.session.async_payment_succeeded", "paid"), WebhookEvent{ID: "evt_1", Type: EventCheckoutCompleted, CheckoutID: "cs_1", PaymentID: "pi_1", Amount: 3000}},
+		{"konbini not paid", session("checkout.session.async_payment_failed", "unpaid"), WebhookEvent{ID: "evt_1", Type: EventCheckoutFailed, CheckoutID: "cs_1", PaymentID: "pi_1"}},
+		{"expired", session("checkout.session.expired", "unpaid"), WebhookEvent{ID: "evt_1", Type: EventCheckoutExpired, CheckoutID: "cs_1"}},
+		{
+			"partial refund",
+			`{"id":"evt_2","type":"charge.refunded","data":{"object":{"id":"ch_1","payment_intent":"pi_1","amount":3000,"amount_refunded":1000}}}`,
+			WebhookEvent{ID: "evt_2", Type: EventRefunded, PaymentID: "pi_1", Amount: 1000},
+		},
+		{"ignored", `{"id":"evt_3","type":"customer.created","data":{"object":{"id":"cus_1"}}}`, WebhookEvent{ID: "evt_3", Type: EventIgnored}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev, err := p.ParseWebhook([]byte(tt.payload), stripeWebhook(t, "whsec_test", now, tt.payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *ev != tt.want {
+				t.Errorf("event = %+v, want %+v", *ev, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripeParseWebhookRejects(t *testing.T) {
+	now := time.Unix(1767225600, 0)
+	p, err := NewStripeProvider(StripeConfig{SecretKey: "sk_test", WebhookSecret: "whsec_test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.now = func() time.Time { return now }
+	payload := `{"id":"evt_1","type":"checkout.session.completed","data":{"object":{"id":"cs_1","payment_status":"paid"}}}`
+
+	tests := []struct {
+		name   string
+		header http.Header
+	}{
+		{"other secret", stripeWebhook(t, "whsec_other", now, payload)},
+		{"too old", stripeWebhook(t, "whsec_test", now.Add(-10*time.Minute), payload)},
+		{"missing", http.Header{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := p.ParseWebhook([]byte(payload), tt.header); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("err = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
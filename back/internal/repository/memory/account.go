@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+)
+
+type accountRepo struct{ s *Store }
+
+// byUser userID の行を ID 順（= 作成順）で返す
+func byUser[T any](m map[uint]T, userID uint, owner func(T) uint) []T {
+	return filter(m, func(v T) bool { return owner(v) == userID })
+}
+
+func (r *accountRepo) Export(userID uint) (*repository.AccountExport, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, ok := r.s.users[userID]
+	if !ok || u.DeletedAt.Valid {
+		return nil, repository.ErrNotFound
+	}
+	out := &repository.AccountExport{User: u}
+
+	out.OrganizationMembers = byUser(r.s.orgMembers, userID, func(m models.OrganizationMember) uint { return m.UserID })
+	for i := range out.OrganizationMembers {
+		out.OrganizationMembers[i].Organization = r.s.organizations[out.OrganizationMembers[i].OrganizationID]
+	}
+	out.EventStaffs = byUser(r.s.staffs, userID, func(s models.EventStaff) uint { return s.UserID })
+	for i := range out.EventStaffs {
+		out.EventStaffs[i].Event = r.s.events[out.EventStaffs[i].EventID]
+	}
+	out.Participants = byUser(r.s.participants, userID, func(p models.EventParticipant) uint { return p.UserID })
+	for i := range out.Participants {
+		out.Participants[i].Ticket = r.s.tickets[out.Participants[i].TicketID]
+	}
+	out.Orders = byUser(r.s.orders, userID, func(o models.Order) uint { return o.UserID })
+	out.Redemptions = byUser(r.s.redemptions, userID, func(rd models.PromoCodeRedemption) uint { return rd.UserID })
+	out.Messages = byUser(r.s.messages, userID, func(m models.Message) uint { return m.UserID })
+	out.Reactions = byUser(r.s.reactions, userID, func(rc models.MessageReaction) uint { return rc.UserID })
+	out.Tasks = filter(r.s.tasks, func(t models.Task) bool { return t.AssigneeID != nil && *t.AssigneeID == userID })
+	sort.SliceStable(out.Tasks, func(i, j int) bool { return out.Tasks[i].Deadline.Before(out.Tasks[j].Deadline) })
+	out.Attendances = byUser(r.s.attendees, userID, func(a models.MeetingAttendee) uint { return a.UserID })
+	out.MinutesRevisions = filter(r.s.revisions, func(rv models.MeetingMinutesRevision) bool {
+		return rv.EditorID != nil && *rv.EditorID == userID
+	})
+	out.Notifications = byUser(r.s.notifications, userID, func(n models.Notification) uint { return n.UserID })
+	out.EventInvitations = filter(r.s.invitations, func(inv models.EventInvitation) bool {
+		return inv.UserID == userID || inv.InviterID == userID
+	})
+	out.OrgInvitations = filter(r.s.orgInvitations, func(inv models.OrganizationInvitation) bool {
+		return inv.UserID == userID || inv.InviterID == userID
+	})
+	out.Sessions = byUser(r.s.sessions, userID, func(s models.Session) uint { return s.UserID })
+	out.PersonalTokens = byUser(r.s.personalTokens, userID, func(t models.PersonalAccessToken) uint { return t.UserID })
+	out.Identities = byUser(r.s.identities, userID, func(l models.UserIdentity) uint { return l.UserID })
+	return out, nil
+}
+
+// deleteWhere 条件に合う行を削除する
+func deleteWhere[T any](m map[uint]T, match func(T) bool) {
+	for id, v := range m {
+		if match(v) {
+			delete(m, id)
+		}
+	}
+}
+
+func (r *accountRepo) Purge(userID uint, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for id, t := range r.s.tasks {
+		if t.AssigneeID != nil && *t.AssigneeID == userID {
+			t.AssigneeID = nil
+			r.s.tasks[id] = t
+		}
+	}
+	deleteWhere(r.s.staffs, func(s models.EventStaff) bool { return s.UserID == userID })
+	deleteWhere(r.s.channelMembers, func(m models.ChannelMember) bool { return m.UserID == userID })
+	deleteWhere(r.s.orgMembers, func(m models.OrganizationMember) bool { return m.UserID == userID })
+	deleteWhere(r.s.reactions, func(rc models.MessageReaction) bool { return rc.UserID == userID })
+	deleteWhere(r.s.mentions, func(m models.MessageMention) bool { return m.UserID == userID })
+	deleteWhere(r.s.notifications, func(n models.Notification) bool { return n.UserID == userID })
+	deleteWhere(r.s.invitations, func(inv models.EventInvitation) bool {
+		return inv.UserID == userID && inv.Status == models.InvitationStatusPending
+	})
+	deleteWhere(r.s.orgInvitations, func(inv models.OrganizationInvitation) bool {
+		return inv.UserID == userID && inv.Status == models.InvitationStatusPending
+	})
+	deleteWhere(r.s.identities, func(l models.UserIdentity) bool { return l.UserID == userID })
+	deleteWhere(r.s.emailTokens, func(t models.EmailToken) bool { return t.UserID == userID })
+	revokeSessions(r.s, userID, 0, at)
+	for id, t := range r.s.personalTokens {
+		if t.UserID == userID && t.RevokedAt == nil {
+			t.RevokedAt = &at
+			r.s.personalTokens[id] = t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+)
+
+type budgetRepo struct{ s *Store }
+
+func (r *budgetRepo) Get(id uint) (*models.Budget, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return get(r.s.budgets, id)
+}
+
+func (r *budgetRepo) ListByEvent(eventID uint) ([]models.Budget, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return filter(r.s.budgets, func(b models.Budget) bool { return b.EventID == eventID }), nil
+}
+
+func (r *budgetRepo) Create(b *models.Budget) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	b.ID = r.s.newID()
+	b.CreatedAt, b.UpdatedAt = now(), now()
+	r.s.budgets[b.ID] = *b
+	return nil
+}
+
+func (r *budgetRepo) Update(b *models.Budget) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.budgets[b.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	b.UpdatedAt = now()
+	r.s.budgets[b.ID] = *b
+	return nil
+}
+
+func (r *budgetRepo) Delete(id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	delete(r.s.budgets, id)
+	return nil
+}
+
+func (r *budgetRepo) FindByTicket(ticketID uint) (*models.Budget, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return findOne(r.s.budgets, func(b models.Budget) bool { return b.TicketID != nil && *b.TicketID == ticketID })
+}
+
+func (r *budgetRepo) AddActual(id uint, delta int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	b, ok := r.s.budgets[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	b.ActualAmount += delta
+	b.UpdatedAt = now()
+	r.s.budgets[id] = b
+	return nil
+}
@@ -0,0 +1,171 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+)
+
+type channelRepo struct{ s *Store }
+
+func (r *channelRepo) Get(id uint) (*models.Channel, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return get(r.s.channels, id)
+}
+
+func (r *channelRepo) ListByEvent(eventID uint) ([]models.Channel, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := filter(r.s.channels, func(ch models.Channel) bool { return ch.EventID == eventID })
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].IsPrivate != list[j].IsPrivate {
+			return !list[i].IsPrivate
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+func (r *channelRepo) CountByEvent(eventID uint) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return int64(len(filter(r.s.channels, func(ch models.Channel) bool { return ch.EventID == eventID }))), nil
+}
+
+func (r *channelRepo) Create(ch *models.Channel) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	ch.ID = r.s.newID()
+	ch.CreatedAt, ch.UpdatedAt = now(), now()
+	r.s.channels[ch.ID] = *ch
+	return nil
+}
+
+func (r *channelRepo) Update(ch *models.Channel) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.channels[ch.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	ch.UpdatedAt = now()
+	r.s.channels[ch.ID] = *ch
+	return nil
+}
+
+func (r *channelRepo) Delete(id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	delete(r.s.channels, id)
+	return nil
+}
+
+func (r *channelRepo) Members(channelID uint) ([]models.ChannelMember, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := filter(r.s.channelMembers, func(m models.ChannelMember) bool { return m.ChannelID == channelID })
+	for i := range list {
+		list[i].User = r.s.user(list[i].UserID)
+	}
+	return list, nil
+}
+
+func (r *channelRepo) GetMember(channelID, userID uint) (*models.ChannelMember, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return findOne(r.s.channelMembers, func(m models.ChannelMember) bool {
+		return m.ChannelID == channelID && m.UserID == userID
+	})
+}
+
+func (r *channelRepo) AddMember(m *models.ChannelMember) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, existing := range r.s.channelMembers {
+		if existing.ChannelID == m.ChannelID && existing.UserID == m.UserID {
+			return ErrDuplicate
+		}
+	}
+	m.ID = r.s.newID()
+	if m.JoinedAt.IsZero() {
+		m.JoinedAt = now()
+	}
+	m.CreatedAt, m.UpdatedAt = now(), now()
+	r.s.channelMembers[m.ID] = *m
+	m.User = r.s.user(m.UserID)
+	return nil
+}
+
+func (r *channelRepo) RemoveMember(channelID, userID uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for id, m := range r.s.channelMembers {
+		if m.ChannelID == channelID && m.UserID == userID {
+			delete(r.s.channelMembers, id)
+		}
+	}
+	return nil
+}
+
+func (r *channelRepo) MemberChannelIDs(eventID, userID uint) ([]uint, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var ids []uint
+	for _, m := range r.s.channelMembers {
+		if ch, ok := r.s.channels[m.ChannelID]; ok && ch.EventID == eventID && m.UserID == userID {
+			ids = append(ids, m.ChannelID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *channelRepo) MarkRead(channelID, userID uint, at time.Time) (*models.ChannelMember, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	m, err := findOne(r.s.channelMembers, func(m models.ChannelMember) bool {
+		return m.ChannelID == channelID && m.UserID == userID
+	})
+	if err != nil {
+		return nil, err
+	}
+	if m.LastReadAt == nil || m.LastReadAt.Before(at) {
+		m.LastReadAt = &at
+		m.UpdatedAt = now()
+		r.s.channelMembers[m.ID] = *m
+	}
+	return m, nil
+}
+
+func (r *channelRepo) UnreadCounts(userID uint) ([]repository.ChannelUnread, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var list []repository.ChannelUnread
+	for _, m := range r.s.channelMembers {
+		ch, ok := r.s.channels[m.ChannelID]
+		if m.UserID != userID || !ok {
+			continue
+		}
+		since := m.JoinedAt
+		if m.LastReadAt != nil {
+			since = *m.LastReadAt
+		}
+		u := repository.ChannelUnread{ChannelID: ch.ID, EventID: ch.EventID}
+		for _, msg := range r.s.timeline(ch.ID) {
+			if msg.UserID == userID || !msg.CreatedAt.After(since) {
+				continue
+			}
+			u.Count++
+			if _, err := findOne(r.s.mentions, func(x models.MessageMention) bool {
+				return x.MessageID == msg.ID && x.UserID == userID
+			}); err == nil {
+				u.Mentions++
+			}
+		}
+		if u.Count > 0 {
+			list = append(list, u)
+		}
+	}
+	return list, nil
+}
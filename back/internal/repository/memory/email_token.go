@@ -0,0 +1,34 @@
+package memory
+
+import (
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+)
+
+type emailTokenRepo struct{ s *Store }
+
+func (r *emailTokenRepo) Create(t *models.EmailToken) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	t.ID = r.s.newID()
+	t.CreatedAt = now()
+	r.s.emailTokens[t.ID] = *t
+	return nil
+}
+
+func (r *emailTokenRepo) Consume(hash string, purpose models.EmailTokenPurpose, at time.Time) (*models.EmailToken, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	t, err := findOne(r.s.emailTokens, func(t models.EmailToken) bool { return t.TokenHash == hash && t.Purpose == purpose })
+	if err != nil {
+		return nil, err
+	}
+	if t.UsedAt != nil || !at.Before(t.ExpiresAt) {
+		return nil, repository.ErrNotFound
+	}
+	t.UsedAt = &at
+	r.s.emailTokens[t.ID] = *t
+	return t, nil
+}
@@ -0,0 +1,153 @@
+package memory
+
+import (
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+)
+
+type eventRepo struct{ s *Store }
+
+func (r *eventRepo) Get(id uint) (*models.Event, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return get(r.s.events, id)
+}
+
+// GetForUpdate トランザクションは txMu で直列化されているので Get と同じ
+func (r *eventRepo) GetForUpdate(id uint) (*models.Event, error) {
+	return r.Get(id)
+}
+
+func (r *eventRepo) GetDetail(id uint) (*models.Event, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	e, err := get(r.s.events, id)
+	if err != nil {
+		return nil, err
+	}
+	e.Organization = r.s.organizations[e.OrganizationID]
+	e.EventStaffs = filter(r.s.staffs, func(s models.EventStaff) bool { return s.EventID == id })
+	for i := range e.EventStaffs {
+		e.EventStaffs[i].User = r.s.user(e.EventStaffs[i].UserID)
+	}
+	e.Tasks = filter(r.s.tasks, func(t models.Task) bool { return t.EventID == id })
+	e.Budgets = filter(r.s.budgets, func(b models.Budget) bool { return b.EventID == id })
+	return e, nil
+}
+
+func (r *eventRepo) ListForStaff(userID uint, orgIDs []uint) ([]models.Event, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	inOrg := map[uint]bool{}
+	for _, id := range orgIDs {
+		inOrg[id] = true
+	}
+	staffOf := map[uint]bool{}
+	for _, s := range r.s.staffs {
+		if s.UserID == userID {
+			staffOf[s.EventID] = true
+		}
+	}
+	events := filter(r.s.events, func(e models.Event) bool { return staffOf[e.ID] && inOrg[e.OrganizationID] })
+	for i := range events {
+		events[i].Organization = r.s.organizations[events[i].OrganizationID]
+	}
+	return events, nil
+}
+
+func (r *eventRepo) Create(e *models.Event) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	e.ID = r.s.newID()
+	if e.Status == "" {
+		e.Status = models.EventStatusDraft
+	}
+	e.CreatedAt, e.UpdatedAt = now(), now()
+	r.s.events[e.ID] = *e
+	return nil
+}
+
+func (r *eventRepo) Update(e *models.Event) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.events[e.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	e.UpdatedAt = now()
+	r.s.events[e.ID] = *e
+	return nil
+}
+
+func (r *eventRepo) Delete(id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	delete(r.s.events, id)
+	return nil
+}
+
+func (r *eventRepo) CountByOrganization(orgID uint) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return int64(len(filter(r.s.events, func(e models.Event) bool { return e.OrganizationID == orgID }))), nil
+}
+
+func (r *eventRepo) GetStaff(eventID, userID uint) (*models.EventStaff, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return findOne(r.s.staffs, func(s models.EventStaff) bool { return s.EventID == eventID && s.UserID == userID })
+}
+
+func (r *eventRepo) StaffUserIDs(eventID uint) ([]uint, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var ids []uint
+	for _, s := range filter(r.s.staffs, func(s models.EventStaff) bool { return s.EventID == eventID }) {
+		ids = append(ids, s.UserID)
+	}
+	return ids, nil
+}
+
+func (r *eventRepo) AddStaff(s *models.EventStaff) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, existing := range r.s.staffs {
+		if existing.EventID == s.EventID && existing.UserID == s.UserID {
+			return ErrDuplicate
+		}
+	}
+	s.ID = r.s.newID()
+	s.CreatedAt, s.UpdatedAt = now(), now()
+	r.s.staffs[s.ID] = *s
+	return nil
+}
+
+func (r *eventRepo) ListStaff(eventID uint) ([]models.EventStaff, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := filter(r.s.staffs, func(s models.EventStaff) bool { return s.EventID == eventID })
+	for i := range list {
+		list[i].User = r.s.user(list[i].UserID)
+	}
+	return list, nil
+}
+
+func (r *eventRepo) ListStaffByUser(userID uint) ([]models.EventStaff, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := filter(r.s.staffs, func(s models.EventStaff) bool { return s.UserID == userID })
+	for i := range list {
+		list[i].Event = r.s.events[list[i].EventID]
+	}
+	return list, nil
+}
+
+func (r *eventRepo) UpdateStaff(s *models.EventStaff) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.staffs[s.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	s.UpdatedAt = now()
+	r.s.staffs[s.ID] = *s
+	return nil
+}
@@ -0,0 +1,25 @@
+package memory
+
+import "sherpa-backend/internal/models"
+
+type identityRepo struct{ s *Store }
+
+func (r *identityRepo) Find(provider, subject string) (*models.UserIdentity, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return findOne(r.s.identities, func(l models.UserIdentity) bool { return l.Provider == provider && l.Subject == subject })
+}
+
+func (r *identityRepo) Create(link *models.UserIdentity) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, existing := range r.s.identities {
+		if existing.Provider == link.Provider && existing.Subject == link.Subject {
+			return ErrDuplicate
+		}
+	}
+	link.ID = r.s.newID()
+	link.CreatedAt, link.UpdatedAt = now(), now()
+	r.s.identities[link.ID] = *link
+	return nil
+}
@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+)
+
+type invitationRepo struct{ s *Store }
+
+func (r *invitationRepo) Get(id uint) (*models.EventInvitation, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return get(r.s.invitations, id)
+}
+
+func (r *invitationRepo) ListByEvent(eventID uint) ([]models.EventInvitation, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := filter(r.s.invitations, func(inv models.EventInvitation) bool { return inv.EventID == eventID })
+	for i := range list {
+		list[i].User = r.s.user(list[i].UserID)
+		list[i].Inviter = r.s.user(list[i].InviterID)
+	}
+	return list, nil
+}
+
+func (r *invitationRepo) ListPendingForUser(userID uint) ([]models.EventInvitation, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := filter(r.s.invitations, func(inv models.EventInvitation) bool {
+		return inv.UserID == userID && inv.Status == models.InvitationStatusPending
+	})
+	for i := range list {
+		list[i].Event = r.s.events[list[i].EventID]
+		list[i].Inviter = r.s.user(list[i].InviterID)
+	}
+	return list, nil
+}
+
+func (r *invitationRepo) FindPending(eventID, userID uint) (*models.EventInvitation, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return findOne(r.s.invitations, func(inv models.EventInvitation) bool {
+		return inv.EventID == eventID && inv.UserID == userID && inv.Status == models.InvitationStatusPending
+	})
+}
+
+func (r *invitationRepo) PendingUserIDs(eventID uint) ([]uint, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var ids []uint
+	for _, inv := range r.s.invitations {
+		if inv.EventID == eventID && inv.Status == models.InvitationStatusPending {
+			ids = append(ids, inv.UserID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *invitationRepo) Create(inv *models.EventInvitation) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	inv.ID = r.s.newID()
+	if inv.Status == "" {
+		inv.Status = models.InvitationStatusPending
+	}
+	inv.CreatedAt, inv.UpdatedAt = now(), now()
+	r.s.invitations[inv.ID] = *inv
+	inv.User = r.s.user(inv.UserID)
+	inv.Inviter = r.s.user(inv.InviterID)
+	return nil
+}
+
+func (r *invitationRepo) Update(inv *models.EventInvitation) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.invitations[inv.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	inv.UpdatedAt = now()
+	r.s.invitations[inv.ID] = *inv
+	return nil
+}
@@ -0,0 +1,240 @@
+package memory
+
+import (
+	"sort"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+)
+
+type meetingRepo struct{ s *Store }
+
+func (r *meetingRepo) Get(id uint) (*models.Meeting, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return get(r.s.meetings, id)
+}
+
+func (r *meetingRepo) GetDetail(id uint) (*models.Meeting, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	m, err := get(r.s.meetings, id)
+	if err != nil {
+		return nil, err
+	}
+	m.AgendaItems = r.s.agenda(id)
+	m.Attendees = r.s.meetingAttendees(id)
+	return m, nil
+}
+
+func (r *meetingRepo) ListByEvent(eventID uint) ([]models.Meeting, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := filter(r.s.meetings, func(m models.Meeting) bool { return m.EventID == eventID })
+	sort.SliceStable(list, func(i, j int) bool { return list[i].StartAt.Before(list[j].StartAt) })
+	for i := range list {
+		list[i].Attendees = r.s.meetingAttendees(list[i].ID)
+	}
+	return list, nil
+}
+
+func (r *meetingRepo) Create(m *models.Meeting) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	m.ID = r.s.newID()
+	m.CreatedAt, m.UpdatedAt = now(), now()
+	r.s.meetings[m.ID] = *m
+	return nil
+}
+
+func (r *meetingRepo) Update(m *models.Meeting) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.meetings[m.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	m.UpdatedAt = now()
+	r.s.meetings[m.ID] = *m
+	return nil
+}
+
+func (r *meetingRepo) Delete(id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	delete(r.s.meetings, id)
+	return nil
+}
+
+func (r *meetingRepo) GetAgendaItem(meetingID, itemID uint) (*models.MeetingAgendaItem, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	item, err := get(r.s.agendaItems, itemID)
+	if err != nil || item.MeetingID != meetingID {
+		return nil, repository.ErrNotFound
+	}
+	return item, nil
+}
+
+func (r *meetingRepo) ListAgenda(meetingID uint) ([]models.MeetingAgendaItem, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.s.agenda(meetingID), nil
+}
+
+func (r *meetingRepo) AddAgendaItem(item *models.MeetingAgendaItem) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	item.ID = r.s.newID()
+	item.CreatedAt, item.UpdatedAt = now(), now()
+	r.s.agendaItems[item.ID] = *item
+	return nil
+}
+
+func (r *meetingRepo) UpdateAgendaItem(item *models.MeetingAgendaItem) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.agendaItems[item.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	item.UpdatedAt = now()
+	r.s.agendaItems[item.ID] = *item
+	return nil
+}
+
+func (r *meetingRepo) DeleteAgendaItem(id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	delete(r.s.agendaItems, id)
+	return nil
+}
+
+func (r *meetingRepo) GetAttendee(meetingID, userID uint) (*models.MeetingAttendee, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	a, err := findOne(r.s.attendees, func(a models.MeetingAttendee) bool {
+		return a.MeetingID == meetingID && a.UserID == userID
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.User = r.s.user(a.UserID)
+	return a, nil
+}
+
+func (r *meetingRepo) AddAttendee(a *models.MeetingAttendee) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, existing := range r.s.attendees {
+		if existing.MeetingID == a.MeetingID && existing.UserID == a.UserID {
+			return ErrDuplicate
+		}
+	}
+	a.ID = r.s.newID()
+	if a.RSVP == "" {
+		a.RSVP = models.RSVPStatusPending
+	}
+	a.CreatedAt, a.UpdatedAt = now(), now()
+	r.s.attendees[a.ID] = *a
+	a.User = r.s.user(a.UserID)
+	return nil
+}
+
+func (r *meetingRepo) UpdateAttendee(a *models.MeetingAttendee) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.attendees[a.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	a.UpdatedAt = now()
+	r.s.attendees[a.ID] = *a
+	return nil
+}
+
+func (r *meetingRepo) RemoveAttendee(meetingID, userID uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for id, a := range r.s.attendees {
+		if a.MeetingID == meetingID && a.UserID == userID {
+			delete(r.s.attendees, id)
+		}
+	}
+	return nil
+}
+
+func (r *meetingRepo) LatestRevision(meetingID uint) (*models.MeetingMinutesRevision, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := r.s.meetingRevisions(meetingID)
+	if len(list) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return &list[0], nil
+}
+
+func (r *meetingRepo) GetRevision(meetingID uint, revision int) (*models.MeetingMinutesRevision, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, rev := range r.s.meetingRevisions(meetingID) {
+		if rev.Revision == revision {
+			return &rev, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *meetingRepo) ListRevisions(meetingID uint) ([]models.MeetingMinutesRevision, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.s.meetingRevisions(meetingID), nil
+}
+
+func (r *meetingRepo) AddRevision(rev *models.MeetingMinutesRevision) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, existing := range r.s.revisions {
+		if existing.MeetingID == rev.MeetingID && existing.Revision == rev.Revision {
+			return ErrDuplicate
+		}
+	}
+	rev.ID = r.s.newID()
+	rev.CreatedAt = now()
+	r.s.revisions[rev.ID] = *rev
+	return nil
+}
+
+// agenda 順番どおりのアジェンダ（Presenter 付き）
+func (s *Store) agenda(meetingID uint) []models.MeetingAgendaItem {
+	list := filter(s.agendaItems, func(item models.MeetingAgendaItem) bool { return item.MeetingID == meetingID })
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Position < list[j].Position })
+	for i := range list {
+		if list[i].PresenterID != nil {
+			if u, ok := s.users[*list[i].PresenterID]; ok {
+				list[i].Presenter = &u
+			}
+		}
+	}
+	return list
+}
+
+// meetingAttendees 参加者（User 付き）
+func (s *Store) meetingAttendees(meetingID uint) []models.MeetingAttendee {
+	list := filter(s.attendees, func(a models.MeetingAttendee) bool { return a.MeetingID == meetingID })
+	for i := range list {
+		list[i].User = s.user(list[i].UserID)
+	}
+	return list
+}
+
+// meetingRevisions 新しい順の版（Editor 付き）
+func (s *Store) meetingRevisions(meetingID uint) []models.MeetingMinutesRevision {
+	list := filter(s.revisions, func(rev models.MeetingMinutesRevision) bool { return rev.MeetingID == meetingID })
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Revision > list[j].Revision })
+	for i := range list {
+		if list[i].EditorID != nil {
+			if u, ok := s.users[*list[i].EditorID]; ok {
+				list[i].Editor = &u
+			}
+		}
+	}
+	return list
+}
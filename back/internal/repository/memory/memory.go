@@ -0,0 +1,299 @@
+// Package memory repository のインメモリ実装。PostgreSQL なしでハンドラを動かすテスト用。
+//
+//	store := memory.New()
+//	store.AddUser(&models.User{Name: "Alice", Email: "alice@example.com"})
+//	handlers.Repos = store.Repositories()
+package memory
+
+import (
+	"errors"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+)
+
+// ErrDuplicate PostgreSQL の一意制約違反に相当する
+var ErrDuplicate = errors.New("duplicate key value violates unique constraint")
+
+// Store すべてのリポジトリが共有するインメモリのデータ
+type Store struct {
+	mu     sync.Mutex
+	txMu   sync.Mutex // トランザクションの直列化
+	nextID uint
+
+	users          map[uint]models.User
+	organizations  map[uint]models.Organization
+	orgMembers     map[uint]models.OrganizationMember
+	events         map[uint]models.Event
+	staffs         map[uint]models.EventStaff
+	tasks          map[uint]models.Task
+	budgets        map[uint]models.Budget
+	meetings       map[uint]models.Meeting
+	agendaItems    map[uint]models.MeetingAgendaItem
+	attendees      map[uint]models.MeetingAttendee
+	revisions      map[uint]models.MeetingMinutesRevision
+	tickets        map[uint]models.Ticket
+	participants   map[uint]models.EventParticipant
+	orders         map[uint]models.Order
+	webhookEvents  map[uint]models.PaymentWebhookEvent
+	promoCodes     map[uint]models.PromoCode
+	redemptions    map[uint]models.PromoCodeRedemption
+	channels       map[uint]models.Channel
+	channelMembers map[uint]models.ChannelMember
+	messages       map[uint]models.Message
+	reactions      map[uint]models.MessageReaction
+	mentions       map[uint]models.MessageMention
+	invitations    map[uint]models.EventInvitation
+	notifications  map[uint]models.Notification
+	orgInvitations map[uint]models.OrganizationInvitation
+	orgDomains     map[uint]models.OrganizationDomain
+	sessions       map[uint]models.Session
+	personalTokens map[uint]models.PersonalAccessToken
+	emailTokens    map[uint]models.EmailToken
+	identities     map[uint]models.UserIdentity
+}
+
+// New 空の Store
+func New() *Store {
+	return &Store{
+		users:          map[uint]models.User{},
+		organizations:  map[uint]models.Organization{},
+		orgMembers:     map[uint]models.OrganizationMember{},
+		events:         map[uint]models.Event{},
+		staffs:         map[uint]models.EventStaff{},
+		tasks:          map[uint]models.Task{},
+		budgets:        map[uint]models.Budget{},
+		meetings:       map[uint]models.Meeting{},
+		agendaItems:    map[uint]models.MeetingAgendaItem{},
+		attendees:      map[uint]models.MeetingAttendee{},
+		revisions:      map[uint]models.MeetingMinutesRevision{},
+		tickets:        map[uint]models.Ticket{},
+		participants:   map[uint]models.EventParticipant{},
+		orders:         map[uint]models.Order{},
+		webhookEvents:  map[uint]models.PaymentWebhookEvent{},
+		promoCodes:     map[uint]models.PromoCode{},
+		redemptions:    map[uint]models.PromoCodeRedemption{},
+		channels:       map[uint]models.Channel{},
+		channelMembers: map[uint]models.ChannelMember{},
+		messages:       map[uint]models.Message{},
+		reactions:      map[uint]models.MessageReaction{},
+		mentions:       map[uint]models.MessageMention{},
+		invitations:    map[uint]models.EventInvitation{},
+		notifications:  map[uint]models.Notification{},
+		orgInvitations: map[uint]models.OrganizationInvitation{},
+		orgDomains:     map[uint]models.OrganizationDomain{},
+		sessions:       map[uint]models.Session{},
+		personalTokens: map[uint]models.PersonalAccessToken{},
+		emailTokens:    map[uint]models.EmailToken{},
+		identities:     map[uint]models.UserIdentity{},
+	}
+}
+
+// Repositories この Store を使うリポジトリ一式
+func (s *Store) Repositories() *repository.Repositories {
+	r := &repository.Repositories{
+		Users:         &userRepo{s},
+		Events:        &eventRepo{s},
+		Tasks:         &taskRepo{s},
+		Budgets:       &budgetRepo{s},
+		Meetings:      &meetingRepo{s},
+		Tickets:       &ticketRepo{s},
+		Orders:        &orderRepo{s},
+		PromoCodes:    &promoCodeRepo{s},
+		Channels:      &channelRepo{s},
+		Messages:      &messageRepo{s},
+		Invitations:   &invitationRepo{s},
+		Notifications: &notificationRepo{s},
+
+		Organizations:  &organizationRepo{s},
+		Sessions:       &sessionRepo{s},
+		PersonalTokens: &personalTokenRepo{s},
+		EmailTokens:    &emailTokenRepo{s},
+		Identities:     &identityRepo{s},
+		Accounts:       &accountRepo{s},
+	}
+	r.Transaction = s.transaction
+	return r
+}
+
+// transaction fn がエラーを返したら、開始時点のスナップショットにすべてのデータを戻す（採番は PostgreSQL のシーケンス同様に戻さない）。
+// トランザクション同士は直列に実行する（SELECT ... FOR UPDATE の行ロックの代わり）。トランザクション外の書き込みとの分離はしない（テスト用）
+func (s *Store) transaction(fn func(tx *repository.Repositories) error) error {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+	return s.nestedTransaction(fn)
+}
+
+// nestedTransaction トランザクション内の Transaction（SAVEPOINT 相当）。txMu は取り直さない
+func (s *Store) nestedTransaction(fn func(tx *repository.Repositories) error) error {
+	snap := s.snapshot()
+	tx := s.Repositories()
+	tx.Transaction = s.nestedTransaction
+	if err := fn(tx); err != nil {
+		s.restore(snap)
+		return err
+	}
+	return nil
+}
+
+// snapshot 全テーブルの複製
+func (s *Store) snapshot() *Store {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &Store{
+		users:          maps.Clone(s.users),
+		organizations:  maps.Clone(s.organizations),
+		orgMembers:     maps.Clone(s.orgMembers),
+		events:         maps.Clone(s.events),
+		staffs:         maps.Clone(s.staffs),
+		tasks:          maps.Clone(s.tasks),
+		budgets:        maps.Clone(s.budgets),
+		meetings:       maps.Clone(s.meetings),
+		agendaItems:    maps.Clone(s.agendaItems),
+		attendees:      maps.Clone(s.attendees),
+		revisions:      maps.Clone(s.revisions),
+		tickets:        maps.Clone(s.tickets),
+		participants:   maps.Clone(s.participants),
+		orders:         maps.Clone(s.orders),
+		webhookEvents:  maps.Clone(s.webhookEvents),
+		promoCodes:     maps.Clone(s.promoCodes),
+		redemptions:    maps.Clone(s.redemptions),
+		channels:       maps.Clone(s.channels),
+		channelMembers: maps.Clone(s.channelMembers),
+		messages:       maps.Clone(s.messages),
+		reactions:      maps.Clone(s.reactions),
+		mentions:       maps.Clone(s.mentions),
+		invitations:    maps.Clone(s.invitations),
+		notifications:  maps.Clone(s.notifications),
+		orgInvitations: maps.Clone(s.orgInvitations),
+		orgDomains:     maps.Clone(s.orgDomains),
+		sessions:       maps.Clone(s.sessions),
+		personalTokens: maps.Clone(s.personalTokens),
+		emailTokens:    maps.Clone(s.emailTokens),
+		identities:     maps.Clone(s.identities),
+	}
+}
+
+// restore snapshot の内容に戻す
+func (s *Store) restore(snap *Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users = snap.users
+	s.organizations = snap.organizations
+	s.orgMembers = snap.orgMembers
+	s.events = snap.events
+	s.staffs = snap.staffs
+	s.tasks = snap.tasks
+	s.budgets = snap.budgets
+	s.meetings = snap.meetings
+	s.agendaItems = snap.agendaItems
+	s.attendees = snap.attendees
+	s.revisions = snap.revisions
+	s.tickets = snap.tickets
+	s.participants = snap.participants
+	s.orders = snap.orders
+	s.webhookEvents = snap.webhookEvents
+	s.promoCodes = snap.promoCodes
+	s.redemptions = snap.redemptions
+	s.channels = snap.channels
+	s.channelMembers = snap.channelMembers
+	s.messages = snap.messages
+	s.reactions = snap.reactions
+	s.mentions = snap.mentions
+	s.invitations = snap.invitations
+	s.notifications = snap.notifications
+	s.orgInvitations = snap.orgInvitations
+	s.orgDomains = snap.orgDomains
+	s.sessions = snap.sessions
+	s.personalTokens = snap.personalTokens
+	s.emailTokens = snap.emailTokens
+	s.identities = snap.identities
+}
+
+// AddUser テストデータとしてユーザーを登録する（ID は自動採番）
+func (s *Store) AddUser(u *models.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u.ID = s.newID()
+	u.CreatedAt, u.UpdatedAt = now(), now()
+	s.users[u.ID] = *u
+}
+
+// AddOrganization テストデータとして組織を登録する
+func (s *Store) AddOrganization(o *models.Organization) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o.ID = s.newID()
+	o.CreatedAt, o.UpdatedAt = now(), now()
+	s.organizations[o.ID] = *o
+}
+
+// AddOrganizationMember テストデータとして組織メンバーを登録する（Status 未指定なら active）
+func (s *Store) AddOrganizationMember(m *models.OrganizationMember) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m.ID = s.newID()
+	if m.Status == "" {
+		m.Status = models.MemberStatusActive
+	}
+	m.CreatedAt, m.UpdatedAt = now(), now()
+	s.orgMembers[m.ID] = *m
+}
+
+// newID 全テーブル共通の連番。呼び出し側で mu を保持していること
+func (s *Store) newID() uint {
+	s.nextID++
+	return s.nextID
+}
+
+func now() time.Time {
+	return time.Now()
+}
+
+// sortedKeys ID 順（= 作成順）に並べたキー
+func sortedKeys[T any](m map[uint]T) []uint {
+	keys := make([]uint, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// filter 条件に合う行を ID 順で返す
+func filter[T any](m map[uint]T, match func(T) bool) []T {
+	out := []T{}
+	for _, k := range sortedKeys(m) {
+		if match(m[k]) {
+			out = append(out, m[k])
+		}
+	}
+	return out
+}
+
+// get ID で1行取得。なければ repository.ErrNotFound
+func get[T any](m map[uint]T, id uint) (*T, error) {
+	v, ok := m[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &v, nil
+}
+
+// findOne 条件に合う最初の行。なければ repository.ErrNotFound
+func findOne[T any](m map[uint]T, match func(T) bool) (*T, error) {
+	list := filter(m, match)
+	if len(list) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return &list[0], nil
+}
+
+// user 関連として埋め込むユーザー（なければゼロ値）
+func (s *Store) user(id uint) models.User {
+	return s.users[id]
+}
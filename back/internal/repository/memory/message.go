@@ -0,0 +1,224 @@
+package memory
+
+import (
+	"slices"
+	"sort"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+)
+
+type messageRepo struct{ s *Store }
+
+func (r *messageRepo) Get(id uint) (*models.Message, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return get(r.s.messages, id)
+}
+
+// timeline チャンネルのタイムラインに出るメッセージ（(CreatedAt, ID) の昇順）
+func (s *Store) timeline(channelID uint) []models.Message {
+	list := filter(s.messages, func(m models.Message) bool {
+		return m.ChannelID == channelID && (m.ParentMessageID == nil || m.AlsoSentToChannel) && !m.IsDeleted
+	})
+	sort.SliceStable(list, func(i, j int) bool { return cursorLess(repository.CursorOf(&list[i]), repository.CursorOf(&list[j])) })
+	return list
+}
+
+func cursorLess(a, b repository.MessageCursor) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
+
+func (r *messageRepo) ListTopLevelBefore(channelID uint, cursor *repository.MessageCursor, limit int) ([]models.Message, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := r.s.timeline(channelID)
+	if cursor != nil {
+		list = slices.DeleteFunc(list, func(m models.Message) bool { return !cursorLess(repository.CursorOf(&m), *cursor) })
+	}
+	if limit > 0 && len(list) > limit {
+		list = list[len(list)-limit:]
+	}
+	r.s.loadTimeline(list)
+	return list, nil
+}
+
+func (r *messageRepo) ListTopLevelAfter(channelID uint, cursor repository.MessageCursor, limit int) ([]models.Message, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := slices.DeleteFunc(r.s.timeline(channelID), func(m models.Message) bool {
+		return !cursorLess(cursor, repository.CursorOf(&m))
+	})
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	r.s.loadTimeline(list)
+	return list, nil
+}
+
+func (s *Store) loadTimeline(list []models.Message) {
+	for i := range list {
+		s.loadMessage(&list[i])
+		s.loadThreadSummary(&list[i])
+	}
+}
+
+func (r *messageRepo) GetThread(id uint) (*models.Message, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	m, err := get(r.s.messages, id)
+	if err != nil {
+		return nil, err
+	}
+	r.s.loadMessage(m)
+	r.s.loadThreadSummary(m)
+	return m, nil
+}
+
+func (r *messageRepo) ListReplies(parentID uint, limit int) ([]models.Message, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := r.s.replies(parentID)
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	for i := range list {
+		r.s.loadMessage(&list[i])
+	}
+	return list, nil
+}
+
+func (r *messageRepo) Create(m *models.Message) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	m.ID = r.s.newID()
+	m.CreatedAt, m.UpdatedAt = now(), now()
+	r.s.messages[m.ID] = *m
+	m.User = r.s.user(m.UserID)
+	return nil
+}
+
+func (r *messageRepo) Update(m *models.Message) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.messages[m.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	m.UpdatedAt = now()
+	r.s.messages[m.ID] = *m
+	r.s.loadMessage(m)
+	return nil
+}
+
+func (r *messageRepo) FindReaction(messageID, userID uint, emoji string) (*models.MessageReaction, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return findOne(r.s.reactions, func(x models.MessageReaction) bool {
+		return x.MessageID == messageID && x.UserID == userID && x.Emoji == emoji
+	})
+}
+
+func (r *messageRepo) AddReaction(reaction *models.MessageReaction) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, x := range r.s.reactions {
+		if x.MessageID == reaction.MessageID && x.UserID == reaction.UserID && x.Emoji == reaction.Emoji {
+			return ErrDuplicate
+		}
+	}
+	reaction.ID = r.s.newID()
+	reaction.CreatedAt = now()
+	r.s.reactions[reaction.ID] = *reaction
+	reaction.User = r.s.user(reaction.UserID)
+	return nil
+}
+
+func (r *messageRepo) RemoveReaction(id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	delete(r.s.reactions, id)
+	return nil
+}
+
+// loadMessage 投稿者とリアクション（User 付き）、メンションを読み込む
+func (s *Store) loadMessage(m *models.Message) {
+	m.User = s.user(m.UserID)
+	m.Reactions = filter(s.reactions, func(x models.MessageReaction) bool { return x.MessageID == m.ID })
+	for i := range m.Reactions {
+		m.Reactions[i].User = s.user(m.Reactions[i].UserID)
+	}
+	m.Mentions = filter(s.mentions, func(x models.MessageMention) bool { return x.MessageID == m.ID })
+}
+
+// replies スレッドの削除されていない返信（古い順）
+func (s *Store) replies(parentID uint) []models.Message {
+	return filter(s.messages, func(m models.Message) bool {
+		return m.ParentMessageID != nil && *m.ParentMessageID == parentID && !m.IsDeleted
+	})
+}
+
+// loadThreadSummary 親メッセージの返信数と最後の返信を埋める
+func (s *Store) loadThreadSummary(m *models.Message) {
+	if m.ParentMessageID != nil {
+		return
+	}
+	list := s.replies(m.ID)
+	m.ReplyCount = int64(len(list))
+	if len(list) == 0 {
+		return
+	}
+	last := list[len(list)-1]
+	u := s.user(last.UserID)
+	m.LastReplyAt, m.LastReplyUser = &last.CreatedAt, &u
+}
+
+func (r *messageRepo) Mentions(messageID uint) ([]models.MessageMention, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return filter(r.s.mentions, func(x models.MessageMention) bool { return x.MessageID == messageID }), nil
+}
+
+func (r *messageRepo) SetMentions(messageID uint, list []models.MessageMention) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	kept := map[uint]models.MessageMention{}
+	for id, x := range r.s.mentions {
+		if x.MessageID == messageID {
+			kept[x.UserID] = x
+			delete(r.s.mentions, id)
+		}
+	}
+	for i := range list {
+		list[i].MessageID = messageID
+		if old, ok := kept[list[i].UserID]; ok {
+			list[i].ID, list[i].CreatedAt = old.ID, old.CreatedAt
+		} else {
+			list[i].ID, list[i].CreatedAt = r.s.newID(), now()
+		}
+		r.s.mentions[list[i].ID] = list[i]
+	}
+	return nil
+}
+
+func (r *messageRepo) ListMentionsForUser(userID, beforeID uint, limit int) ([]models.MessageMention, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := filter(r.s.mentions, func(x models.MessageMention) bool {
+		m, ok := r.s.messages[x.MessageID]
+		return x.UserID == userID && (beforeID == 0 || x.ID < beforeID) && ok && !m.IsDeleted
+	})
+	slices.Reverse(list)
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	for i := range list {
+		m := r.s.messages[list[i].MessageID]
+		m.User = r.s.user(m.UserID)
+		m.Channel = r.s.channels[m.ChannelID]
+		list[i].Message = &m
+	}
+	return list, nil
+}
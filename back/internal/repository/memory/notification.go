@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"sort"
+
+	"sherpa-backend/internal/models"
+)
+
+type notificationRepo struct{ s *Store }
+
+func (r *notificationRepo) Get(id uint) (*models.Notification, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return get(r.s.notifications, id)
+}
+
+func (r *notificationRepo) ListForUser(userID uint, limit int) ([]models.Notification, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := filter(r.s.notifications, func(n models.Notification) bool { return n.UserID == userID })
+	sort.SliceStable(list, func(i, j int) bool { return list[i].ID > list[j].ID })
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return list, nil
+}
+
+func (r *notificationRepo) CountUnread(userID uint) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	unread := filter(r.s.notifications, func(n models.Notification) bool { return n.UserID == userID && n.ReadAt == nil })
+	return int64(len(unread)), nil
+}
+
+func (r *notificationRepo) Create(n *models.Notification) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	n.ID = r.s.newID()
+	n.CreatedAt = now()
+	r.s.notifications[n.ID] = *n
+	return nil
+}
+
+func (r *notificationRepo) MarkRead(id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.markRead(func(n models.Notification) bool { return n.ID == id })
+	return nil
+}
+
+func (r *notificationRepo) MarkRelatedRead(userID uint, relatedType string, relatedID uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.markRead(func(n models.Notification) bool {
+		return n.UserID == userID && n.RelatedTyp == relatedType && n.RelatedID == relatedID
+	})
+	return nil
+}
+
+func (s *Store) markRead(match func(models.Notification) bool) {
+	at := now()
+	for id, n := range s.notifications {
+		if match(n) {
+			n.ReadAt = &at
+			s.notifications[id] = n
+		}
+	}
+}
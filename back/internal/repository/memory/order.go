@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"sort"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+)
+
+type orderRepo struct{ s *Store }
+
+func (r *orderRepo) Get(id uint) (*models.Order, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	o, err := get(r.s.orders, id)
+	if err != nil {
+		return nil, err
+	}
+	r.s.loadOrder(o)
+	return o, nil
+}
+
+func (r *orderRepo) FindByCheckout(provider, checkoutID string) (*models.Order, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return findOne(r.s.orders, func(o models.Order) bool { return o.Provider == provider && o.CheckoutID == checkoutID })
+}
+
+func (r *orderRepo) FindByPayment(provider, paymentID string) (*models.Order, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return findOne(r.s.orders, func(o models.Order) bool { return o.Provider == provider && o.PaymentID == paymentID })
+}
+
+func (r *orderRepo) FindPendingByParticipant(participantID uint) (*models.Order, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := newestFirst(filter(r.s.orders, func(o models.Order) bool {
+		return o.EventParticipantID == participantID && o.Status == models.OrderStatusPending
+	}))
+	if len(list) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return &list[0], nil
+}
+
+func (r *orderRepo) ListByEvent(eventID uint) ([]models.Order, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := newestFirst(filter(r.s.orders, func(o models.Order) bool { return o.EventID == eventID }))
+	for i := range list {
+		r.s.loadOrder(&list[i])
+	}
+	return list, nil
+}
+
+func (r *orderRepo) ListByUser(userID uint) ([]models.Order, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := newestFirst(filter(r.s.orders, func(o models.Order) bool { return o.UserID == userID }))
+	for i := range list {
+		list[i].Ticket = r.s.tickets[list[i].TicketID]
+	}
+	return list, nil
+}
+
+func (r *orderRepo) Create(o *models.Order) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	o.ID = r.s.newID()
+	if o.Status == "" {
+		o.Status = models.OrderStatusPending
+	}
+	o.CreatedAt, o.UpdatedAt = now(), now()
+	r.s.orders[o.ID] = *o
+	return nil
+}
+
+func (r *orderRepo) Update(o *models.Order) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.orders[o.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	o.UpdatedAt = now()
+	r.s.orders[o.ID] = *o
+	return nil
+}
+
+func (r *orderRepo) RecordWebhookEvent(e *models.PaymentWebhookEvent) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, existing := range r.s.webhookEvents {
+		if existing.Provider == e.Provider && existing.EventID == e.EventID {
+			return false, nil
+		}
+	}
+	e.ID = r.s.newID()
+	e.CreatedAt = now()
+	r.s.webhookEvents[e.ID] = *e
+	return true, nil
+}
+
+func newestFirst(list []models.Order) []models.Order {
+	sort.SliceStable(list, func(i, j int) bool { return list[i].ID > list[j].ID })
+	return list
+}
+
+// loadOrder Preload("Ticket").Preload("User") 相当
+func (s *Store) loadOrder(o *models.Order) {
+	o.Ticket = s.tickets[o.TicketID]
+	o.User = s.user(o.UserID)
+}
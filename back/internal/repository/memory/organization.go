@@ -0,0 +1,262 @@
+package memory
+
+import (
+	"slices"
+	"sort"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+)
+
+type organizationRepo struct{ s *Store }
+
+func (r *organizationRepo) Get(id uint) (*models.Organization, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return get(r.s.organizations, id)
+}
+
+func (r *organizationRepo) Create(o *models.Organization) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	o.ID = r.s.newID()
+	o.CreatedAt, o.UpdatedAt = now(), now()
+	r.s.organizations[o.ID] = *o
+	return nil
+}
+
+func (r *organizationRepo) Update(o *models.Organization) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.organizations[o.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	o.UpdatedAt = now()
+	r.s.organizations[o.ID] = *o
+	return nil
+}
+
+func (r *organizationRepo) Delete(id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for k, m := range r.s.orgMembers {
+		if m.OrganizationID == id {
+			delete(r.s.orgMembers, k)
+		}
+	}
+	for k, inv := range r.s.orgInvitations {
+		if inv.OrganizationID == id && inv.Status == models.InvitationStatusPending {
+			delete(r.s.orgInvitations, k)
+		}
+	}
+	delete(r.s.organizations, id)
+	return nil
+}
+
+func (r *organizationRepo) GetMember(orgID, userID uint) (*models.OrganizationMember, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return findOne(r.s.orgMembers, func(m models.OrganizationMember) bool {
+		return m.OrganizationID == orgID && m.UserID == userID
+	})
+}
+
+func (r *organizationRepo) ListMembers(orgID uint, status string) ([]models.OrganizationMember, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := filter(r.s.orgMembers, func(m models.OrganizationMember) bool {
+		return m.OrganizationID == orgID && m.Status == status
+	})
+	for i := range list {
+		list[i].User = r.s.user(list[i].UserID)
+	}
+	return list, nil
+}
+
+func (r *organizationRepo) ListMemberships(userID uint) ([]models.OrganizationMember, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := filter(r.s.orgMembers, func(m models.OrganizationMember) bool {
+		_, exists := r.s.organizations[m.OrganizationID]
+		return m.UserID == userID && exists
+	})
+	for i := range list {
+		list[i].Organization = r.s.organizations[list[i].OrganizationID]
+	}
+	return list, nil
+}
+
+func (r *organizationRepo) MemberOrganizationIDs(userID uint) ([]uint, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var ids []uint
+	for _, m := range filter(r.s.orgMembers, func(m models.OrganizationMember) bool {
+		return m.UserID == userID && m.Status == models.MemberStatusActive
+	}) {
+		ids = append(ids, m.OrganizationID)
+	}
+	return ids, nil
+}
+
+func (r *organizationRepo) CountOwners(orgID uint) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return int64(len(filter(r.s.orgMembers, func(m models.OrganizationMember) bool {
+		return m.OrganizationID == orgID && m.Role == models.OrgRoleOwner && m.Status == models.MemberStatusActive
+	}))), nil
+}
+
+func (r *organizationRepo) AddMember(m *models.OrganizationMember) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	m.ID = r.s.newID()
+	if m.Status == "" {
+		m.Status = models.MemberStatusActive
+	}
+	m.CreatedAt, m.UpdatedAt = now(), now()
+	r.s.orgMembers[m.ID] = *m
+	return nil
+}
+
+func (r *organizationRepo) UpdateMember(m *models.OrganizationMember) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.orgMembers[m.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	m.UpdatedAt = now()
+	r.s.orgMembers[m.ID] = *m
+	return nil
+}
+
+func (r *organizationRepo) RemoveMember(id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	delete(r.s.orgMembers, id)
+	return nil
+}
+
+func (r *organizationRepo) GetInvitation(id uint) (*models.OrganizationInvitation, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return get(r.s.orgInvitations, id)
+}
+
+func (r *organizationRepo) FindPendingInvitation(orgID, userID uint) (*models.OrganizationInvitation, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return findOne(r.s.orgInvitations, func(inv models.OrganizationInvitation) bool {
+		return inv.OrganizationID == orgID && inv.UserID == userID && inv.Status == models.InvitationStatusPending
+	})
+}
+
+func (r *organizationRepo) ListInvitations(orgID uint) ([]models.OrganizationInvitation, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := filter(r.s.orgInvitations, func(inv models.OrganizationInvitation) bool { return inv.OrganizationID == orgID })
+	slices.Reverse(list)
+	for i := range list {
+		list[i].User = r.s.user(list[i].UserID)
+		list[i].Inviter = r.s.user(list[i].InviterID)
+	}
+	return list, nil
+}
+
+func (r *organizationRepo) ListPendingInvitationsForUser(userID uint) ([]models.OrganizationInvitation, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := filter(r.s.orgInvitations, func(inv models.OrganizationInvitation) bool {
+		return inv.UserID == userID && inv.Status == models.InvitationStatusPending
+	})
+	slices.Reverse(list)
+	for i := range list {
+		list[i].Organization = r.s.organizations[list[i].OrganizationID]
+		list[i].Inviter = r.s.user(list[i].InviterID)
+	}
+	return list, nil
+}
+
+func (r *organizationRepo) CreateInvitation(inv *models.OrganizationInvitation) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	inv.ID = r.s.newID()
+	if inv.Status == "" {
+		inv.Status = models.InvitationStatusPending
+	}
+	inv.CreatedAt, inv.UpdatedAt = now(), now()
+	r.s.orgInvitations[inv.ID] = *inv
+	inv.User = r.s.user(inv.UserID)
+	inv.Inviter = r.s.user(inv.InviterID)
+	return nil
+}
+
+func (r *organizationRepo) UpdateInvitation(inv *models.OrganizationInvitation) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.orgInvitations[inv.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	inv.UpdatedAt = now()
+	r.s.orgInvitations[inv.ID] = *inv
+	return nil
+}
+
+func (r *organizationRepo) ListDomains(orgID uint) ([]models.OrganizationDomain, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := filter(r.s.orgDomains, func(d models.OrganizationDomain) bool { return d.OrganizationID == orgID })
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Domain < list[j].Domain })
+	return list, nil
+}
+
+func (r *organizationRepo) GetDomain(orgID, id uint) (*models.OrganizationDomain, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return findOne(r.s.orgDomains, func(d models.OrganizationDomain) bool { return d.ID == id && d.OrganizationID == orgID })
+}
+
+func (r *organizationRepo) FindDomain(orgID uint, domain string) (*models.OrganizationDomain, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return findOne(r.s.orgDomains, func(d models.OrganizationDomain) bool {
+		return d.OrganizationID == orgID && d.Domain == domain
+	})
+}
+
+func (r *organizationRepo) ListVerifiedDomains(domains []string) ([]models.OrganizationDomain, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return filter(r.s.orgDomains, func(d models.OrganizationDomain) bool {
+		return d.VerifiedAt != nil && slices.Contains(domains, d.Domain)
+	}), nil
+}
+
+func (r *organizationRepo) CreateDomain(d *models.OrganizationDomain) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	d.ID = r.s.newID()
+	if d.DefaultRole == "" {
+		d.DefaultRole = models.OrgRoleMember
+	}
+	d.CreatedAt, d.UpdatedAt = now(), now()
+	r.s.orgDomains[d.ID] = *d
+	return nil
+}
+
+func (r *organizationRepo) UpdateDomain(d *models.OrganizationDomain) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.orgDomains[d.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	d.UpdatedAt = now()
+	r.s.orgDomains[d.ID] = *d
+	return nil
+}
+
+func (r *organizationRepo) DeleteDomain(id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	delete(r.s.orgDomains, id)
+	return nil
+}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+)
+
+type personalTokenRepo struct{ s *Store }
+
+func (r *personalTokenRepo) Create(t *models.PersonalAccessToken) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	t.ID = r.s.newID()
+	t.CreatedAt, t.UpdatedAt = now(), now()
+	r.s.personalTokens[t.ID] = *t
+	return nil
+}
+
+func (r *personalTokenRepo) FindByHash(hash string) (*models.PersonalAccessToken, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return findOne(r.s.personalTokens, func(t models.PersonalAccessToken) bool { return t.TokenHash == hash })
+}
+
+func (r *personalTokenRepo) ListActive(userID uint) ([]models.PersonalAccessToken, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := filter(r.s.personalTokens, func(t models.PersonalAccessToken) bool { return t.UserID == userID && t.RevokedAt == nil })
+	sort.SliceStable(list, func(i, j int) bool { return list[i].ID > list[j].ID })
+	return list, nil
+}
+
+func (r *personalTokenRepo) Revoke(id, userID uint, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	t, ok := r.s.personalTokens[id]
+	if !ok || t.UserID != userID || t.RevokedAt != nil {
+		return repository.ErrNotFound
+	}
+	t.RevokedAt = &at
+	r.s.personalTokens[id] = t
+	return nil
+}
+
+func (r *personalTokenRepo) Touch(id uint, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if t, ok := r.s.personalTokens[id]; ok {
+		t.LastUsedAt = &at
+		r.s.personalTokens[id] = t
+	}
+	return nil
+}
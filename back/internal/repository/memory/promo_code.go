@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"strings"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+)
+
+type promoCodeRepo struct{ s *Store }
+
+func (r *promoCodeRepo) Get(id uint) (*models.PromoCode, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	c, err := get(r.s.promoCodes, id)
+	if err != nil {
+		return nil, err
+	}
+	r.s.loadPromoCode(c)
+	return c, nil
+}
+
+// GetForUpdate トランザクションは txMu で直列化されているので Get と同じ
+func (r *promoCodeRepo) GetForUpdate(id uint) (*models.PromoCode, error) {
+	return r.Get(id)
+}
+
+func (r *promoCodeRepo) FindByCode(eventID uint, code string) (*models.PromoCode, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	code = strings.ToUpper(code)
+	return findOne(r.s.promoCodes, func(c models.PromoCode) bool { return c.EventID == eventID && c.Code == code })
+}
+
+func (r *promoCodeRepo) ListByEvent(eventID uint) ([]models.PromoCode, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := filter(r.s.promoCodes, func(c models.PromoCode) bool { return c.EventID == eventID })
+	for i := range list {
+		r.s.loadPromoCode(&list[i])
+	}
+	return list, nil
+}
+
+func (r *promoCodeRepo) Create(c *models.PromoCode) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, existing := range r.s.promoCodes {
+		if existing.EventID == c.EventID && existing.Code == c.Code {
+			return ErrDuplicate
+		}
+	}
+	c.ID = r.s.newID()
+	c.CreatedAt, c.UpdatedAt = now(), now()
+	stored := *c
+	stored.Ticket = nil
+	r.s.promoCodes[c.ID] = stored
+	return nil
+}
+
+func (r *promoCodeRepo) Update(c *models.PromoCode) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	old, ok := r.s.promoCodes[c.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	for _, existing := range r.s.promoCodes {
+		if existing.ID != c.ID && existing.EventID == c.EventID && existing.Code == c.Code {
+			return ErrDuplicate
+		}
+	}
+	c.RedemptionCount = old.RedemptionCount
+	c.UpdatedAt = now()
+	stored := *c
+	stored.Ticket = nil
+	r.s.promoCodes[c.ID] = stored
+	return nil
+}
+
+func (r *promoCodeRepo) Delete(id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	delete(r.s.promoCodes, id)
+	return nil
+}
+
+func (r *promoCodeRepo) Redeem(red *models.PromoCodeRedemption) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	c, ok := r.s.promoCodes[red.PromoCodeID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	c.RedemptionCount++
+	r.s.promoCodes[c.ID] = c
+	red.ID = r.s.newID()
+	red.CreatedAt = now()
+	r.s.redemptions[red.ID] = *red
+	return nil
+}
+
+func (r *promoCodeRepo) CountRedemptionsByUser(codeID, userID uint) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := filter(r.s.redemptions, func(red models.PromoCodeRedemption) bool {
+		return red.PromoCodeID == codeID && red.UserID == userID
+	})
+	return int64(len(list)), nil
+}
+
+func (r *promoCodeRepo) ListRedemptions(codeID uint) ([]models.PromoCodeRedemption, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := filter(r.s.redemptions, func(red models.PromoCodeRedemption) bool { return red.PromoCodeID == codeID })
+	for i := range list {
+		list[i].User = r.s.user(list[i].UserID)
+		list[i].Ticket = r.s.tickets[list[i].TicketID]
+		list[i].EventParticipant = r.s.participants[list[i].EventParticipantID]
+	}
+	return list, nil
+}
+
+// loadPromoCode Preload("Ticket") 相当
+func (s *Store) loadPromoCode(c *models.PromoCode) {
+	if c.TicketID == nil {
+		return
+	}
+	if t, ok := s.tickets[*c.TicketID]; ok {
+		c.Ticket = &t
+	}
+}
@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"sherpa-backend/internal/models"
+)
+
+type sessionRepo struct{ s *Store }
+
+func (r *sessionRepo) Get(id uint) (*models.Session, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return get(r.s.sessions, id)
+}
+
+func (r *sessionRepo) Create(sess *models.Session) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, existing := range r.s.sessions {
+		if existing.RefreshTokenHash == sess.RefreshTokenHash {
+			return ErrDuplicate
+		}
+	}
+	sess.ID = r.s.newID()
+	sess.CreatedAt, sess.UpdatedAt = now(), now()
+	r.s.sessions[sess.ID] = *sess
+	return nil
+}
+
+func (r *sessionRepo) FindByRefreshToken(hash string) (*models.Session, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return findOne(r.s.sessions, func(s models.Session) bool { return s.RefreshTokenHash == hash })
+}
+
+func (r *sessionRepo) FindByPreviousToken(hash string) (*models.Session, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return findOne(r.s.sessions, func(s models.Session) bool {
+		return s.PreviousTokenHash != nil && *s.PreviousTokenHash == hash
+	})
+}
+
+func (r *sessionRepo) Rotate(sess *models.Session, oldHash string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	cur, ok := r.s.sessions[sess.ID]
+	if !ok || cur.RefreshTokenHash != oldHash {
+		return false, nil
+	}
+	cur.RefreshTokenHash = sess.RefreshTokenHash
+	cur.PreviousTokenHash = sess.PreviousTokenHash
+	cur.LastUsedAt = sess.LastUsedAt
+	cur.IPAddress = sess.IPAddress
+	cur.UserAgent = sess.UserAgent
+	cur.UpdatedAt = now()
+	r.s.sessions[sess.ID] = cur
+	return true, nil
+}
+
+func (r *sessionRepo) ListActive(userID uint, at time.Time) ([]models.Session, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := filter(r.s.sessions, func(s models.Session) bool { return s.UserID == userID && s.IsActive(at) })
+	sort.SliceStable(list, func(i, j int) bool { return list[i].LastUsedAt.After(list[j].LastUsedAt) })
+	return list, nil
+}
+
+func (r *sessionRepo) Revoke(id uint, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if sess, ok := r.s.sessions[id]; ok && sess.RevokedAt == nil {
+		sess.RevokedAt = &at
+		r.s.sessions[id] = sess
+	}
+	return nil
+}
+
+func (r *sessionRepo) RevokeAllForUser(userID, exceptID uint, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	revokeSessions(r.s, userID, exceptID, at)
+	return nil
+}
+
+// revokeSessions ユーザーの失効していないセッションを exceptID 以外すべて失効させる。呼び出し側で mu を保持していること
+func revokeSessions(s *Store, userID, exceptID uint, at time.Time) {
+	for id, sess := range s.sessions {
+		if sess.UserID == userID && id != exceptID && sess.RevokedAt == nil {
+			sess.RevokedAt = &at
+			s.sessions[id] = sess
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+)
+
+type taskRepo struct{ s *Store }
+
+func (r *taskRepo) Get(id uint) (*models.Task, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return get(r.s.tasks, id)
+}
+
+func (r *taskRepo) ListByEvent(eventID uint) ([]models.Task, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	tasks := filter(r.s.tasks, func(t models.Task) bool { return t.EventID == eventID })
+	for i := range tasks {
+		r.s.loadAssignee(&tasks[i])
+	}
+	return tasks, nil
+}
+
+func (r *taskRepo) ListByMeeting(meetingID uint) ([]models.Task, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	tasks := filter(r.s.tasks, func(t models.Task) bool {
+		return t.SourceMeetingID != nil && *t.SourceMeetingID == meetingID
+	})
+	for i := range tasks {
+		r.s.loadAssignee(&tasks[i])
+	}
+	return tasks, nil
+}
+
+func (r *taskRepo) Create(t *models.Task) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	t.ID = r.s.newID()
+	if t.Status == "" {
+		t.Status = models.TaskStatusTodo
+	}
+	t.CreatedAt, t.UpdatedAt = now(), now()
+	r.s.tasks[t.ID] = *t
+	return nil
+}
+
+func (r *taskRepo) Update(t *models.Task) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.tasks[t.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	t.UpdatedAt = now()
+	r.s.tasks[t.ID] = *t
+	r.s.loadAssignee(t)
+	return nil
+}
+
+func (r *taskRepo) Delete(id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	delete(r.s.tasks, id)
+	return nil
+}
+
+// loadAssignee Preload("Assignee") 相当
+func (s *Store) loadAssignee(t *models.Task) {
+	t.Assignee = nil
+	if t.AssigneeID == nil {
+		return
+	}
+	if u, ok := s.users[*t.AssigneeID]; ok {
+		t.Assignee = &u
+	}
+}
@@ -0,0 +1,290 @@
+package memory
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type ticketRepo struct{ s *Store }
+
+func (r *ticketRepo) Get(id uint) (*models.Ticket, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	t, ok := r.s.liveTicket(id)
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &t, nil
+}
+
+// GetForUpdate トランザクションは txMu で直列化されているので Get と同じ
+func (r *ticketRepo) GetForUpdate(id uint) (*models.Ticket, error) {
+	return r.Get(id)
+}
+
+func (r *ticketRepo) ListByEvent(eventID uint) ([]models.Ticket, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	tickets := filter(r.s.tickets, func(t models.Ticket) bool { return t.EventID == eventID && !t.DeletedAt.Valid })
+	for i := range tickets {
+		tickets[i].Sold = r.s.countActive(tickets[i].ID)
+	}
+	return tickets, nil
+}
+
+func (r *ticketRepo) Create(t *models.Ticket) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	t.ID = r.s.newID()
+	t.CreatedAt, t.UpdatedAt = now(), now()
+	r.s.tickets[t.ID] = *t
+	return nil
+}
+
+func (r *ticketRepo) Update(t *models.Ticket) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.liveTicket(t.ID); !ok {
+		return repository.ErrNotFound
+	}
+	t.UpdatedAt = now()
+	r.s.tickets[t.ID] = *t
+	return nil
+}
+
+// Delete PostgreSQL と同じく論理削除する（注文・登録の履歴からは削除済みのチケットも読める）
+func (r *ticketRepo) Delete(id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if t, ok := r.s.liveTicket(id); ok {
+		t.DeletedAt = gorm.DeletedAt{Time: now(), Valid: true}
+		r.s.tickets[id] = t
+	}
+	return nil
+}
+
+func (r *ticketRepo) CountActive(ticketID uint) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.s.countActive(ticketID), nil
+}
+
+func (r *ticketRepo) CountOpen(ticketID uint) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var n int64
+	for _, p := range r.s.participants {
+		if p.TicketID == ticketID && slices.Contains(models.OpenParticipantStatuses, p.Status) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *ticketRepo) GetParticipant(id uint) (*models.EventParticipant, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	p, err := get(r.s.participants, id)
+	if err != nil {
+		return nil, err
+	}
+	r.s.loadParticipant(p)
+	return p, nil
+}
+
+func (r *ticketRepo) FindParticipant(ticketID, userID uint) (*models.EventParticipant, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return findOne(r.s.participants, func(p models.EventParticipant) bool {
+		return p.TicketID == ticketID && p.UserID == userID
+	})
+}
+
+func (r *ticketRepo) FindOpenParticipant(eventID, userID uint) (*models.EventParticipant, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return findOne(r.s.participants, func(p models.EventParticipant) bool {
+		t, ok := r.s.liveTicket(p.TicketID)
+		return ok && t.EventID == eventID && p.UserID == userID &&
+			slices.Contains(models.OpenParticipantStatuses, p.Status)
+	})
+}
+
+func (r *ticketRepo) ListParticipants(eventID, ticketID uint, status models.ParticipantStatus) ([]models.EventParticipant, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := filter(r.s.participants, func(p models.EventParticipant) bool {
+		t, ok := r.s.liveTicket(p.TicketID)
+		return ok && t.EventID == eventID &&
+			(ticketID == 0 || p.TicketID == ticketID) &&
+			(status == "" || p.Status == status)
+	})
+	for i := range list {
+		r.s.loadParticipant(&list[i])
+	}
+	return list, nil
+}
+
+func (r *ticketRepo) ListParticipantsByUser(userID uint) ([]models.EventParticipant, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := filter(r.s.participants, func(p models.EventParticipant) bool { return p.UserID == userID })
+	sort.SliceStable(list, func(i, j int) bool { return list[i].ID > list[j].ID })
+	for i := range list {
+		r.s.loadParticipant(&list[i])
+		list[i].Ticket = r.s.tickets[list[i].TicketID] // 削除済みのチケットも読み込む
+		list[i].Ticket.Event = r.s.events[list[i].Ticket.EventID]
+	}
+	return list, nil
+}
+
+func (r *ticketRepo) CreateParticipant(p *models.EventParticipant) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, existing := range r.s.participants {
+		if existing.TicketID == p.TicketID && existing.UserID == p.UserID {
+			return ErrDuplicate
+		}
+	}
+	p.ID = r.s.newID()
+	if p.Status == "" {
+		p.Status = models.ParticipantStatusPending
+	}
+	p.CreatedAt, p.UpdatedAt = now(), now()
+	r.s.participants[p.ID] = *p
+	return nil
+}
+
+func (r *ticketRepo) UpdateParticipant(p *models.EventParticipant) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.participants[p.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	p.UpdatedAt = now()
+	r.s.participants[p.ID] = *p
+	return nil
+}
+
+func (r *ticketRepo) NextWaitlisted(ticketID uint) (*models.EventParticipant, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	list := r.s.waitlist(ticketID)
+	if len(list) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return &list[0], nil
+}
+
+func (r *ticketRepo) WaitlistPosition(p *models.EventParticipant) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for i, w := range r.s.waitlist(p.TicketID) {
+		if w.ID == p.ID {
+			return int64(i + 1), nil
+		}
+	}
+	return 0, nil
+}
+
+func (r *ticketRepo) ExpiredOffers(ticketID uint, now time.Time) ([]models.EventParticipant, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return filter(r.s.participants, func(p models.EventParticipant) bool {
+		return p.TicketID == ticketID && offerExpired(p, now)
+	}), nil
+}
+
+func (r *ticketRepo) TicketIDsWithWaitlist(now time.Time) ([]uint, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	seen := map[uint]bool{}
+	for _, p := range r.s.participants {
+		if p.Status == models.ParticipantStatusWaitlisted || offerExpired(p, now) {
+			seen[p.TicketID] = true
+		}
+	}
+	return sortedKeys(seen), nil
+}
+
+func (r *ticketRepo) CheckIn(id, staffID uint, at time.Time) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	p, ok := r.s.participants[id]
+	if !ok || p.Status != models.ParticipantStatusConfirmed || p.CheckedInAt != nil {
+		return false, nil
+	}
+	p.CheckedInAt, p.CheckedInBy = &at, &staffID
+	p.UpdatedAt = now()
+	r.s.participants[id] = p
+	return true, nil
+}
+
+func (r *ticketRepo) CountCheckIns(eventID uint) (int64, int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var checkedIn, confirmed int64
+	for _, p := range r.s.participants {
+		t, ok := r.s.liveTicket(p.TicketID)
+		if !ok || t.EventID != eventID || p.Status != models.ParticipantStatusConfirmed {
+			continue
+		}
+		confirmed++
+		if p.CheckedInAt != nil {
+			checkedIn++
+		}
+	}
+	return checkedIn, confirmed, nil
+}
+
+func offerExpired(p models.EventParticipant, now time.Time) bool {
+	return p.Status == models.ParticipantStatusOffered && p.OfferExpiresAt != nil && !p.OfferExpiresAt.After(now)
+}
+
+// waitlist チケットのキャンセル待ち（繰り上げる順）。呼び出し側で mu を保持していること
+func (s *Store) waitlist(ticketID uint) []models.EventParticipant {
+	list := filter(s.participants, func(p models.EventParticipant) bool {
+		return p.TicketID == ticketID && p.Status == models.ParticipantStatusWaitlisted
+	})
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i].WaitlistedAt, list[j].WaitlistedAt
+		return a != nil && b != nil && a.Before(*b)
+	})
+	return list
+}
+
+func isActive(p models.EventParticipant) bool {
+	return slices.Contains(models.ActiveParticipantStatuses, p.Status)
+}
+
+// countActive 呼び出し側で mu を保持していること
+func (s *Store) countActive(ticketID uint) int64 {
+	var n int64
+	for _, p := range s.participants {
+		if p.TicketID == ticketID && isActive(p) {
+			n++
+		}
+	}
+	return n
+}
+
+// loadParticipant Preload("Ticket").Preload("User") 相当（削除済みのチケットは読み込まない）
+func (s *Store) loadParticipant(p *models.EventParticipant) {
+	p.Ticket, _ = s.liveTicket(p.TicketID)
+	p.User = s.user(p.UserID)
+}
+
+// liveTicket 論理削除されていないチケット。呼び出し側で mu を保持していること
+func (s *Store) liveTicket(id uint) (models.Ticket, bool) {
+	t, ok := s.tickets[id]
+	if !ok || t.DeletedAt.Valid {
+		return models.Ticket{}, false
+	}
+	return t, true
+}
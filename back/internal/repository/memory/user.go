@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"slices"
+	"strings"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type userRepo struct{ s *Store }
+
+// notDeleted 論理削除されていないユーザー（s.user は削除済みも返す）
+func notDeleted(u models.User) bool {
+	return !u.DeletedAt.Valid
+}
+
+func (r *userRepo) Get(id uint) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return findOne(r.s.users, func(u models.User) bool { return u.ID == id && notDeleted(u) })
+}
+
+func (r *userRepo) FindByEmail(email string) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return findOne(r.s.users, func(u models.User) bool { return strings.EqualFold(u.Email, email) && notDeleted(u) })
+}
+
+func (r *userRepo) ListByOrganization(orgID uint) ([]models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	members := filter(r.s.orgMembers, func(m models.OrganizationMember) bool {
+		return m.OrganizationID == orgID && m.Status == models.MemberStatusActive
+	})
+	users := make([]models.User, 0, len(members))
+	for _, m := range members {
+		if u, ok := r.s.users[m.UserID]; ok && notDeleted(u) {
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+func (r *userRepo) ListByIDs(ids []uint) ([]models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return filter(r.s.users, func(u models.User) bool { return slices.Contains(ids, u.ID) && notDeleted(u) }), nil
+}
+
+func (r *userRepo) Search(q string, limit int) ([]models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	q = strings.ToLower(q)
+	list := filter(r.s.users, func(u models.User) bool {
+		return notDeleted(u) && (strings.Contains(strings.ToLower(u.Name), q) || strings.Contains(strings.ToLower(u.Email), q))
+	})
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	return list, nil
+}
+
+func (r *userRepo) Create(u *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, existing := range r.s.users {
+		if existing.Email == u.Email {
+			return ErrDuplicate
+		}
+	}
+	u.ID = r.s.newID()
+	u.CreatedAt, u.UpdatedAt = now(), now()
+	r.s.users[u.ID] = *u
+	return nil
+}
+
+func (r *userRepo) Update(u *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.users[u.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	u.UpdatedAt = now()
+	r.s.users[u.ID] = *u
+	return nil
+}
+
+func (r *userRepo) Delete(id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if u, ok := r.s.users[id]; ok {
+		u.DeletedAt = gorm.DeletedAt{Time: now(), Valid: true}
+		r.s.users[id] = u
+	}
+	return nil
+}
+
+func (r *userRepo) SetActiveOrganization(userID uint, orgID *uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, ok := r.s.users[userID]
+	if !ok {
+		return nil
+	}
+	if orgID != nil {
+		id := *orgID
+		orgID = &id
+	}
+	u.ActiveOrganizationID = orgID
+	r.s.users[userID] = u
+	return nil
+}
+
+func (r *userRepo) ClearActiveOrganization(orgID uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for id, u := range r.s.users {
+		if u.ActiveOrganizationID != nil && *u.ActiveOrganizationID == orgID {
+			u.ActiveOrganizationID = nil
+			r.s.users[id] = u
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type accountRepo struct{ db *gorm.DB }
+
+func (r *accountRepo) Export(userID uint) (*repository.AccountExport, error) {
+	out := &repository.AccountExport{}
+	if err := first(r.db, &out.User, userID); err != nil {
+		return nil, err
+	}
+	queries := []*gorm.DB{
+		r.db.Preload("Organization").Where("user_id = ?", userID).Order("created_at").Find(&out.OrganizationMembers),
+		r.db.Preload("Event").Where("user_id = ?", userID).Order("created_at").Find(&out.EventStaffs),
+		r.db.Preload("Ticket").Where("user_id = ?", userID).Order("created_at").Find(&out.Participants),
+		r.db.Where("user_id = ?", userID).Order("created_at").Find(&out.Orders),
+		r.db.Where("user_id = ?", userID).Order("created_at").Find(&out.Redemptions),
+		r.db.Where("user_id = ?", userID).Order("created_at").Find(&out.Messages),
+		r.db.Where("user_id = ?", userID).Order("created_at").Find(&out.Reactions),
+		r.db.Where("assignee_id = ?", userID).Order("deadline").Find(&out.Tasks),
+		r.db.Where("user_id = ?", userID).Order("created_at").Find(&out.Attendances),
+		r.db.Where("editor_id = ?", userID).Order("created_at").Find(&out.MinutesRevisions),
+		r.db.Where("user_id = ?", userID).Order("created_at").Find(&out.Notifications),
+		r.db.Where("user_id = ? OR inviter_id = ?", userID, userID).Order("created_at").Find(&out.EventInvitations),
+		r.db.Where("user_id = ? OR inviter_id = ?", userID, userID).Order("created_at").Find(&out.OrgInvitations),
+		r.db.Where("user_id = ?", userID).Order("created_at").Find(&out.Sessions),
+		r.db.Where("user_id = ?", userID).Order("created_at").Find(&out.PersonalTokens),
+		r.db.Where("user_id = ?", userID).Order("created_at").Find(&out.Identities),
+	}
+	for _, q := range queries {
+		if q.Error != nil {
+			return nil, q.Error
+		}
+	}
+	return out, nil
+}
+
+func (r *accountRepo) Purge(userID uint, at time.Time) error {
+	pending := models.InvitationStatusPending
+	steps := []*gorm.DB{
+		r.db.Model(&models.Task{}).Where("assignee_id = ?", userID).Update("assignee_id", nil),
+		r.db.Where("user_id = ?", userID).Delete(&models.EventStaff{}),
+		r.db.Where("user_id = ?", userID).Delete(&models.ChannelMember{}),
+		r.db.Where("user_id = ?", userID).Delete(&models.OrganizationMember{}),
+		r.db.Where("user_id = ?", userID).Delete(&models.MessageReaction{}),
+		r.db.Where("user_id = ?", userID).Delete(&models.MessageMention{}),
+		r.db.Where("user_id = ?", userID).Delete(&models.Notification{}),
+		r.db.Where("user_id = ? AND status = ?", userID, pending).Delete(&models.EventInvitation{}),
+		r.db.Where("user_id = ? AND status = ?", userID, pending).Delete(&models.OrganizationInvitation{}),
+		r.db.Where("user_id = ?", userID).Delete(&models.UserIdentity{}),
+		r.db.Where("user_id = ?", userID).Delete(&models.EmailToken{}),
+		r.db.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", at),
+		r.db.Model(&models.PersonalAccessToken{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", at),
+	}
+	for _, s := range steps {
+		if s.Error != nil {
+			return s.Error
+		}
+	}
+	return nil
+}
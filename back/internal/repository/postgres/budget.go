@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type budgetRepo struct{ db *gorm.DB }
+
+func (r *budgetRepo) Get(id uint) (*models.Budget, error) {
+	var b models.Budget
+	if err := first(r.db, &b, id); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (r *budgetRepo) ListByEvent(eventID uint) ([]models.Budget, error) {
+	var budgets []models.Budget
+	err := r.db.Where("event_id = ?", eventID).Find(&budgets).Error
+	return budgets, err
+}
+
+func (r *budgetRepo) Create(b *models.Budget) error {
+	return r.db.Create(b).Error
+}
+
+func (r *budgetRepo) Update(b *models.Budget) error {
+	return r.db.Save(b).Error
+}
+
+func (r *budgetRepo) Delete(id uint) error {
+	return r.db.Delete(&models.Budget{}, id).Error
+}
+
+func (r *budgetRepo) FindByTicket(ticketID uint) (*models.Budget, error) {
+	var b models.Budget
+	if err := first(r.db.Where("ticket_id = ?", ticketID), &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (r *budgetRepo) AddActual(id uint, delta int) error {
+	res := r.db.Model(&models.Budget{}).Where("id = ?", id).
+		Update("actual_amount", gorm.Expr("COALESCE(actual_amount, 0) + ?", delta))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type channelRepo struct{ db *gorm.DB }
+
+func (r *channelRepo) Get(id uint) (*models.Channel, error) {
+	var ch models.Channel
+	if err := first(r.db, &ch, id); err != nil {
+		return nil, err
+	}
+	return &ch, nil
+}
+
+func (r *channelRepo) ListByEvent(eventID uint) ([]models.Channel, error) {
+	var list []models.Channel
+	err := r.db.Where("event_id = ?", eventID).Order("is_private ASC").Order("name ASC").Find(&list).Error
+	return list, err
+}
+
+func (r *channelRepo) CountByEvent(eventID uint) (int64, error) {
+	var n int64
+	err := r.db.Model(&models.Channel{}).Where("event_id = ?", eventID).Count(&n).Error
+	return n, err
+}
+
+func (r *channelRepo) Create(ch *models.Channel) error {
+	return r.db.Create(ch).Error
+}
+
+func (r *channelRepo) Update(ch *models.Channel) error {
+	return r.db.Save(ch).Error
+}
+
+func (r *channelRepo) Delete(id uint) error {
+	return r.db.Delete(&models.Channel{}, id).Error
+}
+
+func (r *channelRepo) Members(channelID uint) ([]models.ChannelMember, error) {
+	var list []models.ChannelMember
+	err := r.db.Where("channel_id = ?", channelID).Preload("User").Find(&list).Error
+	return list, err
+}
+
+func (r *channelRepo) GetMember(channelID, userID uint) (*models.ChannelMember, error) {
+	var m models.ChannelMember
+	if err := first(r.db.Where("channel_id = ? AND user_id = ?", channelID, userID), &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *channelRepo) AddMember(m *models.ChannelMember) error {
+	if err := r.db.Create(m).Error; err != nil {
+		return err
+	}
+	return r.db.Preload("User").First(m, m.ID).Error
+}
+
+func (r *channelRepo) RemoveMember(channelID, userID uint) error {
+	return r.db.Where("channel_id = ? AND user_id = ?", channelID, userID).Delete(&models.ChannelMember{}).Error
+}
+
+func (r *channelRepo) MemberChannelIDs(eventID, userID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.ChannelMember{}).
+		Joins("JOIN channels ON channels.id = channel_members.channel_id AND channels.deleted_at IS NULL").
+		Where("channels.event_id = ? AND channel_members.user_id = ?", eventID, userID).
+		Pluck("channel_members.channel_id", &ids).Error
+	return ids, err
+}
+
+func (r *channelRepo) MarkRead(channelID, userID uint, at time.Time) (*models.ChannelMember, error) {
+	err := r.db.Model(&models.ChannelMember{}).
+		Where("channel_id = ? AND user_id = ?", channelID, userID).
+		Where("(last_read_at IS NULL OR last_read_at < ?)", at).
+		Update("last_read_at", at).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.GetMember(channelID, userID)
+}
+
+func (r *channelRepo) UnreadCounts(userID uint) ([]repository.ChannelUnread, error) {
+	var list []repository.ChannelUnread
+	err := r.db.Table("messages").
+		Select("messages.channel_id, channels.event_id, COUNT(*) AS count, "+
+			"COUNT(*) FILTER (WHERE EXISTS (SELECT 1 FROM message_mentions WHERE message_mentions.message_id = messages.id AND message_mentions.user_id = ?)) AS mentions", userID).
+		Joins("JOIN channel_members ON channel_members.channel_id = messages.channel_id AND channel_members.user_id = ? AND channel_members.deleted_at IS NULL", userID).
+		Joins("JOIN channels ON channels.id = messages.channel_id AND channels.deleted_at IS NULL").
+		Where("messages.deleted_at IS NULL AND messages.is_deleted = false").
+		Where("(messages.parent_message_id IS NULL OR messages.also_sent_to_channel)").
+		Where("messages.user_id <> ?", userID).
+		Where("messages.created_at > COALESCE(channel_members.last_read_at, channel_members.joined_at)").
+		Group("messages.channel_id, channels.event_id").
+		Scan(&list).Error
+	return list, err
+}
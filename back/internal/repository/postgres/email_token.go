@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type emailTokenRepo struct{ db *gorm.DB }
+
+func (r *emailTokenRepo) Create(t *models.EmailToken) error {
+	return r.db.Create(t).Error
+}
+
+func (r *emailTokenRepo) Consume(hash string, purpose models.EmailTokenPurpose, now time.Time) (*models.EmailToken, error) {
+	var t models.EmailToken
+	if err := first(r.db.Where("token_hash = ? AND purpose = ?", hash, purpose), &t); err != nil {
+		return nil, err
+	}
+	// 使用済みにできた1件だけを成功とする
+	res := r.db.Model(&models.EmailToken{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", t.ID, now).
+		Update("used_at", now)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, repository.ErrNotFound
+	}
+	t.UsedAt = &now
+	return &t, nil
+}
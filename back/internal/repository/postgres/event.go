@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"sherpa-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type eventRepo struct{ db *gorm.DB }
+
+func (r *eventRepo) Get(id uint) (*models.Event, error) {
+	var e models.Event
+	if err := first(r.db, &e, id); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *eventRepo) GetForUpdate(id uint) (*models.Event, error) {
+	var e models.Event
+	if err := first(r.db.Clauses(clause.Locking{Strength: "UPDATE"}), &e, id); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *eventRepo) GetDetail(id uint) (*models.Event, error) {
+	var e models.Event
+	q := r.db.Preload("Organization").
+		Preload("EventStaffs.User").
+		Preload("Tasks").
+		Preload("Budgets")
+	if err := first(q, &e, id); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *eventRepo) ListForStaff(userID uint, orgIDs []uint) ([]models.Event, error) {
+	events := []models.Event{}
+	if len(orgIDs) == 0 {
+		return events, nil
+	}
+	err := r.db.Preload("Organization").
+		Where("id IN (?)", r.db.Model(&models.EventStaff{}).Select("event_id").Where("user_id = ?", userID)).
+		Where("organization_id IN ?", orgIDs).
+		Find(&events).Error
+	return events, err
+}
+
+func (r *eventRepo) Create(e *models.Event) error {
+	return r.db.Create(e).Error
+}
+
+func (r *eventRepo) Update(e *models.Event) error {
+	return r.db.Omit(clause.Associations).Save(e).Error
+}
+
+func (r *eventRepo) Delete(id uint) error {
+	return r.db.Delete(&models.Event{}, id).Error
+}
+
+func (r *eventRepo) CountByOrganization(orgID uint) (int64, error) {
+	var n int64
+	err := r.db.Model(&models.Event{}).Where("organization_id = ?", orgID).Count(&n).Error
+	return n, err
+}
+
+func (r *eventRepo) GetStaff(eventID, userID uint) (*models.EventStaff, error) {
+	var s models.EventStaff
+	if err := first(r.db.Where("event_id = ? AND user_id = ?", eventID, userID), &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *eventRepo) StaffUserIDs(eventID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.EventStaff{}).Where("event_id = ?", eventID).Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+func (r *eventRepo) ListStaff(eventID uint) ([]models.EventStaff, error) {
+	var list []models.EventStaff
+	err := r.db.Preload("User").Where("event_id = ?", eventID).Order("id").Find(&list).Error
+	return list, err
+}
+
+func (r *eventRepo) ListStaffByUser(userID uint) ([]models.EventStaff, error) {
+	var list []models.EventStaff
+	err := r.db.Preload("Event").Where("user_id = ?", userID).Order("id").Find(&list).Error
+	return list, err
+}
+
+func (r *eventRepo) AddStaff(s *models.EventStaff) error {
+	return r.db.Create(s).Error
+}
+
+func (r *eventRepo) UpdateStaff(s *models.EventStaff) error {
+	return r.db.Omit(clause.Associations).Save(s).Error
+}
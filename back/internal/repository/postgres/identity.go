@@ -0,0 +1,21 @@
+package postgres
+
+import (
+	"sherpa-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type identityRepo struct{ db *gorm.DB }
+
+func (r *identityRepo) Find(provider, subject string) (*models.UserIdentity, error) {
+	var link models.UserIdentity
+	if err := first(r.db.Where("provider = ? AND subject = ?", provider, subject), &link); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *identityRepo) Create(link *models.UserIdentity) error {
+	return r.db.Create(link).Error
+}
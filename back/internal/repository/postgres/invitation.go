@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"sherpa-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type invitationRepo struct{ db *gorm.DB }
+
+func (r *invitationRepo) Get(id uint) (*models.EventInvitation, error) {
+	var inv models.EventInvitation
+	if err := first(r.db, &inv, id); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (r *invitationRepo) ListByEvent(eventID uint) ([]models.EventInvitation, error) {
+	var list []models.EventInvitation
+	err := r.db.Where("event_id = ?", eventID).Preload("User").Preload("Inviter").Find(&list).Error
+	return list, err
+}
+
+func (r *invitationRepo) ListPendingForUser(userID uint) ([]models.EventInvitation, error) {
+	var list []models.EventInvitation
+	err := r.db.Where("user_id = ? AND status = ?", userID, models.InvitationStatusPending).
+		Preload("Event").Preload("Inviter").Find(&list).Error
+	return list, err
+}
+
+func (r *invitationRepo) FindPending(eventID, userID uint) (*models.EventInvitation, error) {
+	var inv models.EventInvitation
+	q := r.db.Where("event_id = ? AND user_id = ? AND status = ?", eventID, userID, models.InvitationStatusPending)
+	if err := first(q, &inv); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (r *invitationRepo) PendingUserIDs(eventID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.EventInvitation{}).
+		Where("event_id = ? AND status = ?", eventID, models.InvitationStatusPending).
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+func (r *invitationRepo) Create(inv *models.EventInvitation) error {
+	if err := r.db.Create(inv).Error; err != nil {
+		return err
+	}
+	return r.db.Preload("User").Preload("Inviter").First(inv, inv.ID).Error
+}
+
+func (r *invitationRepo) Update(inv *models.EventInvitation) error {
+	return r.db.Save(inv).Error
+}
@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"sherpa-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type meetingRepo struct{ db *gorm.DB }
+
+func orderAgenda(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC").Order("id ASC")
+}
+
+func (r *meetingRepo) Get(id uint) (*models.Meeting, error) {
+	var m models.Meeting
+	if err := first(r.db, &m, id); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *meetingRepo) GetDetail(id uint) (*models.Meeting, error) {
+	var m models.Meeting
+	q := r.db.Preload("AgendaItems", orderAgenda).
+		Preload("AgendaItems.Presenter").
+		Preload("Attendees").
+		Preload("Attendees.User", withDeletedUsers)
+	if err := first(q, &m, id); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *meetingRepo) ListByEvent(eventID uint) ([]models.Meeting, error) {
+	var list []models.Meeting
+	err := r.db.Where("event_id = ?", eventID).
+		Preload("Attendees").
+		Preload("Attendees.User", withDeletedUsers).
+		Order("start_at ASC").
+		Find(&list).Error
+	return list, err
+}
+
+func (r *meetingRepo) Create(m *models.Meeting) error {
+	return r.db.Omit("AgendaItems", "Attendees").Create(m).Error
+}
+
+func (r *meetingRepo) Update(m *models.Meeting) error {
+	return r.db.Omit("AgendaItems", "Attendees").Save(m).Error
+}
+
+func (r *meetingRepo) Delete(id uint) error {
+	return r.db.Delete(&models.Meeting{}, id).Error
+}
+
+func (r *meetingRepo) GetAgendaItem(meetingID, itemID uint) (*models.MeetingAgendaItem, error) {
+	var item models.MeetingAgendaItem
+	if err := first(r.db.Where("meeting_id = ?", meetingID), &item, itemID); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *meetingRepo) ListAgenda(meetingID uint) ([]models.MeetingAgendaItem, error) {
+	var list []models.MeetingAgendaItem
+	err := orderAgenda(r.db.Where("meeting_id = ?", meetingID)).Preload("Presenter").Find(&list).Error
+	return list, err
+}
+
+func (r *meetingRepo) AddAgendaItem(item *models.MeetingAgendaItem) error {
+	return r.db.Create(item).Error
+}
+
+func (r *meetingRepo) UpdateAgendaItem(item *models.MeetingAgendaItem) error {
+	return r.db.Omit("Presenter").Save(item).Error
+}
+
+func (r *meetingRepo) DeleteAgendaItem(id uint) error {
+	return r.db.Delete(&models.MeetingAgendaItem{}, id).Error
+}
+
+func (r *meetingRepo) GetAttendee(meetingID, userID uint) (*models.MeetingAttendee, error) {
+	var a models.MeetingAttendee
+	if err := first(r.db.Where("meeting_id = ? AND user_id = ?", meetingID, userID).Preload("User"), &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *meetingRepo) AddAttendee(a *models.MeetingAttendee) error {
+	if err := r.db.Omit("User").Create(a).Error; err != nil {
+		return err
+	}
+	return r.db.Preload("User").First(a, a.ID).Error
+}
+
+func (r *meetingRepo) UpdateAttendee(a *models.MeetingAttendee) error {
+	return r.db.Omit("User").Save(a).Error
+}
+
+func (r *meetingRepo) RemoveAttendee(meetingID, userID uint) error {
+	return r.db.Where("meeting_id = ? AND user_id = ?", meetingID, userID).Delete(&models.MeetingAttendee{}).Error
+}
+
+func (r *meetingRepo) LatestRevision(meetingID uint) (*models.MeetingMinutesRevision, error) {
+	var rev models.MeetingMinutesRevision
+	if err := first(r.db.Where("meeting_id = ?", meetingID).Order("revision DESC"), &rev); err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+func (r *meetingRepo) GetRevision(meetingID uint, revision int) (*models.MeetingMinutesRevision, error) {
+	var rev models.MeetingMinutesRevision
+	q := r.db.Where("meeting_id = ? AND revision = ?", meetingID, revision).Preload("Editor", withDeletedUsers)
+	if err := first(q, &rev); err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+func (r *meetingRepo) ListRevisions(meetingID uint) ([]models.MeetingMinutesRevision, error) {
+	var list []models.MeetingMinutesRevision
+	err := r.db.Where("meeting_id = ?", meetingID).
+		Preload("Editor", withDeletedUsers).
+		Order("revision DESC").
+		Find(&list).Error
+	return list, err
+}
+
+func (r *meetingRepo) AddRevision(rev *models.MeetingMinutesRevision) error {
+	return r.db.Omit("Editor").Create(rev).Error
+}
@@ -0,0 +1,228 @@
+package postgres
+
+import (
+	"slices"
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type messageRepo struct{ db *gorm.DB }
+
+// withDeletedUsers 退会済み（論理削除済み）のユーザーも投稿者として読み込む Preload 用スコープ
+func withDeletedUsers(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+func (r *messageRepo) Get(id uint) (*models.Message, error) {
+	var m models.Message
+	if err := first(r.db, &m, id); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// timeline チャンネルのタイムラインに出るメッセージ
+func (r *messageRepo) timeline(channelID uint) *gorm.DB {
+	return r.db.Where("channel_id = ? AND (parent_message_id IS NULL OR also_sent_to_channel) AND is_deleted = ?", channelID, false).
+		Preload("User", withDeletedUsers).
+		Preload("Reactions").
+		Preload("Reactions.User").
+		Preload("Mentions")
+}
+
+func (r *messageRepo) ListTopLevelBefore(channelID uint, cursor *repository.MessageCursor, limit int) ([]models.Message, error) {
+	q := r.timeline(channelID)
+	if cursor != nil {
+		q = q.Where("(created_at, id) < (?, ?)", cursor.CreatedAt, cursor.ID)
+	}
+	list := []models.Message{}
+	if err := q.Order("created_at DESC, id DESC").Limit(limit).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	slices.Reverse(list)
+	return list, r.loadThreadSummaries(list)
+}
+
+func (r *messageRepo) ListTopLevelAfter(channelID uint, cursor repository.MessageCursor, limit int) ([]models.Message, error) {
+	list := []models.Message{}
+	err := r.timeline(channelID).
+		Where("(created_at, id) > (?, ?)", cursor.CreatedAt, cursor.ID).
+		Order("created_at ASC, id ASC").
+		Limit(limit).
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, r.loadThreadSummaries(list)
+}
+
+func (r *messageRepo) GetThread(id uint) (*models.Message, error) {
+	var m models.Message
+	q := r.db.Preload("User", withDeletedUsers).Preload("Reactions").Preload("Reactions.User").Preload("Mentions")
+	if err := first(q, &m, id); err != nil {
+		return nil, err
+	}
+	list := []models.Message{m}
+	if err := r.loadThreadSummaries(list); err != nil {
+		return nil, err
+	}
+	return &list[0], nil
+}
+
+func (r *messageRepo) ListReplies(parentID uint, limit int) ([]models.Message, error) {
+	list := []models.Message{}
+	err := r.db.Where("parent_message_id = ? AND is_deleted = ?", parentID, false).
+		Preload("User", withDeletedUsers).
+		Preload("Reactions").
+		Preload("Reactions.User").
+		Preload("Mentions").
+		Order("created_at ASC, id ASC").
+		Limit(limit).
+		Find(&list).Error
+	return list, err
+}
+
+// loadThreadSummaries 親メッセージごとの返信数と最後の返信（時刻・投稿者）を1クエリで集計して埋める
+func (r *messageRepo) loadThreadSummaries(list []models.Message) error {
+	ids := make([]uint, 0, len(list))
+	for _, m := range list {
+		if m.ParentMessageID == nil {
+			ids = append(ids, m.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	var rows []struct {
+		ParentMessageID uint
+		UserID          uint
+		CreatedAt       time.Time
+		ReplyCount      int64
+	}
+	err := r.db.Raw(`SELECT DISTINCT ON (parent_message_id)
+			parent_message_id, user_id, created_at, COUNT(*) OVER (PARTITION BY parent_message_id) AS reply_count
+		FROM messages
+		WHERE parent_message_id IN ? AND is_deleted = false AND deleted_at IS NULL
+		ORDER BY parent_message_id, created_at DESC, id DESC`, ids).Scan(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return err
+	}
+
+	userIDs := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		userIDs = append(userIDs, row.UserID)
+	}
+	var users []models.User
+	if err := r.db.Unscoped().Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return err
+	}
+	byID := make(map[uint]*models.User, len(users))
+	for i := range users {
+		byID[users[i].ID] = &users[i]
+	}
+	byParent := make(map[uint]int, len(rows))
+	for i, row := range rows {
+		byParent[row.ParentMessageID] = i
+	}
+	for i := range list {
+		j, ok := byParent[list[i].ID]
+		if !ok || list[i].ParentMessageID != nil {
+			continue
+		}
+		at := rows[j].CreatedAt
+		list[i].ReplyCount, list[i].LastReplyAt, list[i].LastReplyUser = rows[j].ReplyCount, &at, byID[rows[j].UserID]
+	}
+	return nil
+}
+
+func (r *messageRepo) Create(m *models.Message) error {
+	if err := r.db.Create(m).Error; err != nil {
+		return err
+	}
+	return r.db.Preload("User").First(m, m.ID).Error
+}
+
+func (r *messageRepo) Update(m *models.Message) error {
+	if err := r.db.Save(m).Error; err != nil {
+		return err
+	}
+	return r.db.Preload("User").Preload("Reactions").Preload("Reactions.User").Preload("Mentions").First(m, m.ID).Error
+}
+
+func (r *messageRepo) FindReaction(messageID, userID uint, emoji string) (*models.MessageReaction, error) {
+	var reaction models.MessageReaction
+	q := r.db.Where("message_id = ? AND user_id = ? AND emoji = ?", messageID, userID, emoji)
+	if err := first(q, &reaction); err != nil {
+		return nil, err
+	}
+	return &reaction, nil
+}
+
+func (r *messageRepo) AddReaction(reaction *models.MessageReaction) error {
+	if err := r.db.Create(reaction).Error; err != nil {
+		return err
+	}
+	return r.db.Preload("User").First(reaction, reaction.ID).Error
+}
+
+func (r *messageRepo) RemoveReaction(id uint) error {
+	return r.db.Delete(&models.MessageReaction{}, id).Error
+}
+
+func (r *messageRepo) Mentions(messageID uint) ([]models.MessageMention, error) {
+	var list []models.MessageMention
+	err := r.db.Where("message_id = ?", messageID).Order("id").Find(&list).Error
+	return list, err
+}
+
+func (r *messageRepo) SetMentions(messageID uint, list []models.MessageMention) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing []models.MessageMention
+		if err := tx.Where("message_id = ?", messageID).Find(&existing).Error; err != nil {
+			return err
+		}
+		kept := map[uint]models.MessageMention{}
+		for _, m := range existing {
+			kept[m.UserID] = m
+		}
+		for i := range list {
+			list[i].MessageID = messageID
+			old, ok := kept[list[i].UserID]
+			if !ok {
+				if err := tx.Create(&list[i]).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			delete(kept, list[i].UserID)
+			list[i].ID, list[i].CreatedAt = old.ID, old.CreatedAt
+			if old.Kind != list[i].Kind {
+				if err := tx.Model(&old).Update("kind", list[i].Kind).Error; err != nil {
+					return err
+				}
+			}
+		}
+		for _, m := range kept {
+			if err := tx.Delete(&m).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *messageRepo) ListMentionsForUser(userID, beforeID uint, limit int) ([]models.MessageMention, error) {
+	q := r.db.Joins("JOIN messages ON messages.id = message_mentions.message_id AND messages.deleted_at IS NULL AND messages.is_deleted = ?", false).
+		Where("message_mentions.user_id = ?", userID)
+	if beforeID != 0 {
+		q = q.Where("message_mentions.id < ?", beforeID)
+	}
+	var list []models.MessageMention
+	err := q.Preload("Message.User", withDeletedUsers).Preload("Message.Channel").
+		Order("message_mentions.id DESC").Limit(limit).Find(&list).Error
+	return list, err
+}
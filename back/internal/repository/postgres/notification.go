@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"time"
+
+	"sherpa-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type notificationRepo struct{ db *gorm.DB }
+
+func (r *notificationRepo) Get(id uint) (*models.Notification, error) {
+	var n models.Notification
+	if err := first(r.db, &n, id); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func (r *notificationRepo) ListForUser(userID uint, limit int) ([]models.Notification, error) {
+	var list []models.Notification
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Limit(limit).Find(&list).Error
+	return list, err
+}
+
+func (r *notificationRepo) CountUnread(userID uint) (int64, error) {
+	var n int64
+	err := r.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&n).Error
+	return n, err
+}
+
+func (r *notificationRepo) Create(n *models.Notification) error {
+	return r.db.Create(n).Error
+}
+
+func (r *notificationRepo) MarkRead(id uint) error {
+	return r.db.Model(&models.Notification{}).Where("id = ?", id).Update("read_at", time.Now()).Error
+}
+
+func (r *notificationRepo) MarkRelatedRead(userID uint, relatedType string, relatedID uint) error {
+	return r.db.Model(&models.Notification{}).
+		Where("user_id = ? AND related_typ = ? AND related_id = ?", userID, relatedType, relatedID).
+		Update("read_at", time.Now()).Error
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"sherpa-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type orderRepo struct{ db *gorm.DB }
+
+func (r *orderRepo) Get(id uint) (*models.Order, error) {
+	var o models.Order
+	if err := first(r.db.Preload("Ticket", withDeleted).Preload("User", withDeletedUsers), &o, id); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *orderRepo) FindByCheckout(provider, checkoutID string) (*models.Order, error) {
+	var o models.Order
+	if err := first(r.db.Where("provider = ? AND checkout_id = ?", provider, checkoutID), &o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *orderRepo) FindByPayment(provider, paymentID string) (*models.Order, error) {
+	var o models.Order
+	if err := first(r.db.Where("provider = ? AND payment_id = ?", provider, paymentID), &o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *orderRepo) FindPendingByParticipant(participantID uint) (*models.Order, error) {
+	var o models.Order
+	q := r.db.Where("event_participant_id = ? AND status = ?", participantID, models.OrderStatusPending).
+		Order("id DESC")
+	if err := first(q, &o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *orderRepo) ListByEvent(eventID uint) ([]models.Order, error) {
+	list := []models.Order{}
+	err := r.db.Where("event_id = ?", eventID).
+		Preload("Ticket", withDeleted).
+		Preload("User", withDeletedUsers).
+		Order("id DESC").
+		Find(&list).Error
+	return list, err
+}
+
+func (r *orderRepo) ListByUser(userID uint) ([]models.Order, error) {
+	list := []models.Order{}
+	err := r.db.Where("user_id = ?", userID).Preload("Ticket", withDeleted).Order("id DESC").Find(&list).Error
+	return list, err
+}
+
+func (r *orderRepo) Create(o *models.Order) error {
+	return r.db.Omit(clause.Associations).Create(o).Error
+}
+
+func (r *orderRepo) Update(o *models.Order) error {
+	return r.db.Omit(clause.Associations).Save(o).Error
+}
+
+// RecordWebhookEvent ON CONFLICT DO NOTHING なので、同じイベントを同時に受けても片方は記録済みを待ってから false になる
+func (r *orderRepo) RecordWebhookEvent(e *models.PaymentWebhookEvent) (bool, error) {
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(e)
+	return res.RowsAffected == 1, res.Error
+}
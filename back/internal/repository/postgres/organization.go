@@ -0,0 +1,172 @@
+package postgres
+
+import (
+	"sherpa-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type organizationRepo struct{ db *gorm.DB }
+
+func (r *organizationRepo) Get(id uint) (*models.Organization, error) {
+	var o models.Organization
+	if err := first(r.db, &o, id); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *organizationRepo) Create(o *models.Organization) error {
+	return r.db.Create(o).Error
+}
+
+func (r *organizationRepo) Update(o *models.Organization) error {
+	return r.db.Omit(clause.Associations).Save(o).Error
+}
+
+func (r *organizationRepo) Delete(id uint) error {
+	if err := r.db.Where("organization_id = ?", id).Delete(&models.OrganizationMember{}).Error; err != nil {
+		return err
+	}
+	if err := r.db.Where("organization_id = ? AND status = ?", id, models.InvitationStatusPending).
+		Delete(&models.OrganizationInvitation{}).Error; err != nil {
+		return err
+	}
+	return r.db.Delete(&models.Organization{}, id).Error
+}
+
+func (r *organizationRepo) GetMember(orgID, userID uint) (*models.OrganizationMember, error) {
+	var m models.OrganizationMember
+	if err := first(r.db.Where("organization_id = ? AND user_id = ?", orgID, userID), &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *organizationRepo) ListMembers(orgID uint, status string) ([]models.OrganizationMember, error) {
+	var list []models.OrganizationMember
+	err := r.db.Preload("User").Where("organization_id = ? AND status = ?", orgID, status).
+		Order("created_at").Find(&list).Error
+	return list, err
+}
+
+func (r *organizationRepo) ListMemberships(userID uint) ([]models.OrganizationMember, error) {
+	var list []models.OrganizationMember
+	err := r.db.InnerJoins("Organization").Where("organization_members.user_id = ?", userID).
+		Order("organization_members.created_at").Find(&list).Error
+	return list, err
+}
+
+func (r *organizationRepo) MemberOrganizationIDs(userID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.OrganizationMember{}).
+		Where("user_id = ? AND status = ?", userID, models.MemberStatusActive).
+		Pluck("organization_id", &ids).Error
+	return ids, err
+}
+
+func (r *organizationRepo) CountOwners(orgID uint) (int64, error) {
+	var n int64
+	err := r.db.Model(&models.OrganizationMember{}).
+		Where("organization_id = ? AND role = ? AND status = ?", orgID, models.OrgRoleOwner, models.MemberStatusActive).
+		Count(&n).Error
+	return n, err
+}
+
+func (r *organizationRepo) AddMember(m *models.OrganizationMember) error {
+	return r.db.Create(m).Error
+}
+
+func (r *organizationRepo) UpdateMember(m *models.OrganizationMember) error {
+	return r.db.Omit(clause.Associations).Save(m).Error
+}
+
+func (r *organizationRepo) RemoveMember(id uint) error {
+	return r.db.Delete(&models.OrganizationMember{}, id).Error
+}
+
+func (r *organizationRepo) GetInvitation(id uint) (*models.OrganizationInvitation, error) {
+	var inv models.OrganizationInvitation
+	if err := first(r.db, &inv, id); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (r *organizationRepo) FindPendingInvitation(orgID, userID uint) (*models.OrganizationInvitation, error) {
+	var inv models.OrganizationInvitation
+	q := r.db.Where("organization_id = ? AND user_id = ? AND status = ?", orgID, userID, models.InvitationStatusPending)
+	if err := first(q, &inv); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (r *organizationRepo) ListInvitations(orgID uint) ([]models.OrganizationInvitation, error) {
+	var list []models.OrganizationInvitation
+	err := r.db.Where("organization_id = ?", orgID).Preload("User").Preload("Inviter").
+		Order("created_at DESC").Find(&list).Error
+	return list, err
+}
+
+func (r *organizationRepo) ListPendingInvitationsForUser(userID uint) ([]models.OrganizationInvitation, error) {
+	var list []models.OrganizationInvitation
+	err := r.db.Where("user_id = ? AND status = ?", userID, models.InvitationStatusPending).
+		Preload("Organization").Preload("Inviter").Order("created_at DESC").Find(&list).Error
+	return list, err
+}
+
+func (r *organizationRepo) CreateInvitation(inv *models.OrganizationInvitation) error {
+	if err := r.db.Create(inv).Error; err != nil {
+		return err
+	}
+	return r.db.Preload("User").Preload("Inviter").First(inv, inv.ID).Error
+}
+
+func (r *organizationRepo) UpdateInvitation(inv *models.OrganizationInvitation) error {
+	return r.db.Omit(clause.Associations).Save(inv).Error
+}
+
+func (r *organizationRepo) ListDomains(orgID uint) ([]models.OrganizationDomain, error) {
+	var list []models.OrganizationDomain
+	err := r.db.Where("organization_id = ?", orgID).Order("domain").Find(&list).Error
+	return list, err
+}
+
+func (r *organizationRepo) GetDomain(orgID, id uint) (*models.OrganizationDomain, error) {
+	var d models.OrganizationDomain
+	if err := first(r.db.Where("id = ? AND organization_id = ?", id, orgID), &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *organizationRepo) FindDomain(orgID uint, domain string) (*models.OrganizationDomain, error) {
+	var d models.OrganizationDomain
+	if err := first(r.db.Where("organization_id = ? AND domain = ?", orgID, domain), &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *organizationRepo) ListVerifiedDomains(domains []string) ([]models.OrganizationDomain, error) {
+	var list []models.OrganizationDomain
+	if len(domains) == 0 {
+		return list, nil
+	}
+	err := r.db.Where("domain IN ? AND verified_at IS NOT NULL", domains).Find(&list).Error
+	return list, err
+}
+
+func (r *organizationRepo) CreateDomain(d *models.OrganizationDomain) error {
+	return r.db.Create(d).Error
+}
+
+func (r *organizationRepo) UpdateDomain(d *models.OrganizationDomain) error {
+	return r.db.Omit(clause.Associations).Save(d).Error
+}
+
+func (r *organizationRepo) DeleteDomain(id uint) error {
+	return r.db.Delete(&models.OrganizationDomain{}, id).Error
+}
@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"time"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type personalTokenRepo struct{ db *gorm.DB }
+
+func (r *personalTokenRepo) Create(t *models.PersonalAccessToken) error {
+	return r.db.Create(t).Error
+}
+
+func (r *personalTokenRepo) FindByHash(hash string) (*models.PersonalAccessToken, error) {
+	var t models.PersonalAccessToken
+	if err := first(r.db.Where("token_hash = ?", hash), &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *personalTokenRepo) ListActive(userID uint) ([]models.PersonalAccessToken, error) {
+	var list []models.PersonalAccessToken
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL", userID).Order("created_at DESC").Find(&list).Error
+	return list, err
+}
+
+func (r *personalTokenRepo) Revoke(id, userID uint, at time.Time) error {
+	res := r.db.Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", at)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *personalTokenRepo) Touch(id uint, at time.Time) error {
+	return r.db.Model(&models.PersonalAccessToken{}).Where("id = ?", id).UpdateColumn("last_used_at", at).Error
+}
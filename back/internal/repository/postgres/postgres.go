@@ -0,0 +1,51 @@
+// Package postgres repository の GORM（PostgreSQL）実装
+package postgres
+
+import (
+	"errors"
+
+	"sherpa-backend/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// New db を使うリポジトリ一式
+func New(db *gorm.DB) *repository.Repositories {
+	r := &repository.Repositories{
+		Users:         &userRepo{db},
+		Events:        &eventRepo{db},
+		Tasks:         &taskRepo{db},
+		Budgets:       &budgetRepo{db},
+		Meetings:      &meetingRepo{db},
+		Tickets:       &ticketRepo{db},
+		Orders:        &orderRepo{db},
+		PromoCodes:    &promoCodeRepo{db},
+		Channels:      &channelRepo{db},
+		Messages:      &messageRepo{db},
+		Invitations:   &invitationRepo{db},
+		Notifications: &notificationRepo{db},
+
+		Organizations:  &organizationRepo{db},
+		Sessions:       &sessionRepo{db},
+		PersonalTokens: &personalTokenRepo{db},
+		EmailTokens:    &emailTokenRepo{db},
+		Identities:     &identityRepo{db},
+		Accounts:       &accountRepo{db},
+	}
+	// ネストした場合は GORM が SAVEPOINT を使う
+	r.Transaction = func(fn func(tx *repository.Repositories) error) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			return fn(New(tx))
+		})
+	}
+	return r
+}
+
+// first 1件取得。見つからなければ repository.ErrNotFound
+func first(q *gorm.DB, dest interface{}, conds ...interface{}) error {
+	err := q.First(dest, conds...).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return repository.ErrNotFound
+	}
+	return err
+}
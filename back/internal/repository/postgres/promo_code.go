@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"strings"
+
+	"sherpa-backend/internal/models"
+	"sherpa-backend/internal/repository"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type promoCodeRepo struct{ db *gorm.DB }
+
+func (r *promoCodeRepo) Get(id uint) (*models.PromoCode, error) {
+	var c models.PromoCode
+	if err := first(r.db.Preload("Ticket", withDeleted), &c, id); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *promoCodeRepo) GetForUpdate(id uint) (*models.PromoCode, error) {
+	var c models.PromoCode
+	if err := first(r.db.Clauses(clause.Locking{Strength: "UPDATE"}), &c, id); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *promoCodeRepo) FindByCode(eventID uint, code string) (*models.PromoCode, error) {
+	var c models.PromoCode
+	if err := first(r.db.Where("event_id = ? AND code = ?", eventID, strings.ToUpper(code)), &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *promoCodeRepo) ListByEvent(eventID uint) ([]models.PromoCode, error) {
+	list := []models.PromoCode{}
+	err := r.db.Where("event_id = ?", eventID).Preload("Ticket", withDeleted).Order("id").Find(&list).Error
+	return list, err
+}
+
+func (r *promoCodeRepo) Create(c *models.PromoCode) error {
+	return r.db.Omit(clause.Associations).Create(c).Error
+}
+
+// Update RedemptionCount は Redeem だけが増やすので書き戻さない
+func (r *promoCodeRepo) Update(c *models.PromoCode) error {
+	return r.db.Omit(clause.Associations, "RedemptionCount").Save(c).Error
+}
+
+func (r *promoCodeRepo) Delete(id uint) error {
+	return r.db.Delete(&models.PromoCode{}, id).Error
+}
+
+func (r *promoCodeRepo) Redeem(red *models.PromoCodeRedemption) error {
+	if err := r.db.Omit(clause.Associations).Create(red).Error; err != nil {
+		return err
+	}
+	res := r.db.Model(&models.PromoCode{}).Where("id = ?", red.PromoCodeID).
+		Update("redemption_count", gorm.Expr("redemption_count + 1"))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *promoCodeRepo) CountRedemptionsByUser(codeID, userID uint) (int64, error) {
+	var n int64
+	err := r.db.Model(&models.PromoCodeRedemption{}).
+		Where("promo_code_id = ? AND user_id = ?", codeID, userID).
+		Count(&n).Error
+	return n, err
+}
+
+func (r *promoCodeRepo) ListRedemptions(codeID uint) ([]models.PromoCodeRedemption, error) {
+	list := []models.PromoCodeRedemption{}
+	err := r.db.Where("promo_code_id = ?", codeID).
+		Preload("User", withDeletedUsers).
+		Preload("Ticket", withDeleted).
+		Preload("EventParticipant", withDeleted).
+		Order("id").
+		Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"time"
+
+	"sherpa-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type sessionRepo struct{ db *gorm.DB }
+
+func (r *sessionRepo) Get(id uint) (*models.Session, error) {
+	var s models.Session
+	if err := first(r.db, &s, id); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *sessionRepo) Create(s *models.Session) error {
+	return r.db.Create(s).Error
+}
+
+func (r *sessionRepo) FindByRefreshToken(hash string) (*models.Session, error) {
+	var s models.Session
+	if err := first(r.db.Where("refresh_token_hash = ?", hash), &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *sessionRepo) FindByPreviousToken(hash string) (*models.Session, error) {
+	var s models.Session
+	if err := first(r.db.Where("previous_token_hash = ?", hash), &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *sessionRepo) Rotate(s *models.Session, oldHash string) (bool, error) {
+	res := r.db.Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ?", s.ID, oldHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":  s.RefreshTokenHash,
+			"previous_token_hash": s.PreviousTokenHash,
+			"last_used_at":        s.LastUsedAt,
+			"ip_address":          s.IPAddress,
+			"user_agent":          s.UserAgent,
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *sessionRepo) ListActive(userID uint, now time.Time) ([]models.Session, error) {
+	var list []models.Session
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_used_at DESC").Find(&list).Error
+	return list, err
+}
+
+func (r *sessionRepo) Revoke(id uint, at time.Time) error {
+	return r.db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at).Error
+}
+
+func (r *sessionRepo) RevokeAllForUser(userID, exceptID uint, at time.Time) error {
+	return r.db.Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, exceptID).
+		Update("revoked_at", at).Error
+}
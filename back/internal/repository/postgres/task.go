@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"sherpa-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type taskRepo struct{ db *gorm.DB }
+
+func (r *taskRepo) Get(id uint) (*models.Task, error) {
+	var t models.Task
+	if err := first(r.db, &t, id); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *taskRepo) ListByEvent(eventID uint) ([]models.Task, error) {
+	var tasks []models.Task
+	err := r.db.Where("event_id = ?", eventID).Preload("Assignee").Find(&tasks).Error
+	return tasks, err
+}
+
+func (r *taskRepo) ListByMeeting(meetingID uint) ([]models.Task, error) {
+	var tasks []models.Task
+	err := r.db.Where("source_meeting_id = ?", meetingID).Preload("Assignee").Order("id").Find(&tasks).Error
+	return tasks, err
+}
+
+func (r *taskRepo) Create(t *models.Task) error {
+	return r.db.Omit(clause.Associations).Create(t).Error
+}
+
+func (r *taskRepo) Update(t *models.Task) error {
+	if err := r.db.Omit(clause.Associations).Save(t).Error; err != nil {
+		return err
+	}
+	return r.db.Preload("Assignee").First(t, t.ID).Error
+}
+
+func (r *taskRepo) Delete(id uint) error {
+	return r.db.Delete(&models.Task{}, id).Error
+}
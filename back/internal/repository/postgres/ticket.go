@@ -0,0 +1,213 @@
+package postgres
+
+import (
+	"time"
+
+	"sherpa-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ticketRepo struct{ db *gorm.DB }
+
+// activeParticipants 席を確保している登録
+func activeParticipants(db *gorm.DB) *gorm.DB {
+	return db.Where("status IN ?", models.ActiveParticipantStatuses)
+}
+
+func (r *ticketRepo) Get(id uint) (*models.Ticket, error) {
+	var t models.Ticket
+	if err := first(r.db, &t, id); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *ticketRepo) GetForUpdate(id uint) (*models.Ticket, error) {
+	var t models.Ticket
+	if err := first(r.db.Clauses(clause.Locking{Strength: "UPDATE"}), &t, id); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *ticketRepo) ListByEvent(eventID uint) ([]models.Ticket, error) {
+	tickets := []models.Ticket{}
+	if err := r.db.Where("event_id = ?", eventID).Order("id").Find(&tickets).Error; err != nil {
+		return nil, err
+	}
+	if len(tickets) == 0 {
+		return tickets, nil
+	}
+	ids := make([]uint, len(tickets))
+	for i, t := range tickets {
+		ids[i] = t.ID
+	}
+	var counts []struct {
+		TicketID uint
+		Count    int64
+	}
+	err := activeParticipants(r.db.Model(&models.EventParticipant{})).
+		Select("ticket_id, COUNT(*) AS count").
+		Where("ticket_id IN ?", ids).
+		Group("ticket_id").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	sold := map[uint]int64{}
+	for _, c := range counts {
+		sold[c.TicketID] = c.Count
+	}
+	for i := range tickets {
+		tickets[i].Sold = sold[tickets[i].ID]
+	}
+	return tickets, nil
+}
+
+func (r *ticketRepo) Create(t *models.Ticket) error {
+	return r.db.Omit(clause.Associations).Create(t).Error
+}
+
+func (r *ticketRepo) Update(t *models.Ticket) error {
+	return r.db.Omit(clause.Associations).Save(t).Error
+}
+
+func (r *ticketRepo) Delete(id uint) error {
+	return r.db.Delete(&models.Ticket{}, id).Error
+}
+
+func (r *ticketRepo) CountActive(ticketID uint) (int64, error) {
+	var n int64
+	err := activeParticipants(r.db.Model(&models.EventParticipant{})).Where("ticket_id = ?", ticketID).Count(&n).Error
+	return n, err
+}
+
+func (r *ticketRepo) CountOpen(ticketID uint) (int64, error) {
+	var n int64
+	err := r.db.Model(&models.EventParticipant{}).
+		Where("ticket_id = ? AND status IN ?", ticketID, models.OpenParticipantStatuses).Count(&n).Error
+	return n, err
+}
+
+func (r *ticketRepo) GetParticipant(id uint) (*models.EventParticipant, error) {
+	var p models.EventParticipant
+	if err := first(r.db.Preload("Ticket").Preload("User", withDeletedUsers), &p, id); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *ticketRepo) FindParticipant(ticketID, userID uint) (*models.EventParticipant, error) {
+	var p models.EventParticipant
+	if err := first(r.db.Where("ticket_id = ? AND user_id = ?", ticketID, userID), &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *ticketRepo) FindOpenParticipant(eventID, userID uint) (*models.EventParticipant, error) {
+	var p models.EventParticipant
+	q := r.db.Where("status IN ?", models.OpenParticipantStatuses).
+		Where("user_id = ?", userID).
+		Where("ticket_id IN (?)", r.db.Model(&models.Ticket{}).Select("id").Where("event_id = ?", eventID))
+	if err := first(q, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *ticketRepo) ListParticipants(eventID, ticketID uint, status models.ParticipantStatus) ([]models.EventParticipant, error) {
+	list := []models.EventParticipant{}
+	q := r.db.Where("ticket_id IN (?)", r.db.Model(&models.Ticket{}).Select("id").Where("event_id = ?", eventID))
+	if ticketID != 0 {
+		q = q.Where("ticket_id = ?", ticketID)
+	}
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	err := q.Preload("User", withDeletedUsers).Preload("Ticket").Order("created_at, id").Find(&list).Error
+	return list, err
+}
+
+func (r *ticketRepo) ListParticipantsByUser(userID uint) ([]models.EventParticipant, error) {
+	list := []models.EventParticipant{}
+	err := r.db.Where("user_id = ?", userID).
+		Preload("Ticket", withDeleted).
+		Preload("Ticket.Event", withDeleted).
+		Order("created_at DESC, id DESC").
+		Find(&list).Error
+	return list, err
+}
+
+// withDeleted 削除済みのチケット・イベントへの登録も履歴として読み込む
+func withDeleted(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+func (r *ticketRepo) CreateParticipant(p *models.EventParticipant) error {
+	return r.db.Omit(clause.Associations).Create(p).Error
+}
+
+func (r *ticketRepo) UpdateParticipant(p *models.EventParticipant) error {
+	return r.db.Omit(clause.Associations).Save(p).Error
+}
+
+func (r *ticketRepo) NextWaitlisted(ticketID uint) (*models.EventParticipant, error) {
+	var p models.EventParticipant
+	q := r.db.Where("ticket_id = ? AND status = ?", ticketID, models.ParticipantStatusWaitlisted).
+		Order("waitlisted_at, id")
+	if err := first(q, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *ticketRepo) WaitlistPosition(p *models.EventParticipant) (int64, error) {
+	var n int64
+	err := r.db.Model(&models.EventParticipant{}).
+		Where("ticket_id = ? AND status = ?", p.TicketID, models.ParticipantStatusWaitlisted).
+		Where("(waitlisted_at, id) <= (?, ?)", p.WaitlistedAt, p.ID).
+		Count(&n).Error
+	return n, err
+}
+
+func (r *ticketRepo) ExpiredOffers(ticketID uint, now time.Time) ([]models.EventParticipant, error) {
+	var list []models.EventParticipant
+	err := r.db.Where("ticket_id = ? AND status = ? AND offer_expires_at <= ?", ticketID, models.ParticipantStatusOffered, now).
+		Order("offer_expires_at, id").
+		Find(&list).Error
+	return list, err
+}
+
+func (r *ticketRepo) TicketIDsWithWaitlist(now time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.EventParticipant{}).
+		Distinct("ticket_id").
+		Where("status = ? OR (status = ? AND offer_expires_at <= ?)",
+			models.ParticipantStatusWaitlisted, models.ParticipantStatusOffered, now).
+		Order("ticket_id").
+		Pluck("ticket_id", &ids).Error
+	return ids, err
+}
+
+func (r *ticketRepo) CheckIn(id, staffID uint, at time.Time) (bool, error) {
+	res := r.db.Model(&models.EventParticipant{}).
+		Where("id = ? AND status = ? AND checked_in_at IS NULL", id, models.ParticipantStatusConfirmed).
+		Updates(map[string]interface{}{"checked_in_at": at, "checked_in_by": staffID})
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *ticketRepo) CountCheckIns(eventID uint) (int64, int64, error) {
+	var row struct {
+		CheckedIn int64
+		Confirmed int64
+	}
+	err := r.db.Model(&models.EventParticipant{}).
+		Select("COUNT(checked_in_at) AS checked_in, COUNT(*) AS confirmed").
+		Where("status = ?", models.ParticipantStatusConfirmed).
+		Where("ticket_id IN (?)", r.db.Model(&models.Ticket{}).Select("id").Where("event_id = ?", eventID)).
+		Scan(&row).Error
+	return row.CheckedIn, row.Confirmed, err
+}
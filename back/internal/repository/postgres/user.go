@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"sherpa-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type userRepo struct{ db *gorm.DB }
+
+func (r *userRepo) Get(id uint) (*models.User, error) {
+	var u models.User
+	if err := first(r.db, &u, id); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *userRepo) FindByEmail(email string) (*models.User, error) {
+	var u models.User
+	if err := first(r.db.Where("LOWER(email) = LOWER(?)", email), &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *userRepo) ListByOrganization(orgID uint) ([]models.User, error) {
+	var members []models.OrganizationMember
+	if err := r.db.Where("organization_id = ? AND status = ?", orgID, models.MemberStatusActive).
+		Preload("User").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	users := make([]models.User, 0, len(members))
+	for _, m := range members {
+		users = append(users, m.User)
+	}
+	return users, nil
+}
+
+func (r *userRepo) ListByIDs(ids []uint) ([]models.User, error) {
+	var list []models.User
+	if len(ids) == 0 {
+		return list, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&list).Error
+	return list, err
+}
+
+func (r *userRepo) Search(q string, limit int) ([]models.User, error) {
+	var list []models.User
+	pattern := "%" + q + "%"
+	err := r.db.Where("name ILIKE ? OR email ILIKE ?", pattern, pattern).Order("id").Limit(limit).Find(&list).Error
+	return list, err
+}
+
+func (r *userRepo) Create(u *models.User) error {
+	return r.db.Create(u).Error
+}
+
+func (r *userRepo) Update(u *models.User) error {
+	return r.db.Omit(clause.Associations).Save(u).Error
+}
+
+func (r *userRepo) Delete(id uint) error {
+	return r.db.Delete(&models.User{}, id).Error
+}
+
+func (r *userRepo) SetActiveOrganization(userID uint, orgID *uint) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userID).
+		Update("active_organization_id", orgID).Error
+}
+
+func (r *userRepo) ClearActiveOrganization(orgID uint) error {
+	return r.db.Model(&models.User{}).Where("active_organization_id = ?", orgID).
+		Update("active_organization_id", nil).Error
+}
@@ -0,0 +1,449 @@
+// Package repository ハンドラが使う集約ごとの永続化インターフェース。
+// 実装は postgres（GORM）と memory（テスト用のインメモリ）の2つ。
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"sherpa-backend/internal/models"
+)
+
+// ErrNotFound 対象のレコードが存在しない（論理削除済みを含む）
+var ErrNotFound = errors.New("record not found")
+
+// Repositories 集約ごとのリポジトリ一式。handlers.Repos に設定して使う
+type Repositories struct {
+	Users         UserRepository
+	Events        EventRepository
+	Tasks         TaskRepository
+	Budgets       BudgetRepository
+	Meetings      MeetingRepository
+	Tickets       TicketRepository
+	Orders        OrderRepository
+	PromoCodes    PromoCodeRepository
+	Channels      ChannelRepository
+	Messages      MessageRepository
+	Invitations   InvitationRepository
+	Notifications NotificationRepository
+
+	Organizations  OrganizationRepository
+	Sessions       SessionRepository
+	PersonalTokens PersonalTokenRepository
+	EmailTokens    EmailTokenRepository
+	Identities     IdentityRepository
+	Accounts       AccountRepository
+
+	// Transaction fn の中の書き込みを1トランザクションにまとめる。
+	// fn には同じトランザクションを使うリポジトリ一式が渡り、fn がエラーを返すとすべて取り消される
+	Transaction func(fn func(tx *Repositories) error) error
+}
+
+// UserRepository ユーザー
+type UserRepository interface {
+	Get(id uint) (*models.User, error)
+	// FindByEmail 大文字・小文字を区別せずに探す
+	FindByEmail(email string) (*models.User, error)
+	// ListByIDs ids のユーザー（見つからない ID は無視する）
+	ListByIDs(ids []uint) ([]models.User, error)
+	// ListByOrganization 組織の承認済みメンバー
+	ListByOrganization(orgID uint) ([]models.User, error)
+	// Search 名前・メールアドレスの部分一致（大文字・小文字を区別しない）で limit 件
+	Search(q string, limit int) ([]models.User, error)
+	Create(u *models.User) error
+	Update(u *models.User) error
+	// Delete 論理削除する。メッセージの投稿者などとしては引き続き読み込まれる
+	Delete(id uint) error
+	// SetActiveOrganization アクティブ組織を設定する。orgID が nil なら外す
+	SetActiveOrganization(userID uint, orgID *uint) error
+	// ClearActiveOrganization orgID をアクティブ組織にしているユーザーの設定を外す
+	ClearActiveOrganization(orgID uint) error
+}
+
+// EventRepository イベントとスタッフ
+type EventRepository interface {
+	Get(id uint) (*models.Event, error)
+	// GetForUpdate トランザクション内で行ロックを取って取得する。イベント内で1人1件の参加登録チェックの直列化に使う
+	GetForUpdate(id uint) (*models.Event, error)
+	// GetDetail 組織・スタッフ（User 付き）・タスク・予算を含めて取得
+	GetDetail(id uint) (*models.Event, error)
+	// ListForStaff userID がスタッフのイベントのうち orgIDs の組織に属するもの（Organization 付き）
+	ListForStaff(userID uint, orgIDs []uint) ([]models.Event, error)
+	Create(e *models.Event) error
+	Update(e *models.Event) error
+	Delete(id uint) error
+
+	// CountByOrganization 組織のイベント数
+	CountByOrganization(orgID uint) (int64, error)
+
+	GetStaff(eventID, userID uint) (*models.EventStaff, error)
+	StaffUserIDs(eventID uint) ([]uint, error)
+	// ListStaff イベントのスタッフ（User 付き）
+	ListStaff(eventID uint) ([]models.EventStaff, error)
+	// ListStaffByUser ユーザーがスタッフになっているイベントの行（Event 付き）
+	ListStaffByUser(userID uint) ([]models.EventStaff, error)
+	AddStaff(s *models.EventStaff) error
+	UpdateStaff(s *models.EventStaff) error
+}
+
+// TaskRepository タスク
+type TaskRepository interface {
+	Get(id uint) (*models.Task, error)
+	// ListByEvent イベントのタスク（Assignee 付き）
+	ListByEvent(eventID uint) ([]models.Task, error)
+	// ListByMeeting 議事録から作ったタスク（Assignee 付き）
+	ListByMeeting(meetingID uint) ([]models.Task, error)
+	Create(t *models.Task) error
+	// Update 保存して Assignee を読み込み直す
+	Update(t *models.Task) error
+	Delete(id uint) error
+}
+
+// BudgetRepository 予算項目
+type BudgetRepository interface {
+	Get(id uint) (*models.Budget, error)
+	ListByEvent(eventID uint) ([]models.Budget, error)
+	Create(b *models.Budget) error
+	Update(b *models.Budget) error
+	Delete(id uint) error
+	// FindByTicket チケット売上として自動計上する収入項目
+	FindByTicket(ticketID uint) (*models.Budget, error)
+	// AddActual 実績額に delta を加える（読み込まずに UPDATE するので同時に計上しても失われない）
+	AddActual(id uint, delta int) error
+}
+
+// MeetingRepository 会議とアジェンダ・参加者・議事録の版
+type MeetingRepository interface {
+	Get(id uint) (*models.Meeting, error)
+	// GetDetail アジェンダ（順番どおり・Presenter 付き）と参加者（User 付き）を含めて取得
+	GetDetail(id uint) (*models.Meeting, error)
+	// ListByEvent 開始日時順。参加者（User 付き）を含む
+	ListByEvent(eventID uint) ([]models.Meeting, error)
+	Create(m *models.Meeting) error
+	Update(m *models.Meeting) error
+	Delete(id uint) error
+
+	GetAgendaItem(meetingID, itemID uint) (*models.MeetingAgendaItem, error)
+	ListAgenda(meetingID uint) ([]models.MeetingAgendaItem, error)
+	AddAgendaItem(item *models.MeetingAgendaItem) error
+	UpdateAgendaItem(item *models.MeetingAgendaItem) error
+	DeleteAgendaItem(id uint) error
+
+	// GetAttendee User 付き
+	GetAttendee(meetingID, userID uint) (*models.MeetingAttendee, error)
+	// AddAttendee 追加して User を読み込む
+	AddAttendee(a *models.MeetingAttendee) error
+	UpdateAttendee(a *models.MeetingAttendee) error
+	RemoveAttendee(meetingID, userID uint) error
+
+	// LatestRevision 最新の版。まだなければ repository.ErrNotFound
+	LatestRevision(meetingID uint) (*models.MeetingMinutesRevision, error)
+	GetRevision(meetingID uint, revision int) (*models.MeetingMinutesRevision, error)
+	// ListRevisions 新しい順（Editor 付き）
+	ListRevisions(meetingID uint) ([]models.MeetingMinutesRevision, error)
+	AddRevision(r *models.MeetingMinutesRevision) error
+}
+
+// TicketRepository チケット種別と参加登録
+type TicketRepository interface {
+	Get(id uint) (*models.Ticket, error)
+	// GetForUpdate トランザクション内で行ロック（SELECT ... FOR UPDATE）を取って取得する。定員チェックの直列化に使う
+	GetForUpdate(id uint) (*models.Ticket, error)
+	// ListByEvent 作成順。Sold を数えて返す
+	ListByEvent(eventID uint) ([]models.Ticket, error)
+	Create(t *models.Ticket) error
+	Update(t *models.Ticket) error
+	Delete(id uint) error
+	// CountActive 保留中・確定済みの参加登録数
+	CountActive(ticketID uint) (int64, error)
+	// CountOpen 取り消されていない（キャンセル待ちを含む）参加登録数
+	CountOpen(ticketID uint) (int64, error)
+
+	// GetParticipant Ticket・User 付き
+	GetParticipant(id uint) (*models.EventParticipant, error)
+	// FindParticipant チケットとユーザーの登録（取り消し済みを含む）
+	FindParticipant(ticketID, userID uint) (*models.EventParticipant, error)
+	// FindOpenParticipant イベント内でユーザーの取り消されていない登録（キャンセル待ちを含む）
+	FindOpenParticipant(eventID, userID uint) (*models.EventParticipant, error)
+	// ListParticipants 登録順（User・Ticket 付き）。ticketID が 0 ならイベント全体、status が空なら全ステータス
+	ListParticipants(eventID, ticketID uint, status models.ParticipantStatus) ([]models.EventParticipant, error)
+	// ListParticipantsByUser ユーザーの登録（Ticket・Ticket.Event 付き）。新しい順
+	ListParticipantsByUser(userID uint) ([]models.EventParticipant, error)
+	CreateParticipant(p *models.EventParticipant) error
+	UpdateParticipant(p *models.EventParticipant) error
+
+	// NextWaitlisted 次に繰り上げるキャンセル待ち（WaitlistedAt の古い順）。いなければ repository.ErrNotFound
+	NextWaitlisted(ticketID uint) (*models.EventParticipant, error)
+	// WaitlistPosition キャンセル待ちの何番目か（1始まり）
+	WaitlistPosition(p *models.EventParticipant) (int64, error)
+	// ExpiredOffers 確定期限が now を過ぎた繰り上げ
+	ExpiredOffers(ticketID uint, now time.Time) ([]models.EventParticipant, error)
+	// TicketIDsWithWaitlist キャンセル待ちか期限切れの繰り上げがあるチケット（定期的な繰り上げ処理の対象）
+	TicketIDsWithWaitlist(now time.Time) ([]uint, error)
+
+	// CheckIn 確定済みで未受付の登録だけを受付済みにする。条件に合わず更新しなかったら false（二重受付の判定に使う）
+	CheckIn(id, staffID uint, at time.Time) (bool, error)
+	// CountCheckIns イベントの確定済み登録のうち受付済みの数と全体の数
+	CountCheckIns(eventID uint) (checkedIn, confirmed int64, err error)
+}
+
+// PromoCodeRepository 割引コードと利用記録
+type PromoCodeRepository interface {
+	// Get Ticket 付き
+	Get(id uint) (*models.PromoCode, error)
+	// GetForUpdate トランザクション内で行ロックを取って取得する。利用回数のチェックの直列化に使う
+	GetForUpdate(id uint) (*models.PromoCode, error)
+	// FindByCode イベント内のコード（大文字で比較する）
+	FindByCode(eventID uint, code string) (*models.PromoCode, error)
+	// ListByEvent 作成順（Ticket 付き）
+	ListByEvent(eventID uint) ([]models.PromoCode, error)
+	Create(c *models.PromoCode) error
+	Update(c *models.PromoCode) error
+	Delete(id uint) error
+
+	// Redeem 利用記録を作り、コードの RedemptionCount を1増やす
+	Redeem(r *models.PromoCodeRedemption) error
+	// CountRedemptionsByUser ユーザーがコードを使った回数
+	CountRedemptionsByUser(codeID, userID uint) (int64, error)
+	// ListRedemptions 利用順（User・Ticket・EventParticipant 付き）
+	ListRedemptions(codeID uint) ([]models.PromoCodeRedemption, error)
+}
+
+// OrderRepository 有料チケットの注文と決済 Webhook の処理記録
+type OrderRepository interface {
+	// Get Ticket・User 付き
+	Get(id uint) (*models.Order, error)
+	FindByCheckout(provider, checkoutID string) (*models.Order, error)
+	FindByPayment(provider, paymentID string) (*models.Order, error)
+	// FindPendingByParticipant 参加登録の支払い待ちの注文（新しいもの）
+	FindPendingByParticipant(participantID uint) (*models.Order, error)
+	// ListByEvent 新しい順（Ticket・User 付き）
+	ListByEvent(eventID uint) ([]models.Order, error)
+	// ListByUser 新しい順（Ticket 付き）
+	ListByUser(userID uint) ([]models.Order, error)
+	Create(o *models.Order) error
+	Update(o *models.Order) error
+
+	// RecordWebhookEvent 処理した Webhook を記録する。同じプロバイダ・イベントIDが記録済みなら false
+	RecordWebhookEvent(e *models.PaymentWebhookEvent) (bool, error)
+}
+
+// ChannelRepository チャンネルとメンバー
+type ChannelRepository interface {
+	Get(id uint) (*models.Channel, error)
+	// ListByEvent 公開チャンネルを先に、名前順
+	ListByEvent(eventID uint) ([]models.Channel, error)
+	CountByEvent(eventID uint) (int64, error)
+	Create(ch *models.Channel) error
+	Update(ch *models.Channel) error
+	Delete(id uint) error
+
+	// Members チャンネルのメンバー（User 付き）
+	Members(channelID uint) ([]models.ChannelMember, error)
+	GetMember(channelID, userID uint) (*models.ChannelMember, error)
+	// AddMember 追加して User を読み込む
+	AddMember(m *models.ChannelMember) error
+	RemoveMember(channelID, userID uint) error
+	// MemberChannelIDs イベント内で userID がメンバーになっているチャンネルのID
+	MemberChannelIDs(eventID, userID uint) ([]uint, error)
+	// MarkRead メンバーの既読位置を at まで進める（戻さない）。メンバーでなければ ErrNotFound
+	MarkRead(channelID, userID uint, at time.Time) (*models.ChannelMember, error)
+	// UnreadCounts userID がメンバーのチャンネルごとの未読数と、そのうち userID あてのメンション数（未読のないチャンネルは含めない）。
+	// 既読位置（未設定なら参加日時）より後のタイムラインのメッセージのうち、自分以外の投稿を数える
+	UnreadCounts(userID uint) ([]ChannelUnread, error)
+}
+
+// ChannelUnread チャンネルの未読数
+type ChannelUnread struct {
+	ChannelID uint
+	EventID   uint
+	Count     int64
+	Mentions  int64
+}
+
+// MessageCursor タイムラインの位置。(CreatedAt, ID) の順で並べるので、同じ時刻のメッセージでも順序が変わらない
+type MessageCursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// CursorOf m の位置
+func CursorOf(m *models.Message) MessageCursor {
+	return MessageCursor{CreatedAt: m.CreatedAt, ID: m.ID}
+}
+
+// MessageRepository メッセージとリアクション
+type MessageRepository interface {
+	Get(id uint) (*models.Message, error)
+	// ListTopLevelBefore チャンネルのタイムライン（スレッド返信はチャンネルにも送信したものだけ、削除済みは除く）のうち
+	// cursor より前の直近 limit 件を古い順で返す。cursor が nil なら最新から。
+	// 投稿者は退会済みも含めて読み込み、スレッドの返信数・最後の返信を付ける
+	ListTopLevelBefore(channelID uint, cursor *MessageCursor, limit int) ([]models.Message, error)
+	// ListTopLevelAfter タイムラインのうち cursor より後の limit 件を古い順で返す
+	ListTopLevelAfter(channelID uint, cursor MessageCursor, limit int) ([]models.Message, error)
+	// GetThread スレッドの親メッセージ（投稿者・リアクション・返信の集計付き）
+	GetThread(id uint) (*models.Message, error)
+	// ListReplies スレッドの返信（削除済みを除く）古い順の limit 件
+	ListReplies(parentID uint, limit int) ([]models.Message, error)
+	// Create 保存して投稿者を読み込む
+	Create(m *models.Message) error
+	// Update 保存して投稿者・リアクションを読み込み直す
+	Update(m *models.Message) error
+
+	FindReaction(messageID, userID uint, emoji string) (*models.MessageReaction, error)
+	// AddReaction 保存して User を読み込む
+	AddReaction(r *models.MessageReaction) error
+	RemoveReaction(id uint) error
+
+	// Mentions メッセージのメンション
+	Mentions(messageID uint) ([]models.MessageMention, error)
+	// SetMentions メッセージのメンションを list に置き換える。引き続きメンションされているユーザーの行は
+	// ID を変えずに種類だけ更新する（編集でメンション一覧の並びが変わらないように）。list には ID を入れて返す
+	SetMentions(messageID uint, list []models.MessageMention) error
+	// ListMentionsForUser userID あてのメンション（削除済みのメッセージは除く）を新しい順に limit 件。
+	// beforeID が 0 でなければそれより前から。Message（投稿者・チャンネル付き）を読み込む
+	ListMentionsForUser(userID, beforeID uint, limit int) ([]models.MessageMention, error)
+}
+
+// InvitationRepository イベントへの招待
+type InvitationRepository interface {
+	Get(id uint) (*models.EventInvitation, error)
+	// ListByEvent イベントの招待（User・Inviter 付き）
+	ListByEvent(eventID uint) ([]models.EventInvitation, error)
+	// ListPendingForUser 自分あての未回答の招待（Event・Inviter 付き）
+	ListPendingForUser(userID uint) ([]models.EventInvitation, error)
+	FindPending(eventID, userID uint) (*models.EventInvitation, error)
+	PendingUserIDs(eventID uint) ([]uint, error)
+	// Create 保存して User・Inviter を読み込む
+	Create(inv *models.EventInvitation) error
+	Update(inv *models.EventInvitation) error
+}
+
+// NotificationRepository 通知
+type NotificationRepository interface {
+	Get(id uint) (*models.Notification, error)
+	// ListForUser 新しい順の limit 件
+	ListForUser(userID uint, limit int) ([]models.Notification, error)
+	CountUnread(userID uint) (int64, error)
+	Create(n *models.Notification) error
+	MarkRead(id uint) error
+	// MarkRelatedRead 招待などに紐づく通知を既読にする
+	MarkRelatedRead(userID uint, relatedType string, relatedID uint) error
+}
+
+// OrganizationRepository 組織とメンバー・招待・メールドメイン
+type OrganizationRepository interface {
+	Get(id uint) (*models.Organization, error)
+	Create(o *models.Organization) error
+	Update(o *models.Organization) error
+	// Delete 組織を削除する。メンバーと未回答の招待も削除する
+	Delete(id uint) error
+
+	// GetMember 承認待ちを含むメンバーの行
+	GetMember(orgID, userID uint) (*models.OrganizationMember, error)
+	// ListMembers status のメンバー（User 付き）。参加順
+	ListMembers(orgID uint, status string) ([]models.OrganizationMember, error)
+	// ListMemberships ユーザーのメンバーシップ（承認待ちを含む・Organization 付き）。参加順で、削除済みの組織は含めない
+	ListMemberships(userID uint) ([]models.OrganizationMember, error)
+	// MemberOrganizationIDs ユーザーが承認済みメンバーとして所属する組織のID
+	MemberOrganizationIDs(userID uint) ([]uint, error)
+	// CountOwners 承認済みの owner 数
+	CountOwners(orgID uint) (int64, error)
+	AddMember(m *models.OrganizationMember) error
+	UpdateMember(m *models.OrganizationMember) error
+	RemoveMember(id uint) error
+
+	GetInvitation(id uint) (*models.OrganizationInvitation, error)
+	FindPendingInvitation(orgID, userID uint) (*models.OrganizationInvitation, error)
+	// ListInvitations 組織の招待。新しい順（User・Inviter 付き）
+	ListInvitations(orgID uint) ([]models.OrganizationInvitation, error)
+	// ListPendingInvitationsForUser 自分あての未回答の招待。新しい順（Organization・Inviter 付き）
+	ListPendingInvitationsForUser(userID uint) ([]models.OrganizationInvitation, error)
+	// CreateInvitation 保存して User・Inviter を読み込む
+	CreateInvitation(inv *models.OrganizationInvitation) error
+	UpdateInvitation(inv *models.OrganizationInvitation) error
+
+	// ListDomains 組織のドメイン（ドメイン名順）
+	ListDomains(orgID uint) ([]models.OrganizationDomain, error)
+	GetDomain(orgID, id uint) (*models.OrganizationDomain, error)
+	FindDomain(orgID uint, domain string) (*models.OrganizationDomain, error)
+	// ListVerifiedDomains domains のうちいずれかの組織で確認済みのもの
+	ListVerifiedDomains(domains []string) ([]models.OrganizationDomain, error)
+	CreateDomain(d *models.OrganizationDomain) error
+	UpdateDomain(d *models.OrganizationDomain) error
+	DeleteDomain(id uint) error
+}
+
+// SessionRepository ログインセッション
+type SessionRepository interface {
+	Get(id uint) (*models.Session, error)
+	Create(s *models.Session) error
+	FindByRefreshToken(hash string) (*models.Session, error)
+	// FindByPreviousToken ローテーション済みの1つ前のリフレッシュトークンのセッション（再利用の検知に使う）
+	FindByPreviousToken(hash string) (*models.Session, error)
+	// Rotate リフレッシュトークンが oldHash のままなら s の RefreshTokenHash・PreviousTokenHash・LastUsedAt・IPAddress・UserAgent を保存する。
+	// 先に別のリクエストがローテーションしていたら false（同じトークンでの同時リフレッシュは1件だけ成功させる）
+	Rotate(s *models.Session, oldHash string) (bool, error)
+	// ListActive 失効・期限切れでないセッション。最終利用の新しい順
+	ListActive(userID uint, now time.Time) ([]models.Session, error)
+	// Revoke 失効させる（失効済みなら何もしない）
+	Revoke(id uint, at time.Time) error
+	// RevokeAllForUser ユーザーのセッションを exceptID 以外すべて失効させる（exceptID が 0 なら全部）
+	RevokeAllForUser(userID, exceptID uint, at time.Time) error
+}
+
+// PersonalTokenRepository パーソナルアクセストークン
+type PersonalTokenRepository interface {
+	Create(t *models.PersonalAccessToken) error
+	FindByHash(hash string) (*models.PersonalAccessToken, error)
+	// ListActive 失効していないトークン。新しい順
+	ListActive(userID uint) ([]models.PersonalAccessToken, error)
+	// Revoke ユーザーの失効していないトークンを失効させる。該当しなければ repository.ErrNotFound
+	Revoke(id, userID uint, at time.Time) error
+	// Touch 最終利用日時を記録する
+	Touch(id uint, at time.Time) error
+}
+
+// EmailTokenRepository メール確認・パスワード再設定・マジックリンクの使い捨てトークン
+type EmailTokenRepository interface {
+	Create(t *models.EmailToken) error
+	// Consume 未使用で期限内のトークンを使用済みにして返す。該当しなければ repository.ErrNotFound（同時に使われても成功は1回だけ）
+	Consume(hash string, purpose models.EmailTokenPurpose, now time.Time) (*models.EmailToken, error)
+}
+
+// IdentityRepository 外部 IdP のアカウントとユーザーの紐付け
+type IdentityRepository interface {
+	Find(provider, subject string) (*models.UserIdentity, error)
+	Create(link *models.UserIdentity) error
+}
+
+// AccountExport 個人データのエクスポートに含める、ユーザーに紐づく行
+type AccountExport struct {
+	User                models.User
+	OrganizationMembers []models.OrganizationMember // Organization 付き
+	EventStaffs         []models.EventStaff         // Event 付き
+	Participants        []models.EventParticipant   // Ticket 付き
+	Orders              []models.Order
+	Redemptions         []models.PromoCodeRedemption
+	Messages            []models.Message
+	Reactions           []models.MessageReaction
+	Tasks               []models.Task // 担当しているタスク
+	Attendances         []models.MeetingAttendee
+	MinutesRevisions    []models.MeetingMinutesRevision // 編集した議事録の版
+	Notifications       []models.Notification
+	EventInvitations    []models.EventInvitation        // 受けた招待と送った招待
+	OrgInvitations      []models.OrganizationInvitation // 受けた招待と送った招待
+	Sessions            []models.Session
+	PersonalTokens      []models.PersonalAccessToken
+	Identities          []models.UserIdentity
+}
+
+// AccountRepository 個人データのエクスポートと退会
+type AccountRepository interface {
+	// Export ユーザーに紐づくデータを集める（タスクは期限順、それ以外は作成順）
+	Export(userID uint) (*AccountExport, error)
+	// Purge 退会するユーザーのデータを消す。タスクの担当を外し、スタッフ・チャンネル・組織のメンバーシップ、
+	// リアクション、メンション、通知、未回答の招待、外部 IdP との紐付け、メールのトークンを削除し、セッションとトークンを失効させる。
+	// ユーザーの行と投稿したメッセージは残す
+	Purge(userID uint, at time.Time) error
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/google/generative-ai-go/genai"
 	"google.golang.org/api/option"
@@ -78,6 +79,15 @@ JSON配列のみを返し、他のテキストは含めないでください。`
 
 // parseTasksFromResponse レスポンステキストからタスク配列を抽出（```json ブロックや生JSONに対応）
 func parseTasksFromResponse(text string) ([]TaskSuggestion, error) {
+	var tasks []TaskSuggestion
+	if err := json.Unmarshal([]byte(jsonArrayText(text)), &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// jsonArrayText ```json ブロックや前後の余分なテキストを除き、JSON 配列の部分だけを返す
+func jsonArrayText(text string) string {
 	text = strings.TrimSpace(text)
 	// ```json ... ``` ブロックを探す
 	if idx := strings.Index(text, "```json"); idx >= 0 {
@@ -97,12 +107,7 @@ func parseTasksFromResponse(text string) ([]TaskSuggestion, error) {
 			text = text[i : j+1]
 		}
 	}
-
-	var tasks []TaskSuggestion
-	if err := json.Unmarshal([]byte(text), &tasks); err != nil {
-		return nil, err
-	}
-	return tasks, nil
+	return text
 }
 
 // TaskSuggestion AIが生成したタスクの提案
@@ -228,6 +233,76 @@ func indexAt(s, sub string, from int) int {
 	return from + idx
 }
 
+// Enabled GEMINI_API_KEY が設定されていて AI を呼び出せるか
+func (s *GeminiService) Enabled() bool {
+	return s.client != nil
+}
+
+// ExtractActionItems 議事録から AI でアクションアイテムを抽出する。
+// members は担当者として使える名前。AI が無効なら空で返す
+func (s *GeminiService) ExtractActionItems(minutes string, members []string, ref time.Time) ([]ActionItem, error) {
+	if s.client == nil {
+		return []ActionItem{}, nil
+	}
+
+	ctx := context.Background()
+	model := s.client.GenerativeModel("gemini-2.5-flash")
+	model.GenerationConfig = genai.GenerationConfig{
+		ResponseMIMEType: "application/json",
+	}
+
+	prompt := fmt.Sprintf(`次の会議の議事録から、誰かが会議後に対応すべきアクションアイテムを抽出してください。
+会議の日付は %s です。担当者は次のメンバーから選び、分からなければ空文字にしてください: %s
+JSON配列形式で、各要素は "title"（簡潔な作業内容）、"assignee"（担当者名）、"due"（期限。YYYY-MM-DD 形式、分からなければ空文字）を持つオブジェクトにしてください。
+JSON配列のみを返し、他のテキストは含めないでください。
+
+議事録:
+%s`, ref.Format("2006-01-02"), strings.Join(members, ", "), minutes)
+
+	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate content: %w", err)
+	}
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return nil, fmt.Errorf("no content generated")
+	}
+
+	text := ""
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if str, ok := part.(genai.Text); ok {
+			text += string(str)
+		}
+	}
+
+	var raw []struct {
+		Title    string `json:"title"`
+		Assignee string `json:"assignee"`
+		Due      string `json:"due"`
+	}
+	if err := json.Unmarshal([]byte(jsonArrayText(text)), &raw); err != nil {
+		log.Printf("[ExtractActionItems] raw response: %q", text)
+		return nil, fmt.Errorf("failed to parse AI response: %w", err)
+	}
+
+	items := make([]ActionItem, 0, len(raw))
+	for _, r := range raw {
+		title := strings.TrimSpace(r.Title)
+		if title == "" {
+			continue
+		}
+		item := ActionItem{
+			Title:    title,
+			Assignee: strings.TrimPrefix(strings.TrimSpace(r.Assignee), "@"),
+			Source:   ActionItemSourceAI,
+		}
+		if r.Due != "" {
+			item.Due = ParseDueDate(r.Due, ref)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
 // Close クライアントを閉じる
 func (s *GeminiService) Close() error {
 	if s.client != nil {
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ActionItem 議事録から取り出したアクションアイテム
+type ActionItem struct {
+	Line     int        `json:"line,omitempty"`     // 議事録の行番号（1始まり）。AI 抽出では 0
+	Title    string     `json:"title"`              // 担当者・期限の記法を除いた本文
+	Assignee string     `json:"assignee,omitempty"` // @ の後ろの名前（未解決のまま）
+	Due      *time.Time `json:"due,omitempty"`      // 期限日の終わり（23:59）
+	Source   string     `json:"source"`             // "checklist" または "ai"
+}
+
+const (
+	ActionItemSourceChecklist = "checklist"
+	ActionItemSourceAI        = "ai"
+)
+
+var (
+	// - [ ] / * [ ] / + [ ] で始まる未完了のチェックリスト行
+	checklistLine = regexp.MustCompile(`^\s*[-*+]\s+\[ \]\s+(.+)$`)
+	mentionToken  = regexp.MustCompile(`(?:^|\s)@([^\s()（）]+)`)
+	// (due 3/12) / (期限 2025-03-12) / （締切: 3/12）
+	dueToken = regexp.MustCompile(`(?i)[(（]\s*(?:due|期限|締切|〆切)\s*[:：]?\s*([^)）]+?)\s*[)）]`)
+	spaces   = regexp.MustCompile(`\s+`)
+)
+
+// ParseActionItems 議事録のチェックリスト記法 `- [ ] @name タイトル (due 3/12)` を読み取る。
+// 完了済み（[x]）の行は対象外。年のない日付は ref（会議の日時）以降で最も近い日とみなす
+func ParseActionItems(minutes string, ref time.Time) []ActionItem {
+	items := []ActionItem{}
+	for i, line := range strings.Split(minutes, "\n") {
+		m := checklistLine.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if m == nil {
+			continue
+		}
+		body := m[1]
+		item := ActionItem{Line: i + 1, Source: ActionItemSourceChecklist}
+		if d := dueToken.FindStringSubmatchIndex(body); d != nil {
+			item.Due = ParseDueDate(body[d[2]:d[3]], ref)
+			body = body[:d[0]] + " " + body[d[1]:]
+		}
+		if a := mentionToken.FindStringSubmatchIndex(body); a != nil {
+			item.Assignee = body[a[2]:a[3]]
+			body = body[:a[0]] + " " + body[a[1]:]
+		}
+		item.Title = strings.TrimSpace(spaces.ReplaceAllString(body, " "))
+		if item.Title == "" {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// ParseDueDate 2025-03-12 / 2025/3/12 / 3/12 形式の日付をその日の終わりにする。読めなければ nil
+func ParseDueDate(s string, ref time.Time) *time.Time {
+	s = strings.TrimSpace(strings.ReplaceAll(s, "-", "/"))
+	parts := strings.Split(s, "/")
+	nums := make([]int, 0, 3)
+	for _, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return nil
+		}
+		nums = append(nums, n)
+	}
+
+	loc := ref.Location()
+	var year, month, day int
+	switch len(nums) {
+	case 3:
+		year, month, day = nums[0], nums[1], nums[2]
+	case 2:
+		year, month, day = ref.Year(), nums[0], nums[1]
+	default:
+		return nil
+	}
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return nil
+	}
+	due := time.Date(year, time.Month(month), day, 23, 59, 0, 0, loc)
+	if due.Day() != day { // 2/30 など
+		return nil
+	}
+	if len(nums) == 2 && due.Before(ref) {
+		due = due.AddDate(1, 0, 0)
+	}
+	return &due
+}
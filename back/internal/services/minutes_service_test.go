@@ -0,0 +1,117 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var jst = time.FixedZone("Asia/Tokyo", 9*60*60)
+
+func endOfDay(y int, m time.Month, d int) *time.Time {
+	t := time.Date(y, m, d, 23, 59, 0, 0, jst)
+	return &t
+}
+
+func TestParseDueDate(t *testing.T) {
+	ref := time.Date(2025, 12, 20, 10, 0, 0, 0, jst)
+	tests := []struct {
+		in   string
+		want *time.Time
+	}{
+		{"2025-03-12", endOfDay(2025, 3, 12)},
+		{"2025/3/12", endOfDay(2025, 3, 12)},
+		{" 2026 / 1 / 5 ", endOfDay(2026, 1, 5)},
+		{"12/25", endOfDay(2025, 12, 25)},
+		{"12/20", endOfDay(2025, 12, 20)},    // 会議の当日
+		{"3/12", endOfDay(2026, 3, 12)},      // 年のない過去の日付は翌年
+		{"12/19", endOfDay(2026, 12, 19)},    // 前日も翌年
+		{"2028/2/29", endOfDay(2028, 2, 29)}, // うるう年
+		{"2/30", nil},
+		{"2026/2/29", nil},
+		{"4/31", nil},
+		{"13/1", nil},
+		{"0/10", nil},
+		{"3/0", nil},
+		{"3", nil},
+		{"2025/3/12/1", nil},
+		{"来週", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got := ParseDueDate(tt.in, ref)
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("ParseDueDate(%q) = %v, want nil", tt.in, got)
+			case tt.want != nil && (got == nil || !got.Equal(*tt.want)):
+				t.Errorf("ParseDueDate(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDueDateKeepsLocation(t *testing.T) {
+	ref := time.Date(2025, 12, 20, 10, 0, 0, 0, jst)
+	got := ParseDueDate("1/5", ref)
+	if got == nil || got.Location() != jst {
+		t.Fatalf("ParseDueDate = %v, want in %v", got, jst)
+	}
+}
+
+func TestParseActionItems(t *testing.T) {
+	ref := time.Date(2025, 12, 20, 10, 0, 0, 0, jst)
+	tests := []struct {
+		name    string
+		minutes string
+		want    []ActionItem
+	}{
+		{
+			name:    "assignee and due",
+			minutes: "- [ ] @alice 会場を予約する (due 3/12)",
+			want:    []ActionItem{{Line: 1, Title: "会場を予約する", Assignee: "alice", Due: endOfDay(2026, 3, 12), Source: ActionItemSourceChecklist}},
+		},
+		{
+			name:    "full-width parentheses",
+			minutes: "* [ ] 資料を作る（期限: 2026-01-10） @佐藤",
+			want:    []ActionItem{{Line: 1, Title: "資料を作る", Assignee: "佐藤", Due: endOfDay(2026, 1, 10), Source: ActionItemSourceChecklist}},
+		},
+		{
+			name:    "締切 and plus bullet",
+			minutes: "+ [ ] 振り返り (締切 12/25)",
+			want:    []ActionItem{{Line: 1, Title: "振り返り", Due: endOfDay(2025, 12, 25), Source: ActionItemSourceChecklist}},
+		},
+		{
+			name:    "invalid due is dropped from title",
+			minutes: "- [ ] 見積もりを取る (due 2/30)",
+			want:    []ActionItem{{Line: 1, Title: "見積もりを取る", Source: ActionItemSourceChecklist}},
+		},
+		{
+			name:    "email is not an assignee",
+			minutes: "- [ ] info@example.com に連絡する",
+			want:    []ActionItem{{Line: 1, Title: "info@example.com に連絡する", Source: ActionItemSourceChecklist}},
+		},
+		{
+			name: "skips done, plain and empty lines",
+			minutes: "# 議事録\r\n" +
+				"- [x] 済んだ作業\r\n" +
+				"- 普通の箇条書き\r\n" +
+				"- [ ]   \r\n" +
+				"  - [ ] @bob   ポスターを  印刷する\r\n",
+			want: []ActionItem{{Line: 5, Title: "ポスターを 印刷する", Assignee: "bob", Source: ActionItemSourceChecklist}},
+		},
+		{
+			name:    "no items",
+			minutes: "特になし",
+			want:    []ActionItem{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseActionItems(tt.minutes, ref)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseActionItems(%q)\n got %+v\nwant %+v", tt.minutes, got, tt.want)
+			}
+		})
+	}
+}
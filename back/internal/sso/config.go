@@ -0,0 +1,178 @@
+package sso
+
+import (
+	"os"
+	"strings"
+)
+
+// ClaimMapping IdP のクレーム名（userinfo の JSON キー）と Identity の対応
+type ClaimMapping struct {
+	Subject       string
+	Email         string
+	EmailVerified string // 空ならメール確認済みとみなさない
+	Name          string
+	Picture       string
+}
+
+// ProviderConfig 1つの IdP の設定
+type ProviderConfig struct {
+	Name        string // URL で使う識別子（google, microsoft, github, keycloak ...）
+	DisplayName string
+
+	// Issuer があれば OIDC Discovery を使い ID トークンを検証する。
+	// 空の場合は OAuth2 のみのプロバイダ（GitHub 等）として AuthURL / TokenURL / UserInfoURL を使う。
+	Issuer          string
+	SkipIssuerCheck bool // マルチテナント（Microsoft common 等）で iss がテナントごとに変わる場合
+
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+	EmailsURL   string // userinfo にメールが無い場合の補完（GitHub の /user/emails）
+
+	Claims  ClaimMapping
+	UsePKCE bool
+}
+
+var defaultClaims = ClaimMapping{
+	Subject:       "sub",
+	Email:         "email",
+	EmailVerified: "email_verified",
+	Name:          "name",
+	Picture:       "picture",
+}
+
+// presets よく使うプロバイダは client id / secret だけで使えるようにする
+var presets = map[string]ProviderConfig{
+	"google": {
+		DisplayName: "Google",
+		Issuer:      "https://accounts.google.com",
+		Scopes:      []string{"openid", "profile", "email"},
+		Claims:      defaultClaims,
+		UsePKCE:     true,
+	},
+	"microsoft": {
+		DisplayName:     "Microsoft",
+		Issuer:          "https://login.microsoftonline.com/common/v2.0",
+		SkipIssuerCheck: true,
+		Scopes:          []string{"openid", "profile", "email"},
+		Claims: ClaimMapping{
+			Subject: "sub",
+			Email:   "email",
+			Name:    "name",
+		},
+		UsePKCE: true,
+	},
+	"github": {
+		DisplayName: "GitHub",
+		AuthURL:     "https://github.com/login/oauth/authorize",
+		TokenURL:    "https://github.com/login/oauth/access_token",
+		UserInfoURL: "https://api.github.com/user",
+		EmailsURL:   "https://api.github.com/user/emails",
+		Scopes:      []string{"read:user", "user:email"},
+		Claims: ClaimMapping{
+			Subject: "id",
+			Email:   "email",
+			Name:    "name",
+			Picture: "avatar_url",
+		},
+		UsePKCE: true,
+	},
+}
+
+// ConfigsFromEnv 環境変数からプロバイダ設定を読み込む。
+//
+//	OIDC_PROVIDERS=google,keycloak
+//	OIDC_<NAME>_ISSUER / _CLIENT_ID / _CLIENT_SECRET / _REDIRECT_URL / _SCOPES / _DISPLAY_NAME
+//	OIDC_<NAME>_AUTH_URL / _TOKEN_URL / _USERINFO_URL / _EMAILS_URL（OAuth2 のみのプロバイダ）
+//	OIDC_<NAME>_CLAIM_SUBJECT / _CLAIM_EMAIL / _CLAIM_EMAIL_VERIFIED / _CLAIM_NAME / _CLAIM_PICTURE
+//	OIDC_<NAME>_PKCE=false / OIDC_<NAME>_SKIP_ISSUER_CHECK=true
+//
+// 後方互換として GOOGLE_CLIENT_ID / GOOGLE_CLIENT_SECRET / GOOGLE_REDIRECT_URL があれば google を追加する。
+// redirectBase はリダイレクトURL未指定時の基点（例: http://localhost:3001）。
+func ConfigsFromEnv(redirectBase string) []ProviderConfig {
+	var out []ProviderConfig
+	seen := map[string]bool{}
+
+	for _, name := range splitList(os.Getenv("OIDC_PROVIDERS")) {
+		name = strings.ToLower(name)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		out = append(out, configFromEnv(name, redirectBase))
+	}
+
+	if !seen["google"] && os.Getenv("GOOGLE_CLIENT_ID") != "" && os.Getenv("GOOGLE_CLIENT_SECRET") != "" {
+		cfg := presets["google"]
+		cfg.Name = "google"
+		cfg.ClientID = os.Getenv("GOOGLE_CLIENT_ID")
+		cfg.ClientSecret = os.Getenv("GOOGLE_CLIENT_SECRET")
+		cfg.RedirectURL = os.Getenv("GOOGLE_REDIRECT_URL")
+		if cfg.RedirectURL == "" {
+			cfg.RedirectURL = redirectBase + "/api/auth/callback"
+		}
+		out = append(out, cfg)
+	}
+	return out
+}
+
+func configFromEnv(name, redirectBase string) ProviderConfig {
+	cfg, ok := presets[name]
+	if !ok {
+		cfg = ProviderConfig{
+			DisplayName: name,
+			Scopes:      []string{"openid", "profile", "email"},
+			Claims:      defaultClaims,
+			UsePKCE:     true,
+		}
+	}
+	cfg.Name = name
+	prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+	env := func(key string) string { return os.Getenv(prefix + key) }
+
+	setIf(&cfg.DisplayName, env("DISPLAY_NAME"))
+	setIf(&cfg.Issuer, env("ISSUER"))
+	setIf(&cfg.ClientID, env("CLIENT_ID"))
+	setIf(&cfg.ClientSecret, env("CLIENT_SECRET"))
+	setIf(&cfg.RedirectURL, env("REDIRECT_URL"))
+	setIf(&cfg.AuthURL, env("AUTH_URL"))
+	setIf(&cfg.TokenURL, env("TOKEN_URL"))
+	setIf(&cfg.UserInfoURL, env("USERINFO_URL"))
+	setIf(&cfg.EmailsURL, env("EMAILS_URL"))
+	setIf(&cfg.Claims.Subject, env("CLAIM_SUBJECT"))
+	setIf(&cfg.Claims.Email, env("CLAIM_EMAIL"))
+	setIf(&cfg.Claims.EmailVerified, env("CLAIM_EMAIL_VERIFIED"))
+	setIf(&cfg.Claims.Name, env("CLAIM_NAME"))
+	setIf(&cfg.Claims.Picture, env("CLAIM_PICTURE"))
+	if v := env("SCOPES"); v != "" {
+		cfg.Scopes = splitList(v)
+	}
+	if v := strings.ToLower(env("PKCE")); v == "false" || v == "0" {
+		cfg.UsePKCE = false
+	}
+	if v := strings.ToLower(env("SKIP_ISSUER_CHECK")); v == "true" || v == "1" {
+		cfg.SkipIssuerCheck = true
+	}
+	if cfg.RedirectURL == "" {
+		cfg.RedirectURL = redirectBase + "/api/auth/oidc/" + name + "/callback"
+	}
+	return cfg
+}
+
+func setIf(dst *string, v string) {
+	if v != "" {
+		*dst = v
+	}
+}
+
+// splitList カンマ・空白区切りの一覧を分割する
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+}
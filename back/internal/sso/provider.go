@@ -0,0 +1,312 @@
+package sso
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// ErrUnknownProvider 未登録のプロバイダ名
+var ErrUnknownProvider = errors.New("unknown identity provider")
+
+// Identity IdP から取得したユーザー情報（クレームマッピング適用後）
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+}
+
+// Provider 1つの IdP。OIDC Discovery はネットワークを伴うため初回利用時に行う。
+type Provider struct {
+	cfg ProviderConfig
+
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	userInfo string
+}
+
+// NewProvider は設定を検証して Provider を生成する
+func NewProvider(cfg ProviderConfig) (*Provider, error) {
+	if cfg.Name == "" || cfg.ClientID == "" || cfg.ClientSecret == "" {
+		return nil, fmt.Errorf("provider %q: client id and secret are required", cfg.Name)
+	}
+	if cfg.Issuer == "" && (cfg.AuthURL == "" || cfg.TokenURL == "" || cfg.UserInfoURL == "") {
+		return nil, fmt.Errorf("provider %q: issuer or auth/token/userinfo URLs are required", cfg.Name)
+	}
+	return &Provider{cfg: cfg}, nil
+}
+
+// Name プロバイダ識別子
+func (p *Provider) Name() string { return p.cfg.Name }
+
+// DisplayName 表示名
+func (p *Provider) DisplayName() string { return p.cfg.DisplayName }
+
+// UsesNonce ID トークンの nonce 検証を行うか（OIDC プロバイダのみ）
+func (p *Provider) UsesNonce() bool { return p.cfg.Issuer != "" }
+
+// UsesPKCE PKCE (S256) を使うか
+func (p *Provider) UsesPKCE() bool { return p.cfg.UsePKCE }
+
+// init Discovery を行い oauth2.Config を組み立てる。失敗時は次回呼び出しで再試行する
+func (p *Provider) init(ctx context.Context) (*oauth2.Config, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.oauth != nil {
+		return p.oauth, nil
+	}
+
+	endpoint := oauth2.Endpoint{AuthURL: p.cfg.AuthURL, TokenURL: p.cfg.TokenURL}
+	userInfo := p.cfg.UserInfoURL
+	if p.cfg.Issuer != "" {
+		discoveryCtx := ctx
+		if p.cfg.SkipIssuerCheck {
+			discoveryCtx = oidc.InsecureIssuerURLContext(ctx, p.cfg.Issuer)
+		}
+		op, err := oidc.NewProvider(discoveryCtx, p.cfg.Issuer)
+		if err != nil {
+			return nil, fmt.Errorf("oidc discovery for %s: %w", p.cfg.Name, err)
+		}
+		endpoint = op.Endpoint()
+		setIf(&endpoint.AuthURL, p.cfg.AuthURL)
+		setIf(&endpoint.TokenURL, p.cfg.TokenURL)
+		var meta struct {
+			UserInfoURL string `json:"userinfo_endpoint"`
+		}
+		if err := op.Claims(&meta); err == nil && userInfo == "" {
+			userInfo = meta.UserInfoURL
+		}
+		p.verifier = op.Verifier(&oidc.Config{
+			ClientID:        p.cfg.ClientID,
+			SkipIssuerCheck: p.cfg.SkipIssuerCheck,
+		})
+	}
+
+	p.oauth = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Scopes:       p.cfg.Scopes,
+		Endpoint:     endpoint,
+	}
+	p.userInfo = userInfo
+	return p.oauth, nil
+}
+
+// AuthCodeURL 認可リクエストURL。nonce・verifier は UsesNonce / UsesPKCE が false なら無視される
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	conf, err := p.init(ctx)
+	if err != nil {
+		return "", err
+	}
+	var opts []oauth2.AuthCodeOption
+	if p.UsesNonce() {
+		opts = append(opts, oidc.Nonce(nonce))
+	}
+	if p.UsesPKCE() {
+		opts = append(opts, oauth2.S256ChallengeOption(verifier))
+	}
+	return conf.AuthCodeURL(state, opts...), nil
+}
+
+// Exchange 認可コードをトークンに交換し、ID トークン（nonce 含む）を検証してユーザー情報を返す
+func (p *Provider) Exchange(ctx context.Context, code, nonce, verifier string) (*Identity, error) {
+	conf, err := p.init(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var opts []oauth2.AuthCodeOption
+	if p.UsesPKCE() {
+		opts = append(opts, oauth2.VerifierOption(verifier))
+	}
+	token, err := conf.Exchange(ctx, code, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("exchange code: %w", err)
+	}
+
+	claims := map[string]interface{}{}
+	if p.verifier != nil {
+		rawIDToken, ok := token.Extra("id_token").(string)
+		if !ok {
+			return nil, errors.New("id_token missing from token response")
+		}
+		idToken, err := p.verifier.Verify(ctx, rawIDToken)
+		if err != nil {
+			return nil, fmt.Errorf("verify id_token: %w", err)
+		}
+		if idToken.Nonce != nonce {
+			return nil, errors.New("id_token nonce mismatch")
+		}
+		if err := idToken.Claims(&claims); err != nil {
+			return nil, fmt.Errorf("parse id_token claims: %w", err)
+		}
+	}
+
+	// ID トークンに必要なクレームが無ければ userinfo で補う
+	id := p.mapClaims(claims)
+	if (id.Email == "" || id.Subject == "") && p.userInfo != "" {
+		info, err := p.fetchJSON(ctx, conf, token, p.userInfo)
+		if err != nil {
+			return nil, err
+		}
+		infoMap, ok := info.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("unexpected userinfo response")
+		}
+		for k, v := range infoMap {
+			if _, exists := claims[k]; !exists {
+				claims[k] = v
+			}
+		}
+		id = p.mapClaims(claims)
+	}
+	if id.Email == "" && p.cfg.EmailsURL != "" {
+		if err := p.fillPrimaryEmail(ctx, conf, token, id); err != nil {
+			return nil, err
+		}
+	}
+	if id.Subject == "" || id.Email == "" {
+		return nil, errors.New("identity provider did not return subject or email")
+	}
+	return id, nil
+}
+
+func (p *Provider) mapClaims(claims map[string]interface{}) *Identity {
+	m := p.cfg.Claims
+	id := &Identity{
+		Subject: claimString(claims, m.Subject),
+		Email:   claimString(claims, m.Email),
+		Name:    claimString(claims, m.Name),
+		Picture: claimString(claims, m.Picture),
+	}
+	if m.EmailVerified != "" {
+		switch v := claims[m.EmailVerified].(type) {
+		case bool:
+			id.EmailVerified = v
+		case string:
+			id.EmailVerified = v == "true"
+		}
+	}
+	if id.Name == "" {
+		id.Name = id.Email
+	}
+	return id
+}
+
+// claimString 文字列・数値どちらのクレームも文字列として取り出す（GitHub の id は数値）
+func claimString(claims map[string]interface{}, key string) string {
+	if key == "" {
+		return ""
+	}
+	switch v := claims[key].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	}
+	return ""
+}
+
+func (p *Provider) fetchJSON(ctx context.Context, conf *oauth2.Config, token *oauth2.Token, url string) (interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := conf.Client(ctx, token).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: status %d", url, resp.StatusCode)
+	}
+	var out interface{}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", url, err)
+	}
+	return out, nil
+}
+
+// fillPrimaryEmail GitHub 形式の [{email, primary, verified}] から主アドレスを採用する
+func (p *Provider) fillPrimaryEmail(ctx context.Context, conf *oauth2.Config, token *oauth2.Token, id *Identity) error {
+	raw, err := p.fetchJSON(ctx, conf, token, p.cfg.EmailsURL)
+	if err != nil {
+		return err
+	}
+	list, _ := raw.([]interface{})
+	for _, item := range list {
+		e, _ := item.(map[string]interface{})
+		if primary, _ := e["primary"].(bool); !primary {
+			continue
+		}
+		id.Email, _ = e["email"].(string)
+		id.EmailVerified, _ = e["verified"].(bool)
+		if id.Name == "" {
+			id.Name = id.Email
+		}
+		return nil
+	}
+	return nil
+}
+
+// Registry 名前でプロバイダを引けるようにしたもの
+type Registry struct {
+	providers map[string]*Provider
+}
+
+// NewRegistry 設定一覧から Registry を組み立てる
+func NewRegistry(configs []ProviderConfig) (*Registry, error) {
+	r := &Registry{providers: make(map[string]*Provider)}
+	for _, cfg := range configs {
+		p, err := NewProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		r.providers[cfg.Name] = p
+	}
+	return r, nil
+}
+
+// Get 名前でプロバイダを取得する
+func (r *Registry) Get(name string) (*Provider, error) {
+	if r == nil {
+		return nil, ErrUnknownProvider
+	}
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// List 名前順のプロバイダ一覧
+func (r *Registry) List() []*Provider {
+	if r == nil {
+		return nil
+	}
+	out := make([]*Provider, 0, len(r.providers))
+	for _, p := range r.providers {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].cfg.Name < out[j].cfg.Name })
+	return out
+}
@@ -32,6 +32,7 @@ type Client struct {
 	userID        uint
 	channels      map[uint]struct{}
 	eventCalendars map[uint]struct{}
+	eventCheckins  map[uint]struct{}
 }
 
 // ServeWS は HTTP を WebSocket にアップグレードし、クライアントを起動する
@@ -49,8 +50,10 @@ func ServeWS(hub *Hub, w http.ResponseWriter, r *http.Request, userID uint) {
 		userID:          userID,
 		channels:        make(map[uint]struct{}),
 		eventCalendars:  make(map[uint]struct{}),
+		eventCheckins:   make(map[uint]struct{}),
 	}
 
+	hub.register(c)
 	go c.writePump()
 	c.readPump()
 }
@@ -89,6 +92,10 @@ func (c *Client) readPump() {
 				c.send <- BuildErrorEvent("channel_id required")
 				continue
 			}
+			if CanJoinChannel == nil || !CanJoinChannel(c.userID, msg.ChannelID) {
+				c.send <- BuildErrorEvent("forbidden")
+				continue
+			}
 			c.hub.Join(c, msg.ChannelID)
 		case "leave":
 			if msg.ChannelID == 0 {
@@ -106,8 +113,36 @@ func (c *Client) readPump() {
 				continue
 			}
 			c.hub.LeaveEventCalendar(c, msg.EventID)
-		case "typing", "typing_stop":
+		case "join_checkins":
+			if msg.EventID == 0 {
+				c.send <- BuildErrorEvent("event_id required")
+				continue
+			}
+			if CanWatchCheckins == nil || !CanWatchCheckins(c.userID, msg.EventID) {
+				c.send <- BuildErrorEvent("forbidden")
+				continue
+			}
+			c.hub.JoinEventCheckins(c, msg.EventID)
+		case "leave_checkins":
+			if msg.EventID == 0 {
+				continue
+			}
+			c.hub.LeaveEventCheckins(c, msg.EventID)
+		case "read":
 			if msg.ChannelID == 0 {
+				c.send <- BuildErrorEvent("channel_id required")
+				continue
+			}
+			if MarkRead == nil || CanJoinChannel == nil || !CanJoinChannel(c.userID, msg.ChannelID) {
+				c.send <- BuildErrorEvent("forbidden")
+				continue
+			}
+			if err := MarkRead(c.userID, msg.ChannelID, msg.MessageID); err != nil {
+				c.send <- BuildErrorEvent(err.Error())
+			}
+		case "typing", "typing_stop":
+			// 購読中のチャンネルにだけ送れる
+			if msg.ChannelID == 0 || !c.hub.IsJoined(c, msg.ChannelID) {
 				continue
 			}
 			payload, _ := json.Marshal(map[string]interface{}{
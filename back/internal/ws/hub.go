@@ -13,9 +13,15 @@ type Hub struct {
 	channels map[uint]map[*Client]struct{}
 	// eventID -> clients subscribed to calendar updates
 	eventCalendars map[uint]map[*Client]struct{}
+	// eventID -> staff clients subscribed to check-in updates
+	eventCheckins map[uint]map[*Client]struct{}
+	// userID -> connected clients（同じユーザーの別セッションへ既読などを届ける）
+	users map[uint]map[*Client]struct{}
 	unregister     chan *Client
 	broadcast      chan *BroadcastMessage
 	calendarBroadcast chan *calendarBroadcast
+	checkinBroadcast  chan *calendarBroadcast
+	userMessages      chan *userMessage
 }
 
 type calendarBroadcast struct {
@@ -23,6 +29,12 @@ type calendarBroadcast struct {
 	Raw     []byte
 }
 
+// userMessage 特定ユーザーのすべての接続へ配信するメッセージ
+type userMessage struct {
+	UserID uint
+	Raw    []byte
+}
+
 // BroadcastMessage 特定チャンネルへ配信するメッセージ
 type BroadcastMessage struct {
 	ChannelID     uint  `json:"-"`
@@ -35,9 +47,13 @@ func NewHub() *Hub {
 	return &Hub{
 		channels:         make(map[uint]map[*Client]struct{}),
 		eventCalendars:   make(map[uint]map[*Client]struct{}),
+		eventCheckins:    make(map[uint]map[*Client]struct{}),
+		users:            make(map[uint]map[*Client]struct{}),
 		unregister:       make(chan *Client),
 		broadcast:        make(chan *BroadcastMessage, 256),
 		calendarBroadcast: make(chan *calendarBroadcast, 64),
+		checkinBroadcast:  make(chan *calendarBroadcast, 64),
+		userMessages:      make(chan *userMessage, 256),
 	}
 }
 
@@ -53,13 +69,35 @@ func (h *Hub) Run() {
 
 		case cb := <-h.calendarBroadcast:
 			h.broadcastToEventCalendar(cb)
+
+		case cb := <-h.checkinBroadcast:
+			h.broadcastToEventSubscribers(h.eventCheckins, cb)
+
+		case um := <-h.userMessages:
+			h.sendToUser(um)
 		}
 	}
 }
 
+// register は接続したクライアントをユーザー単位で登録する
+func (h *Hub) register(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.users[c.userID] == nil {
+		h.users[c.userID] = make(map[*Client]struct{})
+	}
+	h.users[c.userID][c] = struct{}{}
+}
+
 func (h *Hub) removeClient(c *Client) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
+	if m, ok := h.users[c.userID]; ok {
+		delete(m, c)
+		if len(m) == 0 {
+			delete(h.users, c.userID)
+		}
+	}
 	for chID := range c.channels {
 		m, ok := h.channels[chID]
 		if !ok {
@@ -80,6 +118,16 @@ func (h *Hub) removeClient(c *Client) {
 			delete(h.eventCalendars, evID)
 		}
 	}
+	for evID := range c.eventCheckins {
+		m, ok := h.eventCheckins[evID]
+		if !ok {
+			continue
+		}
+		delete(m, c)
+		if len(m) == 0 {
+			delete(h.eventCheckins, evID)
+		}
+	}
 	close(c.send)
 }
 
@@ -109,6 +157,30 @@ func (h *Hub) Leave(c *Client, channelID uint) {
 	}
 }
 
+// IsJoined はクライアントがチャンネルを購読中か
+func (h *Hub) IsJoined(c *Client, channelID uint) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	_, ok := c.channels[channelID]
+	return ok
+}
+
+// EvictFromChannel は keep が false を返すユーザーのクライアントをチャンネルの購読から外す
+func (h *Hub) EvictFromChannel(channelID uint, keep func(userID uint) bool) {
+	h.mu.RLock()
+	var clients []*Client
+	for c := range h.channels[channelID] {
+		clients = append(clients, c)
+	}
+	h.mu.RUnlock()
+	// keep は DB を引くことがあるのでロックの外で呼ぶ
+	for _, c := range clients {
+		if !keep(c.userID) {
+			h.Leave(c, channelID)
+		}
+	}
+}
+
 // JoinEventCalendar はクライアントをイベントカレンダー購読に参加させる
 func (h *Hub) JoinEventCalendar(c *Client, eventID uint) {
 	h.mu.Lock()
@@ -135,9 +207,40 @@ func (h *Hub) LeaveEventCalendar(c *Client, eventID uint) {
 	}
 }
 
+// JoinEventCheckins はクライアントをイベントの受付状況の購読に参加させる（購読できるかは呼び出し側で確認済み）
+func (h *Hub) JoinEventCheckins(c *Client, eventID uint) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.eventCheckins[eventID] == nil {
+		h.eventCheckins[eventID] = make(map[*Client]struct{})
+	}
+	h.eventCheckins[eventID][c] = struct{}{}
+	c.eventCheckins[eventID] = struct{}{}
+}
+
+// LeaveEventCheckins はクライアントをイベントの受付状況の購読から退出させる
+func (h *Hub) LeaveEventCheckins(c *Client, eventID uint) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(c.eventCheckins, eventID)
+	m, ok := h.eventCheckins[eventID]
+	if !ok {
+		return
+	}
+	delete(m, c)
+	if len(m) == 0 {
+		delete(h.eventCheckins, eventID)
+	}
+}
+
 func (h *Hub) broadcastToEventCalendar(cb *calendarBroadcast) {
+	h.broadcastToEventSubscribers(h.eventCalendars, cb)
+}
+
+// broadcastToEventSubscribers は subs のうち cb.EventID を購読しているクライアントに配信する
+func (h *Hub) broadcastToEventSubscribers(subs map[uint]map[*Client]struct{}, cb *calendarBroadcast) {
 	h.mu.RLock()
-	m, ok := h.eventCalendars[cb.EventID]
+	m, ok := subs[cb.EventID]
 	if !ok || len(m) == 0 {
 		h.mu.RUnlock()
 		return
@@ -193,6 +296,36 @@ func (h *Hub) broadcastToChannel(b *BroadcastMessage) {
 	}
 }
 
+// SendToUser はユーザーのすべての接続に配信する。c.send に触れるのは Run だけなので、配信は Run に任せる
+func (h *Hub) SendToUser(userID uint, raw []byte) {
+	h.userMessages <- &userMessage{UserID: userID, Raw: raw}
+}
+
+// sendToUser は Run の中で呼ぶ。送信バッファが詰まった接続には届けない（切断は readPump に任せる）
+func (h *Hub) sendToUser(um *userMessage) {
+	h.mu.RLock()
+	clients := make([]*Client, 0, len(h.users[um.UserID]))
+	for c := range h.users[um.UserID] {
+		clients = append(clients, c)
+	}
+	h.mu.RUnlock()
+
+	for _, c := range clients {
+		select {
+		case c.send <- um.Raw:
+		default:
+			log.Printf("[ws] send buffer full, dropped message for user %d", um.UserID)
+		}
+	}
+}
+
+// IsConnected はユーザーの接続が1つでもあるか
+func (h *Hub) IsConnected(userID uint) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.users[userID]) > 0
+}
+
 // Envelope クライアントへ送る JSON の共通形
 type Envelope struct {
 	Type    string          `json:"type"`
@@ -205,6 +338,7 @@ type ClientMessage struct {
 	Type      string `json:"type"`
 	ChannelID uint   `json:"channel_id"`
 	EventID   uint   `json:"event_id"`
+	MessageID uint   `json:"message_id"`
 	UserName  string `json:"user_name"`
 }
 
@@ -277,3 +411,45 @@ func BroadcastCalendarUpdate(eventID uint) {
 	raw := BuildEvent("calendar_update", []byte("{}"))
 	DefaultHub.calendarBroadcast <- &calendarBroadcast{EventID: eventID, Raw: raw}
 }
+
+// CanWatchCheckins は userID がイベントの受付状況を購読できるか（main で設定する）。nil なら誰も購読できない
+var CanWatchCheckins func(userID, eventID uint) bool
+
+// CanJoinChannel は userID がチャンネルを購読できるか（main で設定する）。nil なら誰も購読できない
+var CanJoinChannel func(userID, channelID uint) bool
+
+// EvictFromChannel は DefaultHub で keep が false を返すユーザーの購読を外す。DefaultHub が nil なら何もしない
+func EvictFromChannel(channelID uint, keep func(userID uint) bool) {
+	if DefaultHub == nil {
+		return
+	}
+	DefaultHub.EvictFromChannel(channelID, keep)
+}
+
+// MarkRead は userID のチャンネルの既読位置を messageID（0 なら最新）まで進める（main で設定する）。nil なら何もしない
+var MarkRead func(userID, channelID, messageID uint) error
+
+// SendEventToUser は type と payload を指定してユーザーのすべての接続に配信する。DefaultHub が nil なら何もしない
+func SendEventToUser(userID uint, typ string, payload []byte) {
+	if DefaultHub == nil {
+		return
+	}
+	DefaultHub.SendToUser(userID, BuildEvent(typ, payload))
+}
+
+// IsOnline は userID が DefaultHub に接続中か（@here の対象）。DefaultHub が nil なら false
+func IsOnline(userID uint) bool {
+	if DefaultHub == nil {
+		return false
+	}
+	return DefaultHub.IsConnected(userID)
+}
+
+// BroadcastCheckinUpdate は指定イベントの受付状況を購読しているスタッフに配信する
+func BroadcastCheckinUpdate(eventID uint, payload []byte) {
+	if DefaultHub == nil {
+		return
+	}
+	raw := BuildEvent("checkin_update", payload)
+	DefaultHub.checkinBroadcast <- &calendarBroadcast{EventID: eventID, Raw: raw}
+}
@@ -0,0 +1,40 @@
+package ws
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSendToUserAfterUnregister(t *testing.T) {
+	h := NewHub()
+	go h.Run()
+	c := &Client{hub: h, send: make(chan []byte, 1), userID: 1, channels: map[uint]struct{}{}, eventCalendars: map[uint]struct{}{}, eventCheckins: map[uint]struct{}{}}
+	h.register(c)
+
+	// バッファが詰まっても切断せず、あふれた分だけ捨てる
+	h.SendToUser(1, []byte("a"))
+	h.SendToUser(1, []byte("b"))
+	select {
+	case got := <-c.send:
+		if string(got) != "a" {
+			t.Fatalf("got %q, want a", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not delivered")
+	}
+
+	// readPump が切断した後に届いた配信で閉じた c.send に送らない
+	h.unregister <- c
+	for i := 0; i < 3; i++ {
+		h.SendToUser(1, []byte("c"))
+	}
+	h.unregister <- &Client{send: make(chan []byte)} // Run が上の配信を処理し終えるのを待つ
+	for got := range c.send {
+		if string(got) == "c" {
+			t.Fatal("message sent after unregister")
+		}
+	}
+	if h.IsConnected(1) {
+		t.Error("user still connected")
+	}
+}
@@ -0,0 +1,17 @@
+-- 0001_initial_schema の取り消し（全データが消えるので注意）
+
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS channel_members;
+DROP TABLE IF EXISTS channels;
+DROP TABLE IF EXISTS event_participants;
+DROP TABLE IF EXISTS tickets;
+DROP TABLE IF EXISTS meetings;
+DROP TABLE IF EXISTS budgets;
+DROP TABLE IF EXISTS tasks;
+DROP TABLE IF EXISTS event_staffs;
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
+DROP TABLE IF EXISTS users;
+
+DROP FUNCTION IF EXISTS update_updated_at_column();
@@ -1,5 +1,6 @@
 -- Sherpa Event Manager - Initial Database Schema
 -- PostgreSQL
+-- GORM の AutoMigrate で作られた既存のデータベースにも適用できるよう、すべて IF NOT EXISTS で書く
 
 -- ==========================================
 -- 1. 基盤・アカウント (Identity)
@@ -15,8 +16,8 @@ CREATE TABLE IF NOT EXISTS users (
     deleted_at TIMESTAMP
 );
 
-CREATE INDEX idx_users_email ON users(email);
-CREATE INDEX idx_users_deleted_at ON users(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users(deleted_at);
 
 CREATE TABLE IF NOT EXISTS organizations (
     id SERIAL PRIMARY KEY,
@@ -27,7 +28,7 @@ CREATE TABLE IF NOT EXISTS organizations (
     deleted_at TIMESTAMP
 );
 
-CREATE INDEX idx_organizations_deleted_at ON organizations(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_organizations_deleted_at ON organizations(deleted_at);
 
 CREATE TABLE IF NOT EXISTS organization_members (
     id SERIAL PRIMARY KEY,
@@ -40,9 +41,9 @@ CREATE TABLE IF NOT EXISTS organization_members (
     UNIQUE(user_id, organization_id)
 );
 
-CREATE INDEX idx_organization_members_user_id ON organization_members(user_id);
-CREATE INDEX idx_organization_members_organization_id ON organization_members(organization_id);
-CREATE INDEX idx_organization_members_deleted_at ON organization_members(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_organization_members_user_id ON organization_members(user_id);
+CREATE INDEX IF NOT EXISTS idx_organization_members_organization_id ON organization_members(organization_id);
+CREATE INDEX IF NOT EXISTS idx_organization_members_deleted_at ON organization_members(deleted_at);
 
 -- ==========================================
 -- 2. イベントコア (Event Core)
@@ -62,10 +63,10 @@ CREATE TABLE IF NOT EXISTS events (
     CHECK (end_at > start_at)
 );
 
-CREATE INDEX idx_events_organization_id ON events(organization_id);
-CREATE INDEX idx_events_status ON events(status);
-CREATE INDEX idx_events_start_at ON events(start_at);
-CREATE INDEX idx_events_deleted_at ON events(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_events_organization_id ON events(organization_id);
+CREATE INDEX IF NOT EXISTS idx_events_status ON events(status);
+CREATE INDEX IF NOT EXISTS idx_events_start_at ON events(start_at);
+CREATE INDEX IF NOT EXISTS idx_events_deleted_at ON events(deleted_at);
 
 CREATE TABLE IF NOT EXISTS event_staffs (
     id SERIAL PRIMARY KEY,
@@ -78,9 +79,9 @@ CREATE TABLE IF NOT EXISTS event_staffs (
     UNIQUE(event_id, user_id)
 );
 
-CREATE INDEX idx_event_staffs_event_id ON event_staffs(event_id);
-CREATE INDEX idx_event_staffs_user_id ON event_staffs(user_id);
-CREATE INDEX idx_event_staffs_deleted_at ON event_staffs(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_event_staffs_event_id ON event_staffs(event_id);
+CREATE INDEX IF NOT EXISTS idx_event_staffs_user_id ON event_staffs(user_id);
+CREATE INDEX IF NOT EXISTS idx_event_staffs_deleted_at ON event_staffs(deleted_at);
 
 -- ==========================================
 -- 3. 運営機能 (Operations)
@@ -99,11 +100,11 @@ CREATE TABLE IF NOT EXISTS tasks (
     deleted_at TIMESTAMP
 );
 
-CREATE INDEX idx_tasks_event_id ON tasks(event_id);
-CREATE INDEX idx_tasks_assignee_id ON tasks(assignee_id);
-CREATE INDEX idx_tasks_status ON tasks(status);
-CREATE INDEX idx_tasks_deadline ON tasks(deadline);
-CREATE INDEX idx_tasks_deleted_at ON tasks(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_tasks_event_id ON tasks(event_id);
+CREATE INDEX IF NOT EXISTS idx_tasks_assignee_id ON tasks(assignee_id);
+CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
+CREATE INDEX IF NOT EXISTS idx_tasks_deadline ON tasks(deadline);
+CREATE INDEX IF NOT EXISTS idx_tasks_deleted_at ON tasks(deleted_at);
 
 CREATE TABLE IF NOT EXISTS budgets (
     id SERIAL PRIMARY KEY,
@@ -118,9 +119,9 @@ CREATE TABLE IF NOT EXISTS budgets (
     CHECK (type IN ('income', 'expense'))
 );
 
-CREATE INDEX idx_budgets_event_id ON budgets(event_id);
-CREATE INDEX idx_budgets_type ON budgets(type);
-CREATE INDEX idx_budgets_deleted_at ON budgets(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_budgets_event_id ON budgets(event_id);
+CREATE INDEX IF NOT EXISTS idx_budgets_type ON budgets(type);
+CREATE INDEX IF NOT EXISTS idx_budgets_deleted_at ON budgets(deleted_at);
 
 CREATE TABLE IF NOT EXISTS meetings (
     id SERIAL PRIMARY KEY,
@@ -133,9 +134,9 @@ CREATE TABLE IF NOT EXISTS meetings (
     deleted_at TIMESTAMP
 );
 
-CREATE INDEX idx_meetings_event_id ON meetings(event_id);
-CREATE INDEX idx_meetings_start_at ON meetings(start_at);
-CREATE INDEX idx_meetings_deleted_at ON meetings(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_meetings_event_id ON meetings(event_id);
+CREATE INDEX IF NOT EXISTS idx_meetings_start_at ON meetings(start_at);
+CREATE INDEX IF NOT EXISTS idx_meetings_deleted_at ON meetings(deleted_at);
 
 -- ==========================================
 -- 4. 参加者管理 (Participants)
@@ -151,8 +152,8 @@ CREATE TABLE IF NOT EXISTS tickets (
     deleted_at TIMESTAMP
 );
 
-CREATE INDEX idx_tickets_event_id ON tickets(event_id);
-CREATE INDEX idx_tickets_deleted_at ON tickets(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_tickets_event_id ON tickets(event_id);
+CREATE INDEX IF NOT EXISTS idx_tickets_deleted_at ON tickets(deleted_at);
 
 CREATE TABLE IF NOT EXISTS event_participants (
     id SERIAL PRIMARY KEY,
@@ -165,10 +166,10 @@ CREATE TABLE IF NOT EXISTS event_participants (
     UNIQUE(ticket_id, user_id)
 );
 
-CREATE INDEX idx_event_participants_ticket_id ON event_participants(ticket_id);
-CREATE INDEX idx_event_participants_user_id ON event_participants(user_id);
-CREATE INDEX idx_event_participants_status ON event_participants(status);
-CREATE INDEX idx_event_participants_deleted_at ON event_participants(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_event_participants_ticket_id ON event_participants(ticket_id);
+CREATE INDEX IF NOT EXISTS idx_event_participants_user_id ON event_participants(user_id);
+CREATE INDEX IF NOT EXISTS idx_event_participants_status ON event_participants(status);
+CREATE INDEX IF NOT EXISTS idx_event_participants_deleted_at ON event_participants(deleted_at);
 
 -- ==========================================
 -- 5. コミュニケーション (Slack-like Chat)
@@ -186,8 +187,8 @@ CREATE TABLE IF NOT EXISTS channels (
     UNIQUE(event_id, name)
 );
 
-CREATE INDEX idx_channels_event_id ON channels(event_id);
-CREATE INDEX idx_channels_deleted_at ON channels(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_channels_event_id ON channels(event_id);
+CREATE INDEX IF NOT EXISTS idx_channels_deleted_at ON channels(deleted_at);
 
 CREATE TABLE IF NOT EXISTS channel_members (
     id SERIAL PRIMARY KEY,
@@ -201,9 +202,9 @@ CREATE TABLE IF NOT EXISTS channel_members (
     UNIQUE(channel_id, user_id)
 );
 
-CREATE INDEX idx_channel_members_channel_id ON channel_members(channel_id);
-CREATE INDEX idx_channel_members_user_id ON channel_members(user_id);
-CREATE INDEX idx_channel_members_deleted_at ON channel_members(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_channel_members_channel_id ON channel_members(channel_id);
+CREATE INDEX IF NOT EXISTS idx_channel_members_user_id ON channel_members(user_id);
+CREATE INDEX IF NOT EXISTS idx_channel_members_deleted_at ON channel_members(deleted_at);
 
 CREATE TABLE IF NOT EXISTS messages (
     id SERIAL PRIMARY KEY,
@@ -217,11 +218,11 @@ CREATE TABLE IF NOT EXISTS messages (
     deleted_at TIMESTAMP
 );
 
-CREATE INDEX idx_messages_channel_id ON messages(channel_id);
-CREATE INDEX idx_messages_user_id ON messages(user_id);
-CREATE INDEX idx_messages_parent_message_id ON messages(parent_message_id);
-CREATE INDEX idx_messages_created_at ON messages(created_at);
-CREATE INDEX idx_messages_deleted_at ON messages(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_messages_channel_id ON messages(channel_id);
+CREATE INDEX IF NOT EXISTS idx_messages_user_id ON messages(user_id);
+CREATE INDEX IF NOT EXISTS idx_messages_parent_message_id ON messages(parent_message_id);
+CREATE INDEX IF NOT EXISTS idx_messages_created_at ON messages(created_at);
+CREATE INDEX IF NOT EXISTS idx_messages_deleted_at ON messages(deleted_at);
 
 -- ==========================================
 -- Functions & Triggers
@@ -237,41 +238,54 @@ END;
 $$ language 'plpgsql';
 
 -- 各テーブルにupdated_at自動更新トリガーを設定
+DROP TRIGGER IF EXISTS update_users_updated_at ON users;
 CREATE TRIGGER update_users_updated_at BEFORE UPDATE ON users
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_organizations_updated_at ON organizations;
 CREATE TRIGGER update_organizations_updated_at BEFORE UPDATE ON organizations
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_organization_members_updated_at ON organization_members;
 CREATE TRIGGER update_organization_members_updated_at BEFORE UPDATE ON organization_members
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_events_updated_at ON events;
 CREATE TRIGGER update_events_updated_at BEFORE UPDATE ON events
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_event_staffs_updated_at ON event_staffs;
 CREATE TRIGGER update_event_staffs_updated_at BEFORE UPDATE ON event_staffs
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_tasks_updated_at ON tasks;
 CREATE TRIGGER update_tasks_updated_at BEFORE UPDATE ON tasks
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_budgets_updated_at ON budgets;
 CREATE TRIGGER update_budgets_updated_at BEFORE UPDATE ON budgets
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_meetings_updated_at ON meetings;
 CREATE TRIGGER update_meetings_updated_at BEFORE UPDATE ON meetings
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_tickets_updated_at ON tickets;
 CREATE TRIGGER update_tickets_updated_at BEFORE UPDATE ON tickets
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_event_participants_updated_at ON event_participants;
 CREATE TRIGGER update_event_participants_updated_at BEFORE UPDATE ON event_participants
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_channels_updated_at ON channels;
 CREATE TRIGGER update_channels_updated_at BEFORE UPDATE ON channels
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_channel_members_updated_at ON channel_members;
 CREATE TRIGGER update_channel_members_updated_at BEFORE UPDATE ON channel_members
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_messages_updated_at ON messages;
 CREATE TRIGGER update_messages_updated_at BEFORE UPDATE ON messages
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
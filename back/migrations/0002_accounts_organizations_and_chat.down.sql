@@ -0,0 +1,18 @@
+-- 0002_accounts_organizations_and_chat の取り消し
+
+DROP TABLE IF EXISTS message_reactions;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS event_invitations;
+DROP TABLE IF EXISTS organization_domains;
+DROP TABLE IF EXISTS organization_invitations;
+DROP TABLE IF EXISTS personal_access_tokens;
+DROP TABLE IF EXISTS user_identities;
+DROP TABLE IF EXISTS email_tokens;
+DROP TABLE IF EXISTS sessions;
+
+ALTER TABLE organization_members DROP COLUMN IF EXISTS status;
+
+DROP INDEX IF EXISTS idx_users_active_organization_id;
+ALTER TABLE users DROP COLUMN IF EXISTS active_organization_id;
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
+ALTER TABLE users DROP COLUMN IF EXISTS password_hash;
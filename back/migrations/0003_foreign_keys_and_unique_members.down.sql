@@ -0,0 +1,11 @@
+-- 0003_foreign_keys_and_unique_members の取り消し
+-- 外部キーは 0001 / 0002 の定義と同じ ON DELETE なので残し、一意性だけ元の（論理削除済みも含む）制約に戻す。
+-- 論理削除済みの行と重複していると失敗するため、その場合は先に該当行を物理削除すること
+
+DROP INDEX IF EXISTS idx_event_staffs_event_user;
+DROP INDEX IF EXISTS idx_channel_members_channel_user;
+DROP INDEX IF EXISTS idx_organization_members_org_user;
+
+ALTER TABLE event_staffs ADD CONSTRAINT event_staffs_event_id_user_id_key UNIQUE (event_id, user_id);
+ALTER TABLE channel_members ADD CONSTRAINT channel_members_channel_id_user_id_key UNIQUE (channel_id, user_id);
+ALTER TABLE organization_members ADD CONSTRAINT organization_members_user_id_organization_id_key UNIQUE (user_id, organization_id);
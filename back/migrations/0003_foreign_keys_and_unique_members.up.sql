@@ -0,0 +1,121 @@
+-- 0003: 外部キーの ON DELETE を明示し、メンバー系テーブルの重複を一意インデックスで防ぐ
+-- AutoMigrate で作られたデータベースには制約がない・名前が違う場合があるため、列ごとに既存の FK を外して張り直す
+
+-- ==========================================
+-- 1. 外部キー
+-- ==========================================
+
+-- tbl.col の単一列 FK をすべて外し、fk_<tbl>_<col> として張り直す。
+-- 参照先のない行が既にある場合は NOT VALID のまま残し（新しい行には効く）、cmd/dbcheck --repair で修復・検証する
+CREATE OR REPLACE FUNCTION sherpa_reset_fk(tbl TEXT, col TEXT, ref TEXT, on_delete TEXT) RETURNS VOID AS $$
+DECLARE
+    c RECORD;
+    fk TEXT := format('fk_%s_%s', tbl, col);
+BEGIN
+    FOR c IN
+        SELECT con.conname
+        FROM pg_constraint con
+        JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = con.conkey[1]
+        WHERE con.contype = 'f' AND con.conrelid = tbl::regclass
+          AND array_length(con.conkey, 1) = 1 AND a.attname = col
+    LOOP
+        EXECUTE format('ALTER TABLE %I DROP CONSTRAINT %I', tbl, c.conname);
+    END LOOP;
+
+    EXECUTE format('ALTER TABLE %I ADD CONSTRAINT %I FOREIGN KEY (%I) REFERENCES %I(id) ON DELETE %s NOT VALID',
+        tbl, fk, col, ref, on_delete);
+    BEGIN
+        EXECUTE format('ALTER TABLE %I VALIDATE CONSTRAINT %I', tbl, fk);
+    EXCEPTION WHEN foreign_key_violation THEN
+        RAISE WARNING '%.% に参照先のない行があります。go run ./cmd/dbcheck --repair で修復してください', tbl, col;
+    END;
+END;
+$$ LANGUAGE plpgsql;
+
+-- アカウント
+SELECT sherpa_reset_fk('users', 'active_organization_id', 'organizations', 'SET NULL');
+SELECT sherpa_reset_fk('sessions', 'user_id', 'users', 'CASCADE');
+SELECT sherpa_reset_fk('email_tokens', 'user_id', 'users', 'CASCADE');
+SELECT sherpa_reset_fk('user_identities', 'user_id', 'users', 'CASCADE');
+SELECT sherpa_reset_fk('personal_access_tokens', 'user_id', 'users', 'CASCADE');
+SELECT sherpa_reset_fk('notifications', 'user_id', 'users', 'CASCADE');
+
+-- 組織
+SELECT sherpa_reset_fk('organization_members', 'user_id', 'users', 'CASCADE');
+SELECT sherpa_reset_fk('organization_members', 'organization_id', 'organizations', 'CASCADE');
+SELECT sherpa_reset_fk('organization_invitations', 'organization_id', 'organizations', 'CASCADE');
+SELECT sherpa_reset_fk('organization_invitations', 'inviter_id', 'users', 'CASCADE');
+SELECT sherpa_reset_fk('organization_invitations', 'user_id', 'users', 'CASCADE');
+SELECT sherpa_reset_fk('organization_domains', 'organization_id', 'organizations', 'CASCADE');
+
+-- イベントと配下（イベントを物理削除すると配下もすべて消える）
+SELECT sherpa_reset_fk('events', 'organization_id', 'organizations', 'CASCADE');
+SELECT sherpa_reset_fk('event_staffs', 'event_id', 'events', 'CASCADE');
+SELECT sherpa_reset_fk('event_staffs', 'user_id', 'users', 'CASCADE');
+SELECT sherpa_reset_fk('event_invitations', 'event_id', 'events', 'CASCADE');
+SELECT sherpa_reset_fk('event_invitations', 'inviter_id', 'users', 'CASCADE');
+SELECT sherpa_reset_fk('event_invitations', 'user_id', 'users', 'CASCADE');
+SELECT sherpa_reset_fk('tasks', 'event_id', 'events', 'CASCADE');
+SELECT sherpa_reset_fk('tasks', 'assignee_id', 'users', 'SET NULL');
+SELECT sherpa_reset_fk('budgets', 'event_id', 'events', 'CASCADE');
+SELECT sherpa_reset_fk('meetings', 'event_id', 'events', 'CASCADE');
+SELECT sherpa_reset_fk('tickets', 'event_id', 'events', 'CASCADE');
+SELECT sherpa_reset_fk('event_participants', 'ticket_id', 'tickets', 'CASCADE');
+SELECT sherpa_reset_fk('event_participants', 'user_id', 'users', 'CASCADE');
+
+-- チャット（チャンネルを物理削除するとメッセージ・メンバー・リアクションも消える）
+SELECT sherpa_reset_fk('channels', 'event_id', 'events', 'CASCADE');
+SELECT sherpa_reset_fk('channel_members', 'channel_id', 'channels', 'CASCADE');
+SELECT sherpa_reset_fk('channel_members', 'user_id', 'users', 'CASCADE');
+SELECT sherpa_reset_fk('messages', 'channel_id', 'channels', 'CASCADE');
+SELECT sherpa_reset_fk('messages', 'user_id', 'users', 'CASCADE');
+SELECT sherpa_reset_fk('messages', 'parent_message_id', 'messages', 'CASCADE');
+SELECT sherpa_reset_fk('message_reactions', 'message_id', 'messages', 'CASCADE');
+SELECT sherpa_reset_fk('message_reactions', 'user_id', 'users', 'CASCADE');
+
+DROP FUNCTION sherpa_reset_fk(TEXT, TEXT, TEXT, TEXT);
+
+-- ==========================================
+-- 2. メンバーの一意性
+-- ==========================================
+-- 論理削除した行があっても再登録できるよう、一意制約は未削除の行だけに掛ける
+
+-- 重複は最も権限の強い行（同じなら最古）を残して論理削除する
+UPDATE event_staffs SET deleted_at = CURRENT_TIMESTAMP
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (
+            PARTITION BY event_id, user_id
+            ORDER BY CASE role WHEN 'Admin' THEN 0 WHEN 'Staff' THEN 1 ELSE 2 END, id
+        ) AS rn
+        FROM event_staffs WHERE deleted_at IS NULL
+    ) d WHERE rn > 1
+);
+
+UPDATE channel_members SET deleted_at = CURRENT_TIMESTAMP
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (PARTITION BY channel_id, user_id ORDER BY id) AS rn
+        FROM channel_members WHERE deleted_at IS NULL
+    ) d WHERE rn > 1
+);
+
+UPDATE organization_members SET deleted_at = CURRENT_TIMESTAMP
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (
+            PARTITION BY organization_id, user_id
+            ORDER BY CASE status WHEN 'active' THEN 0 ELSE 1 END,
+                     CASE role WHEN 'owner' THEN 0 WHEN 'admin' THEN 1 ELSE 2 END, id
+        ) AS rn
+        FROM organization_members WHERE deleted_at IS NULL
+    ) d WHERE rn > 1
+);
+
+ALTER TABLE event_staffs DROP CONSTRAINT IF EXISTS event_staffs_event_id_user_id_key;
+ALTER TABLE channel_members DROP CONSTRAINT IF EXISTS channel_members_channel_id_user_id_key;
+ALTER TABLE organization_members DROP CONSTRAINT IF EXISTS organization_members_user_id_organization_id_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_staffs_event_user ON event_staffs(event_id, user_id) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_channel_members_channel_user ON channel_members(channel_id, user_id) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organization_members_org_user ON organization_members(organization_id, user_id) WHERE deleted_at IS NULL;
@@ -0,0 +1,8 @@
+-- 0004_meeting_agenda_attendees_minutes の取り消し
+
+DROP TABLE IF EXISTS meeting_minutes_revisions;
+DROP TABLE IF EXISTS meeting_attendees;
+DROP TABLE IF EXISTS meeting_agenda_items;
+
+ALTER TABLE meetings DROP COLUMN IF EXISTS location;
+ALTER TABLE meetings DROP COLUMN IF EXISTS end_at;
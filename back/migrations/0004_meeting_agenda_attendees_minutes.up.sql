@@ -0,0 +1,58 @@
+-- 0004: 会議のアジェンダ・参加者（出欠）・議事録の版
+
+ALTER TABLE meetings ADD COLUMN end_at TIMESTAMP;
+ALTER TABLE meetings ADD COLUMN location TEXT;
+
+CREATE TABLE meeting_agenda_items (
+    id SERIAL PRIMARY KEY,
+    meeting_id INTEGER NOT NULL REFERENCES meetings(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL DEFAULT 0,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    duration_minutes INTEGER NOT NULL DEFAULT 0,
+    presenter_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (duration_minutes >= 0)
+);
+
+CREATE INDEX idx_meeting_agenda_items_meeting_id ON meeting_agenda_items(meeting_id, position);
+CREATE INDEX idx_meeting_agenda_items_presenter_id ON meeting_agenda_items(presenter_id);
+
+CREATE TABLE meeting_attendees (
+    id SERIAL PRIMARY KEY,
+    meeting_id INTEGER NOT NULL REFERENCES meetings(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rsvp VARCHAR(20) NOT NULL DEFAULT 'pending',
+    responded_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (meeting_id, user_id),
+    CHECK (rsvp IN ('pending', 'accepted', 'tentative', 'declined'))
+);
+
+CREATE INDEX idx_meeting_attendees_user_id ON meeting_attendees(user_id);
+
+CREATE TABLE meeting_minutes_revisions (
+    id SERIAL PRIMARY KEY,
+    meeting_id INTEGER NOT NULL REFERENCES meetings(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    editor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (meeting_id, revision)
+);
+
+CREATE INDEX idx_meeting_minutes_revisions_editor_id ON meeting_minutes_revisions(editor_id);
+
+-- 既存の議事録を第1版として残す
+INSERT INTO meeting_minutes_revisions (meeting_id, revision, content, created_at)
+SELECT id, 1, minutes, updated_at FROM meetings WHERE minutes IS NOT NULL AND minutes <> '';
+
+DROP TRIGGER IF EXISTS update_meeting_agenda_items_updated_at ON meeting_agenda_items;
+CREATE TRIGGER update_meeting_agenda_items_updated_at BEFORE UPDATE ON meeting_agenda_items
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_meeting_attendees_updated_at ON meeting_attendees;
+CREATE TRIGGER update_meeting_attendees_updated_at BEFORE UPDATE ON meeting_attendees
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
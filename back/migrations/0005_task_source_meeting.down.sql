@@ -0,0 +1,3 @@
+-- 0005_task_source_meeting の取り消し
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS source_meeting_id;
@@ -0,0 +1,5 @@
+-- 0005: 議事録から作ったタスクの元の会議
+
+ALTER TABLE tasks ADD COLUMN source_meeting_id INTEGER REFERENCES meetings(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_tasks_source_meeting_id ON tasks(source_meeting_id);
@@ -0,0 +1,13 @@
+-- 0006_ticket_sales_and_registration の取り消し
+
+DROP INDEX IF EXISTS idx_event_participants_ticket_status;
+
+ALTER TABLE event_participants DROP CONSTRAINT IF EXISTS chk_event_participants_status;
+ALTER TABLE event_participants DROP COLUMN IF EXISTS cancelled_at;
+ALTER TABLE event_participants DROP COLUMN IF EXISTS confirmed_at;
+
+ALTER TABLE tickets DROP CONSTRAINT IF EXISTS chk_tickets_sales_window;
+ALTER TABLE tickets DROP CONSTRAINT IF EXISTS chk_tickets_quantity;
+ALTER TABLE tickets DROP COLUMN IF EXISTS sales_end_at;
+ALTER TABLE tickets DROP COLUMN IF EXISTS sales_start_at;
+ALTER TABLE tickets DROP COLUMN IF EXISTS quantity;
@@ -0,0 +1,20 @@
+-- 0006: チケット種別の販売枚数・販売期間と参加登録のステータス遷移日時
+
+ALTER TABLE tickets ADD COLUMN quantity INTEGER;
+ALTER TABLE tickets ADD COLUMN sales_start_at TIMESTAMP;
+ALTER TABLE tickets ADD COLUMN sales_end_at TIMESTAMP;
+ALTER TABLE tickets ADD CONSTRAINT chk_tickets_quantity CHECK (quantity IS NULL OR quantity >= 0);
+ALTER TABLE tickets ADD CONSTRAINT chk_tickets_sales_window
+    CHECK (sales_start_at IS NULL OR sales_end_at IS NULL OR sales_start_at < sales_end_at);
+
+ALTER TABLE event_participants ADD COLUMN confirmed_at TIMESTAMP;
+ALTER TABLE event_participants ADD COLUMN cancelled_at TIMESTAMP;
+
+UPDATE event_participants SET confirmed_at = updated_at WHERE status = 'confirmed';
+UPDATE event_participants SET cancelled_at = updated_at WHERE status = 'cancelled';
+
+ALTER TABLE event_participants ADD CONSTRAINT chk_event_participants_status
+    CHECK (status IN ('pending', 'confirmed', 'cancelled'));
+
+-- 定員チェック（チケットごとの有効な登録数）用
+CREATE INDEX idx_event_participants_ticket_status ON event_participants(ticket_id, status) WHERE deleted_at IS NULL;
@@ -0,0 +1,14 @@
+-- 0007_participant_waitlist の取り消し（キャンセル待ち・繰り上げ中の登録は取り消し扱いにする）
+
+DROP INDEX IF EXISTS idx_event_participants_offer_expires_at;
+DROP INDEX IF EXISTS idx_event_participants_waitlist;
+
+UPDATE event_participants SET status = 'cancelled', cancelled_at = CURRENT_TIMESTAMP
+WHERE status IN ('waitlisted', 'offered');
+
+ALTER TABLE event_participants DROP CONSTRAINT IF EXISTS chk_event_participants_status;
+ALTER TABLE event_participants ADD CONSTRAINT chk_event_participants_status
+    CHECK (status IN ('pending', 'confirmed', 'cancelled'));
+
+ALTER TABLE event_participants DROP COLUMN IF EXISTS offer_expires_at;
+ALTER TABLE event_participants DROP COLUMN IF EXISTS waitlisted_at;
@@ -0,0 +1,14 @@
+-- 0007: 参加登録のキャンセル待ちと繰り上げの確定期限
+
+ALTER TABLE event_participants ADD COLUMN waitlisted_at TIMESTAMP;
+ALTER TABLE event_participants ADD COLUMN offer_expires_at TIMESTAMP;
+
+ALTER TABLE event_participants DROP CONSTRAINT IF EXISTS chk_event_participants_status;
+ALTER TABLE event_participants ADD CONSTRAINT chk_event_participants_status
+    CHECK (status IN ('pending', 'confirmed', 'cancelled', 'waitlisted', 'offered'));
+
+-- 繰り上げる順番（チケットごとに古い順）と期限切れの検出用
+CREATE INDEX idx_event_participants_waitlist ON event_participants(ticket_id, waitlisted_at, id)
+    WHERE status = 'waitlisted' AND deleted_at IS NULL;
+CREATE INDEX idx_event_participants_offer_expires_at ON event_participants(offer_expires_at)
+    WHERE status = 'offered' AND deleted_at IS NULL;
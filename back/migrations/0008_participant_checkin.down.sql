@@ -0,0 +1,6 @@
+-- 0008_participant_checkin の取り消し
+
+DROP INDEX IF EXISTS idx_event_participants_checked_in;
+
+ALTER TABLE event_participants DROP COLUMN IF EXISTS checked_in_by;
+ALTER TABLE event_participants DROP COLUMN IF EXISTS checked_in_at;
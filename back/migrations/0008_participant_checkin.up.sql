@@ -0,0 +1,8 @@
+-- 0008: 参加者の当日受付（QR チェックイン）
+
+ALTER TABLE event_participants ADD COLUMN checked_in_at TIMESTAMP;
+ALTER TABLE event_participants ADD COLUMN checked_in_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+-- 受付済み人数の集計用
+CREATE INDEX idx_event_participants_checked_in ON event_participants(ticket_id)
+    WHERE checked_in_at IS NOT NULL AND deleted_at IS NULL;
@@ -0,0 +1,7 @@
+-- 0009_ticket_orders の取り消し（自動計上した売上項目は通常の予算項目として残る）
+
+DROP INDEX IF EXISTS idx_budgets_ticket_id;
+ALTER TABLE budgets DROP COLUMN IF EXISTS ticket_id;
+
+DROP TABLE IF EXISTS payment_webhook_events;
+DROP TABLE IF EXISTS orders;
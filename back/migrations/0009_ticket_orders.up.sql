@@ -0,0 +1,47 @@
+-- 0009: 有料チケットの注文・決済 Webhook の処理記録・チケット売上の自動計上
+
+CREATE TABLE orders (
+    id SERIAL PRIMARY KEY,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    ticket_id INTEGER NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+    event_participant_id INTEGER NOT NULL REFERENCES event_participants(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount INTEGER NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'jpy',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    provider VARCHAR(32) NOT NULL,
+    checkout_id TEXT,
+    checkout_url TEXT,
+    payment_id TEXT,
+    expires_at TIMESTAMP,
+    paid_at TIMESTAMP,
+    refunded_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (amount > 0),
+    CHECK (status IN ('pending', 'paid', 'expired', 'failed', 'refunded'))
+);
+
+CREATE INDEX idx_orders_event_id ON orders(event_id);
+CREATE INDEX idx_orders_ticket_id ON orders(ticket_id);
+CREATE INDEX idx_orders_event_participant_id ON orders(event_participant_id);
+CREATE INDEX idx_orders_user_id ON orders(user_id);
+CREATE UNIQUE INDEX idx_orders_provider_checkout_id ON orders(provider, checkout_id) WHERE checkout_id <> '';
+CREATE INDEX idx_orders_provider_payment_id ON orders(provider, payment_id) WHERE payment_id <> '';
+
+DROP TRIGGER IF EXISTS update_orders_updated_at ON orders;
+CREATE TRIGGER update_orders_updated_at BEFORE UPDATE ON orders
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TABLE payment_webhook_events (
+    id SERIAL PRIMARY KEY,
+    provider VARCHAR(32) NOT NULL,
+    event_id TEXT NOT NULL,
+    type VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, event_id)
+);
+
+-- チケット種別ごとの売上（収入）項目。1チケットにつき1項目
+ALTER TABLE budgets ADD COLUMN ticket_id INTEGER REFERENCES tickets(id) ON DELETE SET NULL;
+CREATE UNIQUE INDEX idx_budgets_ticket_id ON budgets(ticket_id) WHERE ticket_id IS NOT NULL AND deleted_at IS NULL;
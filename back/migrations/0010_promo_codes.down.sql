@@ -0,0 +1,7 @@
+-- 0010_promo_codes の取り消し
+
+ALTER TABLE event_participants DROP COLUMN IF EXISTS discount;
+ALTER TABLE event_participants DROP COLUMN IF EXISTS promo_code_id;
+
+DROP TABLE IF EXISTS promo_code_redemptions;
+DROP TABLE IF EXISTS promo_codes;
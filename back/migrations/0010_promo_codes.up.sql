@@ -0,0 +1,58 @@
+-- 0010: チケットの割引コード（学生・スポンサー向けなど）と利用記録
+
+CREATE TABLE promo_codes (
+    id SERIAL PRIMARY KEY,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    ticket_id INTEGER REFERENCES tickets(id) ON DELETE CASCADE,
+    code VARCHAR(32) NOT NULL,
+    description TEXT,
+    discount_type VARCHAR(20) NOT NULL,
+    discount_value INTEGER NOT NULL,
+    max_redemptions INTEGER,
+    per_user_limit INTEGER,
+    redemption_count INTEGER NOT NULL DEFAULT 0,
+    valid_from TIMESTAMP,
+    valid_until TIMESTAMP,
+    created_by INTEGER NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP,
+    CHECK (discount_type IN ('percent', 'fixed')),
+    CHECK (discount_value > 0),
+    CHECK (discount_type <> 'percent' OR discount_value <= 100),
+    CHECK (max_redemptions IS NULL OR max_redemptions >= 0),
+    CHECK (per_user_limit IS NULL OR per_user_limit >= 1),
+    -- 利用回数は行ロックを取って数えるが、上限を超えないことを DB でも保証する
+    CHECK (max_redemptions IS NULL OR redemption_count <= max_redemptions),
+    CHECK (valid_until IS NULL OR valid_from IS NULL OR valid_until > valid_from)
+);
+
+CREATE INDEX idx_promo_codes_event_id ON promo_codes(event_id);
+CREATE INDEX idx_promo_codes_ticket_id ON promo_codes(ticket_id);
+CREATE INDEX idx_promo_codes_deleted_at ON promo_codes(deleted_at);
+-- コードはイベント内で一意（削除済みは除く）
+CREATE UNIQUE INDEX idx_promo_codes_event_code ON promo_codes(event_id, code) WHERE deleted_at IS NULL;
+
+DROP TRIGGER IF EXISTS update_promo_codes_updated_at ON promo_codes;
+CREATE TRIGGER update_promo_codes_updated_at BEFORE UPDATE ON promo_codes
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TABLE promo_code_redemptions (
+    id SERIAL PRIMARY KEY,
+    promo_code_id INTEGER NOT NULL REFERENCES promo_codes(id) ON DELETE CASCADE,
+    event_participant_id INTEGER NOT NULL REFERENCES event_participants(id) ON DELETE CASCADE,
+    ticket_id INTEGER NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    price INTEGER NOT NULL,
+    discount INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (discount >= 0 AND discount <= price)
+);
+
+CREATE INDEX idx_promo_code_redemptions_promo_code_user ON promo_code_redemptions(promo_code_id, user_id);
+CREATE INDEX idx_promo_code_redemptions_event_participant_id ON promo_code_redemptions(event_participant_id);
+CREATE INDEX idx_promo_code_redemptions_user_id ON promo_code_redemptions(user_id);
+
+-- 参加登録に使った割引コードと割引額（支払額はチケット価格 - discount）
+ALTER TABLE event_participants ADD COLUMN promo_code_id INTEGER REFERENCES promo_codes(id) ON DELETE SET NULL;
+ALTER TABLE event_participants ADD COLUMN discount INTEGER NOT NULL DEFAULT 0;
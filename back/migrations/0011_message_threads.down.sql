@@ -0,0 +1,5 @@
+-- 0011_message_threads の取り消し
+
+DROP INDEX IF EXISTS idx_messages_thread;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS also_sent_to_channel;
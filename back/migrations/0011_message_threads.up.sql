@@ -0,0 +1,7 @@
+-- 0011: スレッド返信の「チャンネルにも送信」
+
+ALTER TABLE messages ADD COLUMN also_sent_to_channel BOOLEAN NOT NULL DEFAULT false;
+
+-- スレッドの返信一覧・集計用
+CREATE INDEX idx_messages_thread ON messages(parent_message_id, created_at)
+    WHERE parent_message_id IS NOT NULL AND deleted_at IS NULL;
@@ -0,0 +1,3 @@
+-- 0012_message_mentions の取り消し
+
+DROP TABLE IF EXISTS message_mentions;
@@ -0,0 +1,16 @@
+-- 0012: チャットのメンション（@名前・@channel・@here）
+
+CREATE TABLE message_mentions (
+    id SERIAL PRIMARY KEY,
+    message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    channel_id INTEGER NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (kind IN ('user', 'channel', 'here'))
+);
+
+-- @channel などは展開して1人1件にする
+CREATE UNIQUE INDEX idx_message_mentions_message_user ON message_mentions(message_id, user_id);
+-- メンション一覧（新しい順）用
+CREATE INDEX idx_message_mentions_user ON message_mentions(user_id, id);
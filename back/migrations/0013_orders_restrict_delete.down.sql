@@ -0,0 +1,21 @@
+-- 0013_orders_restrict_delete の取り消し（0009 / 0010 の ON DELETE CASCADE に戻す）
+
+ALTER TABLE promo_code_redemptions
+    DROP CONSTRAINT promo_code_redemptions_promo_code_id_fkey,
+    DROP CONSTRAINT promo_code_redemptions_event_participant_id_fkey,
+    DROP CONSTRAINT promo_code_redemptions_ticket_id_fkey,
+    DROP CONSTRAINT promo_code_redemptions_user_id_fkey,
+    ADD CONSTRAINT promo_code_redemptions_promo_code_id_fkey FOREIGN KEY (promo_code_id) REFERENCES promo_codes(id) ON DELETE CASCADE,
+    ADD CONSTRAINT promo_code_redemptions_event_participant_id_fkey FOREIGN KEY (event_participant_id) REFERENCES event_participants(id) ON DELETE CASCADE,
+    ADD CONSTRAINT promo_code_redemptions_ticket_id_fkey FOREIGN KEY (ticket_id) REFERENCES tickets(id) ON DELETE CASCADE,
+    ADD CONSTRAINT promo_code_redemptions_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE orders
+    DROP CONSTRAINT orders_event_id_fkey,
+    DROP CONSTRAINT orders_ticket_id_fkey,
+    DROP CONSTRAINT orders_event_participant_id_fkey,
+    DROP CONSTRAINT orders_user_id_fkey,
+    ADD CONSTRAINT orders_event_id_fkey FOREIGN KEY (event_id) REFERENCES events(id) ON DELETE CASCADE,
+    ADD CONSTRAINT orders_ticket_id_fkey FOREIGN KEY (ticket_id) REFERENCES tickets(id) ON DELETE CASCADE,
+    ADD CONSTRAINT orders_event_participant_id_fkey FOREIGN KEY (event_participant_id) REFERENCES event_participants(id) ON DELETE CASCADE,
+    ADD CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
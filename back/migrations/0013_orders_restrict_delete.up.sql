@@ -0,0 +1,22 @@
+-- 0013: 注文・割引コードの利用記録は金額の記録なので、イベント・チケット・参加登録・ユーザーの物理削除で消さない
+-- 参照先を物理削除するときは先に注文を整理する必要がある（batch.CleanupMemberLessEvents は注文のあるイベントを残す）
+
+ALTER TABLE orders
+    DROP CONSTRAINT orders_event_id_fkey,
+    DROP CONSTRAINT orders_ticket_id_fkey,
+    DROP CONSTRAINT orders_event_participant_id_fkey,
+    DROP CONSTRAINT orders_user_id_fkey,
+    ADD CONSTRAINT orders_event_id_fkey FOREIGN KEY (event_id) REFERENCES events(id) ON DELETE RESTRICT,
+    ADD CONSTRAINT orders_ticket_id_fkey FOREIGN KEY (ticket_id) REFERENCES tickets(id) ON DELETE RESTRICT,
+    ADD CONSTRAINT orders_event_participant_id_fkey FOREIGN KEY (event_participant_id) REFERENCES event_participants(id) ON DELETE RESTRICT,
+    ADD CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
+
+ALTER TABLE promo_code_redemptions
+    DROP CONSTRAINT promo_code_redemptions_promo_code_id_fkey,
+    DROP CONSTRAINT promo_code_redemptions_event_participant_id_fkey,
+    DROP CONSTRAINT promo_code_redemptions_ticket_id_fkey,
+    DROP CONSTRAINT promo_code_redemptions_user_id_fkey,
+    ADD CONSTRAINT promo_code_redemptions_promo_code_id_fkey FOREIGN KEY (promo_code_id) REFERENCES promo_codes(id) ON DELETE RESTRICT,
+    ADD CONSTRAINT promo_code_redemptions_event_participant_id_fkey FOREIGN KEY (event_participant_id) REFERENCES event_participants(id) ON DELETE RESTRICT,
+    ADD CONSTRAINT promo_code_redemptions_ticket_id_fkey FOREIGN KEY (ticket_id) REFERENCES tickets(id) ON DELETE RESTRICT,
+    ADD CONSTRAINT promo_code_redemptions_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
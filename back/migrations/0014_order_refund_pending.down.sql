@@ -0,0 +1,6 @@
+-- 0014_order_refund_pending の取り消し（返金待ちの注文は支払い済みに戻す。売上には計上されていない）
+
+UPDATE orders SET status = 'paid' WHERE status = 'refund_pending';
+ALTER TABLE orders DROP CONSTRAINT orders_status_check;
+ALTER TABLE orders ADD CONSTRAINT orders_status_check
+    CHECK (status IN ('pending', 'paid', 'expired', 'failed', 'refunded'));
@@ -0,0 +1,5 @@
+-- 0014: 支払われたが席を割り当てない注文（取り消し済みの登録・別の注文で支払い済みの登録）の返金待ちステータス
+
+ALTER TABLE orders DROP CONSTRAINT orders_status_check;
+ALTER TABLE orders ADD CONSTRAINT orders_status_check
+    CHECK (status IN ('pending', 'paid', 'expired', 'failed', 'refund_pending', 'refunded'));
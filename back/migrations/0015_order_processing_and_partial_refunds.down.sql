@@ -0,0 +1,9 @@
+-- 0015_order_processing_and_partial_refunds の取り消し（入金待ちの注文は支払い待ちに戻す。一部返金の記録は失われる）
+
+UPDATE orders SET status = 'pending' WHERE status = 'processing';
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_refunded_amount_check;
+ALTER TABLE orders DROP CONSTRAINT orders_status_check;
+ALTER TABLE orders ADD CONSTRAINT orders_status_check
+    CHECK (status IN ('pending', 'paid', 'expired', 'failed', 'refund_pending', 'refunded'));
+
+ALTER TABLE orders DROP COLUMN IF EXISTS refunded_amount;
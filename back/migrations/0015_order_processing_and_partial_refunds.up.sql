@@ -0,0 +1,9 @@
+-- 0015: 入金待ち（コンビニ払いなど、決済画面の完了後に入金される支払い）の注文ステータスと、一部返金の累計額
+
+ALTER TABLE orders ADD COLUMN refunded_amount INTEGER NOT NULL DEFAULT 0;
+UPDATE orders SET refunded_amount = amount WHERE status = 'refunded';
+
+ALTER TABLE orders DROP CONSTRAINT orders_status_check;
+ALTER TABLE orders ADD CONSTRAINT orders_status_check
+    CHECK (status IN ('pending', 'processing', 'paid', 'expired', 'failed', 'refund_pending', 'refunded'));
+ALTER TABLE orders ADD CONSTRAINT orders_refunded_amount_check CHECK (refunded_amount BETWEEN 0 AND amount);
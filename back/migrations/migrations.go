@@ -0,0 +1,10 @@
+// Package migrations 番号付きの SQL マイグレーション。
+// ファイル名は NNNN_name.up.sql / NNNN_name.down.sql で、バイナリに埋め込んで internal/migrate から適用する。
+package migrations
+
+import "embed"
+
+// FS マイグレーションファイル一式
+//
+//go:embed *.sql
+var FS embed.FS
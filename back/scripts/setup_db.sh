@@ -25,13 +25,9 @@ psql -h $DB_HOST -p $DB_PORT -U $DB_USER -d postgres -tc "SELECT 1 FROM pg_datab
 
 echo "Database '$DB_NAME' created or already exists"
 
-# マイグレーションの実行
-if [ -f migrations/001_initial_schema.sql ]; then
-    echo "Running migrations..."
-    PGPASSWORD=$DB_PASSWORD psql -h $DB_HOST -p $DB_PORT -U $DB_USER -d $DB_NAME -f migrations/001_initial_schema.sql
-    echo "Migrations completed successfully"
-else
-    echo "Warning: Migration file not found"
-fi
+# マイグレーションの実行（migrations/*.up.sql のうち未適用のもの）
+echo "Running migrations..."
+go run ./cmd/migrate up
+echo "Migrations completed successfully"
 
 echo "Database setup completed!"
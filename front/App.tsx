@@ -303,8 +303,8 @@ const App: React.FC = () => {
   if (!user) {
     return (
       <CreateUserPage
-        onLogin={async (token) => {
-          await login(token);
+        onLogin={async (token, refreshToken) => {
+          await login(token, refreshToken);
         }}
       />
     );
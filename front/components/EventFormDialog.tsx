@@ -67,7 +67,6 @@ const EventFormDialog: React.FC<EventFormDialogProps> = ({
     setCreating(true);
     try {
       const { event } = await apiClient.createEvent({
-        organization_id: 1,
         title: formData.title,
         start_at: toRFC3339(formData.start_at),
         end_at: toRFC3339(formData.end_at),
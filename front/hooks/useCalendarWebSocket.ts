@@ -1,4 +1,5 @@
 import { useEffect, useRef, useCallback } from 'react';
+import { apiClient } from '../services/api';
 
 const WS_BASE = (() => {
   const u = import.meta.env.VITE_API_URL || 'http://localhost:3001';
@@ -20,10 +21,32 @@ export function useCalendarWebSocket(eventId: number | null, token: string | nul
   tokenRef.current = token;
   eventIdRef.current = eventId;
 
-  const connect = useCallback(() => {
+  const connectRef = useRef<() => Promise<void>>(async () => {});
+  const epochRef = useRef(0);
+
+  const scheduleReconnect = useCallback(() => {
+    if (!tokenRef.current || eventIdRef.current == null) return;
+    const delay = reconnectDelayRef.current;
+    reconnectDelayRef.current = Math.min(MAX_RECONNECT_DELAY_MS, delay * 2);
+    reconnectTimeoutRef.current = setTimeout(() => connectRef.current(), delay);
+  }, []);
+
+  const connect = useCallback(async () => {
     if (!tokenRef.current || eventIdRef.current == null) return;
 
-    const url = `${WS_BASE}/api/ws?token=${encodeURIComponent(tokenRef.current)}`;
+    // JWT を URL に載せないよう、接続のたびに使い捨てチケットを取得する
+    const epoch = epochRef.current;
+    let ticket: string;
+    try {
+      ticket = (await apiClient.getWSTicket()).ticket;
+    } catch {
+      if (epoch === epochRef.current) scheduleReconnect();
+      return;
+    }
+    // チケット取得中に切断された場合は接続しない
+    if (!tokenRef.current || eventIdRef.current == null || epoch !== epochRef.current) return;
+
+    const url = `${WS_BASE}/api/ws?ticket=${encodeURIComponent(ticket)}`;
     const ws = new WebSocket(url);
     wsRef.current = ws;
 
@@ -37,10 +60,7 @@ export function useCalendarWebSocket(eventId: number | null, token: string | nul
 
     ws.onclose = () => {
       wsRef.current = null;
-      if (!tokenRef.current || eventIdRef.current == null) return;
-      const delay = reconnectDelayRef.current;
-      reconnectDelayRef.current = Math.min(MAX_RECONNECT_DELAY_MS, delay * 2);
-      reconnectTimeoutRef.current = setTimeout(connect, delay);
+      if (epoch === epochRef.current) scheduleReconnect();
     };
 
     ws.onmessage = (ev) => {
@@ -53,9 +73,11 @@ export function useCalendarWebSocket(eventId: number | null, token: string | nul
         // ignore parse errors
       }
     };
-  }, []);
+  }, [scheduleReconnect]);
+  connectRef.current = connect;
 
   useEffect(() => {
+    epochRef.current += 1;
     if (!token || eventId == null) {
       if (reconnectTimeoutRef.current) {
         clearTimeout(reconnectTimeoutRef.current);
@@ -78,6 +100,7 @@ export function useCalendarWebSocket(eventId: number | null, token: string | nul
     connect();
 
     return () => {
+      epochRef.current += 1;
       if (reconnectTimeoutRef.current) {
         clearTimeout(reconnectTimeoutRef.current);
         reconnectTimeoutRef.current = null;
@@ -1,5 +1,6 @@
 import { useState, useEffect, useRef, useCallback } from 'react';
 import type { Message, MessageReaction } from '../types';
+import { apiClient } from '../services/api';
 
 const WS_BASE = (() => {
   const u = import.meta.env.VITE_API_URL || 'http://localhost:3001';
@@ -34,9 +35,33 @@ export function useChatWebSocket(token: string | null) {
   const tokenRef = useRef(token);
   tokenRef.current = token;
 
-  const connect = useCallback(() => {
+  const connectRef = useRef<() => Promise<void>>(async () => {});
+  const epochRef = useRef(0);
+
+  const scheduleReconnect = useCallback(() => {
+    if (!tokenRef.current) return;
+    const delay = reconnectDelayRef.current;
+    reconnectDelayRef.current = Math.min(
+      MAX_RECONNECT_DELAY_MS,
+      delay * 2
+    );
+    reconnectTimeoutRef.current = setTimeout(() => connectRef.current(), delay);
+  }, []);
+
+  const connect = useCallback(async () => {
     if (!tokenRef.current) return;
-    const url = `${WS_BASE}/api/ws?token=${encodeURIComponent(tokenRef.current)}`;
+    // JWT を URL に載せないよう、接続のたびに使い捨てチケットを取得する
+    const epoch = epochRef.current;
+    let ticket: string;
+    try {
+      ticket = (await apiClient.getWSTicket()).ticket;
+    } catch {
+      if (epoch === epochRef.current) scheduleReconnect();
+      return;
+    }
+    // チケット取得中に切断された場合は接続しない
+    if (!tokenRef.current || epoch !== epochRef.current) return;
+    const url = `${WS_BASE}/api/ws?ticket=${encodeURIComponent(ticket)}`;
     const ws = new WebSocket(url);
     wsRef.current = ws;
 
@@ -53,13 +78,7 @@ export function useChatWebSocket(token: string | null) {
     ws.onclose = () => {
       setConnected(false);
       wsRef.current = null;
-      if (!tokenRef.current) return;
-      const delay = reconnectDelayRef.current;
-      reconnectDelayRef.current = Math.min(
-        MAX_RECONNECT_DELAY_MS,
-        delay * 2
-      );
-      reconnectTimeoutRef.current = setTimeout(connect, delay);
+      if (epoch === epochRef.current) scheduleReconnect();
     };
 
     ws.onerror = () => {
@@ -88,9 +107,11 @@ export function useChatWebSocket(token: string | null) {
         setLastError('メッセージの解析に失敗しました');
       }
     };
-  }, []);
+  }, [scheduleReconnect]);
+  connectRef.current = connect;
 
   const disconnect = useCallback(() => {
+    epochRef.current += 1;
     if (reconnectTimeoutRef.current) {
       clearTimeout(reconnectTimeoutRef.current);
       reconnectTimeoutRef.current = null;
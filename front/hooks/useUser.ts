@@ -1,8 +1,7 @@
 import { useState, useEffect, useCallback } from 'react';
 import { User } from '../types';
-import { apiClient } from '../services/api';
+import { apiClient, saveTokens, clearTokens, TOKEN_KEY } from '../services/api';
 
-const TOKEN_KEY = 'sherpa_token';
 const USER_KEY = 'sherpa_user';
 
 function loadStoredUser(): User | null {
@@ -43,7 +42,7 @@ export const useUser = () => {
       setUserState(fetchedUser);
       saveStoredUser(fetchedUser);
     } catch {
-      localStorage.removeItem(TOKEN_KEY);
+      clearTokens();
     } finally {
       setLoading(false);
     }
@@ -57,8 +56,8 @@ export const useUser = () => {
     setUserState(u);
   }, []);
 
-  const login = useCallback(async (token: string) => {
-    localStorage.setItem(TOKEN_KEY, token);
+  const login = useCallback(async (token: string, refreshToken?: string | null) => {
+    saveTokens(token, refreshToken);
     const { user: fetchedUser } = await apiClient.getMe();
     setUserState(fetchedUser);
     saveStoredUser(fetchedUser);
@@ -66,7 +65,8 @@ export const useUser = () => {
   }, []);
 
   const logout = useCallback(() => {
-    localStorage.removeItem(TOKEN_KEY);
+    apiClient.logout().catch(() => {});
+    clearTokens();
     setUserState(null);
     saveStoredUser(null);
   }, []);
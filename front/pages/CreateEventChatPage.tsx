@@ -103,7 +103,6 @@ const CreateEventChatPage: React.FC<CreateEventChatPageProps> = ({ onClose, onEv
     setCreating(true);
     try {
       const { event } = await apiClient.createEvent({
-        organization_id: 1,
         title: suggestedEvent.title,
         start_at: suggestedEvent.start_at,
         end_at: suggestedEvent.end_at,
@@ -1,7 +1,8 @@
 import React, { useState, useEffect } from 'react';
+import { apiClient } from '../services/api';
 
 interface CreateUserPageProps {
-  onLogin: (token: string) => Promise<void>;
+  onLogin: (token: string, refreshToken?: string | null) => Promise<void>;
 }
 
 const CreateUserPage: React.FC<CreateUserPageProps> = ({ onLogin }) => {
@@ -10,14 +11,16 @@ const CreateUserPage: React.FC<CreateUserPageProps> = ({ onLogin }) => {
 
   useEffect(() => {
     const params = new URLSearchParams(window.location.search);
-    const token = params.get('token');
-    if (!token) return;
+    const code = params.get('code');
+    if (!code) return;
+    // コードは1回限りなので、交換前に URL から消しておく
+    window.history.replaceState({}, '', window.location.pathname || '/');
     let cancelled = false;
     const run = async () => {
       setLoading(true);
       try {
-        await onLogin(token);
-        if (!cancelled) window.history.replaceState({}, '', window.location.pathname || '/');
+        const tokens = await apiClient.exchangeAuthCode(code);
+        await onLogin(tokens.token, tokens.refresh_token);
       } catch (err: unknown) {
         if (!cancelled) setError(err instanceof Error ? err.message : 'ログインに失敗しました');
       } finally {
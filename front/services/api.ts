@@ -10,12 +10,57 @@ export class APIError extends Error {
   }
 }
 
-// 共通のfetch関数
+export const TOKEN_KEY = 'sherpa_token';
+export const REFRESH_TOKEN_KEY = 'sherpa_refresh_token';
+
+export function saveTokens(token: string, refreshToken?: string | null) {
+  localStorage.setItem(TOKEN_KEY, token);
+  if (refreshToken) localStorage.setItem(REFRESH_TOKEN_KEY, refreshToken);
+}
+
+export function clearTokens() {
+  localStorage.removeItem(TOKEN_KEY);
+  localStorage.removeItem(REFRESH_TOKEN_KEY);
+}
+
+// 同時に複数の 401 が返っても refresh は1回だけ行う
+let refreshing: Promise<boolean> | null = null;
+
+async function refreshAccessToken(): Promise<boolean> {
+  const refreshToken = localStorage.getItem(REFRESH_TOKEN_KEY);
+  if (!refreshToken) return false;
+  if (!refreshing) {
+    refreshing = (async () => {
+      try {
+        const res = await fetch(`${API_URL}/api/auth/refresh`, {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json' },
+          body: JSON.stringify({ refresh_token: refreshToken }),
+        });
+        if (!res.ok) {
+          clearTokens();
+          return false;
+        }
+        const data: { token: string; refresh_token: string } = await res.json();
+        saveTokens(data.token, data.refresh_token);
+        return true;
+      } catch {
+        return false;
+      } finally {
+        refreshing = null;
+      }
+    })();
+  }
+  return refreshing;
+}
+
+// 共通のfetch関数。アクセストークン期限切れ（401）時は1度だけリフレッシュして再試行する
 async function fetchAPI<T>(
   endpoint: string,
-  options?: RequestInit
+  options?: RequestInit,
+  retried = false
 ): Promise<T> {
-  const token = localStorage.getItem('sherpa_token');
+  const token = localStorage.getItem(TOKEN_KEY);
   const headers: HeadersInit = {
     'Content-Type': 'application/json',
     ...options?.headers,
@@ -29,6 +74,10 @@ async function fetchAPI<T>(
     headers,
   });
 
+  if (response.status === 401 && token && !retried && (await refreshAccessToken())) {
+    return fetchAPI<T>(endpoint, options, true);
+  }
+
   if (!response.ok) {
     const error = await response.json().catch(() => ({ error: response.statusText }));
     throw new APIError(response.status, error.error || response.statusText);
@@ -43,10 +92,31 @@ export const apiClient = {
     return fetchAPI('/api/auth/google');
   },
 
+  // OAuth コールバックで受け取った使い捨てコードをトークンに交換する
+  async exchangeAuthCode(code: string): Promise<{ token: string; refresh_token: string; expires_in: number }> {
+    return fetchAPI('/api/auth/exchange', {
+      method: 'POST',
+      body: JSON.stringify({ code }),
+    });
+  },
+
+  // WebSocket 接続用の使い捨てチケット（30秒）
+  async getWSTicket(): Promise<{ ticket: string; expires_in: number }> {
+    return fetchAPI('/api/ws/ticket', { method: 'POST' });
+  },
+
   async getMe(): Promise<{ user: User }> {
     return fetchAPI('/api/auth/me');
   },
 
+  async logout(): Promise<{ ok: boolean }> {
+    const refreshToken = localStorage.getItem(REFRESH_TOKEN_KEY);
+    return fetchAPI('/api/auth/logout', {
+      method: 'POST',
+      body: JSON.stringify(refreshToken ? { refresh_token: refreshToken } : {}),
+    });
+  },
+
   // ユーザー関連
   async createUser(data: { name: string; email: string }): Promise<{ user: User }> {
     return fetchAPI('/api/users', {
@@ -77,7 +147,7 @@ export const apiClient = {
   },
 
   async createEvent(eventData: {
-    organization_id: number;
+    organization_id?: number; // 省略時はアクティブ組織
     title: string;
     start_at: string;
     end_at: string;
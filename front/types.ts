@@ -6,6 +6,7 @@ export interface User {
   name: string;
   email: string;
   avatar_url?: string;
+  active_organization_id?: number;
   created_at: string;
   updated_at: string;
 }